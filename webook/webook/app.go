@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
 	schedule_service "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/cronJobScheduler/service"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/redisx"
 	"github.com/gin-gonic/gin"
@@ -16,4 +17,6 @@ type App struct {
 	//rankJob   *job.RankingJob
 	// Scheduler
 	cronJobScheduler *schedule_service.CronJobScheduler
+	// 后台导入文章的任务，退出的时候要等它们保存状态
+	articleTransferSvc service.ArticleTransferService
 }
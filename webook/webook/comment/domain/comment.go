@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// Comment 评论。根评论的 RootComment 和 ParentComment 都是 nil
+type Comment struct {
+	Id int64
+	// 谁发的评论
+	Commentator User
+	// 评论的是什么资源，比如说 biz = article, bizId = 文章 ID
+	Biz   string
+	BizId int64
+
+	Content string
+	// 根评论
+	RootComment *Comment
+	// 直接回复的那条评论
+	ParentComment *Comment
+	// 分页查询根评论的时候，会顺带把最早的几条回复放在这里
+	Children []Comment
+
+	Ctime time.Time
+	Utime time.Time
+}
+
+// IsRoot 是不是根评论
+func (c Comment) IsRoot() bool {
+	return c.RootComment == nil
+}
+
+type User struct {
+	Id   int64
+	Name string
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/domain"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+var ErrKeyNotExist = redis.Nil
+
+// CommentCache 只缓存某个资源下面的第一页评论（包含内联的回复）
+// 绝大部分读者只会看第一页
+type CommentCache interface {
+	GetFirstPage(ctx context.Context, biz string, bizId int64) ([]domain.Comment, error)
+	SetFirstPage(ctx context.Context, biz string, bizId int64, cs []domain.Comment) error
+	DelFirstPage(ctx context.Context, biz string, bizId int64) error
+}
+
+type RedisCommentCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewRedisCommentCache(client redis.Cmdable) CommentCache {
+	return &RedisCommentCache{
+		client:     client,
+		expiration: time.Minute * 10,
+	}
+}
+
+func (r *RedisCommentCache) GetFirstPage(ctx context.Context, biz string, bizId int64) ([]domain.Comment, error) {
+	data, err := r.client.Get(ctx, r.firstPageKey(biz, bizId)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var res []domain.Comment
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
+
+func (r *RedisCommentCache) SetFirstPage(ctx context.Context, biz string, bizId int64, cs []domain.Comment) error {
+	data, err := json.Marshal(cs)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.firstPageKey(biz, bizId), data, r.expiration).Err()
+}
+
+func (r *RedisCommentCache) DelFirstPage(ctx context.Context, biz string, bizId int64) error {
+	return r.client.Del(ctx, r.firstPageKey(biz, bizId)).Err()
+}
+
+func (r *RedisCommentCache) firstPageKey(biz string, bizId int64) string {
+	return fmt.Sprintf("comment:first_page:%s:%d", biz, bizId)
+}
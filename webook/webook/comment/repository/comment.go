@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/repository/cache"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/repository/dao"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/ecodeclub/ekit/slice"
+	"golang.org/x/sync/errgroup"
+	"time"
+)
+
+var ErrCommentNotFound = dao.ErrRecordNotFound
+
+// firstPageSize 第一页最多缓存这么多根评论
+const firstPageSize = 50
+
+type CommentRepository interface {
+	Create(ctx context.Context, c domain.Comment) (int64, error)
+	Delete(ctx context.Context, c domain.Comment) (int64, error)
+	FindById(ctx context.Context, id int64) (domain.Comment, error)
+	// FindByBiz 查找根评论，并且每条根评论带上最早的 replyLimit 条回复
+	FindByBiz(ctx context.Context, biz string, bizId int64,
+		minId int64, limit int64, replyLimit int64) ([]domain.Comment, error)
+	FindRepliesByRid(ctx context.Context, rid int64, maxId int64, limit int64) ([]domain.Comment, error)
+}
+
+type CachedCommentRepository struct {
+	dao   dao.CommentDAO
+	cache cache.CommentCache
+	l     logger.Logger
+}
+
+func NewCachedCommentRepository(dao dao.CommentDAO, cache cache.CommentCache,
+	l logger.Logger) CommentRepository {
+	return &CachedCommentRepository{
+		dao:   dao,
+		cache: cache,
+		l:     l,
+	}
+}
+
+func (repo *CachedCommentRepository) Create(ctx context.Context, c domain.Comment) (int64, error) {
+	id, err := repo.dao.Insert(ctx, repo.toEntity(c))
+	if err == nil {
+		repo.delFirstPage(ctx, c.Biz, c.BizId)
+	}
+	return id, err
+}
+
+func (repo *CachedCommentRepository) Delete(ctx context.Context, c domain.Comment) (int64, error) {
+	cnt, err := repo.dao.Delete(ctx, repo.toEntity(c))
+	if err == nil {
+		repo.delFirstPage(ctx, c.Biz, c.BizId)
+	}
+	return cnt, err
+}
+
+func (repo *CachedCommentRepository) FindById(ctx context.Context, id int64) (domain.Comment, error) {
+	c, err := repo.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	return repo.toDomain(c), nil
+}
+
+func (repo *CachedCommentRepository) FindByBiz(ctx context.Context, biz string, bizId int64,
+	minId int64, limit int64, replyLimit int64) ([]domain.Comment, error) {
+	if minId == 0 && limit <= firstPageSize {
+		return repo.firstPage(ctx, biz, bizId, limit, replyLimit)
+	}
+	return repo.findByBiz(ctx, biz, bizId, minId, limit, replyLimit)
+}
+
+// firstPage 第一页总是按照 firstPageSize 来缓存，再按照 limit 截断
+// 这样不管前端一页要多少条，都能命中同一份缓存
+func (repo *CachedCommentRepository) firstPage(ctx context.Context, biz string, bizId int64,
+	limit int64, replyLimit int64) ([]domain.Comment, error) {
+	data, err := repo.cache.GetFirstPage(ctx, biz, bizId)
+	if err != nil {
+		data, err = repo.findByBiz(ctx, biz, bizId, 0, firstPageSize, replyLimit)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			err1 := repo.cache.SetFirstPage(ctx, biz, bizId, data)
+			if err1 != nil {
+				repo.l.Debug("设置评论首页缓存失败", logger.String("biz", biz),
+					logger.Int64("bizId", bizId), logger.Error(err1))
+			}
+		}()
+	}
+	if int64(len(data)) > limit {
+		data = data[:limit]
+	}
+	return data, nil
+}
+
+func (repo *CachedCommentRepository) findByBiz(ctx context.Context, biz string, bizId int64,
+	minId int64, limit int64, replyLimit int64) ([]domain.Comment, error) {
+	roots, err := repo.dao.FindByBiz(ctx, biz, bizId, minId, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Comment, len(roots))
+	var eg errgroup.Group
+	for i, root := range roots {
+		i, root := i, root
+		res[i] = repo.toDomain(root)
+		if replyLimit <= 0 {
+			continue
+		}
+		eg.Go(func() error {
+			replies, err := repo.dao.FindRepliesByRid(ctx, root.Id, 0, replyLimit)
+			if err != nil {
+				return err
+			}
+			res[i].Children = slice.Map[dao.Comment, domain.Comment](replies,
+				func(idx int, src dao.Comment) domain.Comment {
+					return repo.toDomain(src)
+				})
+			return nil
+		})
+	}
+	if err = eg.Wait(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (repo *CachedCommentRepository) FindRepliesByRid(ctx context.Context, rid int64, maxId int64, limit int64) ([]domain.Comment, error) {
+	replies, err := repo.dao.FindRepliesByRid(ctx, rid, maxId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.Comment, domain.Comment](replies, func(idx int, src dao.Comment) domain.Comment {
+		return repo.toDomain(src)
+	}), nil
+}
+
+func (repo *CachedCommentRepository) delFirstPage(ctx context.Context, biz string, bizId int64) {
+	err := repo.cache.DelFirstPage(ctx, biz, bizId)
+	if err != nil {
+		repo.l.Debug("删除评论首页缓存失败", logger.String("biz", biz),
+			logger.Int64("bizId", bizId), logger.Error(err))
+	}
+}
+
+func (repo *CachedCommentRepository) toEntity(c domain.Comment) dao.Comment {
+	res := dao.Comment{
+		Id:      c.Id,
+		Uid:     c.Commentator.Id,
+		Biz:     c.Biz,
+		BizId:   c.BizId,
+		Content: c.Content,
+	}
+	if c.RootComment != nil {
+		res.RootId = sql.NullInt64{Int64: c.RootComment.Id, Valid: true}
+	}
+	if c.ParentComment != nil {
+		res.Pid = sql.NullInt64{Int64: c.ParentComment.Id, Valid: true}
+	}
+	return res
+}
+
+func (repo *CachedCommentRepository) toDomain(c dao.Comment) domain.Comment {
+	res := domain.Comment{
+		Id: c.Id,
+		Commentator: domain.User{
+			Id: c.Uid,
+		},
+		Biz:     c.Biz,
+		BizId:   c.BizId,
+		Content: c.Content,
+		Ctime:   time.UnixMilli(c.Ctime),
+		Utime:   time.UnixMilli(c.Utime),
+	}
+	if c.RootId.Valid {
+		res.RootComment = &domain.Comment{Id: c.RootId.Int64}
+	}
+	if c.Pid.Valid {
+		res.ParentComment = &domain.Comment{Id: c.Pid.Int64}
+	}
+	return res
+}
@@ -0,0 +1,140 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+var ErrRecordNotFound = gorm.ErrRecordNotFound
+
+const (
+	// 软删除用的存储状态，业务上没有感知
+	commentStatusDeleted uint8 = 0
+	commentStatusValid   uint8 = 1
+)
+
+type CommentDAO interface {
+	Insert(ctx context.Context, c Comment) (int64, error)
+	// Delete 软删除。删除根评论的时候，它下面所有的回复也会一起删掉
+	// 返回一共删除了多少条
+	Delete(ctx context.Context, c Comment) (int64, error)
+	FindById(ctx context.Context, id int64) (Comment, error)
+	// FindByBiz 按照 id 倒序分页查找根评论，minId 为 0 的时候从最新的开始
+	FindByBiz(ctx context.Context, biz string, bizId int64, minId int64, limit int64) ([]Comment, error)
+	// FindRepliesByRid 按照 id 正序查找某个根评论下面的回复
+	FindRepliesByRid(ctx context.Context, rid int64, maxId int64, limit int64) ([]Comment, error)
+}
+
+type GORMCommentDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMCommentDAO(db *gorm.DB) CommentDAO {
+	return &GORMCommentDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMCommentDAO) Insert(ctx context.Context, c Comment) (int64, error) {
+	now := time.Now().UnixMilli()
+	c.Ctime = now
+	c.Utime = now
+	c.Status = commentStatusValid
+	err := dao.db.WithContext(ctx).Create(&c).Error
+	return c.Id, err
+}
+
+func (dao *GORMCommentDAO) Delete(ctx context.Context, c Comment) (int64, error) {
+	now := time.Now().UnixMilli()
+	var cnt int64
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 带上 uid，只有评论者自己才能删
+		res := tx.Model(&Comment{}).
+			Where("id = ? AND uid = ? AND status = ?", c.Id, c.Uid, commentStatusValid).
+			Updates(map[string]any{
+				"status": commentStatusDeleted,
+				"utime":  now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("删除评论失败，可能是评论者非法 id %d, uid %d", c.Id, c.Uid)
+		}
+		cnt = res.RowsAffected
+		// 回复被删了，它的子回复还挂在根评论下面，不需要处理
+		if c.RootId.Valid {
+			return nil
+		}
+
+		res = tx.Model(&Comment{}).
+			Where("root_id = ? AND status = ?", c.Id, commentStatusValid).
+			Updates(map[string]any{
+				"status": commentStatusDeleted,
+				"utime":  now,
+			})
+		cnt += res.RowsAffected
+		return res.Error
+	})
+	return cnt, err
+}
+
+func (dao *GORMCommentDAO) FindById(ctx context.Context, id int64) (Comment, error) {
+	var c Comment
+	err := dao.db.WithContext(ctx).
+		Where("id = ? AND status = ?", id, commentStatusValid).
+		First(&c).Error
+	return c, err
+}
+
+func (dao *GORMCommentDAO) FindByBiz(ctx context.Context, biz string, bizId int64, minId int64, limit int64) ([]Comment, error) {
+	var res []Comment
+	db := dao.db.WithContext(ctx).
+		Where("biz = ? AND biz_id = ? AND root_id IS NULL AND status = ?",
+			biz, bizId, commentStatusValid)
+	if minId > 0 {
+		db = db.Where("id < ?", minId)
+	}
+	err := db.Order("id DESC").Limit(int(limit)).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMCommentDAO) FindRepliesByRid(ctx context.Context, rid int64, maxId int64, limit int64) ([]Comment, error) {
+	var res []Comment
+	err := dao.db.WithContext(ctx).
+		Where("root_id = ? AND id > ? AND status = ?", rid, maxId, commentStatusValid).
+		Order("id ASC").Limit(int(limit)).
+		Find(&res).Error
+	return res, err
+}
+
+// Comment 评论表
+// 典型的查询场景：
+// 1. 某篇文章下面的根评论，按照时间倒序分页
+// WHERE biz = ? AND biz_id = ? AND root_id IS NULL ORDER BY id DESC
+// 2. 某条根评论下面的回复，按照时间正序分页
+// WHERE root_id = ? ORDER BY id ASC
+// id 是自增的，所以用 id 排序和用 ctime 排序是一样的，还省掉一个索引
+type Comment struct {
+	Id int64 `gorm:"primaryKey,autoIncrement"`
+	// 评论者
+	Uid int64 `gorm:"index"`
+
+	Biz   string `gorm:"index:biz_type_id;type:varchar(128)"`
+	BizId int64  `gorm:"index:biz_type_id"`
+
+	// 根评论 ID，为 NULL 说明自己就是根评论
+	RootId sql.NullInt64 `gorm:"index"`
+	// 直接回复的评论 ID
+	Pid sql.NullInt64 `gorm:"index"`
+
+	Content string `gorm:"type:text"`
+	// 0-代表删除，1 代表有效
+	Status uint8
+
+	Ctime int64
+	Utime int64
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/repository"
+	intrSvc "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/textfilter"
+)
+
+// BizComment 评论本身也是一种资源，点赞评论的时候 biz 用这个
+// 根评论在 interactive 里面的 comment_cnt 就是它的回复数
+const BizComment = "comment"
+
+var (
+	ErrCommentNotFound  = repository.ErrCommentNotFound
+	ErrPermissionDenied = errors.New("只有评论者自己才能删除评论")
+	ErrInvalidParentBiz = errors.New("回复的评论和资源对不上")
+	// ErrSensitiveContent 评论里有敏感词
+	ErrSensitiveContent = textfilter.ErrBlocked
+)
+
+type CommentService interface {
+	Create(ctx context.Context, c domain.Comment) (int64, error)
+	Delete(ctx context.Context, id int64, uid int64) error
+	// List 分页查询根评论，每条根评论带上最早的几条回复
+	List(ctx context.Context, biz string, bizId int64, minId int64, limit int64) ([]domain.Comment, error)
+	// Replies 分页查询某条根评论下面的回复
+	Replies(ctx context.Context, rid int64, maxId int64, limit int64) ([]domain.Comment, error)
+}
+
+type commentService struct {
+	repo    repository.CommentRepository
+	intrSvc intrSvc.InteractiveService
+	filter  textfilter.Filter
+	l       logger.Logger
+	// 每条根评论内联多少条回复
+	replyLimit int64
+}
+
+func NewCommentService(repo repository.CommentRepository,
+	intrSvc intrSvc.InteractiveService, filter textfilter.Filter,
+	l logger.Logger) CommentService {
+	return &commentService{
+		repo:       repo,
+		intrSvc:    intrSvc,
+		filter:     filter,
+		l:          l,
+		replyLimit: 3,
+	}
+}
+
+func (s *commentService) Create(ctx context.Context, c domain.Comment) (int64, error) {
+	// 评论暂时没有审核流程，需要审核的词也直接拒绝
+	var err error
+	c.Content, err = textfilter.MaskOrBlock(s.filter, c.Content)
+	if err != nil {
+		return 0, err
+	}
+	if c.ParentComment != nil {
+		// 回复的话，要找到根评论
+		parent, err := s.repo.FindById(ctx, c.ParentComment.Id)
+		if err != nil {
+			return 0, err
+		}
+		if parent.Biz != c.Biz || parent.BizId != c.BizId {
+			return 0, ErrInvalidParentBiz
+		}
+		if parent.IsRoot() {
+			c.RootComment = &domain.Comment{Id: parent.Id}
+		} else {
+			c.RootComment = &domain.Comment{Id: parent.RootComment.Id}
+		}
+	}
+
+	id, err := s.repo.Create(ctx, c)
+	if err != nil {
+		return 0, err
+	}
+
+	// 计数失败了不影响评论本身
+	err = s.intrSvc.IncrCommentCnt(ctx, c.Biz, c.BizId)
+	if err != nil {
+		s.l.Error("增加评论计数失败", logger.Error(err),
+			logger.String("biz", c.Biz), logger.Int64("bizId", c.BizId))
+	}
+	if c.RootComment != nil {
+		err = s.intrSvc.IncrCommentCnt(ctx, BizComment, c.RootComment.Id)
+		if err != nil {
+			s.l.Error("增加回复计数失败", logger.Error(err),
+				logger.Int64("rid", c.RootComment.Id))
+		}
+	}
+	return id, nil
+}
+
+func (s *commentService) Delete(ctx context.Context, id int64, uid int64) error {
+	c, err := s.repo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if c.Commentator.Id != uid {
+		return ErrPermissionDenied
+	}
+
+	cnt, err := s.repo.Delete(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	err = s.intrSvc.DecrCommentCnt(ctx, c.Biz, c.BizId, cnt)
+	if err != nil {
+		s.l.Error("减少评论计数失败", logger.Error(err),
+			logger.String("biz", c.Biz), logger.Int64("bizId", c.BizId))
+	}
+	if !c.IsRoot() {
+		err = s.intrSvc.DecrCommentCnt(ctx, BizComment, c.RootComment.Id, cnt)
+		if err != nil {
+			s.l.Error("减少回复计数失败", logger.Error(err),
+				logger.Int64("rid", c.RootComment.Id))
+		}
+	}
+	return nil
+}
+
+func (s *commentService) List(ctx context.Context, biz string, bizId int64, minId int64, limit int64) ([]domain.Comment, error) {
+	return s.repo.FindByBiz(ctx, biz, bizId, minId, limit, s.replyLimit)
+}
+
+func (s *commentService) Replies(ctx context.Context, rid int64, maxId int64, limit int64) ([]domain.Comment, error) {
+	return s.repo.FindRepliesByRid(ctx, rid, maxId, limit)
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/repository"
+	intrSvc "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/textfilter"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"testing"
+)
+
+type fakeCommentRepo struct {
+	repository.CommentRepository
+	comments map[int64]domain.Comment
+	created  []domain.Comment
+	deleted  []domain.Comment
+	// 删除的时候连带删掉了几条
+	delCnt int64
+}
+
+func (r *fakeCommentRepo) FindById(ctx context.Context, id int64) (domain.Comment, error) {
+	c, ok := r.comments[id]
+	if !ok {
+		return domain.Comment{}, repository.ErrCommentNotFound
+	}
+	return c, nil
+}
+
+func (r *fakeCommentRepo) Create(ctx context.Context, c domain.Comment) (int64, error) {
+	r.created = append(r.created, c)
+	return 100, nil
+}
+
+func (r *fakeCommentRepo) Delete(ctx context.Context, c domain.Comment) (int64, error) {
+	r.deleted = append(r.deleted, c)
+	return r.delCnt, nil
+}
+
+// cntCall 记录一次评论计数的变更，Decr 的 delta 是负数
+type cntCall struct {
+	biz   string
+	bizId int64
+	delta int64
+}
+
+type fakeIntrService struct {
+	intrSvc.InteractiveService
+	calls []cntCall
+	err   error
+}
+
+func (s *fakeIntrService) IncrCommentCnt(ctx context.Context, biz string, bizId int64) error {
+	s.calls = append(s.calls, cntCall{biz: biz, bizId: bizId, delta: 1})
+	return s.err
+}
+
+func (s *fakeIntrService) DecrCommentCnt(ctx context.Context, biz string, bizId int64, cnt int64) error {
+	s.calls = append(s.calls, cntCall{biz: biz, bizId: bizId, delta: -cnt})
+	return s.err
+}
+
+// 1 是根评论，2 回复了 1，3 是另外一篇文章下面的根评论
+func commentFixtures() map[int64]domain.Comment {
+	return map[int64]domain.Comment{
+		1: {Id: 1, Commentator: domain.User{Id: 11}, Biz: "article", BizId: 7},
+		2: {Id: 2, Commentator: domain.User{Id: 12}, Biz: "article", BizId: 7,
+			RootComment: &domain.Comment{Id: 1}, ParentComment: &domain.Comment{Id: 1}},
+		3: {Id: 3, Commentator: domain.User{Id: 13}, Biz: "article", BizId: 8},
+	}
+}
+
+func newTestCommentService(repo repository.CommentRepository, intr intrSvc.InteractiveService) CommentService {
+	filter := textfilter.NewACFilter([]textfilter.Word{
+		{Word: "笨蛋", Action: textfilter.ActionMask},
+		{Word: "广告", Action: textfilter.ActionReview},
+		{Word: "赌博", Action: textfilter.ActionBlock},
+	})
+	return NewCommentService(repo, intr, filter, logger.NewZapLogger(zap.NewNop(), false))
+}
+
+func TestCommentService_Create(t *testing.T) {
+	testCases := []struct {
+		name    string
+		c       domain.Comment
+		intrErr error
+
+		wantId      int64
+		wantErr     error
+		wantContent string
+		wantRoot    int64
+		wantCalls   []cntCall
+	}{
+		{
+			name:        "根评论",
+			c:           domain.Comment{Biz: "article", BizId: 7, Content: "写得好"},
+			wantId:      100,
+			wantContent: "写得好",
+			wantCalls:   []cntCall{{biz: "article", bizId: 7, delta: 1}},
+		},
+		{
+			name: "回复根评论",
+			c: domain.Comment{Biz: "article", BizId: 7, Content: "同意",
+				ParentComment: &domain.Comment{Id: 1}},
+			wantId:      100,
+			wantContent: "同意",
+			wantRoot:    1,
+			wantCalls: []cntCall{
+				{biz: "article", bizId: 7, delta: 1},
+				{biz: BizComment, bizId: 1, delta: 1},
+			},
+		},
+		{
+			name: "回复一条回复，根评论还是最上面那条",
+			c: domain.Comment{Biz: "article", BizId: 7, Content: "楼上说得对",
+				ParentComment: &domain.Comment{Id: 2}},
+			wantId:      100,
+			wantContent: "楼上说得对",
+			wantRoot:    1,
+			wantCalls: []cntCall{
+				{biz: "article", bizId: 7, delta: 1},
+				{biz: BizComment, bizId: 1, delta: 1},
+			},
+		},
+		{
+			name: "回复的评论不在同一篇文章下",
+			c: domain.Comment{Biz: "article", BizId: 7, Content: "串台了",
+				ParentComment: &domain.Comment{Id: 3}},
+			wantErr: ErrInvalidParentBiz,
+		},
+		{
+			name: "回复的评论不存在",
+			c: domain.Comment{Biz: "article", BizId: 7, Content: "人呢",
+				ParentComment: &domain.Comment{Id: 404}},
+			wantErr: ErrCommentNotFound,
+		},
+		{
+			name:        "敏感词替换掉",
+			c:           domain.Comment{Biz: "article", BizId: 7, Content: "你个笨蛋"},
+			wantId:      100,
+			wantContent: "你个**",
+			wantCalls:   []cntCall{{biz: "article", bizId: 7, delta: 1}},
+		},
+		{
+			name:    "需要审核的词直接拒绝",
+			c:       domain.Comment{Biz: "article", BizId: 7, Content: "打个广告"},
+			wantErr: ErrSensitiveContent,
+		},
+		{
+			name:    "禁止的词直接拒绝",
+			c:       domain.Comment{Biz: "article", BizId: 7, Content: "来赌博"},
+			wantErr: ErrSensitiveContent,
+		},
+		{
+			name:        "计数失败不影响评论",
+			c:           domain.Comment{Biz: "article", BizId: 7, Content: "写得好"},
+			intrErr:     errors.New("模拟计数失败"),
+			wantId:      100,
+			wantContent: "写得好",
+			wantCalls:   []cntCall{{biz: "article", bizId: 7, delta: 1}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeCommentRepo{comments: commentFixtures()}
+			intr := &fakeIntrService{err: tc.intrErr}
+			svc := newTestCommentService(repo, intr)
+			id, err := svc.Create(context.Background(), tc.c)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantId, id)
+			assert.Equal(t, tc.wantCalls, intr.calls)
+			if tc.wantErr != nil {
+				assert.Empty(t, repo.created)
+				return
+			}
+			if assert.Len(t, repo.created, 1) {
+				created := repo.created[0]
+				assert.Equal(t, tc.wantContent, created.Content)
+				if tc.wantRoot == 0 {
+					assert.Nil(t, created.RootComment)
+				} else if assert.NotNil(t, created.RootComment) {
+					assert.Equal(t, tc.wantRoot, created.RootComment.Id)
+				}
+			}
+		})
+	}
+}
+
+func TestCommentService_Delete(t *testing.T) {
+	testCases := []struct {
+		name   string
+		id     int64
+		uid    int64
+		delCnt int64
+
+		wantErr   error
+		wantCalls []cntCall
+	}{
+		{
+			name:   "删除根评论，回复也一起算上",
+			id:     1,
+			uid:    11,
+			delCnt: 2,
+			wantCalls: []cntCall{
+				{biz: "article", bizId: 7, delta: -2},
+			},
+		},
+		{
+			name:   "删除回复，根评论的回复数也要减",
+			id:     2,
+			uid:    12,
+			delCnt: 1,
+			wantCalls: []cntCall{
+				{biz: "article", bizId: 7, delta: -1},
+				{biz: BizComment, bizId: 1, delta: -1},
+			},
+		},
+		{
+			name:    "不能删除别人的评论",
+			id:      1,
+			uid:     12,
+			wantErr: ErrPermissionDenied,
+		},
+		{
+			name:    "评论不存在",
+			id:      404,
+			uid:     11,
+			wantErr: ErrCommentNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeCommentRepo{comments: commentFixtures(), delCnt: tc.delCnt}
+			intr := &fakeIntrService{}
+			svc := newTestCommentService(repo, intr)
+			err := svc.Delete(context.Background(), tc.id, tc.uid)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantCalls, intr.calls)
+			if tc.wantErr != nil {
+				assert.Empty(t, repo.deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// Collection 收藏夹
+type Collection struct {
+	Id         int64
+	Uid        int64
+	Name       string
+	Visibility CollectionVisibility
+	// ItemCnt 收藏夹里有多少东西
+	ItemCnt int64
+	Ctime   time.Time
+	Utime   time.Time
+}
+
+// VisibleTo 私有的收藏夹只有主人能看
+func (c Collection) VisibleTo(uid int64) bool {
+	return c.Uid == uid || c.Visibility == CollectionPublic
+}
+
+type CollectionVisibility uint8
+
+const (
+	// CollectionPrivate 默认是私有的
+	CollectionPrivate CollectionVisibility = iota
+	// CollectionPublic 公开的收藏夹，别人拿到链接就能看
+	CollectionPublic
+)
+
+func (v CollectionVisibility) ToUint8() uint8 {
+	return uint8(v)
+}
+
+func (v CollectionVisibility) Valid() bool {
+	return v == CollectionPrivate || v == CollectionPublic
+}
+
+// CollectionItem 收藏夹里的一个东西
+type CollectionItem struct {
+	// Id 收藏记录的 id
+	Id    int64
+	Cid   int64
+	Biz   string
+	BizId int64
+	// Ctime 收藏的时间，挪到别的收藏夹也算重新收藏
+	Ctime time.Time
+}
+
+// Cursor 当前这条作为上一页最后一条时，下一页的游标
+func (c CollectionItem) Cursor() Cursor {
+	return Cursor{Utime: c.Ctime, Id: c.Id}
+}
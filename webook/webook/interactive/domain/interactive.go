@@ -1,11 +1,14 @@
 package domain
 
+import "time"
+
 type Interactive struct {
 	Biz        string
 	BizId      int64
 	LikeCnt    int64
 	ReadCnt    int64
 	CollectCnt int64
+	CommentCnt int64
 	// 这个是当下这个资源，你有没有点赞或者收集
 	// 你也可以考虑把这两个字段分离出去，作为一个单独的结构体
 	Liked     bool `json:"liked"`
@@ -17,6 +20,32 @@ type Self struct {
 	Collected bool `json:"collected"`
 }
 
+// LikedItem 用户点赞过的一个东西
+type LikedItem struct {
+	// Id 点赞记录的 id
+	Id    int64
+	Biz   string
+	BizId int64
+	// Ctime 点赞的时间，取消之后再点赞算新的
+	Ctime time.Time
+}
+
+// Cursor 当前这条作为上一页最后一条时，下一页的游标
+func (l LikedItem) Cursor() Cursor {
+	return Cursor{Utime: l.Ctime, Id: l.Id}
+}
+
+// Cursor 我的点赞、我的收藏的游标，按照 (时间, 记录 id) 倒序排列
+// 零值代表第一页
+type Cursor struct {
+	Utime time.Time
+	Id    int64
+}
+
+func (c Cursor) IsZero() bool {
+	return c.Utime.IsZero() && c.Id == 0
+}
+
 type TopWithScore struct {
 	Score  float64
 	Member int64
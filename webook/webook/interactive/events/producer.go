@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBM/sarama"
+)
+
+const TopicInteractiveEvent = "interactive_event"
+
+const (
+	ActionLike      = "like"
+	ActionUnlike    = "unlike"
+	ActionCollect   = "collect"
+	ActionUncollect = "uncollect"
+)
+
+// InteractiveEvent 点赞、收藏成功之后发出去，热榜之类的下游自己消费
+// 阅读还是走原来的 read_article
+type InteractiveEvent struct {
+	Biz    string
+	BizId  int64
+	Uid    int64
+	Action string
+}
+
+type Producer interface {
+	ProduceInteractiveEvent(ctx context.Context, evt InteractiveEvent) error
+}
+
+type KafkaProducer struct {
+	producer sarama.SyncProducer
+}
+
+func NewKafkaProducer(producer sarama.SyncProducer) Producer {
+	return &KafkaProducer{
+		producer: producer,
+	}
+}
+
+func (k *KafkaProducer) ProduceInteractiveEvent(ctx context.Context, evt InteractiveEvent) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = k.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: TopicInteractiveEvent,
+		Value: sarama.ByteEncoder(data),
+	})
+
+	return err
+}
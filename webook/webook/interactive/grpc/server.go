@@ -2,11 +2,13 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	intrv1 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/api/proto/gen/intr/v1"
 	domain2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/domain"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"time"
 )
 
 // InteractiveServiceServer 我这里只是把 service 包装成一个 grpc 而已
@@ -37,7 +39,7 @@ func (i *InteractiveServiceServer) Unlike(ctx context.Context, request *intrv1.U
 
 func (i *InteractiveServiceServer) AddCollect(ctx context.Context, request *intrv1.AddCollectRequest) (*intrv1.AddCollectResponse, error) {
 	err := i.svc.AddCollect(ctx, request.GetBiz(), request.GetBizId(), request.GetCid(), request.GetUid())
-	return &intrv1.AddCollectResponse{}, err
+	return &intrv1.AddCollectResponse{}, i.toStatus(err)
 }
 
 func (i *InteractiveServiceServer) DeleteCollect(ctx context.Context, request *intrv1.DeleteCollectRequest) (*intrv1.DeleteCollectResponse, error) {
@@ -88,6 +90,137 @@ func (i *InteractiveServiceServer) GetByIds(ctx context.Context, request *intrv1
 	}, nil
 }
 
+func (i *InteractiveServiceServer) IncrCommentCnt(ctx context.Context, request *intrv1.IncrCommentCntRequest) (*intrv1.IncrCommentCntResponse, error) {
+	err := i.svc.IncrCommentCnt(ctx, request.GetBiz(), request.GetBizId())
+	return &intrv1.IncrCommentCntResponse{}, err
+}
+
+func (i *InteractiveServiceServer) DecrCommentCnt(ctx context.Context, request *intrv1.DecrCommentCntRequest) (*intrv1.DecrCommentCntResponse, error) {
+	if request.GetCnt() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "cnt 错误")
+	}
+	err := i.svc.DecrCommentCnt(ctx, request.GetBiz(), request.GetBizId(), request.GetCnt())
+	return &intrv1.DecrCommentCntResponse{}, err
+}
+
+func (i *InteractiveServiceServer) SetActive(ctx context.Context, request *intrv1.SetActiveRequest) (*intrv1.SetActiveResponse, error) {
+	err := i.svc.SetActive(ctx, request.GetBiz(), request.GetBizId(), request.GetActive())
+	return &intrv1.SetActiveResponse{}, err
+}
+
+func (i *InteractiveServiceServer) CreateCollection(ctx context.Context, request *intrv1.CreateCollectionRequest) (*intrv1.CreateCollectionResponse, error) {
+	c := i.toCollectionDomain(request.GetCollection())
+	if c.Uid <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "uid 错误")
+	}
+	id, err := i.svc.CreateCollection(ctx, c)
+	return &intrv1.CreateCollectionResponse{Id: id}, err
+}
+
+func (i *InteractiveServiceServer) UpdateCollection(ctx context.Context, request *intrv1.UpdateCollectionRequest) (*intrv1.UpdateCollectionResponse, error) {
+	err := i.svc.UpdateCollection(ctx, i.toCollectionDomain(request.GetCollection()))
+	return &intrv1.UpdateCollectionResponse{}, i.toStatus(err)
+}
+
+func (i *InteractiveServiceServer) DeleteCollection(ctx context.Context, request *intrv1.DeleteCollectionRequest) (*intrv1.DeleteCollectionResponse, error) {
+	err := i.svc.DeleteCollection(ctx, request.GetId(), request.GetUid())
+	return &intrv1.DeleteCollectionResponse{}, i.toStatus(err)
+}
+
+func (i *InteractiveServiceServer) GetCollection(ctx context.Context, request *intrv1.GetCollectionRequest) (*intrv1.GetCollectionResponse, error) {
+	c, err := i.svc.GetCollection(ctx, request.GetId(), request.GetViewer())
+	if err != nil {
+		return &intrv1.GetCollectionResponse{}, i.toStatus(err)
+	}
+	return &intrv1.GetCollectionResponse{
+		Collection: i.toCollectionDTO(c),
+	}, nil
+}
+
+func (i *InteractiveServiceServer) ListCollections(ctx context.Context, request *intrv1.ListCollectionsRequest) (*intrv1.ListCollectionsResponse, error) {
+	cs, err := i.svc.ListCollections(ctx, request.GetUid())
+	if err != nil {
+		return &intrv1.ListCollectionsResponse{}, err
+	}
+	res := make([]*intrv1.Collection, 0, len(cs))
+	for _, c := range cs {
+		res = append(res, i.toCollectionDTO(c))
+	}
+	return &intrv1.ListCollectionsResponse{
+		Collections: res,
+	}, nil
+}
+
+func (i *InteractiveServiceServer) ListCollectionItems(ctx context.Context, request *intrv1.ListCollectionItemsRequest) (*intrv1.ListCollectionItemsResponse, error) {
+	if request.GetOffset() < 0 || request.GetLimit() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "分页参数错误")
+	}
+	items, err := i.svc.ListCollectionItems(ctx, request.GetCid(), request.GetViewer(),
+		int(request.GetOffset()), int(request.GetLimit()))
+	if err != nil {
+		return &intrv1.ListCollectionItemsResponse{}, i.toStatus(err)
+	}
+	return &intrv1.ListCollectionItemsResponse{
+		Items: i.toCollectionItemDTOs(items),
+	}, nil
+}
+
+func (i *InteractiveServiceServer) ListLiked(ctx context.Context, request *intrv1.ListLikedRequest) (*intrv1.ListLikedResponse, error) {
+	if request.GetLimit() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "分页参数错误")
+	}
+	likes, err := i.svc.ListLiked(ctx, request.GetUid(), request.GetBiz(),
+		i.toCursor(request.GetCursorUtime(), request.GetCursorId()), int(request.GetLimit()))
+	if err != nil {
+		return &intrv1.ListLikedResponse{}, err
+	}
+	res := make([]*intrv1.LikedItem, 0, len(likes))
+	for _, like := range likes {
+		res = append(res, &intrv1.LikedItem{
+			Biz:   like.Biz,
+			BizId: like.BizId,
+			Ctime: like.Ctime.UnixMilli(),
+			Id:    like.Id,
+		})
+	}
+	return &intrv1.ListLikedResponse{
+		Items: res,
+	}, nil
+}
+
+func (i *InteractiveServiceServer) ListCollected(ctx context.Context, request *intrv1.ListCollectedRequest) (*intrv1.ListCollectedResponse, error) {
+	if request.GetLimit() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "分页参数错误")
+	}
+	items, err := i.svc.ListCollected(ctx, request.GetUid(), request.GetBiz(),
+		i.toCursor(request.GetCursorUtime(), request.GetCursorId()), int(request.GetLimit()))
+	if err != nil {
+		return &intrv1.ListCollectedResponse{}, err
+	}
+	return &intrv1.ListCollectedResponse{
+		Items: i.toCollectionItemDTOs(items),
+	}, nil
+}
+
+// toCursor 都是 0 的时候是第一页
+func (i *InteractiveServiceServer) toCursor(utime int64, id int64) domain2.Cursor {
+	if utime == 0 && id == 0 {
+		return domain2.Cursor{}
+	}
+	return domain2.Cursor{
+		Utime: time.UnixMilli(utime),
+		Id:    id,
+	}
+}
+
+// toStatus 收藏夹不存在（或者不是你的）转成 NotFound，客户端可以区分
+func (i *InteractiveServiceServer) toStatus(err error) error {
+	if errors.Is(err, service.ErrCollectionNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
+}
+
 func (i *InteractiveServiceServer) mustEmbedUnimplementedInteractiveServiceServer() {
 	//TODO implement me
 	panic("implement me")
@@ -103,5 +236,41 @@ func (i *InteractiveServiceServer) toDTO(intr domain2.Interactive) *intrv1.Inter
 		LikeCnt:    intr.LikeCnt,
 		Liked:      intr.Liked,
 		ReadCnt:    intr.ReadCnt,
+		CommentCnt: intr.CommentCnt,
+	}
+}
+
+func (i *InteractiveServiceServer) toCollectionDomain(c *intrv1.Collection) domain2.Collection {
+	return domain2.Collection{
+		Id:         c.GetId(),
+		Uid:        c.GetUid(),
+		Name:       c.GetName(),
+		Visibility: domain2.CollectionVisibility(c.GetVisibility()),
+	}
+}
+
+func (i *InteractiveServiceServer) toCollectionDTO(c domain2.Collection) *intrv1.Collection {
+	return &intrv1.Collection{
+		Id:         c.Id,
+		Uid:        c.Uid,
+		Name:       c.Name,
+		Visibility: int32(c.Visibility),
+		ItemCnt:    c.ItemCnt,
+		Ctime:      c.Ctime.UnixMilli(),
+		Utime:      c.Utime.UnixMilli(),
+	}
+}
+
+func (i *InteractiveServiceServer) toCollectionItemDTOs(items []domain2.CollectionItem) []*intrv1.CollectionItem {
+	res := make([]*intrv1.CollectionItem, 0, len(items))
+	for _, item := range items {
+		res = append(res, &intrv1.CollectionItem{
+			Cid:   item.Cid,
+			Biz:   item.Biz,
+			BizId: item.BizId,
+			Ctime: item.Ctime.UnixMilli(),
+			Id:    item.Id,
+		})
 	}
+	return res
 }
@@ -21,6 +21,7 @@ const (
 	fieldReadCnt    = "read_cnt"
 	fieldCollectCnt = "collect_cnt"
 	fieldLikeCnt    = "like_cnt"
+	fieldCommentCnt = "comment_cnt"
 )
 
 // 方案1
@@ -37,12 +38,15 @@ type InteractiveCache interface {
 	DecrLikeCntIfPresent(ctx context.Context, biz string, bizId int64) error
 	IncrCollectCntIfPresent(ctx context.Context, biz string, bizId int64) error
 	DecrCollectCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	IncrCommentCntIfPresent(ctx context.Context, biz string, bizId int64, delta int64) error
 	GetCnt(ctx context.Context, biz string, id int64) (domain.Interactive, error)
 	SetCnt(ctx context.Context, biz string, bizId int64, interactive domain.Interactive) error
 	IncrTopLike(ctx context.Context, biz string, bizId int64, limit int64) (int, error)
 	DecrTopLike(ctx context.Context, biz string, bizId int64, limit int64) error
 	GetTopLike(ctx context.Context, biz string, n int64) ([]domain.TopWithScore, error)
 	SetTopLike(ctx context.Context, biz string, intrs []domain.TopWithScore) error
+	RemoveTopLike(ctx context.Context, biz string, bizId int64) error
+	DelCnt(ctx context.Context, biz string, bizId int64) error
 }
 
 type RedisInteractiveCache struct {
@@ -110,6 +114,13 @@ func (r *RedisInteractiveCache) DecrCollectCntIfPresent(ctx context.Context, biz
 		fieldCollectCnt, -1).Err()
 }
 
+// IncrCommentCntIfPresent delta 可以是负数，删除根评论的时候会一次减掉多条
+func (r *RedisInteractiveCache) IncrCommentCntIfPresent(ctx context.Context, biz string, bizId int64, delta int64) error {
+	return r.client.Eval(ctx, luaIncrCnt,
+		[]string{r.key(biz, bizId)},
+		fieldCommentCnt, delta).Err()
+}
+
 func (r *RedisInteractiveCache) GetCnt(ctx context.Context, biz string, bizId int64) (domain.Interactive, error) {
 	// 直接使用 HMGet，即便缓存中没有对应的 key，也不会返回 error
 	//r.client.HMGet(ctx, r.key(biz, bizId),
@@ -131,11 +142,13 @@ func (r *RedisInteractiveCache) GetCnt(ctx context.Context, biz string, bizId in
 	collectCnt, _ := strconv.ParseInt(data[fieldCollectCnt], 10, 64)
 	likeCnt, _ := strconv.ParseInt(data[fieldLikeCnt], 10, 64)
 	readCnt, _ := strconv.ParseInt(data[fieldReadCnt], 10, 64)
+	commentCnt, _ := strconv.ParseInt(data[fieldCommentCnt], 10, 64)
 
 	return domain.Interactive{
 		CollectCnt: collectCnt,
 		LikeCnt:    likeCnt,
 		ReadCnt:    readCnt,
+		CommentCnt: commentCnt,
 	}, err
 }
 
@@ -144,7 +157,8 @@ func (r *RedisInteractiveCache) SetCnt(ctx context.Context, biz string, bizId in
 	err := r.client.HMSet(ctx, key,
 		fieldLikeCnt, interactive.LikeCnt,
 		fieldCollectCnt, interactive.CollectCnt,
-		fieldReadCnt, interactive.ReadCnt).Err()
+		fieldReadCnt, interactive.ReadCnt,
+		fieldCommentCnt, interactive.CommentCnt).Err()
 	if err != nil {
 		return err
 	}
@@ -194,6 +208,14 @@ func (r *RedisInteractiveCache) SetTopLike(ctx context.Context, biz string, intr
 	return r.client.Expire(ctx, fmt.Sprintf("top_like_%s", biz), time.Minute*1).Err()
 }
 
+func (r *RedisInteractiveCache) RemoveTopLike(ctx context.Context, biz string, bizId int64) error {
+	return r.client.ZRem(ctx, fmt.Sprintf("top_like_%s", biz), bizId).Err()
+}
+
+func (r *RedisInteractiveCache) DelCnt(ctx context.Context, biz string, bizId int64) error {
+	return r.client.Del(ctx, r.key(biz, bizId)).Err()
+}
+
 func (r *RedisInteractiveCache) key(biz string, bizId int64) string {
 	return fmt.Sprintf("interactive:%s:%d", biz, bizId)
 }
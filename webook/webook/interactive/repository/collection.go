@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository/dao"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+)
+
+var ErrCollectionNotFound = dao.ErrCollectionNotFound
+
+func (repo *CachedInteractiveRepository) CreateCollection(ctx context.Context, c domain.Collection) (int64, error) {
+	return repo.dao.InsertCollection(ctx, repo.toCollectionEntity(c))
+}
+
+func (repo *CachedInteractiveRepository) UpdateCollection(ctx context.Context, c domain.Collection) error {
+	return repo.dao.UpdateCollection(ctx, repo.toCollectionEntity(c))
+}
+
+func (repo *CachedInteractiveRepository) DeleteCollection(ctx context.Context, id int64, uid int64) ([]domain.CollectionItem, error) {
+	items, err := repo.dao.DeleteCollection(ctx, id, uid)
+	if err != nil {
+		return nil, err
+	}
+	// 和取消收藏一样，缓存里的收藏计数也要减掉
+	for _, item := range items {
+		err = repo.cache.DecrCollectCntIfPresent(ctx, item.Biz, item.BizId)
+		if err != nil {
+			repo.l.Error("删除收藏夹，减少收藏计数失败", logger.Error(err),
+				logger.String("biz", item.Biz), logger.Int64("bizId", item.BizId))
+		}
+	}
+	return slice.Map[dao.UserCollectionBiz, domain.CollectionItem](items,
+		func(idx int, src dao.UserCollectionBiz) domain.CollectionItem {
+			return repo.toCollectionItem(src)
+		}), nil
+}
+
+func (repo *CachedInteractiveRepository) GetCollection(ctx context.Context, id int64) (domain.Collection, error) {
+	c, err := repo.dao.FindCollectionById(ctx, id)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	cnts, err := repo.dao.CountCollectionItems(ctx, c.Uid, []int64{id})
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	res := repo.toCollection(c)
+	res.ItemCnt = cnts[id]
+	return res, nil
+}
+
+func (repo *CachedInteractiveRepository) ListCollections(ctx context.Context, uid int64) ([]domain.Collection, error) {
+	cs, err := repo.dao.FindCollectionsByUid(ctx, uid)
+	if err != nil || len(cs) == 0 {
+		return nil, err
+	}
+	cids := slice.Map[dao.Collection, int64](cs, func(idx int, src dao.Collection) int64 {
+		return src.Id
+	})
+	cnts, err := repo.dao.CountCollectionItems(ctx, uid, cids)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.Collection, domain.Collection](cs, func(idx int, src dao.Collection) domain.Collection {
+		res := repo.toCollection(src)
+		res.ItemCnt = cnts[src.Id]
+		return res
+	}), nil
+}
+
+func (repo *CachedInteractiveRepository) ListCollectionItems(ctx context.Context, cid int64, uid int64, offset int, limit int) ([]domain.CollectionItem, error) {
+	items, err := repo.dao.FindCollectionItems(ctx, cid, uid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.UserCollectionBiz, domain.CollectionItem](items,
+		func(idx int, src dao.UserCollectionBiz) domain.CollectionItem {
+			return repo.toCollectionItem(src)
+		}), nil
+}
+
+func (repo *CachedInteractiveRepository) toCollectionEntity(c domain.Collection) dao.Collection {
+	return dao.Collection{
+		Id:         c.Id,
+		Uid:        c.Uid,
+		Name:       c.Name,
+		Visibility: c.Visibility.ToUint8(),
+	}
+}
+
+func (repo *CachedInteractiveRepository) toCollection(c dao.Collection) domain.Collection {
+	return domain.Collection{
+		Id:         c.Id,
+		Uid:        c.Uid,
+		Name:       c.Name,
+		Visibility: domain.CollectionVisibility(c.Visibility),
+		Ctime:      time.UnixMilli(c.Ctime),
+		Utime:      time.UnixMilli(c.Utime),
+	}
+}
+
+func (repo *CachedInteractiveRepository) toCollectionItem(item dao.UserCollectionBiz) domain.CollectionItem {
+	return domain.CollectionItem{
+		Id:    item.Id,
+		Cid:   item.Cid,
+		Biz:   item.Biz,
+		BizId: item.BizId,
+		Ctime: time.UnixMilli(item.Utime),
+	}
+}
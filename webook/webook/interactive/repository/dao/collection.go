@@ -0,0 +1,122 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// ErrCollectionNotFound 收藏夹不存在，或者不是这个用户的
+var ErrCollectionNotFound = errors.New("收藏夹不存在")
+
+func (dao *GORMInteractiveDAO) InsertCollection(ctx context.Context, c Collection) (int64, error) {
+	now := time.Now().UnixMilli()
+	c.Ctime = now
+	c.Utime = now
+	err := dao.db.WithContext(ctx).Create(&c).Error
+	return c.Id, err
+}
+
+func (dao *GORMInteractiveDAO) UpdateCollection(ctx context.Context, c Collection) error {
+	res := dao.db.WithContext(ctx).Model(&Collection{}).
+		Where("id = ? AND uid = ?", c.Id, c.Uid).
+		Updates(map[string]any{
+			"name":       c.Name,
+			"visibility": c.Visibility,
+			"utime":      time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+func (dao *GORMInteractiveDAO) DeleteCollection(ctx context.Context, id int64, uid int64) ([]UserCollectionBiz, error) {
+	now := time.Now().UnixMilli()
+	var items []UserCollectionBiz
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND uid = ?", id, uid).Delete(&Collection{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrCollectionNotFound
+		}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("cid = ? AND uid = ? AND status = 1", id, uid).
+			Find(&items).Error
+		if err != nil || len(items) == 0 {
+			return err
+		}
+		err = tx.Model(&UserCollectionBiz{}).
+			Where("cid = ? AND uid = ? AND status = 1", id, uid).
+			Updates(map[string]any{
+				"utime":  now,
+				"status": 0,
+			}).Error
+		if err != nil {
+			return err
+		}
+		// 收藏的东西可能属于不同的 biz，只能一条条减
+		for _, item := range items {
+			err = tx.Model(&Interactive{}).
+				Where("biz = ? AND biz_id = ?", item.Biz, item.BizId).
+				Updates(map[string]any{
+					"utime":       now,
+					"collect_cnt": gorm.Expr("collect_cnt - 1"),
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return items, err
+}
+
+func (dao *GORMInteractiveDAO) FindCollectionById(ctx context.Context, id int64) (Collection, error) {
+	var c Collection
+	err := dao.db.WithContext(ctx).Where("id = ?", id).First(&c).Error
+	if err == gorm.ErrRecordNotFound {
+		return c, ErrCollectionNotFound
+	}
+	return c, err
+}
+
+func (dao *GORMInteractiveDAO) FindCollectionsByUid(ctx context.Context, uid int64) ([]Collection, error) {
+	var res []Collection
+	err := dao.db.WithContext(ctx).Where("uid = ?", uid).
+		Order("ctime ASC").Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) CountCollectionItems(ctx context.Context, uid int64, cids []int64) (map[int64]int64, error) {
+	var rows []struct {
+		Cid int64
+		Cnt int64
+	}
+	err := dao.db.WithContext(ctx).Model(&UserCollectionBiz{}).
+		Select("cid, COUNT(*) AS cnt").
+		Where("uid = ? AND cid IN ? AND status = 1", uid, cids).
+		Group("cid").Scan(&rows).Error
+	res := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		res[row.Cid] = row.Cnt
+	}
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) FindCollectionItems(ctx context.Context, cid int64, uid int64, offset int, limit int) ([]UserCollectionBiz, error) {
+	var res []UserCollectionBiz
+	err := dao.db.WithContext(ctx).
+		Where("cid = ? AND uid = ? AND status = 1", cid, uid).
+		Order("utime DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
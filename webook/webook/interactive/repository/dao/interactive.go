@@ -11,16 +11,38 @@ var ErrRecordNotFound = gorm.ErrRecordNotFound
 
 type InteractiveDAO interface {
 	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
+	IncrCommentCnt(ctx context.Context, biz string, bizId int64) error
+	DecrCommentCnt(ctx context.Context, biz string, bizId int64, cnt int64) error
 	BatchIncrReadCnt(ctx context.Context, biz string, bizIds []int64) error
 	InsertLikeInfo(ctx context.Context, biz string, bizId int64, uid int64) error
 	DeleteLikeInfo(ctx context.Context, biz string, id int64, uid int64) error
 	GetLikeInfo(ctx context.Context, biz string, bizId int64, uid int64) (UserLikeBiz, error)
-	InsertCollectionInfo(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error
-	DeleteCollectionInfo(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error
+	// InsertCollectionInfo added 代表是新收藏的，false 是已经收藏过，只是换了收藏夹
+	InsertCollectionInfo(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (added bool, err error)
+	// DeleteCollectionInfo removed 代表确实取消了一个收藏，重复取消或者 cid 不对都是 false
+	DeleteCollectionInfo(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (removed bool, err error)
 	GetCollectionInfo(ctx context.Context, biz string, bizId int64, uid int64) (UserCollectionBiz, error)
 	GetInteractive(ctx context.Context, biz string, bizId int64) (Interactive, error)
 	GetTopLike(ctx context.Context, biz string, limit int64) ([]Interactive, error)
 	GetByIds(ctx context.Context, biz string, bizIds []int64) (map[int64]Interactive, error)
+	SetStatus(ctx context.Context, biz string, bizId int64, active bool) error
+
+	InsertCollection(ctx context.Context, c Collection) (int64, error)
+	// UpdateCollection 只能修改自己的收藏夹
+	UpdateCollection(ctx context.Context, c Collection) error
+	// DeleteCollection 收藏夹里的东西也一起取消收藏，返回被取消收藏的东西
+	DeleteCollection(ctx context.Context, id int64, uid int64) ([]UserCollectionBiz, error)
+	FindCollectionById(ctx context.Context, id int64) (Collection, error)
+	FindCollectionsByUid(ctx context.Context, uid int64) ([]Collection, error)
+	// CountCollectionItems 每个收藏夹里有多少东西，key 是收藏夹 id
+	CountCollectionItems(ctx context.Context, uid int64, cids []int64) (map[int64]int64, error)
+	// FindCollectionItems 按照收藏时间倒序
+	FindCollectionItems(ctx context.Context, cid int64, uid int64, offset int, limit int) ([]UserCollectionBiz, error)
+
+	// FindLikesByUid 用户点赞过的东西，按照 (utime, id) 倒序，biz 为空就是所有的
+	FindLikesByUid(ctx context.Context, uid int64, biz string, cursor Cursor, limit int) ([]UserLikeBiz, error)
+	// FindCollectedByUid 用户收藏过的东西，不管在哪个收藏夹，按照 (utime, id) 倒序
+	FindCollectedByUid(ctx context.Context, uid int64, biz string, cursor Cursor, limit int) ([]UserCollectionBiz, error)
 }
 
 type GORMInteractiveDAO struct {
@@ -71,6 +93,34 @@ func (dao *GORMInteractiveDAO) IncrReadCnt(ctx context.Context, biz string, bizI
 	}).Error
 }
 
+// IncrCommentCnt 和 IncrReadCnt 一样，也是 upsert 的语义
+func (dao *GORMInteractiveDAO) IncrCommentCnt(ctx context.Context, biz string, bizId int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"comment_cnt": gorm.Expr("comment_cnt + 1"),
+			"utime":       now,
+		}),
+	}).Create(&Interactive{
+		Biz:        biz,
+		BizId:      bizId,
+		CommentCnt: 1,
+		Utime:      now,
+		Ctime:      now,
+	}).Error
+}
+
+// DecrCommentCnt 删除根评论的时候，它下面的回复也会一起删掉，所以这里要减去 cnt
+func (dao *GORMInteractiveDAO) DecrCommentCnt(ctx context.Context, biz string, bizId int64, cnt int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Model(&Interactive{}).
+		Where("biz = ? AND biz_id = ? AND comment_cnt >= ?", biz, bizId, cnt).
+		Updates(map[string]any{
+			"utime":       now,
+			"comment_cnt": gorm.Expr("comment_cnt - ?", cnt),
+		}).Error
+}
+
 func (dao *GORMInteractiveDAO) BatchIncrReadCnt(ctx context.Context, biz string, bizIds []int64) error {
 	// 可以用 map 合并吗？
 	// 看情况。如果一批次里面，biz 和 bizid 都相等的占很多，那么就map 合并，性能会更好
@@ -168,31 +218,69 @@ func (dao *GORMInteractiveDAO) GetLikeInfo(ctx context.Context, biz string, bizI
 	return likeInfo, err
 }
 
-// InsertCollectionInfo 插入收藏记录，并且更新计数
-func (dao *GORMInteractiveDAO) InsertCollectionInfo(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error {
+// InsertCollectionInfo 插入收藏记录，只有从没收藏变成收藏的时候才更新计数
+// 已经收藏过的话就是挪到 cid 这个收藏夹，返回的 added 是 false
+func (dao *GORMInteractiveDAO) InsertCollectionInfo(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (bool, error) {
 	now := time.Now().UnixMilli()
-	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 插入收藏项目
-		err := tx.Clauses(clause.OnConflict{
-			DoUpdates: clause.Assignments(map[string]any{
-				"utime":  now,
-				"status": 1,
-			}),
-		}).Create(&UserCollectionBiz{
-			Biz:    biz,
-			BizId:  bizId,
-			Uid:    uid,
-			Cid:    cid,
-			Utime:  now,
-			Ctime:  now,
-			Status: 1,
-		}).Error
-
+	var added bool
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// cid 必须是这个用户自己的收藏夹，0 是默认收藏夹
+		// 加共享锁，防止收藏的同时收藏夹被删掉
+		if cid != 0 {
+			err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+				Where("id = ? AND uid = ?", cid, uid).
+				First(&Collection{}).Error
+			if err == gorm.ErrRecordNotFound {
+				return ErrCollectionNotFound
+			}
+			if err != nil {
+				return err
+			}
+		}
+		// 在事务里锁住原来的记录，判断是新收藏还是换收藏夹
+		// 不然重复收藏或者换收藏夹都会让计数 +1
+		var prev UserCollectionBiz
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("biz = ? AND biz_id = ? AND uid = ?", biz, bizId, uid).
+			Find(&prev).Error
+		if err != nil {
+			return err
+		}
+		switch {
+		case prev.Id > 0 && prev.Status == 1:
+			// 换收藏夹，只改 cid 和 utime
+			return tx.Model(&UserCollectionBiz{}).
+				Where("id = ?", prev.Id).
+				Updates(map[string]any{
+					"utime": now,
+					"cid":   cid,
+				}).Error
+		case prev.Id > 0:
+			// 取消过收藏，重新收藏
+			err = tx.Model(&UserCollectionBiz{}).
+				Where("id = ?", prev.Id).
+				Updates(map[string]any{
+					"utime":  now,
+					"status": 1,
+					"cid":    cid,
+				}).Error
+		default:
+			err = tx.Create(&UserCollectionBiz{
+				Biz:    biz,
+				BizId:  bizId,
+				Uid:    uid,
+				Cid:    cid,
+				Utime:  now,
+				Ctime:  now,
+				Status: 1,
+			}).Error
+		}
 		if err != nil {
 			return err
 		}
 
 		// 这边就是更新数量
+		added = true
 		return tx.Clauses(clause.OnConflict{
 			DoUpdates: clause.Assignments(map[string]any{
 				"utime":       now,
@@ -206,25 +294,32 @@ func (dao *GORMInteractiveDAO) InsertCollectionInfo(ctx context.Context, biz str
 			CollectCnt: 1,
 		}).Error
 	})
+	return added && err == nil, err
 }
 
-func (dao *GORMInteractiveDAO) DeleteCollectionInfo(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error {
+// DeleteCollectionInfo 和 InsertCollectionInfo 一样，只有从收藏变成没收藏的时候才更新计数
+func (dao *GORMInteractiveDAO) DeleteCollectionInfo(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (bool, error) {
 	now := time.Now().UnixMilli()
-	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	var removed bool
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 两个操作
 		// 一个是软删除收藏记录
-		// 一个是减点赞数量
-		err := tx.Model(&UserCollectionBiz{}).
-			Where("biz = ? AND biz_id = ? AND uid = ? AND cid = ?", biz, bizId, uid, cid).
+		// 一个是减收藏数量
+		res := tx.Model(&UserCollectionBiz{}).
+			Where("biz = ? AND biz_id = ? AND uid = ? AND cid = ? AND status = 1", biz, bizId, uid, cid).
 			Updates(map[string]any{
 				"utime":  now,
 				"status": 0,
-			}).Error
-
-		if err != nil {
-			return err
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		// 重复取消、收藏夹不对或者根本没收藏过，计数都不能减
+		if res.RowsAffected != 1 {
+			return nil
 		}
 
+		removed = true
 		return tx.Model(&Interactive{}).
 			Where("biz = ? AND biz_id = ?", biz, bizId).
 			Updates(map[string]any{
@@ -232,6 +327,7 @@ func (dao *GORMInteractiveDAO) DeleteCollectionInfo(ctx context.Context, biz str
 				"collect_cnt": gorm.Expr("collect_cnt - 1"),
 			}).Error
 	})
+	return removed && err == nil, err
 }
 
 func (dao *GORMInteractiveDAO) GetCollectionInfo(ctx context.Context, biz string, bizId int64, uid int64) (UserCollectionBiz, error) {
@@ -242,6 +338,32 @@ func (dao *GORMInteractiveDAO) GetCollectionInfo(ctx context.Context, biz string
 	return collectInfo, err
 }
 
+func (dao *GORMInteractiveDAO) FindLikesByUid(ctx context.Context, uid int64, biz string, cursor Cursor, limit int) ([]UserLikeBiz, error) {
+	var res []UserLikeBiz
+	err := dao.byUid(ctx, uid, biz, cursor).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) FindCollectedByUid(ctx context.Context, uid int64, biz string, cursor Cursor, limit int) ([]UserCollectionBiz, error) {
+	var res []UserCollectionBiz
+	err := dao.byUid(ctx, uid, biz, cursor).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+// byUid 我的点赞和我的收藏共用，biz 为空走 uid_utime，不为空走 uid_biz_utime
+// 二级索引里面带着主键，所以 ORDER BY utime DESC, id DESC 不需要额外排序
+func (dao *GORMInteractiveDAO) byUid(ctx context.Context, uid int64, biz string, cursor Cursor) *gorm.DB {
+	db := dao.db.WithContext(ctx).Where("uid = ? AND status = 1", uid)
+	if biz != "" {
+		db = db.Where("biz = ?", biz)
+	}
+	if !cursor.IsZero() {
+		db = db.Where("(utime < ? OR (utime = ? AND id < ?))",
+			cursor.Utime, cursor.Utime, cursor.Id)
+	}
+	return db.Order("utime DESC, id DESC")
+}
+
 func (dao *GORMInteractiveDAO) GetInteractive(ctx context.Context, biz string, bizId int64) (Interactive, error) {
 	var interactive Interactive
 	err := dao.db.WithContext(ctx).Where("biz = ? AND biz_id = ?", biz, bizId).
@@ -253,7 +375,8 @@ func (dao *GORMInteractiveDAO) GetInteractive(ctx context.Context, biz string, b
 func (dao *GORMInteractiveDAO) GetTopLike(ctx context.Context, biz string, limit int64) ([]Interactive, error) {
 	var data []Interactive
 	err := dao.db.WithContext(ctx).Model(&Interactive{}).
-		Where("biz = ?", biz).Limit(int(limit)).Order("like_cnt DESC").
+		Where("biz = ? AND status = ?", biz, InteractiveStatusActive).
+		Limit(int(limit)).Order("like_cnt DESC").
 		Find(&data).Error
 
 	return data, err
@@ -275,6 +398,26 @@ func (dao *GORMInteractiveDAO) GetByIds(ctx context.Context, biz string, ids []i
 	return intrs, nil
 }
 
+// SetStatus 资源被删除的时候，对应的记录置为失效，不再参与 TopLike
+// 记录本身不删，恢复之后计数还在
+func (dao *GORMInteractiveDAO) SetStatus(ctx context.Context, biz string, bizId int64, active bool) error {
+	status := InteractiveStatusInactive
+	if active {
+		status = InteractiveStatusActive
+	}
+	return dao.db.WithContext(ctx).Model(&Interactive{}).
+		Where("biz = ? AND biz_id = ?", biz, bizId).
+		Updates(map[string]any{
+			"status": status,
+			"utime":  time.Now().UnixMilli(),
+		}).Error
+}
+
+const (
+	InteractiveStatusInactive uint8 = iota
+	InteractiveStatusActive
+)
+
 // Interactive 正常来说，一张主表和与它有关联关系的表会共用一个DAO，
 // 所以我们就用一个 DAO 来操作
 // 假如说我要查找点赞数量前 100 的，
@@ -309,8 +452,24 @@ type Interactive struct {
 	ReadCnt    int64
 	LikeCnt    int64
 	CollectCnt int64
-	Ctime      int64
-	Utime      int64
+	// 评论计数，对于 biz 是 comment 的，就是根评论下面的回复数
+	CommentCnt int64
+	// Status 资源被删除的时候置为失效，1 代表有效
+	// 默认值是 1，这样 upsert 新建的记录和老数据都是有效的
+	Status uint8 `gorm:"default:1"`
+	Ctime  int64
+	Utime  int64
+}
+
+// Cursor 我的点赞、我的收藏翻页用的，代表上一页最后一条记录的 (utime, id)
+// 零值代表从头开始取
+type Cursor struct {
+	Utime int64
+	Id    int64
+}
+
+func (c Cursor) IsZero() bool {
+	return c.Utime == 0 && c.Id == 0
 }
 
 // UserLikeBiz 命名无能，用户点赞的某个东西
@@ -328,14 +487,16 @@ type UserLikeBiz struct {
 	// 2. 如果你的场景是，我的点赞数量，需要通过这里来比较/纠正
 	// biz_id 和 biz 在前
 	// select count(*) where biz = ? and biz_id = ?
-	Biz   string `gorm:"uniqueIndex:uid_biz_id_type;type:varchar(128)"`
+	Biz   string `gorm:"uniqueIndex:uid_biz_id_type;type:varchar(128);index:uid_biz_utime,priority:2"`
 	BizId int64  `gorm:"uniqueIndex:uid_biz_id_type"`
 
 	// 谁的操作
-	Uid int64 `gorm:"uniqueIndex:uid_biz_id_type"`
+	// uid_utime 就是上面说的第一种场景，"我的点赞"按照时间倒序翻页
+	// 只看某一个 biz 的时候走 uid_biz_utime
+	Uid int64 `gorm:"uniqueIndex:uid_biz_id_type;index:uid_utime,priority:1;index:uid_biz_utime,priority:1"`
 
 	Ctime int64
-	Utime int64
+	Utime int64 `gorm:"index:uid_utime,priority:2;index:uid_biz_utime,priority:3"`
 	// 如果这样设计，那么，取消点赞的时候，怎么办？
 	// 我删了这个数据
 	// 你就软删除
@@ -350,8 +511,11 @@ type UserLikeBiz struct {
 // Collection 收藏夹
 type Collection struct {
 	Id   int64  `gorm:"primaryKey,autoIncrement"`
-	Name string `gorm:"type=varchar(1024)"`
-	Uid  int64  `gorm:""`
+	Name string `gorm:"type:varchar(1024)"`
+	// 按照用户查收藏夹
+	Uid int64 `gorm:"index"`
+	// Visibility 0-私有，1-公开
+	Visibility uint8
 
 	Ctime int64
 	Utime int64
@@ -364,11 +528,12 @@ type UserCollectionBiz struct {
 	// 作为关联关系中的外键，我们这里需要索引
 	Cid   int64  `gorm:"index"`
 	BizId int64  `gorm:"uniqueIndex:biz_type_id_uid"`
-	Biz   string `gorm:"type:varchar(128);uniqueIndex:biz_type_id_uid"`
+	Biz   string `gorm:"type:varchar(128);uniqueIndex:biz_type_id_uid;index:uid_biz_utime,priority:2"`
 	// 这算是一个冗余，因为正常来说，
 	// 只需要在 Collection 中维持住 Uid 就可以
-	Uid    int64 `gorm:"uniqueIndex:biz_type_id_uid"`
+	// 不过"我的收藏"不管收藏夹，直接走 uid_utime 这个索引，只看某一个 biz 的时候走 uid_biz_utime
+	Uid    int64 `gorm:"uniqueIndex:biz_type_id_uid;index:uid_utime,priority:1;index:uid_biz_utime,priority:1"`
 	Status uint8
 	Ctime  int64
-	Utime  int64
+	Utime  int64 `gorm:"index:uid_utime,priority:2;index:uid_biz_utime,priority:3"`
 }
@@ -0,0 +1,132 @@
+package dao
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"os"
+	"testing"
+)
+
+// 需要一个可以随便清空的 MySQL，比如
+// WEBOOK_TEST_MYSQL_DSN="root:root@tcp(localhost:13316)/webook_test" go test ./...
+// 没有配置的时候跳过
+func initTestDB(t *testing.T) *gorm.DB {
+	dsn, ok := os.LookupEnv("WEBOOK_TEST_MYSQL_DSN")
+	if !ok {
+		t.Skip("没有配置 WEBOOK_TEST_MYSQL_DSN")
+	}
+	db, err := gorm.Open(mysql.Open(dsn))
+	require.NoError(t, err)
+	require.NoError(t, InitTable(db))
+	t.Cleanup(func() {
+		db.Exec("TRUNCATE TABLE interactives")
+		db.Exec("TRUNCATE TABLE collections")
+		db.Exec("TRUNCATE TABLE user_collection_bizs")
+	})
+	return db
+}
+
+func TestGORMInteractiveDAO_InsertCollectionInfo(t *testing.T) {
+	db := initTestDB(t)
+	dao := NewGORMInteractiveDAO(db)
+	ctx := context.Background()
+	cid, err := dao.InsertCollection(ctx, Collection{Name: "收藏夹", Uid: 123})
+	require.NoError(t, err)
+	_, err = dao.InsertCollection(ctx, Collection{Name: "别人的", Uid: 234})
+	require.NoError(t, err)
+
+	collectCnt := func() int64 {
+		intr, err := dao.GetInteractive(ctx, "article", 1)
+		require.NoError(t, err)
+		return intr.CollectCnt
+	}
+
+	// 新收藏，计数 +1
+	added, err := dao.InsertCollectionInfo(ctx, "article", 1, 0, 123)
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.Equal(t, int64(1), collectCnt())
+
+	// 换到另一个收藏夹，计数不变
+	added, err = dao.InsertCollectionInfo(ctx, "article", 1, cid, 123)
+	require.NoError(t, err)
+	assert.False(t, added)
+	assert.Equal(t, int64(1), collectCnt())
+	info, err := dao.GetCollectionInfo(ctx, "article", 1, 123)
+	require.NoError(t, err)
+	assert.Equal(t, cid, info.Cid)
+
+	// 重复收藏到同一个收藏夹，计数也不变
+	added, err = dao.InsertCollectionInfo(ctx, "article", 1, cid, 123)
+	require.NoError(t, err)
+	assert.False(t, added)
+	assert.Equal(t, int64(1), collectCnt())
+
+	// 别人收藏，计数 +1
+	added, err = dao.InsertCollectionInfo(ctx, "article", 1, 0, 234)
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.Equal(t, int64(2), collectCnt())
+
+	// 取消之后再收藏，计数回到原来的值
+	removed, err := dao.DeleteCollectionInfo(ctx, "article", 1, cid, 123)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	assert.Equal(t, int64(1), collectCnt())
+	// 重复取消，或者收藏夹不对，计数不变
+	removed, err = dao.DeleteCollectionInfo(ctx, "article", 1, cid, 123)
+	require.NoError(t, err)
+	assert.False(t, removed)
+	removed, err = dao.DeleteCollectionInfo(ctx, "article", 1, cid+1, 234)
+	require.NoError(t, err)
+	assert.False(t, removed)
+	assert.Equal(t, int64(1), collectCnt())
+	added, err = dao.InsertCollectionInfo(ctx, "article", 1, 0, 123)
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.Equal(t, int64(2), collectCnt())
+
+	// 收藏到别人的收藏夹
+	_, err = dao.InsertCollectionInfo(ctx, "article", 2, cid, 234)
+	assert.Equal(t, ErrCollectionNotFound, err)
+}
+
+func TestGORMInteractiveDAO_FindCollectedByUid(t *testing.T) {
+	db := initTestDB(t)
+	dao := NewGORMInteractiveDAO(db)
+	ctx := context.Background()
+	// 两条收藏时间一样，要靠 id 区分先后
+	items := []UserCollectionBiz{
+		{Biz: "article", BizId: 1, Uid: 123, Status: 1, Utime: 100},
+		{Biz: "article", BizId: 2, Uid: 123, Status: 1, Utime: 200},
+		{Biz: "article", BizId: 3, Uid: 123, Status: 1, Utime: 200},
+		{Biz: "video", BizId: 1, Uid: 123, Status: 1, Utime: 300},
+		{Biz: "article", BizId: 4, Uid: 123, Status: 0, Utime: 400},
+		{Biz: "article", BizId: 5, Uid: 234, Status: 1, Utime: 500},
+	}
+	require.NoError(t, db.Create(&items).Error)
+
+	bizIds := func(res []UserCollectionBiz) []int64 {
+		ids := make([]int64, 0, len(res))
+		for _, item := range res {
+			ids = append(ids, item.BizId)
+		}
+		return ids
+	}
+
+	res, err := dao.FindCollectedByUid(ctx, 123, "article", Cursor{}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 2}, bizIds(res))
+
+	last := res[len(res)-1]
+	res, err = dao.FindCollectedByUid(ctx, 123, "article", Cursor{Utime: last.Utime, Id: last.Id}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, bizIds(res))
+
+	res, err = dao.FindCollectedByUid(ctx, 123, "", Cursor{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 3, 2, 1}, bizIds(res))
+}
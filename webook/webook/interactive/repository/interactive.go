@@ -7,21 +7,40 @@ import (
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository/cache"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository/dao"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/ecodeclub/ekit/slice"
 	"github.com/redis/go-redis/v9"
+	"time"
 )
 
 type InteractiveRepository interface {
 	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
 	BatchIncrReadCnt(ctx context.Context, biz string, bizIds []int64) error
+	IncrCommentCnt(ctx context.Context, biz string, bizId int64) error
+	DecrCommentCnt(ctx context.Context, biz string, bizId int64, cnt int64) error
 	IncrLike(ctx context.Context, biz string, bizId, uid, limit int64) error
 	DecrLike(ctx context.Context, biz string, bizId, uid, limit int64) error
 	GetTopLike(ctx context.Context, biz string, n int64, limit int64) ([]domain.TopWithScore, error)
-	AddCollectionItem(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error
-	DeleteCollectionItem(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error
+	// AddCollectionItem added 代表是新收藏的，已经收藏过的只是换到 cid 这个收藏夹
+	AddCollectionItem(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (added bool, err error)
+	// DeleteCollectionItem removed 代表确实取消了一个收藏
+	DeleteCollectionItem(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (removed bool, err error)
 	GetCnt(ctx context.Context, biz string, bizId int64) (domain.Interactive, error)
 	Liked(ctx context.Context, biz string, bizId int64, uid int64) (bool, error)
 	Collected(ctx context.Context, biz string, bizId int64, uid int64) (bool, error)
 	GetByIds(ctx context.Context, biz string, bizIds []int64) (map[int64]domain.Interactive, error)
+	SetActive(ctx context.Context, biz string, bizId int64, active bool) error
+
+	CreateCollection(ctx context.Context, c domain.Collection) (int64, error)
+	UpdateCollection(ctx context.Context, c domain.Collection) error
+	// DeleteCollection 返回收藏夹里被一起取消收藏的东西
+	DeleteCollection(ctx context.Context, id int64, uid int64) ([]domain.CollectionItem, error)
+	// GetCollection 带上收藏夹里有多少东西
+	GetCollection(ctx context.Context, id int64) (domain.Collection, error)
+	ListCollections(ctx context.Context, uid int64) ([]domain.Collection, error)
+	ListCollectionItems(ctx context.Context, cid int64, uid int64, offset int, limit int) ([]domain.CollectionItem, error)
+
+	ListLiked(ctx context.Context, uid int64, biz string, cursor domain.Cursor, limit int) ([]domain.LikedItem, error)
+	ListCollected(ctx context.Context, uid int64, biz string, cursor domain.Cursor, limit int) ([]domain.CollectionItem, error)
 }
 
 type CachedInteractiveRepository struct {
@@ -73,6 +92,24 @@ func (repo *CachedInteractiveRepository) BatchIncrReadCnt(ctx context.Context, b
 	return err
 }
 
+func (repo *CachedInteractiveRepository) IncrCommentCnt(ctx context.Context, biz string, bizId int64) error {
+	err := repo.dao.IncrCommentCnt(ctx, biz, bizId)
+	if err != nil {
+		return err
+	}
+
+	return repo.cache.IncrCommentCntIfPresent(ctx, biz, bizId, 1)
+}
+
+func (repo *CachedInteractiveRepository) DecrCommentCnt(ctx context.Context, biz string, bizId int64, cnt int64) error {
+	err := repo.dao.DecrCommentCnt(ctx, biz, bizId, cnt)
+	if err != nil {
+		return err
+	}
+
+	return repo.cache.IncrCommentCntIfPresent(ctx, biz, bizId, -cnt)
+}
+
 func (repo *CachedInteractiveRepository) IncrLike(ctx context.Context, biz string, bizId, uid, limit int64) error {
 	// 先插入点赞，然后更新点赞计数，更新缓存
 	err := repo.dao.InsertLikeInfo(ctx, biz, bizId, uid)
@@ -166,26 +203,26 @@ func (repo *CachedInteractiveRepository) GetTopLike(ctx context.Context, biz str
 
 }
 
-func (repo *CachedInteractiveRepository) AddCollectionItem(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error {
+func (repo *CachedInteractiveRepository) AddCollectionItem(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (bool, error) {
 	// 这个地方，你要不要考虑缓存收藏夹？
 	// 以及收藏夹里面的内容
 	// 用户会频繁访问他的收藏夹，那么你就应该缓存，不然你就不需要
 	// 一个东西要不要缓存，你就看用户会不会频繁访问（反复访问）
-	err := repo.dao.InsertCollectionInfo(ctx, biz, bizId, cid, uid)
-	if err != nil {
-		return err
+	added, err := repo.dao.InsertCollectionInfo(ctx, biz, bizId, cid, uid)
+	if err != nil || !added {
+		return added, err
 	}
 
-	return repo.cache.IncrCollectCntIfPresent(ctx, biz, bizId)
+	return true, repo.cache.IncrCollectCntIfPresent(ctx, biz, bizId)
 }
 
-func (repo *CachedInteractiveRepository) DeleteCollectionItem(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error {
-	err := repo.dao.DeleteCollectionInfo(ctx, biz, bizId, cid, uid)
-	if err != nil {
-		return err
+func (repo *CachedInteractiveRepository) DeleteCollectionItem(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (bool, error) {
+	removed, err := repo.dao.DeleteCollectionInfo(ctx, biz, bizId, cid, uid)
+	if err != nil || !removed {
+		return removed, err
 	}
 
-	return repo.cache.DecrCollectCntIfPresent(ctx, biz, bizId)
+	return true, repo.cache.DecrCollectCntIfPresent(ctx, biz, bizId)
 }
 
 func (repo *CachedInteractiveRepository) GetCnt(ctx context.Context, biz string, bizId int64) (domain.Interactive, error) {
@@ -194,7 +231,8 @@ func (repo *CachedInteractiveRepository) GetCnt(ctx context.Context, biz string,
 	if err == nil &&
 		(interactive.CollectCnt != 0 ||
 			interactive.LikeCnt != 0 ||
-			interactive.ReadCnt != 0) {
+			interactive.ReadCnt != 0 ||
+			interactive.CommentCnt != 0) {
 		return interactive, nil
 	}
 
@@ -250,6 +288,42 @@ func (repo *CachedInteractiveRepository) Collected(ctx context.Context, biz stri
 
 }
 
+func (repo *CachedInteractiveRepository) ListLiked(ctx context.Context, uid int64, biz string, cursor domain.Cursor, limit int) ([]domain.LikedItem, error) {
+	likes, err := repo.dao.FindLikesByUid(ctx, uid, biz, repo.toCursor(cursor), limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.UserLikeBiz, domain.LikedItem](likes, func(idx int, src dao.UserLikeBiz) domain.LikedItem {
+		return domain.LikedItem{
+			Id:    src.Id,
+			Biz:   src.Biz,
+			BizId: src.BizId,
+			Ctime: time.UnixMilli(src.Utime),
+		}
+	}), nil
+}
+
+func (repo *CachedInteractiveRepository) ListCollected(ctx context.Context, uid int64, biz string, cursor domain.Cursor, limit int) ([]domain.CollectionItem, error) {
+	items, err := repo.dao.FindCollectedByUid(ctx, uid, biz, repo.toCursor(cursor), limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.UserCollectionBiz, domain.CollectionItem](items,
+		func(idx int, src dao.UserCollectionBiz) domain.CollectionItem {
+			return repo.toCollectionItem(src)
+		}), nil
+}
+
+func (repo *CachedInteractiveRepository) toCursor(c domain.Cursor) dao.Cursor {
+	if c.IsZero() {
+		return dao.Cursor{}
+	}
+	return dao.Cursor{
+		Utime: c.Utime.UnixMilli(),
+		Id:    c.Id,
+	}
+}
+
 func (repo *CachedInteractiveRepository) GetByIds(ctx context.Context, biz string, bizIds []int64) (map[int64]domain.Interactive, error) {
 	interactives := make(map[int64]domain.Interactive)
 	intrs, err := repo.dao.GetByIds(ctx, biz, bizIds)
@@ -264,6 +338,21 @@ func (repo *CachedInteractiveRepository) GetByIds(ctx context.Context, biz strin
 	return interactives, nil
 }
 
+func (repo *CachedInteractiveRepository) SetActive(ctx context.Context, biz string, bizId int64, active bool) error {
+	err := repo.dao.SetStatus(ctx, biz, bizId, active)
+	if err != nil || active {
+		// 恢复的时候不用管缓存，TopLike 过期之后会从数据库里重新加载
+		return err
+	}
+
+	// 失效的时候，要把它从 TopLike 里面移除，计数缓存也删掉
+	err = repo.cache.RemoveTopLike(ctx, biz, bizId)
+	if err != nil {
+		return err
+	}
+	return repo.cache.DelCnt(ctx, biz, bizId)
+}
+
 // 正常来说，参数必然不用指针：方法不要修改参数，通过返回值来修改参数
 // 返回值就看情况。如果是指针实现了接口，那么就返回指针
 // 如果返回值很大，你不想值传递引发复制问题，那么还是返回指针
@@ -277,6 +366,7 @@ func (repo *CachedInteractiveRepository) toDomain(intr dao.Interactive) domain.I
 		LikeCnt:    intr.LikeCnt,
 		CollectCnt: intr.CollectCnt,
 		ReadCnt:    intr.ReadCnt,
+		CommentCnt: intr.CommentCnt,
 	}
 }
 
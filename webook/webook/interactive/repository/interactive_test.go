@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository/cache"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository/dao"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type fakeCollectDAO struct {
+	dao.InteractiveDAO
+	added   bool
+	removed bool
+}
+
+func (d *fakeCollectDAO) InsertCollectionInfo(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (bool, error) {
+	return d.added, nil
+}
+
+func (d *fakeCollectDAO) DeleteCollectionInfo(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (bool, error) {
+	return d.removed, nil
+}
+
+type fakeCollectCache struct {
+	cache.InteractiveCache
+	incrCnt int
+	decrCnt int
+}
+
+func (c *fakeCollectCache) IncrCollectCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	c.incrCnt++
+	return nil
+}
+
+func (c *fakeCollectCache) DecrCollectCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	c.decrCnt++
+	return nil
+}
+
+func TestCachedInteractiveRepository_AddCollectionItem(t *testing.T) {
+	testCases := []struct {
+		name        string
+		added       bool
+		wantIncrCnt int
+	}{
+		{
+			name:        "新收藏，缓存里的计数 +1",
+			added:       true,
+			wantIncrCnt: 1,
+		},
+		{
+			name:        "换收藏夹，缓存里的计数不变",
+			added:       false,
+			wantIncrCnt: 0,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &fakeCollectCache{}
+			repo := NewCachedInteractiveRepository(&fakeCollectDAO{added: tc.added}, c, nil, nil)
+			added, err := repo.AddCollectionItem(context.Background(), "article", 1, 2, 123)
+			require.NoError(t, err)
+			assert.Equal(t, tc.added, added)
+			assert.Equal(t, tc.wantIncrCnt, c.incrCnt)
+		})
+	}
+}
+
+func TestCachedInteractiveRepository_DeleteCollectionItem(t *testing.T) {
+	testCases := []struct {
+		name        string
+		removed     bool
+		wantDecrCnt int
+	}{
+		{
+			name:        "取消收藏，缓存里的计数 -1",
+			removed:     true,
+			wantDecrCnt: 1,
+		},
+		{
+			name:        "重复取消，缓存里的计数不变",
+			removed:     false,
+			wantDecrCnt: 0,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &fakeCollectCache{}
+			repo := NewCachedInteractiveRepository(&fakeCollectDAO{removed: tc.removed}, c, nil, nil)
+			removed, err := repo.DeleteCollectionItem(context.Background(), "article", 1, 2, 123)
+			require.NoError(t, err)
+			assert.Equal(t, tc.removed, removed)
+			assert.Equal(t, tc.wantDecrCnt, c.decrCnt)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/events"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository"
+)
+
+var ErrCollectionNotFound = repository.ErrCollectionNotFound
+
+func (svc *interactiveService) CreateCollection(ctx context.Context, c domain.Collection) (int64, error) {
+	return svc.repo.CreateCollection(ctx, c)
+}
+
+func (svc *interactiveService) UpdateCollection(ctx context.Context, c domain.Collection) error {
+	return svc.repo.UpdateCollection(ctx, c)
+}
+
+func (svc *interactiveService) DeleteCollection(ctx context.Context, id int64, uid int64) error {
+	items, err := svc.repo.DeleteCollection(ctx, id, uid)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		svc.produce(item.Biz, item.BizId, uid, events.ActionUncollect)
+	}
+	return nil
+}
+
+func (svc *interactiveService) GetCollection(ctx context.Context, id int64, viewer int64) (domain.Collection, error) {
+	c, err := svc.repo.GetCollection(ctx, id)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	if !c.VisibleTo(viewer) {
+		return domain.Collection{}, ErrCollectionNotFound
+	}
+	return c, nil
+}
+
+func (svc *interactiveService) ListCollections(ctx context.Context, uid int64) ([]domain.Collection, error) {
+	return svc.repo.ListCollections(ctx, uid)
+}
+
+func (svc *interactiveService) ListCollectionItems(ctx context.Context, cid int64, viewer int64, offset int, limit int) ([]domain.CollectionItem, error) {
+	if cid == 0 {
+		return svc.repo.ListCollectionItems(ctx, cid, viewer, offset, limit)
+	}
+	c, err := svc.GetCollection(ctx, cid, viewer)
+	if err != nil {
+		return nil, err
+	}
+	return svc.repo.ListCollectionItems(ctx, cid, c.Uid, offset, limit)
+}
@@ -3,12 +3,16 @@ package service
 import (
 	"context"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/events"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
 	"golang.org/x/sync/errgroup"
 )
 
 type InteractiveService interface {
 	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
+	IncrCommentCnt(ctx context.Context, biz string, bizId int64) error
+	DecrCommentCnt(ctx context.Context, biz string, bizId int64, cnt int64) error
 	Like(ctx context.Context, biz string, bizId, uid, limit int64) error
 	Unlike(ctx context.Context, biz string, bizId, uid, limit int64) error
 	AddCollect(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error
@@ -16,15 +20,39 @@ type InteractiveService interface {
 	Get(ctx context.Context, biz string, bizId int64, uid int64) (domain.Interactive, error)
 	TopLike(ctx context.Context, biz string, n, limit int64) ([]domain.TopWithScore, error)
 	GetByIds(ctx context.Context, biz string, bizIds []int64) (map[int64]domain.Interactive, error)
+	// SetActive 资源被删除的时候，它的计数就不再参与各种榜单了
+	SetActive(ctx context.Context, biz string, bizId int64, active bool) error
+
+	CreateCollection(ctx context.Context, c domain.Collection) (int64, error)
+	// UpdateCollection 改名字和可见性，只能改自己的
+	UpdateCollection(ctx context.Context, c domain.Collection) error
+	// DeleteCollection 收藏夹里的东西也一起取消收藏
+	DeleteCollection(ctx context.Context, id int64, uid int64) error
+	// GetCollection 私有的收藏夹，别人看到的是不存在
+	GetCollection(ctx context.Context, id int64, viewer int64) (domain.Collection, error)
+	ListCollections(ctx context.Context, uid int64) ([]domain.Collection, error)
+	// ListCollectionItems cid 为 0 是 viewer 自己的默认收藏夹
+	ListCollectionItems(ctx context.Context, cid int64, viewer int64, offset int, limit int) ([]domain.CollectionItem, error)
+
+	// ListLiked 我的点赞，按照点赞时间倒序，biz 为空就是所有的
+	// cursor 是上一页最后一条的游标
+	ListLiked(ctx context.Context, uid int64, biz string, cursor domain.Cursor, limit int) ([]domain.LikedItem, error)
+	// ListCollected 我的收藏，不分收藏夹，按照收藏时间倒序
+	ListCollected(ctx context.Context, uid int64, biz string, cursor domain.Cursor, limit int) ([]domain.CollectionItem, error)
 }
 
 type interactiveService struct {
-	repo repository.InteractiveRepository
+	repo     repository.InteractiveRepository
+	producer events.Producer
+	l        logger.Logger
 }
 
-func NewInteractiveService(repo repository.InteractiveRepository) InteractiveService {
+func NewInteractiveService(repo repository.InteractiveRepository,
+	producer events.Producer, l logger.Logger) InteractiveService {
 	return &interactiveService{
-		repo: repo,
+		repo:     repo,
+		producer: producer,
+		l:        l,
 	}
 }
 
@@ -32,20 +60,63 @@ func (svc *interactiveService) IncrReadCnt(ctx context.Context, biz string, bizI
 	return svc.repo.IncrReadCnt(ctx, biz, bizId)
 }
 
+func (svc *interactiveService) IncrCommentCnt(ctx context.Context, biz string, bizId int64) error {
+	return svc.repo.IncrCommentCnt(ctx, biz, bizId)
+}
+
+func (svc *interactiveService) DecrCommentCnt(ctx context.Context, biz string, bizId int64, cnt int64) error {
+	return svc.repo.DecrCommentCnt(ctx, biz, bizId, cnt)
+}
+
 func (svc *interactiveService) Like(ctx context.Context, biz string, bizId, uid, limit int64) error {
-	return svc.repo.IncrLike(ctx, biz, bizId, uid, limit)
+	err := svc.repo.IncrLike(ctx, biz, bizId, uid, limit)
+	if err == nil {
+		svc.produce(biz, bizId, uid, events.ActionLike)
+	}
+	return err
 }
 
 func (svc *interactiveService) Unlike(ctx context.Context, biz string, bizId, uid, limit int64) error {
-	return svc.repo.DecrLike(ctx, biz, bizId, uid, limit)
+	err := svc.repo.DecrLike(ctx, biz, bizId, uid, limit)
+	if err == nil {
+		svc.produce(biz, bizId, uid, events.ActionUnlike)
+	}
+	return err
 }
 
 func (svc *interactiveService) AddCollect(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error {
-	return svc.repo.AddCollectionItem(ctx, biz, bizId, cid, uid)
+	added, err := svc.repo.AddCollectionItem(ctx, biz, bizId, cid, uid)
+	// 换收藏夹不算新的收藏，不发事件
+	if err == nil && added {
+		svc.produce(biz, bizId, uid, events.ActionCollect)
+	}
+	return err
 }
 
 func (svc *interactiveService) DeleteCollect(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error {
-	return svc.repo.DeleteCollectionItem(ctx, biz, bizId, cid, uid)
+	removed, err := svc.repo.DeleteCollectionItem(ctx, biz, bizId, cid, uid)
+	// 重复取消不算，不然热榜会被扣分
+	if err == nil && removed {
+		svc.produce(biz, bizId, uid, events.ActionUncollect)
+	}
+	return err
+}
+
+// produce 互动事件发不出去不影响互动本身，下游靠定时的修正兜底
+func (svc *interactiveService) produce(biz string, bizId, uid int64, action string) {
+	go func() {
+		err := svc.producer.ProduceInteractiveEvent(context.Background(), events.InteractiveEvent{
+			Biz:    biz,
+			BizId:  bizId,
+			Uid:    uid,
+			Action: action,
+		})
+		if err != nil {
+			svc.l.Error("发送互动事件失败", logger.Error(err),
+				logger.String("biz", biz), logger.Int64("bizId", bizId),
+				logger.String("action", action))
+		}
+	}()
 }
 
 func (svc *interactiveService) Get(ctx context.Context, biz string, bizId int64, uid int64) (domain.Interactive, error) {
@@ -87,6 +158,18 @@ func (svc *interactiveService) TopLike(ctx context.Context, biz string, n, limit
 	return svc.repo.GetTopLike(ctx, biz, n, limit)
 }
 
+func (svc *interactiveService) ListLiked(ctx context.Context, uid int64, biz string, cursor domain.Cursor, limit int) ([]domain.LikedItem, error) {
+	return svc.repo.ListLiked(ctx, uid, biz, cursor, limit)
+}
+
+func (svc *interactiveService) ListCollected(ctx context.Context, uid int64, biz string, cursor domain.Cursor, limit int) ([]domain.CollectionItem, error) {
+	return svc.repo.ListCollected(ctx, uid, biz, cursor, limit)
+}
+
 func (svc *interactiveService) GetByIds(ctx context.Context, biz string, bizIds []int64) (map[int64]domain.Interactive, error) {
 	return svc.repo.GetByIds(ctx, biz, bizIds)
 }
+
+func (svc *interactiveService) SetActive(ctx context.Context, biz string, bizId int64, active bool) error {
+	return svc.repo.SetActive(ctx, biz, bizId, active)
+}
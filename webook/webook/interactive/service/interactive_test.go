@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/events"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type fakeCollectRepo struct {
+	repository.InteractiveRepository
+	added   bool
+	removed bool
+}
+
+func (r *fakeCollectRepo) AddCollectionItem(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (bool, error) {
+	return r.added, nil
+}
+
+func (r *fakeCollectRepo) DeleteCollectionItem(ctx context.Context, biz string, bizId int64, cid int64, uid int64) (bool, error) {
+	return r.removed, nil
+}
+
+type fakeProducer struct {
+	evts chan events.InteractiveEvent
+}
+
+func (p *fakeProducer) ProduceInteractiveEvent(ctx context.Context, evt events.InteractiveEvent) error {
+	p.evts <- evt
+	return nil
+}
+
+func TestInteractiveService_AddCollect(t *testing.T) {
+	testCases := []struct {
+		name      string
+		added     bool
+		wantEvent bool
+	}{
+		{
+			name:      "新收藏，发送收藏事件",
+			added:     true,
+			wantEvent: true,
+		},
+		{
+			name:      "换收藏夹，不发送事件",
+			added:     false,
+			wantEvent: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &fakeProducer{evts: make(chan events.InteractiveEvent, 1)}
+			svc := NewInteractiveService(&fakeCollectRepo{added: tc.added}, p, nil)
+			err := svc.AddCollect(context.Background(), "article", 1, 2, 123)
+			require.NoError(t, err)
+			// 事件是异步发送的
+			select {
+			case evt := <-p.evts:
+				assert.True(t, tc.wantEvent)
+				assert.Equal(t, events.ActionCollect, evt.Action)
+				assert.Equal(t, int64(1), evt.BizId)
+			case <-time.After(time.Millisecond * 100):
+				assert.False(t, tc.wantEvent)
+			}
+		})
+	}
+}
+
+func TestInteractiveService_DeleteCollect(t *testing.T) {
+	testCases := []struct {
+		name      string
+		removed   bool
+		wantEvent bool
+	}{
+		{
+			name:      "取消收藏，发送取消收藏事件",
+			removed:   true,
+			wantEvent: true,
+		},
+		{
+			name:      "重复取消，不发送事件",
+			removed:   false,
+			wantEvent: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &fakeProducer{evts: make(chan events.InteractiveEvent, 1)}
+			svc := NewInteractiveService(&fakeCollectRepo{removed: tc.removed}, p, nil)
+			err := svc.DeleteCollect(context.Background(), "article", 1, 2, 123)
+			require.NoError(t, err)
+			select {
+			case evt := <-p.evts:
+				assert.True(t, tc.wantEvent)
+				assert.Equal(t, events.ActionUncollect, evt.Action)
+			case <-time.After(time.Millisecond * 100):
+				assert.False(t, tc.wantEvent)
+			}
+		})
+	}
+}
@@ -20,17 +20,106 @@ const (
 	UserTooManySendSMS = 401004
 	// 无权限
 	UserUnauthorized = 401005
+	// 昵称或者简介里有敏感词
+	UserSensitiveContent = 401006
 	// 系统错误
 	UserInternalServerError = 501001
 )
 
 // 文章模块， 模块代码02
 const (
-	ArticleOK                  = 202001
-	ArticleInvalidInput        = 402001
+	ArticleOK           = 202001
+	ArticleInvalidInput = 402001
+	// ArticleVersionConflict 草稿已经被别人（或者别的标签页）修改过了
+	ArticleVersionConflict = 402002
+	// ArticleNotFound 文章不存在，或者不是你的
+	ArticleNotFound = 402003
+	// ArticleSensitiveContent 标题或者内容里有敏感词
+	ArticleSensitiveContent = 402004
+	// ArticleImportTooLarge 导入的压缩包太大，或者文件太多
+	ArticleImportTooLarge = 402005
+	// ArticleImportInvalidFile 导入的不是合法的 zip 压缩包
+	ArticleImportInvalidFile = 402006
+	// ArticleImportJobNotFound 导入任务不存在、已经过期，或者不是你的
+	ArticleImportJobNotFound = 402007
+	// ArticlePermissionDenied 只有审核员能用的接口
+	ArticlePermissionDenied    = 402008
 	ArticleInternalServerError = 502001
 )
 
+// 评论模块， 模块代码03
+const (
+	CommentOK               = 203001
+	CommentInvalidInput     = 403001
+	CommentNotFound         = 403002
+	CommentPermissionDenied = 403003
+	CommentSensitiveContent = 403004
+	CommentInternalServer   = 503001
+)
+
+// 文件上传模块， 模块代码04
+const (
+	MediaOK              = 204001
+	MediaInvalidInput    = 404001
+	MediaTooLarge        = 404002
+	MediaUnsupportedType = 404003
+	MediaInternalServer  = 504001
+)
+
+// 审核模块， 模块代码05
+const (
+	ModerationOK               = 205001
+	ModerationInvalidInput     = 405001
+	ModerationPermissionDenied = 405002
+	ModerationReviewNotFound   = 405003
+	// ModerationReviewOutdated 审核期间作者又修改了文章，或者已经被别的审核员处理了
+	ModerationReviewOutdated = 405004
+	ModerationInternalServer = 505001
+)
+
+// 系列模块， 模块代码06
+const (
+	SeriesOK           = 206001
+	SeriesInvalidInput = 406001
+	// SeriesNotFound 系列不存在，或者不是你的
+	SeriesNotFound = 406002
+	// SeriesArticleConflict 文章已经在别的系列里了
+	SeriesArticleConflict = 406003
+	SeriesInternalServer  = 506001
+)
+
+// 会员模块， 模块代码07
+const (
+	MembershipOK           = 207001
+	MembershipInvalidInput = 407001
+	// MembershipPaymentFailed 支付渠道拒绝了这次支付
+	MembershipPaymentFailed  = 407002
+	MembershipInternalServer = 507001
+)
+
+// 阅读历史模块， 模块代码08
+const (
+	HistoryOK             = 208001
+	HistoryInvalidInput   = 408001
+	HistoryInternalServer = 508001
+)
+
+// 收藏夹模块， 模块代码09
+const (
+	CollectionOK           = 209001
+	CollectionInvalidInput = 409001
+	// CollectionNotFound 收藏夹不存在，不是你的，或者是别人私有的
+	CollectionNotFound       = 409002
+	CollectionInternalServer = 509001
+)
+
+// 互动模块（我的点赞、我的收藏）， 模块代码10
+const (
+	InteractiveOK             = 210001
+	InteractiveInvalidInput   = 410001
+	InteractiveInternalServer = 510001
+)
+
 var (
 	// UserInvalidInputV1 这个东西是你 DEBUG 用的，不是给 C 端用户用的
 	UserInvalidInputV1 = Code{
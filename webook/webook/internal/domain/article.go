@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
 
 type Article struct {
 	Id      int64
@@ -8,8 +12,18 @@ type Article struct {
 	Content string
 	Author  Author
 	Status  ArticleStatus
+	// Access 谁能看全文，看不了的只能看到摘要
+	Access ArticleAccess
+	// Tags 作者自己打的标签，用 NormalizeTags 处理过
+	Tags []string
+	// Locked 当前读者没有权限看全文，Content 只剩下摘要
+	Locked bool
+	// Version 乐观锁版本号，编辑草稿的时候要带上
+	Version int64
 	Ctime   time.Time
 	Utime   time.Time
+	// Dtime 放进回收站的时间，零值代表没有删除
+	Dtime time.Time
 }
 
 func (a Article) Abstract() string {
@@ -24,6 +38,32 @@ func (a Article) Abstract() string {
 	return string(cs[:100])
 }
 
+// Preview 没有权限看全文的读者看到的版本
+func (a Article) Preview() Article {
+	a.Content = a.Abstract()
+	a.Locked = true
+	return a
+}
+
+// Cursor 当前文章作为上一页最后一条时，下一页的游标
+func (a Article) Cursor() ArticleCursor {
+	return ArticleCursor{
+		Utime: a.Utime,
+		Id:    a.Id,
+	}
+}
+
+// ArticleCursor 文章列表的游标，列表都按照 (utime, id) 倒序排列
+// 零值代表第一页
+type ArticleCursor struct {
+	Utime time.Time
+	Id    int64
+}
+
+func (c ArticleCursor) IsZero() bool {
+	return c.Utime.IsZero() && c.Id == 0
+}
+
 type Author struct {
 	Id   int64
 	Name string
@@ -42,6 +82,10 @@ const (
 	ArticleStatusUnpublished
 	ArticleStatusPublished
 	ArticleStatusPrivate
+	// ArticleStatusPendingReview 提交了发表，等待审核，线上看不到这次修改
+	ArticleStatusPendingReview
+	// ArticleStatusRejected 审核没通过，作者修改之后可以重新提交
+	ArticleStatusRejected
 )
 
 func (as ArticleStatus) ToUint8() uint8 {
@@ -60,6 +104,10 @@ func (as ArticleStatus) String() string {
 		return "Published"
 	case ArticleStatusUnpublished:
 		return "Unpublished"
+	case ArticleStatusPendingReview:
+		return "PendingReview"
+	case ArticleStatusRejected:
+		return "Rejected"
 	default:
 		return "Unknown"
 	}
@@ -68,3 +116,53 @@ func (as ArticleStatus) String() string {
 func (as ArticleStatus) IsValid() bool {
 	return as != ArticleStatusUnknown
 }
+
+// ArticleAccess 文章的访问级别，零值是公开
+type ArticleAccess uint8
+
+const (
+	ArticleAccessPublic ArticleAccess = iota
+	// ArticleAccessLogin 登录了才能看全文
+	ArticleAccessLogin
+	// ArticleAccessSubscriber 会员才能看全文
+	ArticleAccessSubscriber
+)
+
+func (aa ArticleAccess) ToUint8() uint8 {
+	return uint8(aa)
+}
+
+func (aa ArticleAccess) IsValid() bool {
+	return aa <= ArticleAccessSubscriber
+}
+
+const (
+	// MaxArticleTags 一篇文章最多几个标签
+	MaxArticleTags = 10
+	// MaxArticleTagLen 单个标签最多几个字
+	MaxArticleTagLen = 32
+)
+
+// NormalizeTags 去掉首尾空格、空标签和重复的标签
+// 存储的时候用逗号拼起来，所以标签里面不能有逗号
+// 返回保留下来的标签和不符合要求被丢掉的标签
+func NormalizeTags(tags []string) (kept []string, dropped []string) {
+	seen := make(map[string]struct{}, len(tags))
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		if strings.Contains(t, ",") || utf8.RuneCountInString(t) > MaxArticleTagLen ||
+			len(kept) >= MaxArticleTags {
+			dropped = append(dropped, t)
+			continue
+		}
+		seen[t] = struct{}{}
+		kept = append(kept, t)
+	}
+	return kept, dropped
+}
@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// ImportJob 一次 Markdown 批量导入
+// 文件少的时候直接在请求里导入完，多的时候放到后台，前端根据 Id 轮询
+type ImportJob struct {
+	Id     string
+	Uid    int64
+	Status ImportStatus
+	// Total 压缩包里需要处理的文件数，Results 是已经处理完的
+	Total   int
+	Results []ImportFileResult
+	// Error 任务失败的原因，只有 ImportStatusFailed 的时候才有
+	Error string
+	Ctime time.Time
+	Utime time.Time
+}
+
+type ImportStatus uint8
+
+const (
+	ImportStatusUnknown ImportStatus = iota
+	ImportStatusRunning
+	ImportStatusDone
+	// ImportStatusFailed 整个任务失败了，比如超时，已经导入的文章不会回滚
+	ImportStatusFailed
+)
+
+func (s ImportStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+// ImportFileResult 每个文件的校验和导入结果
+type ImportFileResult struct {
+	File string
+	// Id 导入成功之后的草稿 id
+	Id int64
+	// Error 不为空代表这个文件没有导入
+	Error string
+	// Warning 导入了，但是有些内容被忽略了
+	Warning string
+}
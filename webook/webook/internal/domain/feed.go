@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// FeedDoc 渲染好的订阅源，整个缓存起来
+type FeedDoc struct {
+	Body        []byte
+	ContentType string
+	// ETag 和 LastModified 用来支持条件请求，内容不变就返回 304
+	ETag         string
+	LastModified time.Time
+}
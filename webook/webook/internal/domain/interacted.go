@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// InteractedItem 用户点赞过或者收藏过的一个东西
+type InteractedItem struct {
+	// Id 点赞或者收藏记录的 id
+	Id    int64
+	Biz   string
+	BizId int64
+	// Cid 收藏夹 id，只有收藏才有，0 是默认收藏夹
+	Cid int64
+	// Time 点赞或者收藏的时间
+	Time time.Time
+	// Article 只有 biz 是 article 的才有，文章撤回或者删除了的 Id 是 0
+	Article Article
+}
+
+// Cursor 当前这条作为上一页最后一条时，下一页的游标
+func (i InteractedItem) Cursor() InteractedCursor {
+	return InteractedCursor{
+		Utime: i.Time,
+		Id:    i.Id,
+	}
+}
+
+// InteractedCursor 我的点赞、我的收藏的游标，按照 (时间, 记录 id) 倒序排列
+// 零值代表第一页
+type InteractedCursor struct {
+	Utime time.Time
+	Id    int64
+}
+
+func (c InteractedCursor) IsZero() bool {
+	return c.Utime.IsZero() && c.Id == 0
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// Membership 用户的会员资格，ExpireAt 之前都能看会员文章
+type Membership struct {
+	Uid      int64
+	ExpireAt time.Time
+}
+
+func (m Membership) IsActive(now time.Time) bool {
+	return m.ExpireAt.After(now)
+}
+
+// MembershipPlan 可以购买的会员套餐
+type MembershipPlan struct {
+	Id   int64
+	Name string
+	// Price 单位是分
+	Price    int64
+	Duration time.Duration
+}
+
+// MembershipOrder 购买会员的订单，支付成功之后才会延长会员
+type MembershipOrder struct {
+	Id       int64
+	Sn       string
+	Uid      int64
+	PlanId   int64
+	Amount   int64
+	Duration time.Duration
+	Status   MembershipOrderStatus
+	// TxnId 支付渠道的流水号
+	TxnId string
+	Ctime time.Time
+	Utime time.Time
+}
+
+type MembershipOrderStatus uint8
+
+const (
+	MembershipOrderStatusUnknown MembershipOrderStatus = iota
+	MembershipOrderStatusPending
+	MembershipOrderStatusPaid
+	MembershipOrderStatusFailed
+)
+
+func (s MembershipOrderStatus) ToUint8() uint8 {
+	return uint8(s)
+}
@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// TrustLevel 作者的信任等级
+type TrustLevel uint8
+
+const (
+	// TrustLevelNormal 默认等级，发表要经过审核
+	TrustLevelNormal TrustLevel = iota
+	// TrustLevelTrusted 可信作者，发表不需要审核
+	TrustLevelTrusted
+	// TrustLevelModerator 审核员，自己发表不需要审核，还可以审核别人
+	TrustLevelModerator
+)
+
+func (tl TrustLevel) ToUint8() uint8 {
+	return uint8(tl)
+}
+
+func (tl TrustLevel) BypassReview() bool {
+	return tl >= TrustLevelTrusted
+}
+
+func (tl TrustLevel) CanModerate() bool {
+	return tl == TrustLevelModerator
+}
+
+func (tl TrustLevel) IsValid() bool {
+	return tl <= TrustLevelModerator
+}
+
+type ReviewStatus uint8
+
+const (
+	ReviewStatusUnknown ReviewStatus = iota
+	ReviewStatusPending
+	ReviewStatusApproved
+	ReviewStatusRejected
+)
+
+func (rs ReviewStatus) ToUint8() uint8 {
+	return uint8(rs)
+}
+
+// ArticleReview 一次发表审核
+type ArticleReview struct {
+	Id int64
+	// 提交审核时候的文章，Version 用来判断审核期间作者有没有改过
+	Article   Article
+	Moderator int64
+	Status    ReviewStatus
+	// Reason 拒绝的理由
+	Reason string
+	Ctime  time.Time
+	Utime  time.Time
+}
@@ -0,0 +1,103 @@
+package domain
+
+import "time"
+
+// Ranking 一次计算出来的热榜，Items 按照分数从高到低排好了
+type Ranking struct {
+	// Name 热榜的名字，比如 daily、weekly
+	Name  string
+	Items []RankingItem
+	// Ctime 这一份热榜是什么时候算出来的
+	Ctime time.Time
+}
+
+type RankingItem struct {
+	Article Article
+	Score   float64
+}
+
+// Articles 只要文章，不关心分数的地方用，比如 RSS
+func (r Ranking) Articles() []Article {
+	res := make([]Article, 0, len(r.Items))
+	for _, item := range r.Items {
+		res = append(res, item.Article)
+	}
+	return res
+}
+
+// Remove 把某篇文章从热榜里面拿掉，剩下的顺序不变，生成时间也不变
+func (r Ranking) Remove(id int64) Ranking {
+	items := make([]RankingItem, 0, len(r.Items))
+	for _, item := range r.Items {
+		if item.Article.Id != id {
+			items = append(items, item)
+		}
+	}
+	return Ranking{
+		Name:  r.Name,
+		Items: items,
+		Ctime: r.Ctime,
+	}
+}
+
+// RankingScore 一篇文章热度的计算过程，explain 的时候给人看的
+type RankingScore struct {
+	Strategy string
+	// Formula 代入了参数之后的公式
+	Formula    string
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+	// Points 按照权重加起来的互动分
+	Points float64
+	// Age 距离文章最后一次更新过了多久
+	Age time.Duration
+	// Decay 时间衰减系数，Score = max(Points, 0) * Decay
+	Decay float64
+	Score float64
+}
+
+// RankingEvent 一篇文章在一段时间里的互动增量，取消点赞、取消收藏是负数
+type RankingEvent struct {
+	Aid        int64
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+}
+
+// RankingDelta 实时热榜上一篇文章要加的互动分，按照 Utime 衰减
+type RankingDelta struct {
+	Aid    int64
+	Points float64
+	Utime  time.Time
+}
+
+// RankingPosition 一篇文章在某一次快照里的名次
+type RankingPosition struct {
+	Ctime time.Time
+	// Rank 从 1 开始
+	Rank  int
+	Score float64
+}
+
+// RankingMove 相邻两次快照之间一篇文章名次的变化
+type RankingMove struct {
+	Article Article
+	// Rank 0 代表掉出了热榜
+	Rank int
+	// PrevRank 0 代表新上榜
+	PrevRank  int
+	Score     float64
+	PrevScore float64
+	// Delta 上升了多少名，下降是负数。
+	// 新上榜的当作是从榜单最后一名的后面升上来的，掉出去的也一样
+	Delta int
+}
+
+// RankingMovers 最近两次快照之间名次变化最大的文章
+type RankingMovers struct {
+	Name  string
+	From  time.Time
+	To    time.Time
+	Moves []RankingMove
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// ReadHistory 用户读过的一篇文章，同一篇文章只有一条，Utime 是最后一次阅读的时间
+type ReadHistory struct {
+	Uid int64
+	Aid int64
+	// Position 客户端上报的阅读位置，具体含义由客户端决定，比如滚动到的段落
+	Position int64
+	// Progress 阅读进度的百分比，100 代表读完了
+	Progress int
+	Utime    time.Time
+	// Article 只有标题之类的，文章撤回或者删除了的 Id 是 0
+	Article Article
+}
+
+// Finished 读完了的不会出现在"继续阅读"里
+func (h ReadHistory) Finished() bool {
+	return h.Progress >= 100
+}
+
+// Cursor 当前记录作为上一页最后一条时，下一页的游标
+func (h ReadHistory) Cursor() ArticleCursor {
+	return ArticleCursor{
+		Utime: h.Utime,
+		Id:    h.Aid,
+	}
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// Series 系列，作者把几篇文章按照阅读顺序组织起来，比如分成好几篇的教程
+// 一篇文章最多属于一个系列
+type Series struct {
+	Id          int64
+	Author      Author
+	Title       string
+	Description string
+	// Aids 按照阅读顺序排列的文章 id
+	Aids  []int64
+	Ctime time.Time
+	Utime time.Time
+}
+
+// SeriesNav 一篇文章在系列里的位置
+// 只算线上可见的文章，Prev 和 Next 没有的时候是零值
+type SeriesNav struct {
+	Series Series
+	// Index 从 1 开始
+	Index int
+	Total int
+	Prev  Article
+	Next  Article
+}
@@ -0,0 +1,11 @@
+package domain
+
+// TopArticle 点赞榜上的一篇文章，计数是组装的时候的快照
+type TopArticle struct {
+	Article Article
+	// Score 点赞榜上的分数，也就是点赞数
+	Score      float64
+	LikeCnt    int64
+	ReadCnt    int64
+	CollectCnt int64
+}
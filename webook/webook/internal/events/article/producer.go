@@ -8,6 +8,8 @@ import (
 
 type Producer interface {
 	ProduceReadEvent(ctx context.Context, evt ReadEvent) error
+	// ProduceModerationEvent 审核结果通知作者
+	ProduceModerationEvent(ctx context.Context, evt ModerationEvent) error
 	//ProduceReadEventV1(ctx context.Context, v1 ReadEventV1)
 }
 
@@ -37,6 +39,20 @@ func (k *KafkaProducer) ProduceReadEvent(ctx context.Context, evt ReadEvent) err
 	return err
 }
 
+func (k *KafkaProducer) ProduceModerationEvent(ctx context.Context, evt ModerationEvent) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = k.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: "article_moderation",
+		Value: sarama.ByteEncoder(data),
+	})
+
+	return err
+}
+
 type ReadEvent struct {
 	Uid int64
 	Aid int64
@@ -46,3 +62,12 @@ type ReadEventV1 struct {
 	Uids []int64
 	Aids []int64
 }
+
+type ModerationEvent struct {
+	// Uid 作者
+	Uid      int64
+	Aid      int64
+	Title    string
+	Approved bool
+	Reason   string
+}
@@ -0,0 +1,60 @@
+package article
+
+import (
+	"context"
+	"github.com/IBM/sarama"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/saramax"
+	"time"
+)
+
+var _ events.Consumer = (*ReadHistoryConsumer)(nil)
+
+// ReadHistoryConsumer 把阅读事件记到用户的阅读历史里
+// 和阅读计数用的是不同的消费者组，两边各自消费一遍
+type ReadHistoryConsumer struct {
+	client sarama.Client
+	repo   repository.ReadHistoryRepository
+	l      logger.Logger
+}
+
+func NewReadHistoryConsumer(client sarama.Client,
+	repo repository.ReadHistoryRepository, l logger.Logger) *ReadHistoryConsumer {
+	return &ReadHistoryConsumer{
+		client: client,
+		repo:   repo,
+		l:      l,
+	}
+}
+
+func (c *ReadHistoryConsumer) Start() error {
+	cg, err := sarama.NewConsumerGroupFromClient("read_history", c.client)
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := cg.Consume(context.Background(), []string{"read_article"},
+			saramax.NewHandler[ReadEvent](c.l, c.Consume))
+		if err != nil {
+			c.l.Error("退出了消费循环异常", logger.Error(err))
+		}
+	}()
+	return nil
+}
+
+// Consume 是幂等的，重复消费只会更新阅读时间
+func (c *ReadHistoryConsumer) Consume(msg *sarama.ConsumerMessage, evt ReadEvent) error {
+	// 没有登录的读者不记录
+	if evt.Uid <= 0 {
+		return nil
+	}
+	readTime := msg.Timestamp
+	if readTime.IsZero() {
+		readTime = time.Now()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return c.repo.Record(ctx, evt.Uid, evt.Aid, readTime)
+}
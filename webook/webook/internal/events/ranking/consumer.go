@@ -0,0 +1,103 @@
+package ranking
+
+import (
+	"context"
+	"github.com/IBM/sarama"
+	intrEvents "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/events"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/saramax"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+)
+
+var _ events.Consumer = (*RankingEventConsumer)(nil)
+
+// RankingEventConsumer 实时热榜，把阅读、点赞、收藏事件转成分数的增量
+// 阅读和互动是两个 topic，各用一个消费者组，互不影响
+type RankingEventConsumer struct {
+	client sarama.Client
+	svc    service.RankingStreamService
+	l      logger.Logger
+}
+
+func NewRankingEventConsumer(client sarama.Client,
+	svc service.RankingStreamService, l logger.Logger) *RankingEventConsumer {
+	return &RankingEventConsumer{
+		client: client,
+		svc:    svc,
+		l:      l,
+	}
+}
+
+func (c *RankingEventConsumer) Start() error {
+	readCg, err := sarama.NewConsumerGroupFromClient("ranking_read", c.client)
+	if err != nil {
+		return err
+	}
+	intrCg, err := sarama.NewConsumerGroupFromClient("ranking_interactive", c.client)
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := readCg.Consume(context.Background(), []string{"read_article"},
+			saramax.NewBatchHandler[article.ReadEvent](c.l, c.ConsumeRead))
+		if err != nil {
+			c.l.Error("退出了消费循环异常", logger.Error(err))
+		}
+	}()
+	go func() {
+		err := intrCg.Consume(context.Background(), []string{intrEvents.TopicInteractiveEvent},
+			saramax.NewBatchHandler[intrEvents.InteractiveEvent](c.l, c.ConsumeInteractive))
+		if err != nil {
+			c.l.Error("退出了消费循环异常", logger.Error(err))
+		}
+	}()
+	return nil
+}
+
+// ConsumeRead 不是幂等的，重复消费会多算分数，等定时修正
+func (c *RankingEventConsumer) ConsumeRead(msgs []*sarama.ConsumerMessage, evts []article.ReadEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return c.svc.Record(ctx, slice.Map[article.ReadEvent, domain.RankingEvent](evts,
+		func(idx int, src article.ReadEvent) domain.RankingEvent {
+			return domain.RankingEvent{
+				Aid:     src.Aid,
+				ReadCnt: 1,
+			}
+		}))
+}
+
+func (c *RankingEventConsumer) ConsumeInteractive(msgs []*sarama.ConsumerMessage, evts []intrEvents.InteractiveEvent) error {
+	res := make([]domain.RankingEvent, 0, len(evts))
+	for _, evt := range evts {
+		// 热榜只有文章
+		if evt.Biz != "article" {
+			continue
+		}
+		re := domain.RankingEvent{Aid: evt.BizId}
+		switch evt.Action {
+		case intrEvents.ActionLike:
+			re.LikeCnt = 1
+		case intrEvents.ActionUnlike:
+			re.LikeCnt = -1
+		case intrEvents.ActionCollect:
+			re.CollectCnt = 1
+		case intrEvents.ActionUncollect:
+			re.CollectCnt = -1
+		default:
+			continue
+		}
+		res = append(res, re)
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return c.svc.Record(ctx, res)
+}
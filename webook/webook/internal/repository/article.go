@@ -4,52 +4,106 @@ import (
 	"context"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
-	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
 	"github.com/ecodeclub/ekit/slice"
+	"strings"
 	"time"
 )
 
+var (
+	ErrVersionConflict = article.ErrVersionConflict
+	ErrArticleNotFound = article.ErrArticleNotFound
+)
+
+// VersionConflictError 乐观锁冲突，携带服务端当前的版本号
+type VersionConflictError = article.VersionConflictError
+
+// firstPageSize 作者文章列表第一页缓存的条数
+const firstPageSize = 100
+
 type ArticleRepository interface {
 	Create(ctx context.Context, article domain.Article) (int64, error)
 	Update(ctx context.Context, article domain.Article) error
 	Sync(ctx context.Context, article domain.Article) (int64, error)
 	SyncStatus(ctx context.Context, article domain.Article) error
-	List(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
+	List(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.Article, error)
 	GetById(ctx context.Context, id int64, uid int64) (domain.Article, error)
 	GetPublishedById(ctx context.Context, id int64, uid int64) (domain.Article, error)
-	ListPub(ctx context.Context, start time.Time, offset, limit int) ([]domain.Article, error)
+	// GetPubByIds 批量查线上的文章，带作者昵称，按照 ids 的顺序返回，找不到的直接跳过
+	// 和 GetPublishedById 一样不过滤状态，调用者自己判断能不能展示
+	GetPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error)
+	ListPub(ctx context.Context, start time.Time, cursor domain.ArticleCursor, limit int) ([]domain.Article, error)
+	ListPubByAuthor(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.Article, error)
+	// ListPubUpdatedAfter 按照 (utime, id) 正序扫描线上库的变更，返回的文章只有 Id 和 Utime
+	ListPubUpdatedAfter(ctx context.Context, cursor domain.ArticleCursor, before time.Time, limit int) ([]domain.Article, error)
+	// ListPubByIdRange 返回的文章只有 Id 和 Utime
+	ListPubByIdRange(ctx context.Context, minId int64, maxId int64) ([]domain.Article, error)
+	MoveToTrash(ctx context.Context, id int64, uid int64) error
+	Restore(ctx context.Context, id int64, uid int64) error
+	ListTrash(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.Article, error)
+	PurgeTrash(ctx context.Context, before time.Time, limit int) ([]int64, error)
 }
 
 type CachedArticleRepository struct {
-	dao     article.ArticleDAO
-	userDAO dao.UserDAO
-	cache   cache.ArticleCache
-	l       logger.Logger
+	dao      article.ArticleDAO
+	userRepo UserRepository
+	cache    cache.ArticleCache
+	l        logger.Logger
 }
 
-func NewCachedArticleRepository(dao article.ArticleDAO, userDAO dao.UserDAO,
+func NewCachedArticleRepository(dao article.ArticleDAO, userRepo UserRepository,
 	cache cache.ArticleCache, l logger.Logger) ArticleRepository {
 	return &CachedArticleRepository{
-		dao:     dao,
-		userDAO: userDAO,
-		cache:   cache,
-		l:       l,
+		dao:      dao,
+		userRepo: userRepo,
+		cache:    cache,
+		l:        l,
 	}
 }
 
 func (repo *CachedArticleRepository) Create(ctx context.Context, article domain.Article) (int64, error) {
-	return repo.dao.Insert(ctx, repo.toEntity(article))
+	id, err := repo.dao.Insert(ctx, repo.toEntity(article))
+	if err == nil {
+		repo.delFirstPage(ctx, article.Author.Id)
+	}
+	return id, err
 }
 
 func (repo *CachedArticleRepository) Update(ctx context.Context, article domain.Article) error {
-	return repo.dao.UpdateById(ctx, repo.toEntity(article))
+	err := repo.dao.UpdateById(ctx, repo.toEntity(article))
+	if err == nil {
+		// 版本号变了，缓存里的草稿就不能再用了
+		err1 := repo.cache.Del(ctx, article.Id)
+		if err1 != nil {
+			repo.l.Debug("删除文章缓存失败", logger.Int64("id", article.Id),
+				logger.Error(err1))
+		}
+		// utime 变了，这篇文章在列表里的位置也就变了
+		repo.delFirstPage(ctx, article.Author.Id)
+	}
+	return err
+}
+
+// delFirstPage 第一页缓存里面的顺序要和数据库里的 (utime, id) 一致，
+// 不然根据第一页最后一条生成的游标去查第二页，就会重复或者遗漏
+func (repo *CachedArticleRepository) delFirstPage(ctx context.Context, uid int64) {
+	err := repo.cache.DelFirstPage(ctx, uid)
+	if err != nil {
+		repo.l.Debug("删除首页缓存失败", logger.Int64("uid", uid),
+			logger.Error(err))
+	}
 }
 
 func (repo *CachedArticleRepository) Sync(ctx context.Context, article domain.Article) (int64, error) {
 	id, err := repo.dao.Sync(ctx, repo.toEntity(article))
 	if err == nil {
+		// 草稿的版本号也变了
+		err1 := repo.cache.Del(ctx, id)
+		if err1 != nil {
+			repo.l.Debug("删除文章缓存失败", logger.Int64("id", id),
+				logger.Error(err1))
+		}
 		go func() {
 			// 删除缓存
 			err1 := repo.cache.DelFirstPage(ctx, article.Author.Id)
@@ -71,40 +125,70 @@ func (repo *CachedArticleRepository) Sync(ctx context.Context, article domain.Ar
 }
 
 func (repo *CachedArticleRepository) SyncStatus(ctx context.Context, article domain.Article) error {
-	return repo.dao.SyncStatus(ctx, repo.toEntity(article))
+	err := repo.dao.SyncStatus(ctx, repo.toEntity(article))
+	if err == nil {
+		// 撤回或者下架之后，线上库的缓存不能再当成已发表的文章返回
+		repo.delCaches(ctx, article.Id, article.Author.Id)
+	}
+	return err
+}
+
+// delCaches 状态或者 utime 变了，制作库、线上库的缓存，以及作者的第一页缓存都要删掉
+// 数据库已经改成功了，缓存删不掉只记日志，等缓存过期
+func (repo *CachedArticleRepository) delCaches(ctx context.Context, id int64, uid int64) {
+	err := repo.cache.Del(ctx, id)
+	if err != nil {
+		repo.l.Debug("删除文章缓存失败", logger.Int64("id", id),
+			logger.Error(err))
+	}
+	err = repo.cache.DelPub(ctx, id)
+	if err != nil {
+		repo.l.Debug("删除读者文章缓存失败", logger.Int64("id", id),
+			logger.Error(err))
+	}
+	repo.delFirstPage(ctx, uid)
 }
 
-func (repo *CachedArticleRepository) List(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
+func (repo *CachedArticleRepository) List(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.Article, error) {
 	// 你在这个地方，集成你的复杂的缓存方案
 	// 经验表明当作者打开第一页列表时，大概率会打开第一篇文章，
 	// 因此在这里将第一篇文章做一个preCache, 同时到期时间设置很短，这样即使
 	// 预测失败，也不会消耗太多空间
-	if offset == 0 && limit <= 100 {
-		data, err := repo.cache.GetFirstPage(ctx, uid)
-		if err == nil {
-			go func() {
-				// 缓存第一篇文章
-				err1 := repo.preCacheFirstArticle(ctx, data)
-				if err1 != nil {
-					repo.l.Debug("提前预加载缓存失败", logger.Int64("uid", uid),
-						logger.Error(err1))
-				}
-			}()
-			//return data[:limit], err
-			return data, err
+	// 第一页缓存固定存 firstPageSize 条，不管第一次请求的 limit 是多少，取的时候再截断
+	// limit 比缓存的还大就直接查数据库
+	if !cursor.IsZero() || limit > firstPageSize {
+		arts, err := repo.dao.GetByAuthor(ctx, uid, repo.toCursor(cursor), limit)
+		if err != nil {
+			return nil, err
 		}
+		return slice.Map[article.Article, domain.Article](arts, func(idx int, src article.Article) domain.Article {
+			return repo.toDomain(src)
+		}), nil
+	}
+
+	data, err := repo.cache.GetFirstPage(ctx, uid)
+	if err == nil {
+		go func() {
+			// 缓存第一篇文章
+			err1 := repo.preCacheFirstArticle(ctx, data)
+			if err1 != nil {
+				repo.l.Debug("提前预加载缓存失败", logger.Int64("uid", uid),
+					logger.Error(err1))
+			}
+		}()
+		return repo.firstN(data, limit), nil
 	}
-	arts, err := repo.dao.GetByAuthor(ctx, uid, offset, limit)
+	arts, err := repo.dao.GetByAuthor(ctx, uid, article.Cursor{}, firstPageSize)
 	if err != nil {
 		return nil, err
 	}
-	data := slice.Map[article.Article, domain.Article](arts, func(idx int, src article.Article) domain.Article {
+	data = slice.Map[article.Article, domain.Article](arts, func(idx int, src article.Article) domain.Article {
 		return repo.toDomain(src)
 	})
 
 	// 回写缓存的时候，可以同步，也可以异步
 	go func() {
-		err = repo.cache.SetFirstPage(ctx, uid, data)
+		err := repo.cache.SetFirstPage(ctx, uid, data)
 		if err == nil {
 			err1 := repo.preCacheFirstArticle(ctx, data)
 			if err1 != nil {
@@ -115,7 +199,14 @@ func (repo *CachedArticleRepository) List(ctx context.Context, uid int64, offset
 		}
 	}()
 
-	return data, nil
+	return repo.firstN(data, limit), nil
+}
+
+func (repo *CachedArticleRepository) firstN(data []domain.Article, n int) []domain.Article {
+	if len(data) > n {
+		return data[:n]
+	}
+	return data
 }
 
 func (repo *CachedArticleRepository) GetById(ctx context.Context, id int64, uid int64) (domain.Article, error) {
@@ -132,33 +223,91 @@ func (repo *CachedArticleRepository) GetById(ctx context.Context, id int64, uid
 }
 
 func (repo *CachedArticleRepository) GetPublishedById(ctx context.Context, id int64, uid int64) (domain.Article, error) {
-	article, err := repo.cache.GetPub(ctx, id)
+	art, err := repo.cache.GetPub(ctx, id)
 	if err == nil {
-		return article, nil
+		return art, nil
 	}
 
 	data, err := repo.dao.GetPublishedById(ctx, id)
-	if err != nil {
+	if err != nil || data.Id == 0 {
 		return domain.Article{}, err
 	}
 
-	// 这里还要得到用户的昵称
-	user, err := repo.userDAO.FindById(ctx, uid)
+	// 这里还要得到作者的昵称
+	arts, err := repo.withAuthors(ctx, []article.Article{data})
 	if err != nil {
 		return domain.Article{}, err
 	}
-	article = repo.toDomainWithUser(data, user)
+	art = arts[0]
 
 	// 回写到缓存
 	go func() {
-		err1 := repo.cache.SetPub(ctx, article, time.Minute*30)
+		err1 := repo.cache.SetPub(ctx, art, time.Minute*30)
 		if err1 != nil {
 			repo.l.Debug("GetPublishedById写入缓存失败.",
-				logger.Error(err1), logger.Int64("article ID", article.Id))
+				logger.Error(err1), logger.Int64("article ID", art.Id))
 		}
 	}()
 
-	return article, nil
+	return art, nil
+}
+
+func (repo *CachedArticleRepository) GetPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error) {
+	m, err := repo.cache.GetPubs(ctx, ids)
+	if err != nil {
+		repo.l.Debug("批量获取读者文章缓存失败", logger.Error(err))
+		m = make(map[int64]domain.Article, len(ids))
+	}
+	missing := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := m[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		entities, err := repo.dao.GetPubByIds(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		loaded, err := repo.withAuthors(ctx, entities)
+		if err != nil {
+			return nil, err
+		}
+		for _, art := range loaded {
+			m[art.Id] = art
+		}
+		err = repo.cache.SetPubs(ctx, loaded, time.Minute*30)
+		if err != nil {
+			repo.l.Debug("批量回写读者文章缓存失败", logger.Error(err))
+		}
+	}
+	return repo.inOrder(ids, m), nil
+}
+
+// withAuthors 一次查出所有作者的昵称，作者不存在的昵称为空
+// 用户走 UserRepository，先 MGET 缓存，没命中的再查数据库
+func (repo *CachedArticleRepository) withAuthors(ctx context.Context, arts []article.Article) ([]domain.Article, error) {
+	uids := make([]int64, 0, len(arts))
+	for _, art := range arts {
+		uids = append(uids, art.AuthorId)
+	}
+	users, err := repo.userRepo.FindByIds(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[article.Article, domain.Article](arts, func(idx int, src article.Article) domain.Article {
+		return repo.toDomainWithUser(src, users[src.AuthorId])
+	}), nil
+}
+
+func (repo *CachedArticleRepository) inOrder(ids []int64, m map[int64]domain.Article) []domain.Article {
+	res := make([]domain.Article, 0, len(ids))
+	for _, id := range ids {
+		if art, ok := m[id]; ok {
+			res = append(res, art)
+		}
+	}
+	return res
 }
 
 func (repo *CachedArticleRepository) preCacheFirstArticle(ctx context.Context, data []domain.Article) error {
@@ -169,8 +318,35 @@ func (repo *CachedArticleRepository) preCacheFirstArticle(ctx context.Context, d
 	return nil
 }
 
-func (repo *CachedArticleRepository) ListPub(ctx context.Context, start time.Time, offset, limit int) ([]domain.Article, error) {
-	res, err := repo.dao.ListPub(ctx, start, offset, limit)
+func (repo *CachedArticleRepository) ListPub(ctx context.Context, start time.Time, cursor domain.ArticleCursor, limit int) ([]domain.Article, error) {
+	res, err := repo.dao.ListPub(ctx, start, repo.toCursor(cursor), limit)
+	if err != nil {
+		return nil, err
+	}
+	return repo.withAuthors(ctx, res)
+}
+
+func (repo *CachedArticleRepository) ListPubByAuthor(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.Article, error) {
+	res, err := repo.dao.ListPubByAuthor(ctx, uid, repo.toCursor(cursor), limit)
+	if err != nil {
+		return nil, err
+	}
+	return repo.withAuthors(ctx, res)
+}
+
+func (repo *CachedArticleRepository) ListPubUpdatedAfter(ctx context.Context, cursor domain.ArticleCursor, before time.Time, limit int) ([]domain.Article, error) {
+	res, err := repo.dao.ListPubUpdatedAfter(ctx, repo.toCursor(cursor), before.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return slice.Map[article.Article, domain.Article](res, func(idx int, src article.Article) domain.Article {
+		return repo.toDomain(src)
+	}), nil
+}
+
+func (repo *CachedArticleRepository) ListPubByIdRange(ctx context.Context, minId int64, maxId int64) ([]domain.Article, error) {
+	res, err := repo.dao.ListPubByIdRange(ctx, minId, maxId)
 	if err != nil {
 		return nil, err
 	}
@@ -180,14 +356,63 @@ func (repo *CachedArticleRepository) ListPub(ctx context.Context, start time.Tim
 	}), nil
 }
 
+func (repo *CachedArticleRepository) MoveToTrash(ctx context.Context, id int64, uid int64) error {
+	err := repo.dao.MoveToTrash(ctx, id, uid)
+	if err == nil {
+		repo.delCaches(ctx, id, uid)
+	}
+	return err
+}
+
+func (repo *CachedArticleRepository) Restore(ctx context.Context, id int64, uid int64) error {
+	err := repo.dao.Restore(ctx, id, uid)
+	if err == nil {
+		repo.delFirstPage(ctx, uid)
+	}
+	return err
+}
+
+func (repo *CachedArticleRepository) ListTrash(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.Article, error) {
+	res, err := repo.dao.ListTrash(ctx, uid, repo.toCursor(cursor), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return slice.Map[article.Article, domain.Article](res, func(idx int, src article.Article) domain.Article {
+		return repo.toDomain(src)
+	}), nil
+}
+
+func (repo *CachedArticleRepository) PurgeTrash(ctx context.Context, before time.Time, limit int) ([]int64, error) {
+	return repo.dao.PurgeTrash(ctx, before, limit)
+}
+
+func (repo *CachedArticleRepository) toCursor(cursor domain.ArticleCursor) article.Cursor {
+	if cursor.IsZero() {
+		return article.Cursor{}
+	}
+	return article.Cursor{
+		Utime: cursor.Utime.UnixMilli(),
+		Id:    cursor.Id,
+	}
+}
+
 func (repo *CachedArticleRepository) toEntity(art domain.Article) article.Article {
-	return article.Article{
+	res := article.Article{
 		Id:       art.Id,
 		Content:  art.Content,
 		Title:    art.Title,
 		AuthorId: art.Author.Id,
 		Status:   art.Status.ToUint8(),
+		Access:   art.Access.ToUint8(),
+		Tags:     strings.Join(art.Tags, ","),
+		Version:  art.Version,
+	}
+	// 只有新建的时候会用到 Ctime，零值就让 DAO 用当前时间
+	if !art.Ctime.IsZero() {
+		res.Ctime = art.Ctime.UnixMilli()
 	}
+	return res
 }
 
 func (repo *CachedArticleRepository) toDomain(art article.Article) domain.Article {
@@ -198,13 +423,31 @@ func (repo *CachedArticleRepository) toDomain(art article.Article) domain.Articl
 		Author: domain.Author{
 			Id: art.AuthorId,
 		},
-		Status: domain.ArticleStatus(art.Status),
-		Utime:  time.UnixMilli(art.Utime),
-		Ctime:  time.UnixMilli(art.Ctime),
+		Status:  domain.ArticleStatus(art.Status),
+		Access:  domain.ArticleAccess(art.Access),
+		Tags:    repo.tags(art),
+		Version: art.Version,
+		Utime:   time.UnixMilli(art.Utime),
+		Ctime:   time.UnixMilli(art.Ctime),
+		Dtime:   repo.dtime(art),
+	}
+}
+
+func (repo *CachedArticleRepository) tags(art article.Article) []string {
+	if art.Tags == "" {
+		return nil
+	}
+	return strings.Split(art.Tags, ",")
+}
+
+func (repo *CachedArticleRepository) dtime(art article.Article) time.Time {
+	if art.Dtime == 0 {
+		return time.Time{}
 	}
+	return time.UnixMilli(art.Dtime)
 }
 
-func (repo *CachedArticleRepository) toDomainWithUser(art article.Article, user dao.User) domain.Article {
+func (repo *CachedArticleRepository) toDomainWithUser(art article.Article, user domain.User) domain.Article {
 	return domain.Article{
 		Id:      art.Id,
 		Title:   art.Title,
@@ -213,8 +456,12 @@ func (repo *CachedArticleRepository) toDomainWithUser(art article.Article, user
 			Id:   art.AuthorId,
 			Name: user.Nickname,
 		},
-		Status: domain.ArticleStatus(art.Status),
-		Utime:  time.UnixMilli(art.Utime),
-		Ctime:  time.UnixMilli(art.Ctime),
+		Status:  domain.ArticleStatus(art.Status),
+		Access:  domain.ArticleAccess(art.Access),
+		Tags:    repo.tags(art),
+		Version: art.Version,
+		Utime:   time.UnixMilli(art.Utime),
+		Ctime:   time.UnixMilli(art.Ctime),
+		Dtime:   repo.dtime(art),
 	}
 }
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
+)
+
+var ErrImportJobNotFound = cache.ErrKeyNotExist
+
+type ImportJobRepository interface {
+	Get(ctx context.Context, id string) (domain.ImportJob, error)
+	Save(ctx context.Context, job domain.ImportJob) error
+}
+
+type CachedImportJobRepository struct {
+	cache cache.ImportJobCache
+}
+
+func NewCachedImportJobRepository(cache cache.ImportJobCache) ImportJobRepository {
+	return &CachedImportJobRepository{
+		cache: cache,
+	}
+}
+
+func (repo *CachedImportJobRepository) Get(ctx context.Context, id string) (domain.ImportJob, error) {
+	return repo.cache.Get(ctx, id)
+}
+
+func (repo *CachedImportJobRepository) Save(ctx context.Context, job domain.ImportJob) error {
+	return repo.cache.Set(ctx, job)
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"testing"
+)
+
+type fakeArticleDAO struct {
+	article.ArticleDAO
+	err error
+}
+
+func (d *fakeArticleDAO) SyncStatus(ctx context.Context, art article.Article) error {
+	return d.err
+}
+
+func (d *fakeArticleDAO) MoveToTrash(ctx context.Context, id int64, uid int64) error {
+	return d.err
+}
+
+// fakeArticleCache 记录删除了哪些缓存，删除的时候都返回 err
+type fakeArticleCache struct {
+	cache.ArticleCache
+	err     error
+	deleted []string
+}
+
+func (c *fakeArticleCache) Del(ctx context.Context, id int64) error {
+	c.deleted = append(c.deleted, "draft")
+	return c.err
+}
+
+func (c *fakeArticleCache) DelPub(ctx context.Context, id int64) error {
+	c.deleted = append(c.deleted, "pub")
+	return c.err
+}
+
+func (c *fakeArticleCache) DelFirstPage(ctx context.Context, uid int64) error {
+	c.deleted = append(c.deleted, "first_page")
+	return c.err
+}
+
+func TestCachedArticleRepository_DelCaches(t *testing.T) {
+	l := logger.NewZapLogger(zap.NewNop(), false)
+	art := domain.Article{Id: 1, Author: domain.Author{Id: 123}, Status: domain.ArticleStatusPrivate}
+	ops := map[string]func(repo ArticleRepository) error{
+		"SyncStatus": func(repo ArticleRepository) error {
+			return repo.SyncStatus(context.Background(), art)
+		},
+		"MoveToTrash": func(repo ArticleRepository) error {
+			return repo.MoveToTrash(context.Background(), art.Id, art.Author.Id)
+		},
+	}
+	testCases := []struct {
+		name        string
+		daoErr      error
+		cacheErr    error
+		wantErr     error
+		wantDeleted []string
+	}{
+		{
+			name:        "成功",
+			wantDeleted: []string{"draft", "pub", "first_page"},
+		},
+		{
+			name:        "缓存删不掉也算成功",
+			cacheErr:    errors.New("redis 崩了"),
+			wantDeleted: []string{"draft", "pub", "first_page"},
+		},
+		{
+			name:    "数据库失败不动缓存",
+			daoErr:  ErrArticleNotFound,
+			wantErr: ErrArticleNotFound,
+		},
+	}
+	for op, fn := range ops {
+		for _, tc := range testCases {
+			t.Run(op+"/"+tc.name, func(t *testing.T) {
+				c := &fakeArticleCache{err: tc.cacheErr}
+				repo := NewCachedArticleRepository(&fakeArticleDAO{err: tc.daoErr}, nil, c, l)
+				err := fn(repo)
+				assert.Equal(t, tc.wantErr, err)
+				assert.Equal(t, tc.wantDeleted, c.deleted)
+			})
+		}
+	}
+}
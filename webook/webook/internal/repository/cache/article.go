@@ -12,11 +12,17 @@ import (
 type ArticleCache interface {
 	Get(ctx context.Context, id int64) (domain.Article, error)
 	Set(ctx context.Context, article domain.Article, time time.Duration) error
+	Del(ctx context.Context, id int64) error
 	GetFirstPage(ctx context.Context, uid int64) ([]domain.Article, error)
 	SetFirstPage(ctx context.Context, uid int64, data []domain.Article) error
 	DelFirstPage(ctx context.Context, uid int64) error
 	GetPub(ctx context.Context, id int64) (domain.Article, error)
 	SetPub(ctx context.Context, article domain.Article, time time.Duration) error
+	DelPub(ctx context.Context, id int64) error
+	// GetPubs 一次 MGET，返回的只有缓存里有的
+	GetPubs(ctx context.Context, ids []int64) (map[int64]domain.Article, error)
+	// SetPubs 用 pipeline 一次写回去
+	SetPubs(ctx context.Context, arts []domain.Article, time time.Duration) error
 }
 
 type RedisArticleCache struct {
@@ -61,6 +67,10 @@ func (r *RedisArticleCache) Set(ctx context.Context, article domain.Article, tim
 	return r.client.Set(ctx, r.articleIdKey(article.Id), data, time).Err()
 }
 
+func (r *RedisArticleCache) Del(ctx context.Context, id int64) error {
+	return r.client.Del(ctx, r.articleIdKey(id)).Err()
+}
+
 func (r *RedisArticleCache) SetPub(ctx context.Context, article domain.Article, time time.Duration) error {
 	data, err := json.Marshal(article)
 	if err != nil {
@@ -69,6 +79,53 @@ func (r *RedisArticleCache) SetPub(ctx context.Context, article domain.Article,
 	return r.client.Set(ctx, r.publishArticleIdKey(article.Id), data, time).Err()
 }
 
+func (r *RedisArticleCache) DelPub(ctx context.Context, id int64) error {
+	return r.client.Del(ctx, r.publishArticleIdKey(id)).Err()
+}
+
+func (r *RedisArticleCache) GetPubs(ctx context.Context, ids []int64) (map[int64]domain.Article, error) {
+	res := make(map[int64]domain.Article, len(ids))
+	if len(ids) == 0 {
+		return res, nil
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, r.publishArticleIdKey(id))
+	}
+	vals, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return res, err
+	}
+	for _, val := range vals {
+		str, ok := val.(string)
+		if !ok {
+			continue
+		}
+		var art domain.Article
+		// 解析不了的当作没有缓存，回头再从数据库里查
+		if json.Unmarshal([]byte(str), &art) == nil {
+			res[art.Id] = art
+		}
+	}
+	return res, nil
+}
+
+func (r *RedisArticleCache) SetPubs(ctx context.Context, arts []domain.Article, time time.Duration) error {
+	if len(arts) == 0 {
+		return nil
+	}
+	pipe := r.client.Pipeline()
+	for _, art := range arts {
+		data, err := json.Marshal(art)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, r.publishArticleIdKey(art.Id), data, time)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 func (r *RedisArticleCache) GetFirstPage(ctx context.Context, uid int64) ([]domain.Article, error) {
 	data, err := r.client.Get(ctx, r.firstPageKey(uid)).Bytes()
 	if err != nil {
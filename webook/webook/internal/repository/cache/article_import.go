@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+type ImportJobCache interface {
+	Get(ctx context.Context, id string) (domain.ImportJob, error)
+	Set(ctx context.Context, job domain.ImportJob) error
+}
+
+// RedisImportJobCache 导入任务的进度只需要保留一段时间，没必要落库
+type RedisImportJobCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewRedisImportJobCache(client redis.Cmdable) ImportJobCache {
+	return &RedisImportJobCache{
+		client:     client,
+		expiration: time.Hour * 24,
+	}
+}
+
+func (r *RedisImportJobCache) Get(ctx context.Context, id string) (domain.ImportJob, error) {
+	data, err := r.client.Get(ctx, r.key(id)).Bytes()
+	if err != nil {
+		return domain.ImportJob{}, err
+	}
+	var res domain.ImportJob
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
+
+func (r *RedisImportJobCache) Set(ctx context.Context, job domain.ImportJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.key(job.Id), data, r.expiration).Err()
+}
+
+func (r *RedisImportJobCache) key(id string) string {
+	return "article:import:" + id
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+type FeedCache interface {
+	Get(ctx context.Context, key string) (domain.FeedDoc, error)
+	Set(ctx context.Context, key string, doc domain.FeedDoc) error
+	Del(ctx context.Context, keys ...string) error
+}
+
+type RedisFeedCache struct {
+	client redis.Cmdable
+	// 订阅源不需要很实时，阅读器一般也是几十分钟拉一次
+	expiration time.Duration
+}
+
+func NewRedisFeedCache(client redis.Cmdable) FeedCache {
+	return &RedisFeedCache{
+		client:     client,
+		expiration: time.Minute * 5,
+	}
+}
+
+func (r *RedisFeedCache) Get(ctx context.Context, key string) (domain.FeedDoc, error) {
+	data, err := r.client.Get(ctx, r.key(key)).Bytes()
+	if err != nil {
+		return domain.FeedDoc{}, err
+	}
+	var res domain.FeedDoc
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
+
+func (r *RedisFeedCache) Set(ctx context.Context, key string, doc domain.FeedDoc) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.key(key), data, r.expiration).Err()
+}
+
+func (r *RedisFeedCache) Del(ctx context.Context, keys ...string) error {
+	redisKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		redisKeys = append(redisKeys, r.key(key))
+	}
+	return r.client.Del(ctx, redisKeys...).Err()
+}
+
+func (r *RedisFeedCache) key(key string) string {
+	return "feed:" + key
+}
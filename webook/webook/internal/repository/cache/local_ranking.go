@@ -3,7 +3,7 @@ package cache
 import (
 	"context"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
-	"github.com/ecodeclub/ekit/syncx/atomicx"
+	"github.com/ecodeclub/ekit/syncx"
 	"github.com/pkg/errors"
 	"time"
 )
@@ -11,36 +11,50 @@ import (
 type LocalRankingCache struct {
 	// 我用我的泛型封装
 	// 你可以考虑直接使用 uber 的，或者 SDK 自带的
-	topN       *atomicx.Value[[]domain.Article]
-	ddl        *atomicx.Value[time.Time] // 下一次调度时间
+	rankings   *syncx.Map[string, localRanking]
 	expiration time.Duration
 }
 
+type localRanking struct {
+	ranking domain.Ranking
+	ddl     time.Time // 下一次调度时间
+}
+
 func NewLocalRankingCache(expiration time.Duration) *LocalRankingCache {
 	return &LocalRankingCache{
-		topN:       atomicx.NewValue[[]domain.Article](),
-		ddl:        atomicx.NewValueOf(time.Now()),
+		rankings:   &syncx.Map[string, localRanking]{},
 		expiration: expiration,
 	}
 }
 
-func (cache *LocalRankingCache) Set(ctx context.Context, arts []domain.Article) error {
+func (cache *LocalRankingCache) Set(ctx context.Context, ranking domain.Ranking) error {
 	// 也可以按照 id => Article 缓存
-	cache.topN.Store(arts)
-	ddl := time.Now().Add(cache.expiration)
-	cache.ddl.Store(ddl)
+	cache.rankings.Store(ranking.Name, localRanking{
+		ranking: ranking,
+		ddl:     time.Now().Add(cache.expiration),
+	})
 	return nil
 }
 
-func (cache *LocalRankingCache) Get(ctx context.Context) ([]domain.Article, error) {
-	arts := cache.topN.Load()
-	if len(arts) == 0 || cache.ddl.Load().Before(time.Now()) {
-		return nil, errors.New("本地缓存未命中")
+func (cache *LocalRankingCache) Get(ctx context.Context, name string) (domain.Ranking, error) {
+	val, ok := cache.rankings.Load(name)
+	if !ok || len(val.ranking.Items) == 0 || val.ddl.Before(time.Now()) {
+		return domain.Ranking{}, errors.New("本地缓存未命中")
 	}
-	return arts, nil
+	return val.ranking, nil
+}
+
+// Remove 只改本实例的本地缓存，其它实例的本地缓存要等过期
+func (cache *LocalRankingCache) Remove(ctx context.Context, id int64) error {
+	cache.rankings.Range(func(name string, val localRanking) bool {
+		val.ranking = val.ranking.Remove(id)
+		cache.rankings.Store(name, val)
+		return true
+	})
+	return nil
 }
 
-func (cache *LocalRankingCache) ForceGet(ctx context.Context) ([]domain.Article, error) {
-	arts := cache.topN.Load()
-	return arts, nil
+func (cache *LocalRankingCache) ForceGet(ctx context.Context, name string) (domain.Ranking, error) {
+	val, _ := cache.rankings.Load(name)
+	return val.ranking, nil
 }
@@ -0,0 +1,22 @@
+-- 按照阅读时间排序的 zset，member 是文章 id
+local key = KEYS[1]
+-- 阅读位置之类的放在 hash 里，field 是文章 id
+local itemKey = KEYS[2]
+local aid = ARGV[1]
+local utime = tonumber(ARGV[2])
+local item = ARGV[3]
+-- 最多缓存多少条
+local capacity = tonumber(ARGV[4])
+local expiration = tonumber(ARGV[5])
+
+redis.call("ZADD", key, utime, aid)
+redis.call("HSET", itemKey, aid, item)
+-- 超出容量的，把最早读的那些删掉
+local trimmed = redis.call("ZRANGE", key, 0, -(capacity + 1))
+if #trimmed > 0 then
+    redis.call("ZREMRANGEBYRANK", key, 0, -(capacity + 1))
+    redis.call("HDEL", itemKey, unpack(trimmed))
+end
+redis.call("EXPIRE", key, expiration)
+redis.call("EXPIRE", itemKey, expiration)
+return 0
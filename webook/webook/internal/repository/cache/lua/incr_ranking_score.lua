@@ -0,0 +1,24 @@
+-- 实时热榜的分数，member 是文章 id
+-- 存的是 互动分 * 2 ^ ((utime - epoch) / halfLife)，排序和衰减之后的分数一样，
+-- 不用随着时间去改每一篇文章的分数
+local key = KEYS[1]
+-- epoch 和 halfLife 放在 hash 里
+local metaKey = KEYS[2]
+local halfLife = tonumber(ARGV[1])
+local now = tonumber(ARGV[2])
+
+local meta = redis.call("HMGET", metaKey, "epoch", "halfLife")
+local epoch = tonumber(meta[1])
+if epoch == nil or tonumber(meta[2]) ~= halfLife then
+    -- 还没有初始化，或者半衰期改了，原来的分数没法换算，清掉等下一次修正
+    redis.call("DEL", key)
+    epoch = now
+    redis.call("HSET", metaKey, "epoch", epoch, "halfLife", halfLife)
+end
+
+-- 后面是 aid, points, utime 三个一组
+for i = 3, #ARGV, 3 do
+    local delta = tonumber(ARGV[i + 1]) * 2 ^ ((tonumber(ARGV[i + 2]) - epoch) / halfLife)
+    redis.call("ZINCRBY", key, delta, ARGV[i])
+end
+return 0
@@ -0,0 +1,14 @@
+-- 把文章从热榜里面拿掉，新的值在 Go 里面算好，这里只在旧值没有变的时候才写回去
+-- 后面是 field, 旧值, 新值 三个一组
+-- 期间热榜被重新计算过、或者整个 key 已经过期了，HGET 拿到的和旧值对不上，返回 0 让调用方重试
+-- 这样不会把一个没有过期时间的 key 写回去，HSET 本身也不改变过期时间
+local key = KEYS[1]
+for i = 1, #ARGV, 3 do
+    if redis.call("HGET", key, ARGV[i]) ~= ARGV[i + 1] then
+        return 0
+    end
+end
+for i = 1, #ARGV, 3 do
+    redis.call("HSET", key, ARGV[i], ARGV[i + 2])
+end
+return 1
@@ -0,0 +1,14 @@
+-- 用全量计算的结果整个替换实时热榜的分数，顺便把 epoch 挪到现在，
+-- 这样存下来的就是现在的分数，指数也不会越来越大
+local key = KEYS[1]
+local metaKey = KEYS[2]
+local halfLife = tonumber(ARGV[1])
+local now = tonumber(ARGV[2])
+
+redis.call("DEL", key)
+redis.call("HSET", metaKey, "epoch", now, "halfLife", halfLife)
+-- 后面是 score, aid 两个一组，正好是 ZADD 的参数
+for i = 3, #ARGV, 2 do
+    redis.call("ZADD", key, ARGV[i], ARGV[i + 1])
+end
+return 0
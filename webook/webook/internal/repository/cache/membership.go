@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// MembershipCache 看会员文章的时候每次都要查，所以按照 uid 缓存会员的过期时间
+type MembershipCache interface {
+	// Get 返回过期时间的毫秒数，0 代表不是会员，也会缓存
+	Get(ctx context.Context, uid int64) (int64, error)
+	Set(ctx context.Context, uid int64, expireAt int64) error
+	Del(ctx context.Context, uid int64) error
+}
+
+type RedisMembershipCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewRedisMembershipCache(client redis.Cmdable) MembershipCache {
+	return &RedisMembershipCache{
+		client:     client,
+		expiration: time.Minute * 10,
+	}
+}
+
+func (r *RedisMembershipCache) Get(ctx context.Context, uid int64) (int64, error) {
+	return r.client.Get(ctx, r.key(uid)).Int64()
+}
+
+func (r *RedisMembershipCache) Set(ctx context.Context, uid int64, expireAt int64) error {
+	return r.client.Set(ctx, r.key(uid), expireAt, r.expiration).Err()
+}
+
+func (r *RedisMembershipCache) Del(ctx context.Context, uid int64) error {
+	return r.client.Del(ctx, r.key(uid)).Err()
+}
+
+func (r *RedisMembershipCache) key(uid int64) string {
+	return fmt.Sprintf("membership:%d", uid)
+}
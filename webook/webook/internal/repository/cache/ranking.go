@@ -2,17 +2,30 @@ package cache
 
 import (
 	"context"
+	_ "embed"
 	"encoding/json"
+	"errors"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
 	"github.com/redis/go-redis/v9"
 	"time"
 )
 
+var (
+	//go:embed lua/remove_ranking_item.lua
+	luaRemoveRankingItem string
+	// ErrRankingConcurrentModified 删除文章的时候热榜一直在被修改，重试几次都没有成功
+	ErrRankingConcurrentModified = errors.New("热榜被并发修改")
+)
+
 type RankingCache interface {
-	Set(ctx context.Context, arts []domain.Article) error
-	Get(ctx context.Context) ([]domain.Article, error)
+	Set(ctx context.Context, ranking domain.Ranking) error
+	Get(ctx context.Context, name string) (domain.Ranking, error)
+	// Remove 从所有的热榜里面拿掉
+	Remove(ctx context.Context, id int64) error
 }
 
+// RedisRankingCache 所有的热榜放在同一个 hash 里，field 是热榜的名字
+// 它们是同一个任务一起算出来的，一起过期
 type RedisRankingCache struct {
 	client     redis.Cmdable
 	key        string
@@ -27,26 +40,76 @@ func NewRedisRankingCache(client redis.Cmdable, key string, expiration time.Dura
 	}
 }
 
-func (r *RedisRankingCache) Set(ctx context.Context, arts []domain.Article) error {
-	for i := 0; i < len(arts); i++ {
-		arts[i].Content = "" //热榜无需保存文章内容，设为空节省redis空间
+func (r *RedisRankingCache) Set(ctx context.Context, ranking domain.Ranking) error {
+	// Items 和调用方（比如本地缓存）共用同一个底层数组，复制一份再改
+	items := make([]domain.RankingItem, len(ranking.Items))
+	copy(items, ranking.Items)
+	for i := 0; i < len(items); i++ {
+		items[i].Article.Content = "" //热榜无需保存文章内容，设为空节省redis空间
 	}
-	val, err := json.Marshal(arts)
+	ranking.Items = items
+	val, err := json.Marshal(ranking)
 	if err != nil {
 		return err
 	}
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, r.key, ranking.Name, val)
 	// 这个过期时间要稍微长一点，最好是超过计算热榜的时间（包含重试在内的时间）
 	// 你甚至可以直接永不过期
-	return r.client.Set(ctx, r.key, val, time.Minute*10).Err()
+	pipe.Expire(ctx, r.key, r.expiration)
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
-func (r *RedisRankingCache) Get(ctx context.Context) ([]domain.Article, error) {
-	data, err := r.client.Get(ctx, r.key).Bytes()
+func (r *RedisRankingCache) Get(ctx context.Context, name string) (domain.Ranking, error) {
+	data, err := r.client.HGet(ctx, r.key, name).Bytes()
 	if err != nil {
-		return nil, err
+		return domain.Ranking{}, err
 	}
 
-	var res []domain.Article
-	err = json.Unmarshal(data, res)
+	var res domain.Ranking
+	err = json.Unmarshal(data, &res)
 	return res, err
 }
+
+// Remove 文章被删除的时候，把它从热榜里面拿掉，剩下的顺序不变
+// 读出来改完再用 lua 比较并写回去，期间被别人改过就重新来一遍
+func (r *RedisRankingCache) Remove(ctx context.Context, id int64) error {
+	for i := 0; i < 3; i++ {
+		ok, err := r.remove(ctx, id)
+		if err != nil || ok {
+			return err
+		}
+	}
+	return ErrRankingConcurrentModified
+}
+
+func (r *RedisRankingCache) remove(ctx context.Context, id int64) (bool, error) {
+	data, err := r.client.HGetAll(ctx, r.key).Result()
+	if err != nil {
+		return false, err
+	}
+	args := make([]any, 0, len(data)*3)
+	for name, val := range data {
+		var ranking domain.Ranking
+		err = json.Unmarshal([]byte(val), &ranking)
+		if err != nil {
+			return false, err
+		}
+		removed := ranking.Remove(id)
+		if len(removed.Items) == len(ranking.Items) {
+			// 不在这个热榜里
+			continue
+		}
+		res, err := json.Marshal(removed)
+		if err != nil {
+			return false, err
+		}
+		args = append(args, name, val, res)
+	}
+	if len(args) == 0 {
+		return true, nil
+	}
+	res, err := r.client.Eval(ctx, luaRemoveRankingItem, []string{r.key}, args...).Int()
+	return res == 1, err
+}
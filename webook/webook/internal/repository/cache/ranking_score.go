@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	//go:embed lua/incr_ranking_score.lua
+	luaIncrRankingScore string
+	//go:embed lua/reset_ranking_score.lua
+	luaResetRankingScore string
+)
+
+// RedisRankingScoreCache 实时热榜的分数，互动事件来了就累加，定时用全量计算的结果修正
+type RedisRankingScoreCache struct {
+	client  redis.Cmdable
+	key     string
+	metaKey string
+}
+
+func NewRedisRankingScoreCache(client redis.Cmdable, key string) *RedisRankingScoreCache {
+	return &RedisRankingScoreCache{
+		client:  client,
+		key:     key,
+		metaKey: key + ":meta",
+	}
+}
+
+func (r *RedisRankingScoreCache) Incr(ctx context.Context, deltas []domain.RankingDelta, halfLife time.Duration) error {
+	args := make([]any, 0, 2+len(deltas)*3)
+	args = append(args, halfLife.Milliseconds(), time.Now().UnixMilli())
+	for _, d := range deltas {
+		args = append(args, d.Aid, d.Points, d.Utime.UnixMilli())
+	}
+	return r.client.Eval(ctx, luaIncrRankingScore, []string{r.key, r.metaKey}, args...).Err()
+}
+
+// Reset items 里的分数是现在的分数
+func (r *RedisRankingScoreCache) Reset(ctx context.Context, items []domain.RankingItem, halfLife time.Duration) error {
+	args := make([]any, 0, 2+len(items)*2)
+	args = append(args, halfLife.Milliseconds(), time.Now().UnixMilli())
+	for _, item := range items {
+		args = append(args, item.Score, item.Article.Id)
+	}
+	return r.client.Eval(ctx, luaResetRankingScore, []string{r.key, r.metaKey}, args...).Err()
+}
+
+// Trim 只留分数最高的 capacity 篇
+func (r *RedisRankingScoreCache) Trim(ctx context.Context, capacity int) error {
+	return r.client.ZRemRangeByRank(ctx, r.key, 0, int64(-(capacity + 1))).Err()
+}
+
+// Top 返回的文章只有 Id，分数已经衰减到了现在
+func (r *RedisRankingScoreCache) Top(ctx context.Context, n int) ([]domain.RankingItem, error) {
+	pipe := r.client.Pipeline()
+	zs := pipe.ZRevRangeWithScores(ctx, r.key, 0, int64(n-1))
+	meta := pipe.HMGet(ctx, r.metaKey, "epoch", "halfLife")
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vals := meta.Val()
+	epoch, err1 := parseInt(vals[0])
+	halfLife, err2 := parseInt(vals[1])
+	if err1 != nil || err2 != nil || halfLife <= 0 {
+		// 还没有初始化
+		return nil, nil
+	}
+	// 从 epoch 衰减到现在
+	decay := math.Pow(2, float64(epoch-time.Now().UnixMilli())/float64(halfLife))
+	res := make([]domain.RankingItem, 0, len(zs.Val()))
+	for _, z := range zs.Val() {
+		id, err := strconv.ParseInt(z.Member.(string), 10, 64)
+		if err != nil {
+			continue
+		}
+		res = append(res, domain.RankingItem{
+			Article: domain.Article{Id: id},
+			Score:   math.Max(z.Score*decay, 0),
+		})
+	}
+	return res, nil
+}
+
+func (r *RedisRankingScoreCache) Remove(ctx context.Context, id int64) error {
+	return r.client.ZRem(ctx, r.key, id).Err()
+}
+
+func parseInt(val any) (int64, error) {
+	str, _ := val.(string)
+	return strconv.ParseInt(str, 10, 64)
+}
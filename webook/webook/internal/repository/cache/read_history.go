@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed lua/add_read_history.lua
+var luaAddReadHistory string
+
+// ReadHistoryCache 每个用户只缓存最近读的 capacity 篇，更早的去数据库里查
+// 缓存里没有的记录，阅读时间一定不晚于缓存里最早的那条
+type ReadHistoryCache interface {
+	Add(ctx context.Context, h domain.ReadHistory) error
+	// List 按照 (utime, aid) 倒序，缓存里的不够的时候返回的会少于 limit 条
+	// 只要有一条记录不完整就返回 ErrKeyNotExist
+	List(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.ReadHistory, error)
+	Del(ctx context.Context, uid int64, aids ...int64) error
+	DelAll(ctx context.Context, uid int64) error
+}
+
+type RedisReadHistoryCache struct {
+	client     redis.Cmdable
+	capacity   int
+	expiration time.Duration
+}
+
+func NewRedisReadHistoryCache(client redis.Cmdable) ReadHistoryCache {
+	return &RedisReadHistoryCache{
+		client:     client,
+		capacity:   200,
+		expiration: time.Hour * 24 * 7,
+	}
+}
+
+func (r *RedisReadHistoryCache) Add(ctx context.Context, h domain.ReadHistory) error {
+	data, err := json.Marshal(readHistoryItem{
+		Position: h.Position,
+		Progress: h.Progress,
+		Utime:    h.Utime.UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+	return r.client.Eval(ctx, luaAddReadHistory,
+		[]string{r.key(h.Uid), r.itemKey(h.Uid)},
+		h.Aid, h.Utime.UnixMilli(), data, r.capacity, int64(r.expiration/time.Second)).Err()
+}
+
+func (r *RedisReadHistoryCache) List(ctx context.Context, uid int64,
+	cursor domain.ArticleCursor, limit int) ([]domain.ReadHistory, error) {
+	max := "+inf"
+	if !cursor.IsZero() {
+		max = strconv.FormatInt(cursor.Utime.UnixMilli(), 10)
+	}
+	// 同一毫秒里读的文章在 zset 里是按照字符串排序的，多取一些在这里重新排序
+	members, err := r.client.ZRevRangeByScore(ctx, r.key(uid), &redis.ZRangeBy{
+		Max:   max,
+		Min:   "-inf",
+		Count: int64(limit + 20),
+	}).Result()
+	if err != nil || len(members) == 0 {
+		return nil, err
+	}
+	vals, err := r.client.HMGet(ctx, r.itemKey(uid), members...).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.ReadHistory, 0, len(members))
+	for i, val := range vals {
+		str, ok := val.(string)
+		if !ok {
+			return nil, ErrKeyNotExist
+		}
+		var item readHistoryItem
+		err = json.Unmarshal([]byte(str), &item)
+		if err != nil {
+			return nil, err
+		}
+		aid, err := strconv.ParseInt(members[i], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		h := domain.ReadHistory{
+			Uid:      uid,
+			Aid:      aid,
+			Position: item.Position,
+			Progress: item.Progress,
+			Utime:    time.UnixMilli(item.Utime),
+		}
+		if !cursor.IsZero() && !r.before(h.Cursor(), cursor) {
+			continue
+		}
+		res = append(res, h)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return r.before(res[j].Cursor(), res[i].Cursor())
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (r *RedisReadHistoryCache) Del(ctx context.Context, uid int64, aids ...int64) error {
+	if len(aids) == 0 {
+		return nil
+	}
+	members := make([]any, 0, len(aids))
+	fields := make([]string, 0, len(aids))
+	for _, aid := range aids {
+		members = append(members, aid)
+		fields = append(fields, strconv.FormatInt(aid, 10))
+	}
+	pipe := r.client.TxPipeline()
+	pipe.ZRem(ctx, r.key(uid), members...)
+	pipe.HDel(ctx, r.itemKey(uid), fields...)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisReadHistoryCache) DelAll(ctx context.Context, uid int64) error {
+	return r.client.Del(ctx, r.key(uid), r.itemKey(uid)).Err()
+}
+
+// before a 是否排在 b 后面，也就是 (utime, aid) 比 b 小
+func (r *RedisReadHistoryCache) before(a, b domain.ArticleCursor) bool {
+	au, bu := a.Utime.UnixMilli(), b.Utime.UnixMilli()
+	return au < bu || (au == bu && a.Id < b.Id)
+}
+
+func (r *RedisReadHistoryCache) key(uid int64) string {
+	return fmt.Sprintf("read_history:%d", uid)
+}
+
+func (r *RedisReadHistoryCache) itemKey(uid int64) string {
+	return fmt.Sprintf("read_history:item:%d", uid)
+}
+
+type readHistoryItem struct {
+	Position int64 `json:"position"`
+	Progress int   `json:"progress"`
+	Utime    int64 `json:"utime"`
+}
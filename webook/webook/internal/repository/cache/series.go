@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// SeriesCache 文章详情页每次都要查文章属于哪个系列，所以两个都缓存
+type SeriesCache interface {
+	// Get 包括系列里的文章 id
+	Get(ctx context.Context, id int64) (domain.Series, error)
+	Set(ctx context.Context, s domain.Series) error
+	Del(ctx context.Context, id int64) error
+	// GetByArticle 文章所在的系列 id，0 代表不在任何系列里，也会缓存
+	GetByArticle(ctx context.Context, aid int64) (int64, error)
+	SetByArticle(ctx context.Context, aid int64, sid int64) error
+	DelByArticle(ctx context.Context, aids ...int64) error
+}
+
+type RedisSeriesCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewRedisSeriesCache(client redis.Cmdable) SeriesCache {
+	return &RedisSeriesCache{
+		client:     client,
+		expiration: time.Minute * 10,
+	}
+}
+
+func (r *RedisSeriesCache) Get(ctx context.Context, id int64) (domain.Series, error) {
+	data, err := r.client.Get(ctx, r.key(id)).Bytes()
+	if err != nil {
+		return domain.Series{}, err
+	}
+	var s domain.Series
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+func (r *RedisSeriesCache) Set(ctx context.Context, s domain.Series) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.key(s.Id), data, r.expiration).Err()
+}
+
+func (r *RedisSeriesCache) Del(ctx context.Context, id int64) error {
+	return r.client.Del(ctx, r.key(id)).Err()
+}
+
+func (r *RedisSeriesCache) GetByArticle(ctx context.Context, aid int64) (int64, error) {
+	return r.client.Get(ctx, r.articleKey(aid)).Int64()
+}
+
+func (r *RedisSeriesCache) SetByArticle(ctx context.Context, aid int64, sid int64) error {
+	return r.client.Set(ctx, r.articleKey(aid), sid, r.expiration).Err()
+}
+
+func (r *RedisSeriesCache) DelByArticle(ctx context.Context, aids ...int64) error {
+	if len(aids) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(aids))
+	for _, aid := range aids {
+		keys = append(keys, r.articleKey(aid))
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
+
+func (r *RedisSeriesCache) key(id int64) string {
+	return fmt.Sprintf("series:%d", id)
+}
+
+func (r *RedisSeriesCache) articleKey(aid int64) string {
+	return fmt.Sprintf("series:article:%d", aid)
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// TopLikeCache 组装好的点赞榜，按照取前几名分别缓存
+type TopLikeCache interface {
+	Get(ctx context.Context, n int64) ([]domain.TopArticle, error)
+	Set(ctx context.Context, n int64, arts []domain.TopArticle) error
+}
+
+type RedisTopLikeCache struct {
+	client redis.Cmdable
+	// 点赞数一直在变，只缓存很短的时间，挡住突发的请求就可以了
+	expiration time.Duration
+}
+
+func NewRedisTopLikeCache(client redis.Cmdable) TopLikeCache {
+	return &RedisTopLikeCache{
+		client:     client,
+		expiration: time.Second * 30,
+	}
+}
+
+func (r *RedisTopLikeCache) Get(ctx context.Context, n int64) ([]domain.TopArticle, error) {
+	data, err := r.client.Get(ctx, r.key(n)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var res []domain.TopArticle
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
+
+func (r *RedisTopLikeCache) Set(ctx context.Context, n int64, arts []domain.TopArticle) error {
+	// 调用方还要用这些文章，复制一份再改
+	vals := make([]domain.TopArticle, len(arts))
+	copy(vals, arts)
+	for i := range vals {
+		// 榜单上只展示摘要
+		vals[i].Article.Content = vals[i].Article.Abstract()
+	}
+	data, err := json.Marshal(vals)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.key(n), data, r.expiration).Err()
+}
+
+func (r *RedisTopLikeCache) key(n int64) string {
+	return fmt.Sprintf("top_like:article:%d", n)
+}
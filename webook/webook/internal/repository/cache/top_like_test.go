@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unreachableRedis 连不上的 redis，只用来检查写 redis 之前有没有改掉调用方的数据
+func unreachableRedis() redis.Cmdable {
+	return redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: time.Millisecond * 100,
+		MaxRetries:  -1,
+	})
+}
+
+func TestRedisTopLikeCache_SetKeepsContent(t *testing.T) {
+	content := strings.Repeat("正文", 200)
+	arts := []domain.TopArticle{
+		{Article: domain.Article{Id: 1, Content: content}},
+	}
+	c := NewRedisTopLikeCache(unreachableRedis())
+	_ = c.Set(context.Background(), 10, arts)
+	assert.Equal(t, content, arts[0].Article.Content)
+}
+
+func TestRedisRankingCache_SetKeepsContent(t *testing.T) {
+	ranking := domain.Ranking{
+		Name: "daily",
+		Items: []domain.RankingItem{
+			{Article: domain.Article{Id: 1, Content: "正文"}},
+		},
+	}
+	c := NewRedisRankingCache(unreachableRedis(), "ranking", time.Minute)
+	_ = c.Set(context.Background(), ranking)
+	assert.Equal(t, "正文", ranking.Items[0].Article.Content)
+}
@@ -14,6 +14,10 @@ var ErrKeyNotExist = redis.Nil
 type UserCache interface {
 	Get(ctx context.Context, id int64) (domain.User, error)
 	Set(ctx context.Context, u domain.User) error
+	// GetMulti 一次 MGET，返回的只有缓存里有的
+	GetMulti(ctx context.Context, ids []int64) (map[int64]domain.User, error)
+	// SetMulti 用 pipeline 一次写回去
+	SetMulti(ctx context.Context, us []domain.User) error
 }
 
 type RedisUserCache struct {
@@ -56,6 +60,49 @@ func (cache *RedisUserCache) Set(ctx context.Context, u domain.User) error {
 	return cache.client.Set(ctx, key, val, cache.expiration).Err()
 }
 
+func (cache *RedisUserCache) GetMulti(ctx context.Context, ids []int64) (map[int64]domain.User, error) {
+	res := make(map[int64]domain.User, len(ids))
+	if len(ids) == 0 {
+		return res, nil
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, cache.key(id))
+	}
+	vals, err := cache.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return res, err
+	}
+	for _, val := range vals {
+		str, ok := val.(string)
+		if !ok {
+			continue
+		}
+		var u domain.User
+		// 解析不了的当作没有缓存，回头再从数据库里查
+		if json.Unmarshal([]byte(str), &u) == nil {
+			res[u.Id] = u
+		}
+	}
+	return res, nil
+}
+
+func (cache *RedisUserCache) SetMulti(ctx context.Context, us []domain.User) error {
+	if len(us) == 0 {
+		return nil
+	}
+	pipe := cache.client.Pipeline()
+	for _, u := range us {
+		val, err := json.Marshal(u)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, cache.key(u.Id), val, cache.expiration)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 func (cache *RedisUserCache) key(id int64) string {
 	return fmt.Sprintf("user:info:%d", id)
 }
@@ -15,7 +15,7 @@ type GORMArticleDAO struct {
 func (dao *GORMArticleDAO) GetById(ctx context.Context, id int64, uid int64) (Article, error) {
 	var article Article
 	err := dao.db.WithContext(ctx).Model(&Article{}).
-		Where("id = ? AND author_id = ?", id, uid).
+		Where("id = ? AND author_id = ? AND dtime = 0", id, uid).
 		Find(&article).Error
 	if err != nil {
 		return Article{}, err
@@ -32,12 +32,18 @@ func NewGORMArticleDAO(db *gorm.DB) ArticleDAO {
 
 func (dao *GORMArticleDAO) Insert(ctx context.Context, article Article) (int64, error) {
 	now := time.Now().UnixMilli()
-	article.Ctime = now
+	// 从别的平台导入的文章会带上原来的创建时间
+	if article.Ctime == 0 {
+		article.Ctime = now
+	}
 	article.Utime = now
+	article.Version = 1
 	err := dao.db.WithContext(ctx).Create(&article).Error
 	return article.Id, err
 }
 
+// UpdateById 带乐观锁的更新，article.Version 是前端编辑时拿到的版本号
+// 更新成功之后，版本号就是 article.Version + 1
 func (dao *GORMArticleDAO) UpdateById(ctx context.Context, article Article) error {
 	now := time.Now().UnixMilli()
 	article.Utime = now
@@ -45,18 +51,35 @@ func (dao *GORMArticleDAO) UpdateById(ctx context.Context, article Article) erro
 	//dao.db.WithContext(ctx).Save(&article)
 	// 可读性很差
 	// 这里需要加上文章作者和传入的uid一致，以防止修改其他人的文章
+	// 回收站里的文章不能编辑，也不能发表，Sync 也是走这里的
 	res := dao.db.WithContext(ctx).Model(&Article{}).
-		Where("id = ? AND author_id = ?", article.Id, article.AuthorId).
+		Where("id = ? AND author_id = ? AND version = ? AND dtime = 0",
+			article.Id, article.AuthorId, article.Version).
 		Updates(map[string]any{
 			"title":   article.Title,
 			"content": article.Content,
 			"utime":   article.Utime,
 			"status":  article.Status,
+			"access":  article.Access,
+			"tags":    article.Tags,
+			"version": gorm.Expr("version + 1"),
 		})
 	if res.Error != nil {
 		return res.Error
 	}
 	if res.RowsAffected == 0 {
+		// 区分一下是版本号不对，还是创作者不对，或者已经放进回收站了
+		var cur Article
+		err := dao.db.WithContext(ctx).Model(&Article{}).
+			Select("id", "version", "dtime").
+			Where("id = ? AND author_id = ?", article.Id, article.AuthorId).
+			First(&cur).Error
+		if err == nil && cur.Dtime > 0 {
+			return ErrArticleNotFound
+		}
+		if err == nil {
+			return VersionConflictError{Id: cur.Id, Version: cur.Version}
+		}
 		//dangerousDBOp.Count(1)
 		// 补充一点日志
 		return fmt.Errorf("更新失败，可能是创作者非法 id %d, author_id %d",
@@ -80,8 +103,11 @@ func (dao *GORMArticleDAO) Sync(ctx context.Context, article Article) (int64, er
 		txDao := NewGORMArticleDAO(tx)
 		if id > 0 {
 			err = txDao.UpdateById(ctx, article)
+			article.Version++
 		} else {
 			id, err = txDao.Insert(ctx, article)
+			article.Id = id
+			article.Version = 1
 		}
 		if err != nil {
 			return err
@@ -119,6 +145,9 @@ func (dao *GORMArticleDAO) Upsert(ctx context.Context, article PublishArticle) e
 			"content": article.Content,
 			"utime":   article.Utime,
 			"status":  article.Status,
+			"access":  article.Access,
+			"tags":    article.Tags,
+			"version": article.Version,
 		}),
 	}).Create(&article).Error
 	// MySQL 最终的语句 INSERT xxx ON DUPLICATE KEY UPDATE xxx
@@ -162,15 +191,12 @@ func (dao *GORMArticleDAO) SyncStatus(ctx context.Context, article Article) erro
 	return err
 }
 
-func (dao *GORMArticleDAO) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) {
+func (dao *GORMArticleDAO) GetByAuthor(ctx context.Context, uid int64, cursor Cursor, limit int) ([]Article, error) {
 	var arts []Article
-	err := dao.db.WithContext(ctx).Model(&Article{}).
-		Where("author_id=?", uid).Offset(offset).Limit(limit).
-		Order("utime DESC").
-		//Order(clause.OrderBy{Columns: []clause.OrderByColumn{
-		//	{Column: clause.Column{Name: "utime"}, Desc: true},
-		//	{Column: clause.Column{Name: "ctime"}, Desc: false},
-		//}}).
+	query := dao.db.WithContext(ctx).Model(&Article{}).
+		Where("author_id=? AND dtime = 0", uid)
+	err := dao.afterCursor(query, cursor).Limit(limit).
+		Order("utime DESC, id DESC").
 		Find(&arts).Error
 
 	return arts, err
@@ -179,7 +205,7 @@ func (dao *GORMArticleDAO) GetByAuthor(ctx context.Context, uid int64, offset in
 func (dao *GORMArticleDAO) GetPublishedById(ctx context.Context, id int64) (Article, error) {
 	var article Article
 	err := dao.db.WithContext(ctx).Model(&PublishArticle{}).
-		Where("id = ?", id).
+		Where("id = ? AND dtime = 0", id).
 		Find(&article).Error
 	if err != nil {
 		return Article{}, err
@@ -188,22 +214,142 @@ func (dao *GORMArticleDAO) GetPublishedById(ctx context.Context, id int64) (Arti
 	return article, nil
 }
 
-func (dao *GORMArticleDAO) ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]Article, error) {
+func (dao *GORMArticleDAO) GetPubByIds(ctx context.Context, ids []int64) ([]Article, error) {
 	var arts []Article
-	err := dao.db.WithContext(ctx).Model(&Article{}).
-		Where("utime < ?", start.UnixMilli()).Offset(offset).Limit(limit).
-		Order("utime DESC").
-		//Order(clause.OrderBy{Columns: []clause.OrderByColumn{
-		//	{Column: clause.Column{Name: "utime"}, Desc: true},
-		//	{Column: clause.Column{Name: "ctime"}, Desc: false},
-		//}}).
+	if len(ids) == 0 {
+		return arts, nil
+	}
+	err := dao.db.WithContext(ctx).Model(&PublishArticle{}).
+		Where("id IN ? AND dtime = 0", ids).
+		Find(&arts).Error
+	return arts, err
+}
+
+func (dao *GORMArticleDAO) ListPub(ctx context.Context, start time.Time, cursor Cursor, limit int) ([]Article, error) {
+	var arts []Article
+	query := dao.db.WithContext(ctx).Model(&PublishArticle{}).
+		Where("utime < ? AND dtime = 0", start.UnixMilli())
+	err := dao.afterCursor(query, cursor).Limit(limit).
+		Order("utime DESC, id DESC").
+		Find(&arts).Error
+
+	return arts, err
+}
+
+func (dao *GORMArticleDAO) ListPubByAuthor(ctx context.Context, uid int64, cursor Cursor, limit int) ([]Article, error) {
+	var arts []Article
+	query := dao.db.WithContext(ctx).Model(&PublishArticle{}).
+		Where("author_id = ? AND status = ? AND dtime = 0",
+			uid, statusPublished)
+	err := dao.afterCursor(query, cursor).Limit(limit).
+		Order("utime DESC, id DESC").
 		Find(&arts).Error
 
 	return arts, err
 }
 
+func (dao *GORMArticleDAO) ListPubUpdatedAfter(ctx context.Context, cursor Cursor, before int64, limit int) ([]Article, error) {
+	var arts []Article
+	err := dao.db.WithContext(ctx).Model(&PublishArticle{}).
+		Select("id", "utime").
+		Where("utime < ?", before).
+		Where("utime > ? OR (utime = ? AND id > ?)",
+			cursor.Utime, cursor.Utime, cursor.Id).
+		Order("utime ASC, id ASC").Limit(limit).
+		Find(&arts).Error
+	return arts, err
+}
+
+func (dao *GORMArticleDAO) ListPubByIdRange(ctx context.Context, minId int64, maxId int64) ([]Article, error) {
+	var arts []Article
+	err := dao.db.WithContext(ctx).Model(&PublishArticle{}).
+		Select("id", "utime").
+		Where("id >= ? AND id < ? AND status = ? AND dtime = 0",
+			minId, maxId, statusPublished).
+		Order("id ASC").
+		Find(&arts).Error
+	return arts, err
+}
+
+// afterCursor 只取排在 cursor 后面的数据，也就是
+// utime < cursor.Utime OR (utime = cursor.Utime AND id < cursor.Id)
+// utime 可能重复，所以要带上 id 才能保证不重不漏
+func (dao *GORMArticleDAO) afterCursor(query *gorm.DB, cursor Cursor) *gorm.DB {
+	if cursor.IsZero() {
+		return query
+	}
+	return query.Where("utime < ? OR (utime = ? AND id < ?)",
+		cursor.Utime, cursor.Utime, cursor.Id)
+}
+
 // 事务传播机制是指如果当前有事务，就在事务内部执行 Insert
 // 如果没有事务：
 // 1. 开启事务，执行 Insert
 // 2. 直接执行
 // 3. 报错
+
+func (dao *GORMArticleDAO) MoveToTrash(ctx context.Context, id int64, uid int64) error {
+	return dao.setDtime(ctx, id, uid, time.Now().UnixMilli())
+}
+
+func (dao *GORMArticleDAO) Restore(ctx context.Context, id int64, uid int64) error {
+	return dao.setDtime(ctx, id, uid, 0)
+}
+
+// setDtime 制作库和线上库要一起改，线上库可能没有这篇文章（从来没发表过）
+// 线上库的 utime 也要更新，站点地图之类的增量任务靠 utime 发现变更
+func (dao *GORMArticleDAO) setDtime(ctx context.Context, id int64, uid int64, dtime int64) error {
+	// 放进回收站的时候，只处理不在回收站里的；恢复的时候反过来
+	cond := "dtime = 0"
+	if dtime == 0 {
+		cond = "dtime > 0"
+	}
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Article{}).
+			Where("id = ? AND author_id = ?", id, uid).
+			Where(cond).
+			Update("dtime", dtime)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrArticleNotFound
+		}
+		return tx.Model(&PublishArticle{}).
+			Where("id = ? AND author_id = ?", id, uid).
+			Updates(map[string]any{
+				"dtime": dtime,
+				"utime": time.Now().UnixMilli(),
+			}).Error
+	})
+}
+
+func (dao *GORMArticleDAO) ListTrash(ctx context.Context, uid int64, cursor Cursor, limit int) ([]Article, error) {
+	var arts []Article
+	query := dao.db.WithContext(ctx).Model(&Article{}).
+		Where("author_id=? AND dtime > 0", uid)
+	err := dao.afterCursor(query, cursor).Limit(limit).
+		Order("utime DESC, id DESC").
+		Find(&arts).Error
+
+	return arts, err
+}
+
+func (dao *GORMArticleDAO) PurgeTrash(ctx context.Context, before time.Time, limit int) ([]int64, error) {
+	var ids []int64
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Article{}).
+			Where("dtime > 0 AND dtime < ?", before.UnixMilli()).
+			Limit(limit).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		err = tx.Where("id IN ?", ids).Delete(&Article{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&PublishArticle{}).Error
+	})
+	return ids, err
+}
@@ -36,8 +36,11 @@ func NewMongoArticle(client *mongo.Client, node *snowflake.Node) ArticleDAO {
 
 func (m *MongoArticle) Insert(ctx context.Context, article Article) (int64, error) {
 	now := time.Now().UnixMilli()
-	article.Ctime = now
+	if article.Ctime == 0 {
+		article.Ctime = now
+	}
 	article.Utime = now
+	article.Version = 1
 
 	id := m.node.Generate().Int64()
 	article.Id = id
@@ -52,19 +55,41 @@ func (m *MongoArticle) UpdateById(ctx context.Context, article Article) error {
 	now := time.Now().UnixMilli()
 	article.Utime = now
 
-	filter := bson.M{"id": article.Id, "author_id": article.AuthorId}
-	update := bson.M{"$set": bson.M{
-		"content": article.Content,
-		"title":   article.Title,
-		"utime":   article.Utime,
-		"status":  article.Status,
-	}}
+	// 和 GORM 一样，带上版本号做乐观锁
+	// version 是 omitempty 的，加版本号之前的文档没有这个字段，对应的是版本 0
+	var version any = article.Version
+	if article.Version == 0 {
+		version = bson.M{"$exists": false}
+	}
+	// 回收站里的文章不能编辑，也不能发表
+	filter := bson.M{"id": article.Id, "author_id": article.AuthorId,
+		"version": version, "dtime": bson.M{"$exists": false}}
+	update := bson.M{
+		"$set": bson.M{
+			"content": article.Content,
+			"title":   article.Title,
+			"utime":   article.Utime,
+			"status":  article.Status,
+			"access":  article.Access,
+			"tags":    article.Tags,
+		},
+		"$inc": bson.M{"version": 1},
+	}
 	res, err := m.col.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
 
-	if res.ModifiedCount == 0 {
+	if res.MatchedCount == 0 {
+		var cur Article
+		err = m.col.FindOne(ctx, bson.M{"id": article.Id, "author_id": article.AuthorId}).
+			Decode(&cur)
+		if err == nil && cur.Dtime > 0 {
+			return ErrArticleNotFound
+		}
+		if err == nil {
+			return VersionConflictError{Id: cur.Id, Version: cur.Version}
+		}
 		return fmt.Errorf("更新失败，可能是创作者非法 id %d, author_id %d",
 			article.Id, article.AuthorId)
 	}
@@ -82,8 +107,10 @@ func (m *MongoArticle) Sync(ctx context.Context, article Article) (int64, error)
 
 	if id > 0 {
 		err = m.UpdateById(ctx, article)
+		article.Version++
 	} else {
 		id, err = m.Insert(ctx, article)
+		article.Version = 1
 	}
 
 	if err != nil {
@@ -252,24 +279,210 @@ func (m *MongoArticle) SyncStatus(ctx context.Context, article Article) error {
 	return nil
 }
 
-func (m *MongoArticle) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) {
-	//TODO implement me
-	panic("implement me")
+func (m *MongoArticle) GetByAuthor(ctx context.Context, uid int64, cursor Cursor, limit int) ([]Article, error) {
+	filter := m.afterCursor(bson.D{bson.E{Key: "author_id", Value: uid},
+		bson.E{Key: "dtime", Value: bson.M{"$exists": false}}}, cursor)
+	return m.findPage(ctx, m.col, filter, limit)
 }
 
 func (m *MongoArticle) GetById(ctx context.Context, id int64, uid int64) (Article, error) {
-	//TODO implement me
-	panic("implement me")
+	filter := bson.M{"id": id, "author_id": uid, "dtime": bson.M{"$exists": false}}
+	return m.findOne(ctx, m.col, filter)
 }
 
 func (m *MongoArticle) GetPublishedById(ctx context.Context, id int64) (Article, error) {
-	//TODO implement me
-	panic("implement me")
+	filter := bson.M{"id": id, "dtime": bson.M{"$exists": false}}
+	return m.findOne(ctx, m.liveCol, filter)
+}
+
+func (m *MongoArticle) GetPubByIds(ctx context.Context, ids []int64) ([]Article, error) {
+	return m.findByIds(ctx, m.liveCol, ids)
+}
+
+func (m *MongoArticle) findByIds(ctx context.Context, col *mongo.Collection, ids []int64) ([]Article, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	cur, err := col.Find(ctx, bson.M{"id": bson.M{"$in": ids}, "dtime": bson.M{"$exists": false}})
+	if err != nil {
+		return nil, err
+	}
+	var arts []Article
+	err = cur.All(ctx, &arts)
+	return arts, err
+}
+
+// findOne 和 GORM 的 Find 一样，找不到的时候返回零值，不返回错误
+func (m *MongoArticle) findOne(ctx context.Context, col *mongo.Collection, filter bson.M) (Article, error) {
+	var art Article
+	err := col.FindOne(ctx, filter).Decode(&art)
+	if err == mongo.ErrNoDocuments {
+		return Article{}, nil
+	}
+	return art, err
+}
+
+func (m *MongoArticle) ListPub(ctx context.Context, start time.Time, cursor Cursor, limit int) ([]Article, error) {
+	filter := m.afterCursor(bson.D{bson.E{Key: "utime",
+		Value: bson.M{"$lt": start.UnixMilli()}},
+		bson.E{Key: "dtime", Value: bson.M{"$exists": false}}}, cursor)
+	return m.findPage(ctx, m.liveCol, filter, limit)
 }
 
-func (m *MongoArticle) ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]Article, error) {
-	//TODO implement me
-	panic("implement me")
+func (m *MongoArticle) ListPubByAuthor(ctx context.Context, uid int64, cursor Cursor, limit int) ([]Article, error) {
+	filter := m.afterCursor(bson.D{bson.E{Key: "author_id", Value: uid},
+		bson.E{Key: "status", Value: statusPublished},
+		bson.E{Key: "dtime", Value: bson.M{"$exists": false}}}, cursor)
+	return m.findPage(ctx, m.liveCol, filter, limit)
+}
+
+// MoveToTrash dtime 是 omitempty 的，所以没有 dtime 字段就代表不在回收站里
+func (m *MongoArticle) MoveToTrash(ctx context.Context, id int64, uid int64) error {
+	return m.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		now := time.Now().UnixMilli()
+		filter := bson.M{"id": id, "author_id": uid, "dtime": bson.M{"$exists": false}}
+		update := bson.M{"$set": bson.M{"dtime": now}}
+		res, err := m.col.UpdateOne(sc, filter, update)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return ErrArticleNotFound
+		}
+		// 和 GORM 的实现一样，线上库的 utime 也要更新
+		_, err = m.liveCol.UpdateOne(sc, filter,
+			bson.M{"$set": bson.M{"dtime": now, "utime": now}})
+		return err
+	})
+}
+
+func (m *MongoArticle) Restore(ctx context.Context, id int64, uid int64) error {
+	return m.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		filter := bson.M{"id": id, "author_id": uid, "dtime": bson.M{"$exists": true}}
+		update := bson.M{"$unset": bson.M{"dtime": ""}}
+		res, err := m.col.UpdateOne(sc, filter, update)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return ErrArticleNotFound
+		}
+		_, err = m.liveCol.UpdateOne(sc, filter, bson.M{
+			"$unset": bson.M{"dtime": ""},
+			"$set":   bson.M{"utime": time.Now().UnixMilli()},
+		})
+		return err
+	})
+}
+
+func (m *MongoArticle) ListTrash(ctx context.Context, uid int64, cursor Cursor, limit int) ([]Article, error) {
+	filter := m.afterCursor(bson.D{bson.E{Key: "author_id", Value: uid},
+		bson.E{Key: "dtime", Value: bson.M{"$exists": true}}}, cursor)
+	return m.findPage(ctx, m.col, filter, limit)
+}
+
+func (m *MongoArticle) PurgeTrash(ctx context.Context, before time.Time, limit int) ([]int64, error) {
+	opts := options.Find().SetLimit(int64(limit)).
+		SetProjection(bson.M{"id": 1})
+	cur, err := m.col.Find(ctx, bson.M{"dtime": bson.M{"$lt": before.UnixMilli()}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var arts []Article
+	err = cur.All(ctx, &arts)
+	if err != nil || len(arts) == 0 {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(arts))
+	for _, art := range arts {
+		ids = append(ids, art.Id)
+	}
+	err = m.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		filter := bson.M{"id": bson.M{"$in": ids}}
+		_, err1 := m.col.DeleteMany(sc, filter)
+		if err1 != nil {
+			return err1
+		}
+		_, err1 = m.liveCol.DeleteMany(sc, filter)
+		return err1
+	})
+	return ids, err
+}
+
+func (m *MongoArticle) ListPubUpdatedAfter(ctx context.Context, cursor Cursor, before int64, limit int) ([]Article, error) {
+	filter := bson.D{
+		bson.E{Key: "utime", Value: bson.M{"$lt": before}},
+		bson.E{Key: "$or", Value: bson.A{
+			bson.M{"utime": bson.M{"$gt": cursor.Utime}},
+			bson.M{"utime": cursor.Utime, "id": bson.M{"$gt": cursor.Id}},
+		}},
+	}
+	opts := options.Find().
+		SetSort(bson.D{bson.E{Key: "utime", Value: 1}, bson.E{Key: "id", Value: 1}}).
+		SetProjection(bson.M{"id": 1, "utime": 1}).
+		SetLimit(int64(limit))
+	return m.find(ctx, filter, opts)
+}
+
+func (m *MongoArticle) ListPubByIdRange(ctx context.Context, minId int64, maxId int64) ([]Article, error) {
+	filter := bson.D{
+		bson.E{Key: "id", Value: bson.M{"$gte": minId, "$lt": maxId}},
+		bson.E{Key: "status", Value: statusPublished},
+		bson.E{Key: "dtime", Value: bson.M{"$exists": false}},
+	}
+	opts := options.Find().
+		SetSort(bson.D{bson.E{Key: "id", Value: 1}}).
+		SetProjection(bson.M{"id": 1, "utime": 1})
+	return m.find(ctx, filter, opts)
+}
+
+func (m *MongoArticle) find(ctx context.Context, filter bson.D, opts *options.FindOptions) ([]Article, error) {
+	cur, err := m.liveCol.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var arts []Article
+	err = cur.All(ctx, &arts)
+	return arts, err
+}
+
+// inTransaction 制作库和线上库要一起改的时候用
+func (m *MongoArticle) inTransaction(ctx context.Context,
+	fn func(sc mongo.SessionContext) error) error {
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// afterCursor 和 GORM 的实现一样，按照 (utime, id) 倒序，只取 cursor 后面的
+func (m *MongoArticle) afterCursor(filter bson.D, cursor Cursor) bson.D {
+	if cursor.IsZero() {
+		return filter
+	}
+	return append(filter, bson.E{Key: "$or", Value: bson.A{
+		bson.M{"utime": bson.M{"$lt": cursor.Utime}},
+		bson.M{"utime": cursor.Utime, "id": bson.M{"$lt": cursor.Id}},
+	}})
+}
+
+func (m *MongoArticle) findPage(ctx context.Context, col *mongo.Collection,
+	filter bson.D, limit int) ([]Article, error) {
+	opts := options.Find().
+		SetSort(bson.D{bson.E{Key: "utime", Value: -1}, bson.E{Key: "id", Value: -1}}).
+		SetLimit(int64(limit))
+	cur, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var arts []Article
+	err = cur.All(ctx, &arts)
+	return arts, err
 }
 
 func InitCollections(db *mongo.Database) error {
@@ -286,6 +499,20 @@ func InitCollections(db *mongo.Database) error {
 			},
 			Options: options.Index(),
 		},
+		{
+			// 游标分页
+			Keys: bson.D{bson.E{Key: "author_id", Value: 1},
+				bson.E{Key: "utime", Value: -1},
+				bson.E{Key: "id", Value: -1},
+			},
+			Options: options.Index(),
+		},
+		{
+			Keys: bson.D{bson.E{Key: "utime", Value: -1},
+				bson.E{Key: "id", Value: -1},
+			},
+			Options: options.Index(),
+		},
 	}
 	_, err := db.Collection("articles").Indexes().
 		CreateMany(ctx, index)
@@ -0,0 +1,179 @@
+package consistency
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 不一致的类型
+const (
+	// MismatchPubMissing 制作库是已发表或者仅自己可见，线上库却没有
+	MismatchPubMissing = "pub_missing"
+	MismatchTitle      = "title"
+	MismatchStatus     = "status"
+	MismatchContent    = "content"
+)
+
+var (
+	statusPublished = domain.ArticleStatusPublished.ToUint8()
+	statusPrivate   = domain.ArticleStatusPrivate.ToUint8()
+)
+
+// DAO 检查需要的方法，GORM、MongoDB、OSS 和迁移用的双写实现都有
+type DAO interface {
+	ScanDrafts(ctx context.Context, afterId int64, limit int) ([]article.Article, error)
+	FindPublished(ctx context.Context, ids []int64) ([]article.PublishArticle, error)
+	Upsert(ctx context.Context, art article.PublishArticle) error
+	SyncStatus(ctx context.Context, art article.Article) error
+}
+
+// Mismatch 一篇文章的不一致
+type Mismatch struct {
+	Id   int64
+	Type string
+}
+
+// Checker 对比制作库和线上库
+// GORM 的 Sync 是在一个事务里写两张表的，但是 SyncStatus、MongoDB 和 OSS 的实现都可能只写成功一半
+// 只有制作库是已发表或者仅自己可见的才需要对比，其余状态代表作者改过了还没发表，本来就和线上库不一样
+type Checker struct {
+	dao       DAO
+	l         logger.Logger
+	batchSize int
+	mismatch  *prometheus.CounterVec
+	repaired  *prometheus.CounterVec
+}
+
+func NewChecker(dao DAO, l logger.Logger) *Checker {
+	mismatch := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "Golang",
+		Subsystem: "webook",
+		Name:      "article_consistency_mismatch",
+		Help:      "制作库和线上库不一致的文章数",
+	}, []string{"type"})
+	repaired := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "Golang",
+		Subsystem: "webook",
+		Name:      "article_consistency_repair",
+		Help:      "按照制作库修复线上库的次数",
+	}, []string{"result"})
+	prometheus.MustRegister(mismatch, repaired)
+	return &Checker{
+		dao:       dao,
+		l:         l,
+		batchSize: 100,
+		mismatch:  mismatch,
+		repaired:  repaired,
+	}
+}
+
+// Check 扫描全部文章，repair 为 true 的时候按照制作库覆盖线上库
+// 返回发现的不一致，修复失败不会中断扫描
+func (c *Checker) Check(ctx context.Context, repair bool) ([]Mismatch, error) {
+	var (
+		afterId int64
+		res     []Mismatch
+	)
+	for {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		drafts, err := c.dao.ScanDrafts(ctx, afterId, c.batchSize)
+		if err != nil {
+			return res, err
+		}
+		if len(drafts) == 0 {
+			return res, nil
+		}
+		afterId = drafts[len(drafts)-1].Id
+
+		mismatches, err := c.checkBatch(ctx, drafts, repair)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, mismatches...)
+	}
+}
+
+func (c *Checker) checkBatch(ctx context.Context, drafts []article.Article, repair bool) ([]Mismatch, error) {
+	ids := make([]int64, 0, len(drafts))
+	for _, d := range drafts {
+		if d.Status == statusPublished || d.Status == statusPrivate {
+			ids = append(ids, d.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	pubs, err := c.dao.FindPublished(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	pubMap := make(map[int64]article.PublishArticle, len(pubs))
+	for _, p := range pubs {
+		pubMap[p.Id] = p
+	}
+
+	var res []Mismatch
+	for _, d := range drafts {
+		if d.Status != statusPublished && d.Status != statusPrivate {
+			continue
+		}
+		p, ok := pubMap[d.Id]
+		typ := c.compare(d, p, ok)
+		if typ == "" {
+			continue
+		}
+		res = append(res, Mismatch{Id: d.Id, Type: typ})
+		c.mismatch.WithLabelValues(typ).Inc()
+		c.l.Warn("制作库和线上库不一致", logger.Int64("id", d.Id),
+			logger.String("type", typ), logger.Uint8("status", d.Status))
+		if repair {
+			c.repair(ctx, d, typ)
+		}
+	}
+	return res, nil
+}
+
+// compare 一篇文章只报告第一个不一致的地方，修复的时候是整篇覆盖的
+// 仅自己可见的文章，OSS 的实现撤回的时候就把线上的内容删掉了，所以不对比内容
+func (c *Checker) compare(d article.Article, p article.PublishArticle, found bool) string {
+	switch {
+	case !found:
+		return MismatchPubMissing
+	case d.Title != p.Title:
+		return MismatchTitle
+	case d.Status != p.Status:
+		return MismatchStatus
+	case d.Status != statusPrivate && d.Content != p.Content:
+		return MismatchContent
+	default:
+		return ""
+	}
+}
+
+// repair 已发表的按照制作库整篇覆盖
+// 仅自己可见的最后要走一遍 SyncStatus，OSS 的实现会把线上的内容删掉，不能因为修复又传上去
+func (c *Checker) repair(ctx context.Context, d article.Article, typ string) {
+	var err error
+	switch {
+	case d.Status != statusPrivate:
+		err = c.dao.Upsert(ctx, article.PublishArticle(d))
+	case typ == MismatchStatus:
+		err = c.dao.SyncStatus(ctx, d)
+	default:
+		err = c.dao.Upsert(ctx, article.PublishArticle(d))
+		if err == nil {
+			err = c.dao.SyncStatus(ctx, d)
+		}
+	}
+	if err != nil {
+		c.repaired.WithLabelValues("fail").Inc()
+		c.l.Error("修复线上库失败", logger.Error(err), logger.Int64("id", d.Id))
+		return
+	}
+	c.repaired.WithLabelValues("ok").Inc()
+}
@@ -0,0 +1,116 @@
+package consistency
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+// fakeDAO 记录修复的时候调了哪些方法
+type fakeDAO struct {
+	drafts []article.Article
+	pubs   []article.PublishArticle
+	calls  []string
+}
+
+func (d *fakeDAO) ScanDrafts(ctx context.Context, afterId int64, limit int) ([]article.Article, error) {
+	var res []article.Article
+	for _, art := range d.drafts {
+		if art.Id > afterId && len(res) < limit {
+			res = append(res, art)
+		}
+	}
+	return res, nil
+}
+
+func (d *fakeDAO) FindPublished(ctx context.Context, ids []int64) ([]article.PublishArticle, error) {
+	return d.pubs, nil
+}
+
+func (d *fakeDAO) Upsert(ctx context.Context, art article.PublishArticle) error {
+	d.calls = append(d.calls, "Upsert")
+	return nil
+}
+
+func (d *fakeDAO) SyncStatus(ctx context.Context, art article.Article) error {
+	d.calls = append(d.calls, "SyncStatus")
+	return nil
+}
+
+// newTestChecker 不注册到 prometheus，不然多个测试会重复注册
+func newTestChecker(dao DAO) *Checker {
+	return &Checker{
+		dao:       dao,
+		l:         logger.NewZapLogger(zap.NewNop(), false),
+		batchSize: 2,
+		mismatch:  prometheus.NewCounterVec(prometheus.CounterOpts{Name: "mismatch"}, []string{"type"}),
+		repaired:  prometheus.NewCounterVec(prometheus.CounterOpts{Name: "repaired"}, []string{"result"}),
+	}
+}
+
+func TestChecker_Check(t *testing.T) {
+	testCases := []struct {
+		name      string
+		draft     article.Article
+		pubs      []article.PublishArticle
+		want      []Mismatch
+		wantCalls []string
+	}{
+		{
+			name:  "一致",
+			draft: article.Article{Id: 1, Title: "标题", Content: "内容", Status: statusPublished},
+			pubs:  []article.PublishArticle{{Id: 1, Title: "标题", Content: "内容", Status: statusPublished}},
+		},
+		{
+			name:      "已发表的内容不一致",
+			draft:     article.Article{Id: 1, Title: "标题", Content: "新内容", Status: statusPublished},
+			pubs:      []article.PublishArticle{{Id: 1, Title: "标题", Content: "内容", Status: statusPublished}},
+			want:      []Mismatch{{Id: 1, Type: MismatchContent}},
+			wantCalls: []string{"Upsert"},
+		},
+		{
+			name:      "线上库没有",
+			draft:     article.Article{Id: 1, Title: "标题", Content: "内容", Status: statusPublished},
+			want:      []Mismatch{{Id: 1, Type: MismatchPubMissing}},
+			wantCalls: []string{"Upsert"},
+		},
+		{
+			// OSS 的实现撤回之后线上的内容已经删掉了
+			name:  "仅自己可见的不对比内容",
+			draft: article.Article{Id: 1, Title: "标题", Content: "内容", Status: statusPrivate},
+			pubs:  []article.PublishArticle{{Id: 1, Title: "标题", Status: statusPrivate}},
+		},
+		{
+			name:      "仅自己可见的状态不一致只同步状态",
+			draft:     article.Article{Id: 1, Title: "标题", Content: "内容", Status: statusPrivate},
+			pubs:      []article.PublishArticle{{Id: 1, Title: "标题", Content: "内容", Status: statusPublished}},
+			want:      []Mismatch{{Id: 1, Type: MismatchStatus}},
+			wantCalls: []string{"SyncStatus"},
+		},
+		{
+			name:      "仅自己可见的标题不一致，覆盖之后再同步状态",
+			draft:     article.Article{Id: 1, Title: "新标题", Content: "内容", Status: statusPrivate},
+			pubs:      []article.PublishArticle{{Id: 1, Title: "标题", Status: statusPrivate}},
+			want:      []Mismatch{{Id: 1, Type: MismatchTitle}},
+			wantCalls: []string{"Upsert", "SyncStatus"},
+		},
+		{
+			name:  "未发表的不对比",
+			draft: article.Article{Id: 1, Title: "标题", Content: "内容"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dao := &fakeDAO{drafts: []article.Article{tc.draft}, pubs: tc.pubs}
+			res, err := newTestChecker(dao).Check(context.Background(), true)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, res)
+			assert.Equal(t, tc.wantCalls, dao.calls)
+		})
+	}
+}
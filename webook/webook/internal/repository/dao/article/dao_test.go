@@ -0,0 +1,267 @@
+package article
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/objectstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"os"
+	"testing"
+	"time"
+)
+
+// 需要一个可以随便清空的 MySQL，比如
+// WEBOOK_TEST_MYSQL_DSN="root:root@tcp(localhost:13316)/webook_test" go test ./...
+// 没有配置的时候跳过
+func initTestDB(t *testing.T) *gorm.DB {
+	dsn, ok := os.LookupEnv("WEBOOK_TEST_MYSQL_DSN")
+	if !ok {
+		t.Skip("没有配置 WEBOOK_TEST_MYSQL_DSN")
+	}
+	db, err := gorm.Open(mysql.Open(dsn))
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Article{}, &PublishArticle{}))
+	return db
+}
+
+// ArticleDAOSuite 所有 ArticleDAO 的实现都要满足的行为
+// 新加一个实现的时候，在下面加一个 TestXXX 跑一遍这个套件
+type ArticleDAOSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	newDAO func(db *gorm.DB) ArticleDAO
+	dao    ArticleDAO
+}
+
+func TestGORMArticleDAO(t *testing.T) {
+	suite.Run(t, &ArticleDAOSuite{
+		db:     initTestDB(t),
+		newDAO: NewGORMArticleDAO,
+	})
+}
+
+func TestS3DAO(t *testing.T) {
+	dir := t.TempDir()
+	suite.Run(t, &ArticleDAOSuite{
+		db: initTestDB(t),
+		newDAO: func(db *gorm.DB) ArticleDAO {
+			return NewOssDAO(objectstore.NewLocalStore(dir, ""), db)
+		},
+	})
+}
+
+func (s *ArticleDAOSuite) SetupTest() {
+	s.dao = s.newDAO(s.db)
+}
+
+func (s *ArticleDAOSuite) TearDownTest() {
+	s.db.Exec("TRUNCATE TABLE articles")
+	s.db.Exec("TRUNCATE TABLE publish_articles")
+}
+
+func (s *ArticleDAOSuite) TestInsertAndUpdate() {
+	t := s.T()
+	ctx := context.Background()
+	id, err := s.dao.Insert(ctx, Article{
+		Title: "标题", Content: "内容", AuthorId: 123, Tags: "go,gin",
+	})
+	require.NoError(t, err)
+
+	art, err := s.dao.GetById(ctx, id, 123)
+	require.NoError(t, err)
+	assert.Equal(t, "内容", art.Content)
+	assert.Equal(t, "go,gin", art.Tags)
+	assert.Equal(t, int64(1), art.Version)
+
+	// 别人的文章查不到
+	art, err = s.dao.GetById(ctx, id, 234)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), art.Id)
+
+	err = s.dao.UpdateById(ctx, Article{
+		Id: id, Title: "新标题", Content: "新内容", AuthorId: 123, Version: 1,
+	})
+	require.NoError(t, err)
+	art, err = s.dao.GetById(ctx, id, 123)
+	require.NoError(t, err)
+	assert.Equal(t, "新内容", art.Content)
+	assert.Equal(t, "", art.Tags)
+	assert.Equal(t, int64(2), art.Version)
+
+	// 拿着旧的版本号更新，带回当前的版本号
+	err = s.dao.UpdateById(ctx, Article{
+		Id: id, Title: "标题", Content: "内容", AuthorId: 123, Version: 1,
+	})
+	var conflict VersionConflictError
+	require.True(t, errors.As(err, &conflict))
+	assert.Equal(t, int64(2), conflict.Version)
+
+	// 改别人的文章
+	err = s.dao.UpdateById(ctx, Article{
+		Id: id, Title: "标题", Content: "内容", AuthorId: 234, Version: 2,
+	})
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrVersionConflict))
+}
+
+func (s *ArticleDAOSuite) TestSync() {
+	t := s.T()
+	ctx := context.Background()
+	id, err := s.dao.Sync(ctx, Article{
+		Title: "标题", Content: "第一版", AuthorId: 123, Status: statusPublished,
+	})
+	require.NoError(t, err)
+	pub, err := s.dao.GetPublishedById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "第一版", pub.Content)
+	assert.Equal(t, int64(1), pub.Version)
+
+	// 再发表一次，制作库和线上库的版本号一起 +1
+	_, err = s.dao.Sync(ctx, Article{
+		Id: id, Title: "标题", Content: "第二版", AuthorId: 123,
+		Status: statusPublished, Version: 1,
+	})
+	require.NoError(t, err)
+	pub, err = s.dao.GetPublishedById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "第二版", pub.Content)
+	assert.Equal(t, int64(2), pub.Version)
+	art, err := s.dao.GetById(ctx, id, 123)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), art.Version)
+
+	// 版本冲突的时候线上库不变
+	_, err = s.dao.Sync(ctx, Article{
+		Id: id, Title: "标题", Content: "第三版", AuthorId: 123,
+		Status: statusPublished, Version: 1,
+	})
+	assert.True(t, errors.Is(err, ErrVersionConflict))
+	pub, err = s.dao.GetPublishedById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "第二版", pub.Content)
+}
+
+func (s *ArticleDAOSuite) TestUpsert() {
+	t := s.T()
+	ctx := context.Background()
+	err := s.dao.Upsert(ctx, PublishArticle{
+		Id: 10, Title: "标题", Content: "内容", AuthorId: 123,
+		Status: statusPublished, Version: 3,
+	})
+	require.NoError(t, err)
+	// 同一个版本再写一次
+	err = s.dao.Upsert(ctx, PublishArticle{
+		Id: 10, Title: "标题", Content: "内容", AuthorId: 123,
+		Status: statusPublished, Version: 3,
+	})
+	require.NoError(t, err)
+	pub, err := s.dao.GetPublishedById(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "内容", pub.Content)
+	assert.Equal(t, int64(3), pub.Version)
+}
+
+func (s *ArticleDAOSuite) TestSyncStatus() {
+	t := s.T()
+	ctx := context.Background()
+	id, err := s.dao.Sync(ctx, Article{
+		Title: "标题", Content: "内容", AuthorId: 123, Status: statusPublished,
+	})
+	require.NoError(t, err)
+
+	err = s.dao.SyncStatus(ctx, Article{Id: id, AuthorId: 123, Status: statusPrivate})
+	require.NoError(t, err)
+	pub, err := s.dao.GetPublishedById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, statusPrivate, pub.Status)
+	arts, err := s.dao.ListPubByAuthor(ctx, 123, Cursor{}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, arts)
+
+	// 别人的文章改不了
+	err = s.dao.SyncStatus(ctx, Article{Id: id, AuthorId: 234, Status: statusPublished})
+	assert.Error(t, err)
+}
+
+func (s *ArticleDAOSuite) TestListPub() {
+	t := s.T()
+	ctx := context.Background()
+	ids := make([]int64, 0, 3)
+	for _, content := range []string{"一", "二", "三"} {
+		id, err := s.dao.Sync(ctx, Article{
+			Title: content, Content: content, AuthorId: 123, Status: statusPublished,
+		})
+		require.NoError(t, err)
+		ids = append(ids, id)
+		// utime 是毫秒，错开一下保证顺序
+		time.Sleep(time.Millisecond * 2)
+	}
+
+	arts, err := s.dao.GetPubByIds(ctx, ids)
+	require.NoError(t, err)
+	contents := make(map[int64]string, len(arts))
+	for _, art := range arts {
+		contents[art.Id] = art.Content
+	}
+	assert.Equal(t, map[int64]string{ids[0]: "一", ids[1]: "二", ids[2]: "三"}, contents)
+
+	// 按照 (utime, id) 倒序翻页
+	first, err := s.dao.ListPubByAuthor(ctx, 123, Cursor{}, 2)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, "三", first[0].Content)
+	assert.Equal(t, "二", first[1].Content)
+	last := first[1]
+	second, err := s.dao.ListPubByAuthor(ctx, 123, Cursor{Utime: last.Utime, Id: last.Id}, 2)
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.Equal(t, "一", second[0].Content)
+
+	arts, err = s.dao.ListPub(ctx, time.Now().Add(time.Second), Cursor{}, 10)
+	require.NoError(t, err)
+	assert.Len(t, arts, 3)
+}
+
+func (s *ArticleDAOSuite) TestTrash() {
+	t := s.T()
+	ctx := context.Background()
+	id, err := s.dao.Sync(ctx, Article{
+		Title: "标题", Content: "内容", AuthorId: 123, Status: statusPublished,
+	})
+	require.NoError(t, err)
+
+	err = s.dao.MoveToTrash(ctx, id, 123)
+	require.NoError(t, err)
+	art, err := s.dao.GetById(ctx, id, 123)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), art.Id)
+	pub, err := s.dao.GetPublishedById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pub.Id)
+	trash, err := s.dao.ListTrash(ctx, 123, Cursor{}, 10)
+	require.NoError(t, err)
+	assert.Len(t, trash, 1)
+	// 已经在回收站里了
+	assert.Equal(t, ErrArticleNotFound, s.dao.MoveToTrash(ctx, id, 123))
+
+	err = s.dao.Restore(ctx, id, 123)
+	require.NoError(t, err)
+	pub, err = s.dao.GetPublishedById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "内容", pub.Content)
+
+	err = s.dao.MoveToTrash(ctx, id, 123)
+	require.NoError(t, err)
+	// 还没到时间的不删
+	purged, err := s.dao.PurgeTrash(ctx, time.Now().Add(-time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+	purged, err = s.dao.PurgeTrash(ctx, time.Now().Add(time.Second), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{id}, purged)
+	assert.Equal(t, ErrArticleNotFound, s.dao.Restore(ctx, id, 123))
+}
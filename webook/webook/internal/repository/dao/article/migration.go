@@ -0,0 +1,28 @@
+package article
+
+import "context"
+
+// MigrationDAO 在 GORM 和 MongoDB 之间迁移数据用的
+// 和 ArticleDAO 不一样，这里的方法不区分作者，也不过滤回收站，数据原样读出、原样写入
+type MigrationDAO interface {
+	// ScanDrafts 按照 id 正序，取 id 大于 afterId 的 limit 条制作库数据
+	ScanDrafts(ctx context.Context, afterId int64, limit int) ([]Article, error)
+	FindDrafts(ctx context.Context, ids []int64) ([]Article, error)
+	FindPublished(ctx context.Context, ids []int64) ([]PublishArticle, error)
+	// OverwriteDraft 存在就整行覆盖，不存在就插入，id、版本号和各个时间都保持不变
+	OverwriteDraft(ctx context.Context, art Article) error
+	OverwritePublished(ctx context.Context, art PublishArticle) error
+	DeleteDrafts(ctx context.Context, ids []int64) error
+	DeletePublished(ctx context.Context, ids []int64) error
+}
+
+// MigratableDAO 能参与迁移的 ArticleDAO
+type MigratableDAO interface {
+	ArticleDAO
+	MigrationDAO
+}
+
+var (
+	_ MigratableDAO = (*GORMArticleDAO)(nil)
+	_ MigratableDAO = (*MongoArticle)(nil)
+)
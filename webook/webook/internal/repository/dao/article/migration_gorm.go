@@ -0,0 +1,53 @@
+package article
+
+import (
+	"context"
+	"gorm.io/gorm/clause"
+)
+
+func (dao *GORMArticleDAO) ScanDrafts(ctx context.Context, afterId int64, limit int) ([]Article, error) {
+	var arts []Article
+	err := dao.db.WithContext(ctx).Model(&Article{}).
+		Where("id > ?", afterId).
+		Order("id ASC").Limit(limit).
+		Find(&arts).Error
+	return arts, err
+}
+
+func (dao *GORMArticleDAO) FindDrafts(ctx context.Context, ids []int64) ([]Article, error) {
+	var arts []Article
+	err := dao.db.WithContext(ctx).Model(&Article{}).
+		Where("id IN ?", ids).
+		Find(&arts).Error
+	return arts, err
+}
+
+func (dao *GORMArticleDAO) FindPublished(ctx context.Context, ids []int64) ([]PublishArticle, error) {
+	var arts []PublishArticle
+	err := dao.db.WithContext(ctx).Model(&PublishArticle{}).
+		Where("id IN ?", ids).
+		Find(&arts).Error
+	return arts, err
+}
+
+// OverwriteDraft 从 MongoDB 迁过来的 id 是雪花算法生成的，直接插入也没问题
+// 自增主键会跟着变大，以后再切回 GORM 也不会冲突
+func (dao *GORMArticleDAO) OverwriteDraft(ctx context.Context, art Article) error {
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		UpdateAll: true,
+	}).Create(&art).Error
+}
+
+func (dao *GORMArticleDAO) OverwritePublished(ctx context.Context, art PublishArticle) error {
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		UpdateAll: true,
+	}).Create(&art).Error
+}
+
+func (dao *GORMArticleDAO) DeleteDrafts(ctx context.Context, ids []int64) error {
+	return dao.db.WithContext(ctx).Where("id IN ?", ids).Delete(&Article{}).Error
+}
+
+func (dao *GORMArticleDAO) DeletePublished(ctx context.Context, ids []int64) error {
+	return dao.db.WithContext(ctx).Where("id IN ?", ids).Delete(&PublishArticle{}).Error
+}
@@ -0,0 +1,66 @@
+package article
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (m *MongoArticle) ScanDrafts(ctx context.Context, afterId int64, limit int) ([]Article, error) {
+	opts := options.Find().
+		SetSort(bson.D{bson.E{Key: "id", Value: 1}}).
+		SetLimit(int64(limit))
+	cur, err := m.col.Find(ctx, bson.M{"id": bson.M{"$gt": afterId}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var arts []Article
+	err = cur.All(ctx, &arts)
+	return arts, err
+}
+
+func (m *MongoArticle) FindDrafts(ctx context.Context, ids []int64) ([]Article, error) {
+	cur, err := m.col.Find(ctx, bson.M{"id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	var arts []Article
+	err = cur.All(ctx, &arts)
+	return arts, err
+}
+
+func (m *MongoArticle) FindPublished(ctx context.Context, ids []int64) ([]PublishArticle, error) {
+	cur, err := m.liveCol.Find(ctx, bson.M{"id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	var arts []PublishArticle
+	err = cur.All(ctx, &arts)
+	return arts, err
+}
+
+// OverwriteDraft 整个文档替换掉，dtime 是 omitempty 的，不在回收站里的就不会有这个字段
+func (m *MongoArticle) OverwriteDraft(ctx context.Context, art Article) error {
+	return m.replace(ctx, m.col, art.Id, art)
+}
+
+func (m *MongoArticle) OverwritePublished(ctx context.Context, art PublishArticle) error {
+	return m.replace(ctx, m.liveCol, art.Id, art)
+}
+
+func (m *MongoArticle) DeleteDrafts(ctx context.Context, ids []int64) error {
+	_, err := m.col.DeleteMany(ctx, bson.M{"id": bson.M{"$in": ids}})
+	return err
+}
+
+func (m *MongoArticle) DeletePublished(ctx context.Context, ids []int64) error {
+	_, err := m.liveCol.DeleteMany(ctx, bson.M{"id": bson.M{"$in": ids}})
+	return err
+}
+
+func (m *MongoArticle) replace(ctx context.Context, col *mongo.Collection, id int64, doc any) error {
+	_, err := col.ReplaceOne(ctx, bson.M{"id": id}, doc,
+		options.Replace().SetUpsert(true))
+	return err
+}
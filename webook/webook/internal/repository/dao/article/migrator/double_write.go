@@ -0,0 +1,187 @@
+package migrator
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"time"
+)
+
+var _ article.MigratableDAO = (*DoubleWriteDAO)(nil)
+
+// DoubleWriteDAO 读写都走为准的一边，双写阶段写成功之后，再把改动的文章整行同步到另一边
+// 不在另一边重放同样的操作，是因为两边生成的 id、版本号和时间戳都不一样，重放出来的数据对不上
+// 另一边同步失败不影响业务，发一个修复事件，异步修复
+type DoubleWriteDAO struct {
+	m *Migrator
+}
+
+func (d *DoubleWriteDAO) Insert(ctx context.Context, art article.Article) (int64, error) {
+	id, err := d.base().Insert(ctx, art)
+	if err == nil {
+		d.sync(ctx, id)
+	}
+	return id, err
+}
+
+func (d *DoubleWriteDAO) UpdateById(ctx context.Context, art article.Article) error {
+	err := d.base().UpdateById(ctx, art)
+	if err == nil {
+		d.sync(ctx, art.Id)
+	}
+	return err
+}
+
+func (d *DoubleWriteDAO) Sync(ctx context.Context, art article.Article) (int64, error) {
+	id, err := d.base().Sync(ctx, art)
+	if err == nil {
+		d.sync(ctx, id)
+	}
+	return id, err
+}
+
+func (d *DoubleWriteDAO) Upsert(ctx context.Context, art article.PublishArticle) error {
+	err := d.base().Upsert(ctx, art)
+	if err == nil {
+		d.sync(ctx, art.Id)
+	}
+	return err
+}
+
+func (d *DoubleWriteDAO) SyncStatus(ctx context.Context, art article.Article) error {
+	err := d.base().SyncStatus(ctx, art)
+	if err == nil {
+		d.sync(ctx, art.Id)
+	}
+	return err
+}
+
+func (d *DoubleWriteDAO) MoveToTrash(ctx context.Context, id int64, uid int64) error {
+	err := d.base().MoveToTrash(ctx, id, uid)
+	if err == nil {
+		d.sync(ctx, id)
+	}
+	return err
+}
+
+func (d *DoubleWriteDAO) Restore(ctx context.Context, id int64, uid int64) error {
+	err := d.base().Restore(ctx, id, uid)
+	if err == nil {
+		d.sync(ctx, id)
+	}
+	return err
+}
+
+func (d *DoubleWriteDAO) PurgeTrash(ctx context.Context, before time.Time, limit int) ([]int64, error) {
+	ids, err := d.base().PurgeTrash(ctx, before, limit)
+	if err == nil {
+		d.sync(ctx, ids...)
+	}
+	return ids, err
+}
+
+func (d *DoubleWriteDAO) GetByAuthor(ctx context.Context, uid int64, cursor article.Cursor, limit int) ([]article.Article, error) {
+	return d.base().GetByAuthor(ctx, uid, cursor, limit)
+}
+
+func (d *DoubleWriteDAO) GetById(ctx context.Context, id int64, uid int64) (article.Article, error) {
+	return d.base().GetById(ctx, id, uid)
+}
+
+func (d *DoubleWriteDAO) GetPublishedById(ctx context.Context, id int64) (article.Article, error) {
+	return d.base().GetPublishedById(ctx, id)
+}
+
+func (d *DoubleWriteDAO) GetPubByIds(ctx context.Context, ids []int64) ([]article.Article, error) {
+	return d.base().GetPubByIds(ctx, ids)
+}
+
+func (d *DoubleWriteDAO) ListPub(ctx context.Context, start time.Time, cursor article.Cursor, limit int) ([]article.Article, error) {
+	return d.base().ListPub(ctx, start, cursor, limit)
+}
+
+func (d *DoubleWriteDAO) ListPubByAuthor(ctx context.Context, uid int64, cursor article.Cursor, limit int) ([]article.Article, error) {
+	return d.base().ListPubByAuthor(ctx, uid, cursor, limit)
+}
+
+func (d *DoubleWriteDAO) ListPubUpdatedAfter(ctx context.Context, cursor article.Cursor, before int64, limit int) ([]article.Article, error) {
+	return d.base().ListPubUpdatedAfter(ctx, cursor, before, limit)
+}
+
+func (d *DoubleWriteDAO) ListPubByIdRange(ctx context.Context, minId int64, maxId int64) ([]article.Article, error) {
+	return d.base().ListPubByIdRange(ctx, minId, maxId)
+}
+
+func (d *DoubleWriteDAO) ListTrash(ctx context.Context, uid int64, cursor article.Cursor, limit int) ([]article.Article, error) {
+	return d.base().ListTrash(ctx, uid, cursor, limit)
+}
+
+// 下面这些是给一致性检查之类的运维任务用的，和业务读写一样走为准的一边
+
+func (d *DoubleWriteDAO) ScanDrafts(ctx context.Context, afterId int64, limit int) ([]article.Article, error) {
+	return d.base().ScanDrafts(ctx, afterId, limit)
+}
+
+func (d *DoubleWriteDAO) FindDrafts(ctx context.Context, ids []int64) ([]article.Article, error) {
+	return d.base().FindDrafts(ctx, ids)
+}
+
+func (d *DoubleWriteDAO) FindPublished(ctx context.Context, ids []int64) ([]article.PublishArticle, error) {
+	return d.base().FindPublished(ctx, ids)
+}
+
+func (d *DoubleWriteDAO) OverwriteDraft(ctx context.Context, art article.Article) error {
+	err := d.base().OverwriteDraft(ctx, art)
+	if err == nil {
+		d.sync(ctx, art.Id)
+	}
+	return err
+}
+
+func (d *DoubleWriteDAO) OverwritePublished(ctx context.Context, art article.PublishArticle) error {
+	err := d.base().OverwritePublished(ctx, art)
+	if err == nil {
+		d.sync(ctx, art.Id)
+	}
+	return err
+}
+
+func (d *DoubleWriteDAO) DeleteDrafts(ctx context.Context, ids []int64) error {
+	err := d.base().DeleteDrafts(ctx, ids)
+	if err == nil {
+		d.sync(ctx, ids...)
+	}
+	return err
+}
+
+func (d *DoubleWriteDAO) DeletePublished(ctx context.Context, ids []int64) error {
+	err := d.base().DeletePublished(ctx, ids)
+	if err == nil {
+		d.sync(ctx, ids...)
+	}
+	return err
+}
+
+func (d *DoubleWriteDAO) base() article.MigratableDAO {
+	b, _ := d.m.sides(base(ArticlePattern.Load()))
+	return b
+}
+
+// sync 只有双写阶段才需要同步到另一边
+func (d *DoubleWriteDAO) sync(ctx context.Context, ids ...int64) {
+	pattern := ArticlePattern.Load()
+	if len(ids) == 0 || (pattern != PatternSrcFirst && pattern != PatternDstFirst) {
+		return
+	}
+	b := base(pattern)
+	from, to := d.m.sides(b)
+	err := copyRows(ctx, from, to, ids)
+	if err == nil {
+		return
+	}
+	d.m.l.Error("双写同步失败", logger.Error(err),
+		logger.String("pattern", pattern), logger.Field{Key: "ids", Value: ids})
+	for _, id := range ids {
+		d.m.notify(ctx, InconsistentEvent{Id: id, Base: b, Type: InconsistentWriteFailed})
+	}
+}
@@ -0,0 +1,96 @@
+package migrator
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBM/sarama"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/saramax"
+	"time"
+)
+
+const topicInconsistent = "article_migration_inconsistent"
+
+const (
+	// InconsistentNEQ 两边都有，但是内容不一样
+	InconsistentNEQ = "neq"
+	// InconsistentTargetMissing 以之为准的一边有，另一边没有
+	InconsistentTargetMissing = "target_missing"
+	// InconsistentBaseMissing 以之为准的一边没有，另一边有
+	InconsistentBaseMissing = "base_missing"
+	// InconsistentWriteFailed 双写的时候同步另一边失败了
+	InconsistentWriteFailed = "write_failed"
+)
+
+// InconsistentEvent 修复的时候不看 Type，都是按照 Base 那边的数据覆盖另一边
+// Type 只是用来做监控和排查问题的
+type InconsistentEvent struct {
+	Id   int64
+	Base string
+	Type string
+}
+
+type Producer interface {
+	ProduceInconsistentEvent(ctx context.Context, evt InconsistentEvent) error
+}
+
+type KafkaProducer struct {
+	producer sarama.SyncProducer
+}
+
+func NewKafkaProducer(producer sarama.SyncProducer) Producer {
+	return &KafkaProducer{
+		producer: producer,
+	}
+}
+
+func (k *KafkaProducer) ProduceInconsistentEvent(ctx context.Context, evt InconsistentEvent) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, _, err = k.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: topicInconsistent,
+		Value: sarama.ByteEncoder(data),
+	})
+	return err
+}
+
+var _ events.Consumer = (*Consumer)(nil)
+
+// Consumer 收到不一致的事件就修复，修复是幂等的，重复消费没关系
+type Consumer struct {
+	client sarama.Client
+	m      *Migrator
+	l      logger.Logger
+}
+
+func NewConsumer(client sarama.Client, m *Migrator, l logger.Logger) *Consumer {
+	return &Consumer{
+		client: client,
+		m:      m,
+		l:      l,
+	}
+}
+
+func (c *Consumer) Start() error {
+	cg, err := sarama.NewConsumerGroupFromClient("article_migrator", c.client)
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := cg.Consume(context.Background(), []string{topicInconsistent},
+			saramax.NewHandler[InconsistentEvent](c.l, c.Consume))
+		if err != nil {
+			c.l.Error("退出了消费循环异常", logger.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (c *Consumer) Consume(msg *sarama.ConsumerMessage, evt InconsistentEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return c.m.Fix(ctx, evt)
+}
@@ -0,0 +1,138 @@
+package migrator
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+)
+
+// Migrator 文章数据在两个存储之间迁移
+// 步骤：
+// 1. src_only 阶段全量复制，然后切到 src_first 开始双写
+// 2. 跑校验，修复全量复制期间的增量数据，直到没有不一致
+// 3. 切到 dst_first，继续双写和校验，观察一段时间
+// 4. 切到 dst_only，迁移完成
+type Migrator struct {
+	src       article.MigratableDAO
+	dst       article.MigratableDAO
+	producer  Producer
+	l         logger.Logger
+	batchSize int
+}
+
+func NewMigrator(src article.MigratableDAO, dst article.MigratableDAO,
+	producer Producer, l logger.Logger) *Migrator {
+	return &Migrator{
+		src:       src,
+		dst:       dst,
+		producer:  producer,
+		l:         l,
+		batchSize: 100,
+	}
+}
+
+// DAO 业务代码用的 ArticleDAO，读写哪边由当前阶段决定
+func (m *Migrator) DAO() article.ArticleDAO {
+	return &DoubleWriteDAO{m: m}
+}
+
+// FullCopy 把当前为准的一边全量复制到另一边，返回复制的文章数
+// 复制期间的业务写入要靠之后的校验来修复
+func (m *Migrator) FullCopy(ctx context.Context) (int, error) {
+	from, to := m.sides(base(ArticlePattern.Load()))
+	var (
+		afterId int64
+		cnt     int
+	)
+	for {
+		arts, err := from.ScanDrafts(ctx, afterId, m.batchSize)
+		if err != nil {
+			return cnt, err
+		}
+		if len(arts) == 0 {
+			return cnt, nil
+		}
+		ids := make([]int64, 0, len(arts))
+		for _, art := range arts {
+			ids = append(ids, art.Id)
+		}
+		err = copyRows(ctx, from, to, ids)
+		if err != nil {
+			return cnt, err
+		}
+		cnt += len(ids)
+		afterId = ids[len(ids)-1]
+	}
+}
+
+// Fix 按照 evt.Base 那一边的数据覆盖另一边
+func (m *Migrator) Fix(ctx context.Context, evt InconsistentEvent) error {
+	from, to := m.sides(evt.Base)
+	return copyRows(ctx, from, to, []int64{evt.Id})
+}
+
+// sides 返回以之为准的一边和另一边
+func (m *Migrator) sides(b string) (article.MigratableDAO, article.MigratableDAO) {
+	if b == BaseDst {
+		return m.dst, m.src
+	}
+	return m.src, m.dst
+}
+
+// notify 发不出去只能记日志，等下一轮校验再发现
+func (m *Migrator) notify(ctx context.Context, evt InconsistentEvent) {
+	err := m.producer.ProduceInconsistentEvent(ctx, evt)
+	if err != nil {
+		m.l.Error("发送数据不一致事件失败", logger.Error(err),
+			logger.Int64("id", evt.Id), logger.String("base", evt.Base),
+			logger.String("type", evt.Type))
+	}
+}
+
+// copyRows 把 ids 对应的制作库和线上库数据从 from 复制到 to，from 里没有的在 to 里也删掉
+func copyRows(ctx context.Context, from article.MigrationDAO, to article.MigrationDAO, ids []int64) error {
+	drafts, err := from.FindDrafts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	pubs, err := from.FindPublished(ctx, ids)
+	if err != nil {
+		return err
+	}
+	draftIds := make(map[int64]struct{}, len(drafts))
+	for _, art := range drafts {
+		draftIds[art.Id] = struct{}{}
+		err = to.OverwriteDraft(ctx, art)
+		if err != nil {
+			return err
+		}
+	}
+	pubIds := make(map[int64]struct{}, len(pubs))
+	for _, art := range pubs {
+		pubIds[art.Id] = struct{}{}
+		err = to.OverwritePublished(ctx, art)
+		if err != nil {
+			return err
+		}
+	}
+	if gone := missing(ids, draftIds); len(gone) > 0 {
+		err = to.DeleteDrafts(ctx, gone)
+		if err != nil {
+			return err
+		}
+	}
+	if gone := missing(ids, pubIds); len(gone) > 0 {
+		return to.DeletePublished(ctx, gone)
+	}
+	return nil
+}
+
+func missing(ids []int64, found map[int64]struct{}) []int64 {
+	var res []int64
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			res = append(res, id)
+		}
+	}
+	return res
+}
@@ -0,0 +1,48 @@
+package migrator
+
+import (
+	"errors"
+	"go.uber.org/atomic"
+)
+
+// 迁移分四个阶段，按顺序切换，出了问题就退回上一个阶段
+// src 是迁移前的存储，dst 是迁移后的存储
+const (
+	// PatternSrcOnly 只读写 src，这个时候可以全量复制
+	PatternSrcOnly = "src_only"
+	// PatternSrcFirst 以 src 为准，写完 src 之后把改动的数据同步到 dst
+	PatternSrcFirst = "src_first"
+	// PatternDstFirst 以 dst 为准，写完 dst 之后把改动的数据同步到 src
+	PatternDstFirst = "dst_first"
+	// PatternDstOnly 只读写 dst，迁移完成
+	PatternDstOnly = "dst_only"
+)
+
+const (
+	BaseSrc = "src"
+	BaseDst = "dst"
+)
+
+var ErrUnknownPattern = errors.New("未知的迁移阶段")
+
+// ArticlePattern 文章存储当前所处的迁移阶段，配置文件变更的时候会更新
+var ArticlePattern = atomic.NewString(PatternSrcOnly)
+
+// UpdatePattern 配置错了就保持原来的阶段不变
+func UpdatePattern(pattern string) error {
+	switch pattern {
+	case PatternSrcOnly, PatternSrcFirst, PatternDstFirst, PatternDstOnly:
+		ArticlePattern.Store(pattern)
+		return nil
+	default:
+		return ErrUnknownPattern
+	}
+}
+
+// base 以哪一边为准
+func base(pattern string) string {
+	if pattern == PatternDstFirst || pattern == PatternDstOnly {
+		return BaseDst
+	}
+	return BaseSrc
+}
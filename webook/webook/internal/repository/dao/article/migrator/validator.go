@@ -0,0 +1,148 @@
+package migrator
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article"
+)
+
+// Validate 逐条对比两边的数据，发现不一致就发修复事件，返回不一致的条数
+// 先从为准的一边出发找出不一样和缺失的，再反过来找出多出来的
+// 只有双写阶段才有意义，单写的时候另一边本来就不会更新
+// 对比的时候业务可能正在写，会有误报，但是修复是按为准的一边覆盖，误报了也没关系
+func (m *Migrator) Validate(ctx context.Context) (int, error) {
+	pattern := ArticlePattern.Load()
+	if pattern != PatternSrcFirst && pattern != PatternDstFirst {
+		return 0, nil
+	}
+	b := base(pattern)
+	from, to := m.sides(b)
+	cnt, err := m.validate(ctx, b, from, to, false)
+	if err != nil {
+		return cnt, err
+	}
+	extra, err := m.validate(ctx, b, to, from, true)
+	return cnt + extra, err
+}
+
+// validate 扫描 scan 这一边，和 other 对比
+// reverse 为 true 的时候 scan 是另一边，只需要找出为准的一边没有的
+func (m *Migrator) validate(ctx context.Context, b string,
+	scan article.MigrationDAO, other article.MigrationDAO, reverse bool) (int, error) {
+	var (
+		afterId int64
+		cnt     int
+	)
+	for {
+		if ctx.Err() != nil {
+			return cnt, ctx.Err()
+		}
+		drafts, err := scan.ScanDrafts(ctx, afterId, m.batchSize)
+		if err != nil {
+			return cnt, err
+		}
+		if len(drafts) == 0 {
+			return cnt, nil
+		}
+		ids := make([]int64, 0, len(drafts))
+		for _, art := range drafts {
+			ids = append(ids, art.Id)
+		}
+		afterId = ids[len(ids)-1]
+
+		var typs map[int64]string
+		if reverse {
+			typs, err = m.findExtra(ctx, ids, other)
+		} else {
+			typs, err = m.compare(ctx, drafts, scan, other)
+		}
+		if err != nil {
+			return cnt, err
+		}
+		for id, typ := range typs {
+			m.notify(ctx, InconsistentEvent{Id: id, Base: b, Type: typ})
+		}
+		cnt += len(typs)
+	}
+}
+
+// compare 制作库和线上库都要对比
+func (m *Migrator) compare(ctx context.Context, drafts []article.Article,
+	base article.MigrationDAO, target article.MigrationDAO) (map[int64]string, error) {
+	ids := make([]int64, 0, len(drafts))
+	for _, art := range drafts {
+		ids = append(ids, art.Id)
+	}
+	targetDrafts, err := target.FindDrafts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	basePubs, err := base.FindPublished(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	targetPubs, err := target.FindPublished(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[int64]string)
+	targetDraftMap := make(map[int64]article.Article, len(targetDrafts))
+	for _, art := range targetDrafts {
+		targetDraftMap[art.Id] = art
+	}
+	for _, art := range drafts {
+		t, ok := targetDraftMap[art.Id]
+		if !ok {
+			res[art.Id] = InconsistentTargetMissing
+		} else if t != art {
+			res[art.Id] = InconsistentNEQ
+		}
+	}
+
+	targetPubMap := make(map[int64]article.PublishArticle, len(targetPubs))
+	for _, art := range targetPubs {
+		targetPubMap[art.Id] = art
+	}
+	basePubIds := make(map[int64]struct{}, len(basePubs))
+	for _, art := range basePubs {
+		basePubIds[art.Id] = struct{}{}
+		t, ok := targetPubMap[art.Id]
+		if !ok {
+			m.mark(res, art.Id, InconsistentTargetMissing)
+		} else if t != art {
+			m.mark(res, art.Id, InconsistentNEQ)
+		}
+	}
+	// 线上库只在另一边有
+	for _, art := range targetPubs {
+		if _, ok := basePubIds[art.Id]; !ok {
+			m.mark(res, art.Id, InconsistentBaseMissing)
+		}
+	}
+	return res, nil
+}
+
+// findExtra ids 是另一边的，找出为准的一边没有的
+func (m *Migrator) findExtra(ctx context.Context, ids []int64,
+	base article.MigrationDAO) (map[int64]string, error) {
+	drafts, err := base.FindDrafts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int64]struct{}, len(drafts))
+	for _, art := range drafts {
+		found[art.Id] = struct{}{}
+	}
+	res := make(map[int64]string)
+	for _, id := range missing(ids, found) {
+		res[id] = InconsistentBaseMissing
+	}
+	return res, nil
+}
+
+// mark 一篇文章只发一个事件，修复的时候制作库和线上库是一起修的
+func (m *Migrator) mark(res map[int64]string, id int64, typ string) {
+	if _, ok := res[id]; !ok {
+		res[id] = typ
+	}
+}
@@ -1,107 +1,313 @@
 package article
 
 import (
-	"bytes"
 	"context"
-	_ "github.com/aws/aws-sdk-go"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"errors"
+	"fmt"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
-	"github.com/ecodeclub/ekit"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/objectstore"
+	"golang.org/x/sync/errgroup"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
-	"strconv"
 	"time"
 )
 
-var statusPrivate = domain.ArticleStatusPrivate.ToUint8()
+var (
+	statusPrivate   = domain.ArticleStatusPrivate.ToUint8()
+	statusPublished = domain.ArticleStatusPublished.ToUint8()
+)
 
+// S3DAO 线上库的内容存在对象存储里，数据库里的线上表只保存元数据，content 列留空
+// 对象的 key 带上版本号，每次发表都是写一个新对象，数据库提交成功之后再删掉旧版本
+// 这样不管哪一步失败，线上表指向的那个版本的内容总是完整的
 type S3DAO struct {
-	oss *s3.S3
+	store objectstore.Store
 	// 通过组合 GORMArticleDAO 来简化操作
 	// 当然在实践中，你是不太会有组合的机会
 	// 你操作制作库总是一样的
 	// 你就是操作线上库的时候不一样
 	GORMArticleDAO
-	bucket *string
+	// retries 上传和删除对象失败之后的重试次数
+	retries int
+	// retryInterval 第一次重试之前等待的时间，之后每次翻倍
+	retryInterval time.Duration
+	// concurrency 列表读取内容的并发数
+	concurrency int
 }
 
 // NewOssDAO 因为组合 GORMArticleDAO 是一个内部实现细节
 // 所以这里要直接传入 DB
-func NewOssDAO(oss *s3.S3, db *gorm.DB) ArticleDAO {
+// store 可以是 S3 兼容的对象存储，也可以是本地磁盘。
+// 注意不要和对外提供访问的媒体文件共用，否则撤回的文章在删除对象之前还能被直接访问到
+func NewOssDAO(store objectstore.Store, db *gorm.DB) ArticleDAO {
 	return &S3DAO{
-		oss: oss,
-		// 你也可以考虑利用依赖注入来传入。
-		// 但是事实上这个很少变，所以你可以延迟到必要的时候再注入
-		bucket: ekit.ToPtr[string]("webook-1314583317"),
+		store: store,
 		GORMArticleDAO: GORMArticleDAO{
 			db: db,
 		},
+		retries:       3,
+		retryInterval: time.Millisecond * 100,
+		concurrency:   10,
 	}
 }
 
 func (o *S3DAO) Sync(ctx context.Context, art Article) (int64, error) {
-	// 保存制作库
-	// 保存线上库，并且把 content 上传到 OSS
-	//
-	var (
-		id = art.Id
-	)
+	var id = art.Id
 	// 制作库流量不大，并发不高，你就保存到数据库就可以
 	// 当然，有钱或者体量大，就还是考虑 OSS
-	err := o.db.Transaction(func(tx *gorm.DB) error {
+	err := o.publish(ctx, func(tx *gorm.DB) (PublishArticle, error) {
 		var err error
-		now := time.Now().UnixMilli()
 		// 制作库
 		txDAO := NewGORMArticleDAO(tx)
 		if id == 0 {
 			id, err = txDAO.Insert(ctx, art)
+			art.Version = 1
 		} else {
 			err = txDAO.UpdateById(ctx, art)
+			art.Version++
+		}
+		art.Id = id
+		return PublishArticle(art), err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Upsert 和 GORMArticleDAO 一样，只是内容放到对象存储里
+func (o *S3DAO) Upsert(ctx context.Context, art PublishArticle) error {
+	return o.publish(ctx, func(tx *gorm.DB) (PublishArticle, error) {
+		return art, nil
+	})
+}
+
+// publish 在一个事务里面写线上表，最后再上传内容，上传失败了数据库也跟着回滚
+// 事务没有提交的话，刚上传的对象不会被任何数据引用，删掉它；
+// 提交了的话，被替换掉的旧版本没人引用了，删掉旧的
+// prepare 在同一个事务里面执行，返回要写进线上表的文章
+func (o *S3DAO) publish(ctx context.Context, prepare func(tx *gorm.DB) (PublishArticle, error)) error {
+	var (
+		newKey string
+		oldKey string
+	)
+	err := o.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		art, err := prepare(tx)
+		if err != nil {
+			return err
 		}
+		var old PublishArticle
+		err = tx.Model(&PublishArticle{}).Select("id", "version").
+			Where("id = ?", art.Id).Find(&old).Error
 		if err != nil {
 			return err
 		}
-		art.Id = id
-		publishArt := PublishedArticleV1{
-			Id:       art.Id,
-			Title:    art.Title,
-			AuthorId: art.AuthorId,
-			Status:   art.Status,
-			Ctime:    now,
-			Utime:    now,
+		if old.Id > 0 {
+			oldKey = o.key(old.Id, old.Version)
+		}
+		err = o.upsert(tx, art)
+		if err != nil {
+			return err
 		}
-		// 线上库不保存 Content,要准备上传到 OSS 里面
-		return tx.Clauses(clause.OnConflict{
-			// ID 冲突的时候。实际上，在 MYSQL 里面你写不写都可以
-			Columns: []clause.Column{{Name: "id"}},
-			DoUpdates: clause.Assignments(map[string]interface{}{
-				"title":  art.Title,
-				"utime":  now,
-				"status": art.Status,
-				// 要参与 SQL 运算的
-			}),
-		}).Create(&publishArt).Error
+		// 上传返回失败的时候对象也可能已经写进去了，所以先记下来
+		newKey = o.key(art.Id, art.Version)
+		return o.retry(ctx, func() error {
+			return o.store.Put(ctx, newKey, []byte(art.Content), "text/plain;charset=utf-8")
+		})
 	})
-	// 说明保存到数据库的时候失败了
+	// 版本号没变的时候新旧是同一个对象，线上表还在引用它，不能删
+	if newKey == oldKey {
+		return err
+	}
 	if err != nil {
-		return 0, err
+		if newKey != "" {
+			o.deleteObject(ctx, newKey)
+		}
+		return err
 	}
-	// 接下来就是保存到 OSS 里面
-	// 你要有监控，你要有重试，你要有补偿机制
-	_, err = o.oss.PutObjectWithContext(ctx, &s3.PutObjectInput{
-		Bucket:      o.bucket,
-		Key:         ekit.ToPtr[string](strconv.FormatInt(art.Id, 10)),
-		Body:        bytes.NewReader([]byte(art.Content)),
-		ContentType: ekit.ToPtr[string]("text/plain;charset=utf-8"),
-	})
-	return id, err
+	if oldKey != "" {
+		o.deleteObject(ctx, oldKey)
+	}
+	return nil
 }
 
+func (o *S3DAO) upsert(tx *gorm.DB, art PublishArticle) error {
+	now := time.Now().UnixMilli()
+	art.Content = ""
+	art.Ctime = now
+	art.Utime = now
+	return tx.Clauses(clause.OnConflict{
+		// ID 冲突的时候。实际上，在 MYSQL 里面你写不写都可以
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"title": art.Title,
+			// 从 GORMArticleDAO 切换过来的，把数据库里的旧内容清掉
+			"content": "",
+			"utime":   now,
+			"status":  art.Status,
+			"access":  art.Access,
+			"tags":    art.Tags,
+			"version": art.Version,
+		}),
+	}).Create(&art).Error
+}
+
+// SyncStatus 撤回之后删掉线上的内容，重新发表会走 Sync 再上传
 func (o *S3DAO) SyncStatus(ctx context.Context, art Article) error {
-	panic("implement me")
+	err := o.GORMArticleDAO.SyncStatus(ctx, art)
+	if err != nil || art.Status == statusPublished {
+		return err
+	}
+	var pub PublishArticle
+	err = o.db.WithContext(ctx).Model(&PublishArticle{}).
+		Select("id", "version").
+		Where("id = ?", art.Id).Find(&pub).Error
+	if err != nil || pub.Id == 0 {
+		return err
+	}
+	return o.retry(ctx, func() error {
+		return o.store.Delete(ctx, o.key(pub.Id, pub.Version))
+	})
+}
+
+func (o *S3DAO) GetPublishedById(ctx context.Context, id int64) (Article, error) {
+	art, err := o.GORMArticleDAO.GetPublishedById(ctx, id)
+	if err != nil || art.Id == 0 {
+		return art, err
+	}
+	err = o.fillContent(ctx, &art)
+	return art, err
+}
+
+func (o *S3DAO) GetPubByIds(ctx context.Context, ids []int64) ([]Article, error) {
+	arts, err := o.GORMArticleDAO.GetPubByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return arts, o.fillContents(ctx, arts)
+}
+
+func (o *S3DAO) ListPub(ctx context.Context, start time.Time, cursor Cursor, limit int) ([]Article, error) {
+	arts, err := o.GORMArticleDAO.ListPub(ctx, start, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	return arts, o.fillContents(ctx, arts)
+}
+
+func (o *S3DAO) ListPubByAuthor(ctx context.Context, uid int64, cursor Cursor, limit int) ([]Article, error) {
+	arts, err := o.GORMArticleDAO.ListPubByAuthor(ctx, uid, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	return arts, o.fillContents(ctx, arts)
+}
+
+// PurgeTrash 和 GORMArticleDAO 一样，只是还要删掉对象存储里的内容
+func (o *S3DAO) PurgeTrash(ctx context.Context, before time.Time, limit int) ([]int64, error) {
+	var (
+		ids  []int64
+		pubs []PublishArticle
+	)
+	err := o.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Article{}).
+			Where("dtime > 0 AND dtime < ?", before.UnixMilli()).
+			Limit(limit).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		err = tx.Model(&PublishArticle{}).Select("id", "version").
+			Where("id IN ?", ids).Find(&pubs).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("id IN ?", ids).Delete(&Article{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&PublishArticle{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, pub := range pubs {
+		o.deleteObject(ctx, o.key(pub.Id, pub.Version))
+	}
+	return ids, nil
+}
+
+// FindPublished 和 GORMArticleDAO 一样，内容从对象存储里读
+func (o *S3DAO) FindPublished(ctx context.Context, ids []int64) ([]PublishArticle, error) {
+	pubs, err := o.GORMArticleDAO.FindPublished(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pubs {
+		art := Article(pubs[i])
+		err = o.fillContent(ctx, &art)
+		if err != nil {
+			return nil, err
+		}
+		pubs[i] = PublishArticle(art)
+	}
+	return pubs, nil
+}
+
+// fillContents 列表里的内容并发读取
+// 某一篇的对象丢了不影响整个列表，这一篇的内容就是空的，由一致性检查去发现和修复
+func (o *S3DAO) fillContents(ctx context.Context, arts []Article) error {
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(o.concurrency)
+	for i := range arts {
+		art := &arts[i]
+		eg.Go(func() error {
+			err := o.fillContent(ctx, art)
+			if errors.Is(err, objectstore.ErrNotFound) {
+				return nil
+			}
+			return err
+		})
+	}
+	return eg.Wait()
+}
+
+// fillContent 撤回了的文章内容已经删掉了，找不到是正常的
+func (o *S3DAO) fillContent(ctx context.Context, art *Article) error {
+	data, err := o.store.Get(ctx, o.key(art.Id, art.Version))
+	if errors.Is(err, objectstore.ErrNotFound) && art.Status != statusPublished {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取文章内容失败 id %d, version %d: %w", art.Id, art.Version, err)
+	}
+	art.Content = string(data)
+	return nil
+}
+
+// deleteObject 删除失败只是留下一个没人引用的对象，不影响业务，所以不返回错误
+// 这种对象可以靠 bucket 的生命周期规则或者对账任务清理
+func (o *S3DAO) deleteObject(ctx context.Context, key string) {
+	_ = o.retry(ctx, func() error {
+		return o.store.Delete(ctx, key)
+	})
+}
+
+func (o *S3DAO) retry(ctx context.Context, fn func() error) error {
+	interval := o.retryInterval
+	err := fn()
+	for i := 0; err != nil && i < o.retries; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+		err = fn()
+	}
+	return err
 }
 
-func (o *S3DAO) ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]Article, error) {
-	//TODO implement me
-	panic("implement me")
+func (o *S3DAO) key(id int64, version int64) string {
+	return fmt.Sprintf("articles/%d/%d", id, version)
 }
@@ -0,0 +1,109 @@
+package article
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/objectstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// failingStore 可以让 Put 失败，用来测试补偿
+type failingStore struct {
+	objectstore.Store
+	putErr error
+}
+
+func (s *failingStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if s.putErr != nil {
+		return s.putErr
+	}
+	return s.Store.Put(ctx, key, data, contentType)
+}
+
+func newTestS3DAO(t *testing.T) (*S3DAO, *failingStore) {
+	db := initTestDB(t)
+	t.Cleanup(func() {
+		db.Exec("TRUNCATE TABLE articles")
+		db.Exec("TRUNCATE TABLE publish_articles")
+	})
+	store := &failingStore{Store: objectstore.NewLocalStore(t.TempDir(), "")}
+	dao := NewOssDAO(store, db).(*S3DAO)
+	dao.retries = 0
+	return dao, store
+}
+
+func TestS3DAO_SyncPutFailed(t *testing.T) {
+	dao, store := newTestS3DAO(t)
+	ctx := context.Background()
+	id, err := dao.Sync(ctx, Article{
+		Title: "标题", Content: "第一版", AuthorId: 123, Status: statusPublished,
+	})
+	require.NoError(t, err)
+
+	// 上传失败，制作库和线上库都回滚，旧版本的内容还在
+	store.putErr = errors.New("mock error")
+	_, err = dao.Sync(ctx, Article{
+		Id: id, Title: "标题", Content: "第二版", AuthorId: 123,
+		Status: statusPublished, Version: 1,
+	})
+	assert.Error(t, err)
+	art, err := dao.GetById(ctx, id, 123)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), art.Version)
+	pub, err := dao.GetPublishedById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "第一版", pub.Content)
+
+	// 成功之后旧版本的对象被删掉
+	store.putErr = nil
+	_, err = dao.Sync(ctx, Article{
+		Id: id, Title: "标题", Content: "第二版", AuthorId: 123,
+		Status: statusPublished, Version: 1,
+	})
+	require.NoError(t, err)
+	_, err = store.Get(ctx, dao.key(id, 1))
+	assert.Equal(t, objectstore.ErrNotFound, err)
+	data, err := store.Get(ctx, dao.key(id, 2))
+	require.NoError(t, err)
+	assert.Equal(t, "第二版", string(data))
+}
+
+func TestS3DAO_UpsertPutFailed(t *testing.T) {
+	dao, store := newTestS3DAO(t)
+	ctx := context.Background()
+	store.putErr = errors.New("mock error")
+	err := dao.Upsert(ctx, PublishArticle{
+		Id: 10, Title: "标题", Content: "内容", AuthorId: 123,
+		Status: statusPublished, Version: 1,
+	})
+	assert.Error(t, err)
+	// 线上表没有指向一个不存在的对象
+	pub, err := dao.GetPublishedById(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pub.Id)
+}
+
+// 列表里某一篇的对象丢了，其他的照常返回
+func TestS3DAO_ListMissingObject(t *testing.T) {
+	dao, store := newTestS3DAO(t)
+	ctx := context.Background()
+	ids := make([]int64, 0, 2)
+	for _, content := range []string{"一", "二"} {
+		id, err := dao.Sync(ctx, Article{
+			Title: content, Content: content, AuthorId: 123, Status: statusPublished,
+		})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	require.NoError(t, store.Delete(ctx, dao.key(ids[0], 1)))
+
+	arts, err := dao.GetPubByIds(ctx, ids)
+	require.NoError(t, err)
+	contents := make(map[int64]string, len(arts))
+	for _, art := range arts {
+		contents[art.Id] = art.Content
+	}
+	assert.Equal(t, map[int64]string{ids[0]: "", ids[1]: "二"}, contents)
+}
@@ -2,19 +2,74 @@ package article
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 )
 
+var (
+	ErrVersionConflict = errors.New("文章已经被别人修改过了")
+	// ErrArticleNotFound 文章不存在，或者不是这个作者的
+	ErrArticleNotFound = errors.New("文章不存在")
+)
+
+// VersionConflictError 乐观锁冲突的时候，把服务端当前的版本号带回去
+// 前端可以据此提示用户刷新，或者拿着新版本号强制覆盖
+type VersionConflictError struct {
+	Id      int64
+	Version int64
+}
+
+func (e VersionConflictError) Error() string {
+	return fmt.Sprintf("%s, id %d, 当前版本 %d", ErrVersionConflict.Error(), e.Id, e.Version)
+}
+
+func (e VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
+
 type ArticleDAO interface {
 	Insert(ctx context.Context, article Article) (int64, error)
 	UpdateById(ctx context.Context, article Article) error
 	Sync(ctx context.Context, article Article) (int64, error)
 	Upsert(ctx context.Context, article PublishArticle) error
 	SyncStatus(ctx context.Context, article Article) error
-	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error)
+	// GetByAuthor 按照 (utime, id) 倒序，取 cursor 之后的 limit 条
+	GetByAuthor(ctx context.Context, uid int64, cursor Cursor, limit int) ([]Article, error)
 	GetById(ctx context.Context, id int64, uid int64) (Article, error)
 	GetPublishedById(ctx context.Context, id int64) (Article, error)
-	ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]Article, error)
+	// GetPubByIds 批量查线上库，和 GetPublishedById 一样不过滤状态
+	GetPubByIds(ctx context.Context, ids []int64) ([]Article, error)
+	// ListPub 取 utime 在 start 之前，并且排在 cursor 之后的 limit 条
+	ListPub(ctx context.Context, start time.Time, cursor Cursor, limit int) ([]Article, error)
+	// ListPubByAuthor 某个作者线上可见的文章，和 ListPub 一样按 (utime, id) 倒序
+	ListPubByAuthor(ctx context.Context, uid int64, cursor Cursor, limit int) ([]Article, error)
+	// ListPubUpdatedAfter 按照 (utime, id) 正序，取 cursor 之后、utime 在 before 之前的 limit 条
+	// 包括回收站里的和仅自己可见的，只返回 id 和 utime，给增量任务用
+	ListPubUpdatedAfter(ctx context.Context, cursor Cursor, before int64, limit int) ([]Article, error)
+	// ListPubByIdRange id 在 [minId, maxId) 之间所有对外可见的文章，只返回 id 和 utime
+	ListPubByIdRange(ctx context.Context, minId int64, maxId int64) ([]Article, error)
+
+	// MoveToTrash 把制作库和线上库的文章都放进回收站
+	MoveToTrash(ctx context.Context, id int64, uid int64) error
+	// Restore 从回收站里恢复，原来的状态不变
+	Restore(ctx context.Context, id int64, uid int64) error
+	// ListTrash 回收站里的文章，和 GetByAuthor 一样按 (utime, id) 倒序
+	ListTrash(ctx context.Context, uid int64, cursor Cursor, limit int) ([]Article, error)
+	// PurgeTrash 彻底删除 before 之前放进回收站的文章，返回被删除的文章 id
+	PurgeTrash(ctx context.Context, before time.Time, limit int) ([]int64, error)
+}
+
+// Cursor 游标分页用的，代表上一页最后一条数据的 (utime, id)
+// 零值代表从头开始取
+// 用 offset 的话，翻得越深越慢，而且翻页的过程中有人发表了文章，就会出现重复或者遗漏
+type Cursor struct {
+	Utime int64
+	Id    int64
+}
+
+func (c Cursor) IsZero() bool {
+	return c.Utime == 0 && c.Id == 0
 }
 
 // Article 这是制作库的
@@ -42,10 +97,24 @@ type Article struct {
 	// 在 author_id 上创建索引
 	AuthorId int64 `gorm:"index" bson:"author_id,omitempty"`
 	Status   uint8 `bson:"status,omitempty"`
+	// Access 访问级别，0 是公开的，所以不能 omitempty，不然改回公开的时候 $set 不会生效
+	Access uint8 `bson:"access"`
+	// Tags 逗号分隔的标签，不会拿来查询，所以不拆表
+	// 和 Access 一样不能 omitempty，不然清空标签的时候 $set 不会生效
+	Tags string `gorm:"type:varchar(1024)" bson:"tags"`
 	//AuthorId int64 `gorm:"index=aid_ctime"`
 	//Ctime    int64 `gorm:"index=aid_ctime"`
 	Ctime int64 `bson:"ctime,omitempty"`
-	Utime int64 `bson:"utime,omitempty"`
+	// 列表都是按照 (utime, id) 游标分页的
+	Utime int64 `gorm:"index" bson:"utime,omitempty"`
+	// Version 乐观锁，每次编辑都 +1
+	// 两个标签页同时编辑同一篇草稿的时候，后保存的那个会因为版本号对不上而失败
+	// 加列之前的文章是 0，不能是 NULL，不然 version = ? 永远匹配不上
+	Version int64 `gorm:"not null;default:0" bson:"version,omitempty"`
+	// Dtime 放进回收站的时间，0 代表没有删除
+	// 回收站里的文章超过一定天数之后，会被定时任务彻底删除
+	// 查询都是 dtime = 0，所以不能是 NULL，不然加列之后原来的文章都查不出来了
+	Dtime int64 `gorm:"index;not null;default:0" bson:"dtime,omitempty"`
 }
 
 // PublishArticle 这个代表的是线上表
@@ -54,12 +123,3 @@ type Article struct {
 }*/
 
 type PublishArticle Article
-
-type PublishedArticleV1 struct {
-	Id       int64
-	Title    string
-	AuthorId int64
-	Status   uint8
-	Ctime    int64
-	Utime    int64
-}
@@ -10,5 +10,13 @@ func InitTable(db *gorm.DB) error {
 	return db.AutoMigrate(&User{},
 		&article.Article{},
 		&article.PublishArticle{},
+		&ArticleReview{},
+		&AuthorTrust{},
+		&Series{},
+		&SeriesArticle{},
+		&Membership{},
+		&MembershipOrder{},
+		&ReadHistory{},
+		&RankingSnapshotItem{},
 		&dao.Job{})
 }
@@ -0,0 +1,147 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrMembershipNotFound = gorm.ErrRecordNotFound
+	// ErrOrderNotPending 订单已经支付或者失败了，不能再改状态
+	ErrOrderNotPending = errors.New("订单不是待支付状态")
+)
+
+type MembershipDAO interface {
+	FindByUid(ctx context.Context, uid int64) (Membership, error)
+	InsertOrder(ctx context.Context, o MembershipOrder) (int64, error)
+	// MarkPaid 在一个事务里把订单标记为已支付并且延长会员
+	// 会员没过期的从原来的过期时间往后延，过期了的从现在开始算
+	MarkPaid(ctx context.Context, sn string, txnId string) (Membership, error)
+	MarkFailed(ctx context.Context, sn string) error
+}
+
+type GORMMembershipDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMMembershipDAO(db *gorm.DB) MembershipDAO {
+	return &GORMMembershipDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMMembershipDAO) FindByUid(ctx context.Context, uid int64) (Membership, error) {
+	var m Membership
+	err := dao.db.WithContext(ctx).Where("uid = ?", uid).First(&m).Error
+	return m, err
+}
+
+func (dao *GORMMembershipDAO) InsertOrder(ctx context.Context, o MembershipOrder) (int64, error) {
+	now := time.Now().UnixMilli()
+	o.Ctime = now
+	o.Utime = now
+	err := dao.db.WithContext(ctx).Create(&o).Error
+	return o.Id, err
+}
+
+func (dao *GORMMembershipDAO) MarkPaid(ctx context.Context, sn string, txnId string) (Membership, error) {
+	var m Membership
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var o MembershipOrder
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("sn = ?", sn).First(&o).Error
+		if err != nil {
+			return err
+		}
+		if o.Status != membershipOrderStatusPending {
+			return ErrOrderNotPending
+		}
+		now := time.Now().UnixMilli()
+		err = tx.Model(&MembershipOrder{}).Where("id = ?", o.Id).
+			Updates(map[string]any{
+				"status": membershipOrderStatusPaid,
+				"txn_id": txnId,
+				"utime":  now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uid = ?", o.Uid).First(&m).Error
+		switch {
+		case err == nil:
+			if m.ExpireAt < now {
+				m.ExpireAt = now
+			}
+			m.ExpireAt += o.Duration
+			m.Utime = now
+			return tx.Model(&Membership{}).Where("id = ?", m.Id).
+				Updates(map[string]any{
+					"expire_at": m.ExpireAt,
+					"utime":     now,
+				}).Error
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			m = Membership{
+				Uid:      o.Uid,
+				ExpireAt: now + o.Duration,
+				Ctime:    now,
+				Utime:    now,
+			}
+			// uid 上有唯一索引，同一个用户并发第一次购买的时候会有一个失败
+			return tx.Create(&m).Error
+		default:
+			return err
+		}
+	})
+	return m, err
+}
+
+func (dao *GORMMembershipDAO) MarkFailed(ctx context.Context, sn string) error {
+	res := dao.db.WithContext(ctx).Model(&MembershipOrder{}).
+		Where("sn = ? AND status = ?", sn, membershipOrderStatusPending).
+		Updates(map[string]any{
+			"status": membershipOrderStatusFailed,
+			"utime":  time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrOrderNotPending
+	}
+	return nil
+}
+
+// 和 domain.MembershipOrderStatus 保持一致
+const (
+	membershipOrderStatusPending uint8 = iota + 1
+	membershipOrderStatusPaid
+	membershipOrderStatusFailed
+)
+
+// Membership 每个用户一行，ExpireAt 是毫秒数
+type Membership struct {
+	Id       int64 `gorm:"primaryKey,autoIncrement"`
+	Uid      int64 `gorm:"uniqueIndex"`
+	ExpireAt int64
+	Ctime    int64
+	Utime    int64
+}
+
+// MembershipOrder 购买会员的订单，Duration 是毫秒数
+type MembershipOrder struct {
+	Id       int64  `gorm:"primaryKey,autoIncrement"`
+	Sn       string `gorm:"type:varchar(64);uniqueIndex"`
+	Uid      int64  `gorm:"index"`
+	PlanId   int64
+	Amount   int64
+	Duration int64
+	Status   uint8
+	TxnId    string `gorm:"type:varchar(128)"`
+	Ctime    int64
+	Utime    int64
+}
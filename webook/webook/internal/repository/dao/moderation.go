@@ -0,0 +1,161 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrReviewNotFound = gorm.ErrRecordNotFound
+	// ErrReviewNotPending 已经被别的审核员处理过了
+	ErrReviewNotPending = errors.New("审核已经被处理过了")
+)
+
+type ModerationDAO interface {
+	// UpsertPendingReview 同一篇文章只保留一条待审核的记录，重复提交就覆盖
+	UpsertPendingReview(ctx context.Context, r ArticleReview) (int64, error)
+	FindReviewById(ctx context.Context, id int64) (ArticleReview, error)
+	FindLatestReview(ctx context.Context, aid int64) (ArticleReview, error)
+	// FindPendingReviews 按照提交的先后顺序，取 id 大于 minId 的
+	FindPendingReviews(ctx context.Context, minId int64, limit int) ([]ArticleReview, error)
+	// Resolve 把待审核的记录置为通过或者拒绝
+	Resolve(ctx context.Context, r ArticleReview) error
+	GetTrustLevel(ctx context.Context, uid int64) (uint8, error)
+	SetTrustLevel(ctx context.Context, uid int64, level uint8) error
+}
+
+type GORMModerationDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMModerationDAO(db *gorm.DB) ModerationDAO {
+	return &GORMModerationDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMModerationDAO) UpsertPendingReview(ctx context.Context, r ArticleReview) (int64, error) {
+	now := time.Now().UnixMilli()
+	r.Utime = now
+	r.Status = reviewStatusPending
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var old ArticleReview
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("aid = ? AND status = ?", r.Aid, reviewStatusPending).
+			First(&old).Error
+		switch err {
+		case nil:
+			r.Id = old.Id
+			return tx.Model(&ArticleReview{}).Where("id = ?", old.Id).
+				Updates(map[string]any{
+					"title":   r.Title,
+					"version": r.Version,
+					"utime":   now,
+				}).Error
+		case gorm.ErrRecordNotFound:
+			r.Ctime = now
+			return tx.Create(&r).Error
+		default:
+			return err
+		}
+	})
+	return r.Id, err
+}
+
+func (dao *GORMModerationDAO) FindReviewById(ctx context.Context, id int64) (ArticleReview, error) {
+	var r ArticleReview
+	err := dao.db.WithContext(ctx).Where("id = ?", id).First(&r).Error
+	return r, err
+}
+
+func (dao *GORMModerationDAO) FindLatestReview(ctx context.Context, aid int64) (ArticleReview, error) {
+	var r ArticleReview
+	err := dao.db.WithContext(ctx).Where("aid = ?", aid).
+		Order("id DESC").First(&r).Error
+	return r, err
+}
+
+func (dao *GORMModerationDAO) FindPendingReviews(ctx context.Context, minId int64, limit int) ([]ArticleReview, error) {
+	var res []ArticleReview
+	err := dao.db.WithContext(ctx).
+		Where("status = ? AND id > ?", reviewStatusPending, minId).
+		Order("id ASC").Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMModerationDAO) Resolve(ctx context.Context, r ArticleReview) error {
+	res := dao.db.WithContext(ctx).Model(&ArticleReview{}).
+		Where("id = ? AND status = ?", r.Id, reviewStatusPending).
+		Updates(map[string]any{
+			"status":       r.Status,
+			"moderator_id": r.ModeratorId,
+			"reason":       r.Reason,
+			"utime":        time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrReviewNotPending
+	}
+	return nil
+}
+
+func (dao *GORMModerationDAO) GetTrustLevel(ctx context.Context, uid int64) (uint8, error) {
+	var t AuthorTrust
+	err := dao.db.WithContext(ctx).Where("uid = ?", uid).First(&t).Error
+	if err == gorm.ErrRecordNotFound {
+		// 没有记录就是普通作者
+		return 0, nil
+	}
+	return t.Level, err
+}
+
+func (dao *GORMModerationDAO) SetTrustLevel(ctx context.Context, uid int64, level uint8) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"level": level,
+			"utime": now,
+		}),
+	}).Create(&AuthorTrust{
+		Uid:   uid,
+		Level: level,
+		Ctime: now,
+		Utime: now,
+	}).Error
+}
+
+const (
+	reviewStatusPending uint8 = 1
+)
+
+// ArticleReview 发表审核记录
+// 审核员的队列是 WHERE status = 1 ORDER BY id
+type ArticleReview struct {
+	Id       int64 `gorm:"primaryKey,autoIncrement"`
+	Aid      int64 `gorm:"index"`
+	AuthorId int64
+	// 提交审核时的标题和版本号，审核通过的时候版本号对不上，说明作者又改过了
+	Title       string `gorm:"type:varchar(1024)"`
+	Version     int64
+	ModeratorId int64
+	Status      uint8  `gorm:"index"`
+	Reason      string `gorm:"type:varchar(1024)"`
+	Ctime       int64
+	Utime       int64
+}
+
+// AuthorTrust 作者的信任等级，没有记录的就是普通作者
+// 第一个审核员需要手动在数据库里插入
+type AuthorTrust struct {
+	Id    int64 `gorm:"primaryKey,autoIncrement"`
+	Uid   int64 `gorm:"uniqueIndex"`
+	Level uint8
+	Ctime int64
+	Utime int64
+}
@@ -0,0 +1,89 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+type RankingSnapshotDAO interface {
+	// Insert 一次快照的所有行，要么都插入，要么都不插入
+	Insert(ctx context.Context, items []RankingSnapshotItem) error
+	// ListCtimes 最近 limit 次快照的时间，从新到旧
+	ListCtimes(ctx context.Context, name string, limit int) ([]int64, error)
+	// FindByCtime 一次快照，按照名次排好
+	FindByCtime(ctx context.Context, name string, ctime int64) ([]RankingSnapshotItem, error)
+	// FindByAid 一篇文章最近 limit 次上榜的记录，从新到旧
+	FindByAid(ctx context.Context, name string, aid int64, limit int) ([]RankingSnapshotItem, error)
+	// DeleteBefore 删掉 before 之前的快照，一次最多删 limit 行，返回删了多少行
+	DeleteBefore(ctx context.Context, before int64, limit int) (int64, error)
+}
+
+type GORMRankingSnapshotDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMRankingSnapshotDAO(db *gorm.DB) RankingSnapshotDAO {
+	return &GORMRankingSnapshotDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMRankingSnapshotDAO) Insert(ctx context.Context, items []RankingSnapshotItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(items, 100).Error
+	})
+}
+
+func (dao *GORMRankingSnapshotDAO) ListCtimes(ctx context.Context, name string, limit int) ([]int64, error) {
+	var res []int64
+	err := dao.db.WithContext(ctx).Model(&RankingSnapshotItem{}).
+		Where("name = ?", name).
+		Distinct("ctime").
+		Order("ctime DESC").
+		Limit(limit).
+		Pluck("ctime", &res).Error
+	return res, err
+}
+
+func (dao *GORMRankingSnapshotDAO) FindByCtime(ctx context.Context, name string, ctime int64) ([]RankingSnapshotItem, error) {
+	var res []RankingSnapshotItem
+	err := dao.db.WithContext(ctx).
+		Where("name = ? AND ctime = ?", name, ctime).
+		Order("`rank` ASC").
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMRankingSnapshotDAO) FindByAid(ctx context.Context, name string, aid int64, limit int) ([]RankingSnapshotItem, error) {
+	var res []RankingSnapshotItem
+	err := dao.db.WithContext(ctx).
+		Where("aid = ? AND name = ?", aid, name).
+		Order("ctime DESC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMRankingSnapshotDAO) DeleteBefore(ctx context.Context, before int64, limit int) (int64, error) {
+	// 分批删，免得一个大事务锁太久
+	res := dao.db.WithContext(ctx).
+		Where("ctime < ?", before).
+		Limit(limit).
+		Delete(&RankingSnapshotItem{})
+	return res.RowsAffected, res.Error
+}
+
+// RankingSnapshotItem 一次快照里的一篇文章，名字和时间冗余在每一行上，
+// 整次快照按照 (name, ctime) 查，一篇文章的历史按照 (aid, name, ctime) 查
+type RankingSnapshotItem struct {
+	Id    int64  `gorm:"primaryKey,autoIncrement"`
+	Name  string `gorm:"type:varchar(32);index:name_ctime,priority:1;index:aid_name_ctime,priority:2"`
+	Ctime int64  `gorm:"index:name_ctime,priority:2;index:aid_name_ctime,priority:3;index:ctime"`
+	Aid   int64  `gorm:"index:aid_name_ctime,priority:1"`
+	// Rank 从 1 开始
+	Rank  int
+	Score float64
+}
@@ -0,0 +1,102 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type ReadHistoryDAO interface {
+	// Touch 记录一次阅读，已经有记录的只更新阅读时间，不会改阅读位置
+	Touch(ctx context.Context, uid int64, aid int64, utime int64) error
+	// UpsertProgress 记录阅读位置，同时也算一次阅读
+	UpsertProgress(ctx context.Context, h ReadHistory) error
+	Find(ctx context.Context, uid int64, aid int64) (ReadHistory, error)
+	// List 按照 (utime, aid) 倒序分页，cursor 为零值的时候是第一页
+	List(ctx context.Context, uid int64, cursorUtime int64, cursorAid int64, limit int) ([]ReadHistory, error)
+	Delete(ctx context.Context, uid int64, aids []int64) error
+	DeleteAll(ctx context.Context, uid int64) error
+}
+
+type GORMReadHistoryDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMReadHistoryDAO(db *gorm.DB) ReadHistoryDAO {
+	return &GORMReadHistoryDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMReadHistoryDAO) Touch(ctx context.Context, uid int64, aid int64, utime int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			// 阅读事件是异步消费的，可能比上报的阅读位置晚到，阅读时间不能往回改
+			"utime": gorm.Expr("GREATEST(utime, ?)", utime),
+		}),
+	}).Create(&ReadHistory{
+		Uid:   uid,
+		Aid:   aid,
+		Ctime: now,
+		Utime: utime,
+	}).Error
+}
+
+func (dao *GORMReadHistoryDAO) UpsertProgress(ctx context.Context, h ReadHistory) error {
+	now := time.Now().UnixMilli()
+	h.Ctime = now
+	h.Utime = now
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"position": h.Position,
+			"progress": h.Progress,
+			"utime":    now,
+		}),
+	}).Create(&h).Error
+}
+
+func (dao *GORMReadHistoryDAO) Find(ctx context.Context, uid int64, aid int64) (ReadHistory, error) {
+	var h ReadHistory
+	err := dao.db.WithContext(ctx).
+		Where("uid = ? AND aid = ?", uid, aid).
+		First(&h).Error
+	return h, err
+}
+
+func (dao *GORMReadHistoryDAO) List(ctx context.Context, uid int64,
+	cursorUtime int64, cursorAid int64, limit int) ([]ReadHistory, error) {
+	var res []ReadHistory
+	query := dao.db.WithContext(ctx).Where("uid = ?", uid)
+	if cursorUtime > 0 || cursorAid > 0 {
+		query = query.Where("utime < ? OR (utime = ? AND aid < ?)",
+			cursorUtime, cursorUtime, cursorAid)
+	}
+	err := query.Order("utime DESC, aid DESC").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMReadHistoryDAO) Delete(ctx context.Context, uid int64, aids []int64) error {
+	return dao.db.WithContext(ctx).
+		Where("uid = ? AND aid IN ?", uid, aids).
+		Delete(&ReadHistory{}).Error
+}
+
+func (dao *GORMReadHistoryDAO) DeleteAll(ctx context.Context, uid int64) error {
+	return dao.db.WithContext(ctx).
+		Where("uid = ?", uid).
+		Delete(&ReadHistory{}).Error
+}
+
+// ReadHistory 每个用户每篇文章一行
+// 历史列表按照 (uid, utime) 查，所以有联合索引
+type ReadHistory struct {
+	Id       int64 `gorm:"primaryKey,autoIncrement"`
+	Uid      int64 `gorm:"uniqueIndex:uid_aid;index:uid_utime,priority:1"`
+	Aid      int64 `gorm:"uniqueIndex:uid_aid"`
+	Position int64
+	Progress int
+	Ctime    int64
+	Utime    int64 `gorm:"index:uid_utime,priority:2"`
+}
@@ -0,0 +1,181 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	// ErrSeriesNotFound 系列不存在，或者不是这个作者的
+	ErrSeriesNotFound = gorm.ErrRecordNotFound
+	// ErrArticleInOtherSeries 一篇文章只能属于一个系列
+	ErrArticleInOtherSeries = errors.New("文章已经在别的系列里了")
+)
+
+type SeriesDAO interface {
+	Insert(ctx context.Context, s Series) (int64, error)
+	// Update 只能修改自己的系列
+	Update(ctx context.Context, s Series) error
+	// Delete 系列里的文章不受影响，只是不再属于这个系列
+	Delete(ctx context.Context, id int64, uid int64) error
+	FindById(ctx context.Context, id int64) (Series, error)
+	FindByAuthor(ctx context.Context, uid int64) ([]Series, error)
+	// SetArticles 用 aids 整个替换掉系列里的文章，aids 的顺序就是阅读顺序
+	SetArticles(ctx context.Context, id int64, uid int64, aids []int64) error
+	// FindArticles 按照阅读顺序返回系列里的文章 id
+	FindArticles(ctx context.Context, id int64) ([]int64, error)
+	// FindByArticle 文章所在的系列 id，不在任何系列里返回 0
+	FindByArticle(ctx context.Context, aid int64) (int64, error)
+}
+
+type GORMSeriesDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMSeriesDAO(db *gorm.DB) SeriesDAO {
+	return &GORMSeriesDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMSeriesDAO) Insert(ctx context.Context, s Series) (int64, error) {
+	now := time.Now().UnixMilli()
+	s.Ctime = now
+	s.Utime = now
+	err := dao.db.WithContext(ctx).Create(&s).Error
+	return s.Id, err
+}
+
+func (dao *GORMSeriesDAO) Update(ctx context.Context, s Series) error {
+	res := dao.db.WithContext(ctx).Model(&Series{}).
+		Where("id = ? AND author_id = ?", s.Id, s.AuthorId).
+		Updates(map[string]any{
+			"title":       s.Title,
+			"description": s.Description,
+			"utime":       time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSeriesNotFound
+	}
+	return nil
+}
+
+func (dao *GORMSeriesDAO) Delete(ctx context.Context, id int64, uid int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND author_id = ?", id, uid).Delete(&Series{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrSeriesNotFound
+		}
+		return tx.Where("series_id = ?", id).Delete(&SeriesArticle{}).Error
+	})
+}
+
+func (dao *GORMSeriesDAO) FindById(ctx context.Context, id int64) (Series, error) {
+	var s Series
+	err := dao.db.WithContext(ctx).Where("id = ?", id).First(&s).Error
+	return s, err
+}
+
+func (dao *GORMSeriesDAO) FindByAuthor(ctx context.Context, uid int64) ([]Series, error) {
+	var res []Series
+	err := dao.db.WithContext(ctx).Where("author_id = ?", uid).
+		Order("utime DESC").Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMSeriesDAO) SetArticles(ctx context.Context, id int64, uid int64, aids []int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 锁住系列，同一个系列的两次修改串行执行
+		var s Series
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND author_id = ?", id, uid).
+			First(&s).Error
+		if err != nil {
+			return err
+		}
+		if len(aids) > 0 {
+			var cnt int64
+			err = tx.Model(&SeriesArticle{}).
+				Where("aid IN ? AND series_id != ?", aids, id).
+				Count(&cnt).Error
+			if err != nil {
+				return err
+			}
+			if cnt > 0 {
+				return ErrArticleInOtherSeries
+			}
+		}
+		err = tx.Where("series_id = ?", id).Delete(&SeriesArticle{}).Error
+		if err != nil {
+			return err
+		}
+		if len(aids) > 0 {
+			rows := make([]SeriesArticle, 0, len(aids))
+			for i, aid := range aids {
+				rows = append(rows, SeriesArticle{
+					SeriesId: id,
+					Aid:      aid,
+					Position: i + 1,
+					Ctime:    now,
+				})
+			}
+			// aid 上有唯一索引，并发加到两个系列里的时候会有一个失败
+			err = tx.Create(&rows).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&Series{}).Where("id = ?", id).
+			Update("utime", now).Error
+	})
+}
+
+func (dao *GORMSeriesDAO) FindArticles(ctx context.Context, id int64) ([]int64, error) {
+	var aids []int64
+	err := dao.db.WithContext(ctx).Model(&SeriesArticle{}).
+		Where("series_id = ?", id).
+		Order("position ASC").
+		Pluck("aid", &aids).Error
+	return aids, err
+}
+
+func (dao *GORMSeriesDAO) FindByArticle(ctx context.Context, aid int64) (int64, error) {
+	var ids []int64
+	err := dao.db.WithContext(ctx).Model(&SeriesArticle{}).
+		Where("aid = ?", aid).Limit(1).
+		Pluck("series_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// Series 作者的系列
+type Series struct {
+	Id          int64  `gorm:"primaryKey,autoIncrement"`
+	AuthorId    int64  `gorm:"index"`
+	Title       string `gorm:"type:varchar(256)"`
+	Description string `gorm:"type:varchar(1024)"`
+	Ctime       int64
+	Utime       int64
+}
+
+// SeriesArticle 系列里的文章，按照 position 排序
+// 文章详情页要查文章属于哪个系列，所以 aid 上有唯一索引
+type SeriesArticle struct {
+	Id       int64 `gorm:"primaryKey,autoIncrement"`
+	SeriesId int64 `gorm:"index:series_position"`
+	Aid      int64 `gorm:"uniqueIndex"`
+	Position int   `gorm:"index:series_position"`
+	Ctime    int64
+}
@@ -19,6 +19,8 @@ type UserDAO interface {
 	FindByEmail(ctx context.Context, email string) (User, error)
 	FindByPhone(ctx context.Context, phone string) (User, error)
 	FindById(ctx context.Context, id int64) (User, error)
+	// FindByIds 找不到的直接跳过，不保证顺序
+	FindByIds(ctx context.Context, ids []int64) ([]User, error)
 	FindByWechat(ctx context.Context, openId string) (User, error)
 	UpdateProfile(ctx context.Context, u User) error
 	UpdatePassword(ctx context.Context, u User) error
@@ -102,6 +104,15 @@ func (dao *GORMUserDAO) FindById(ctx context.Context, id int64) (User, error) {
 	return u, err
 }
 
+func (dao *GORMUserDAO) FindByIds(ctx context.Context, ids []int64) ([]User, error) {
+	var us []User
+	if len(ids) == 0 {
+		return us, nil
+	}
+	err := dao.db.WithContext(ctx).Where("id IN ?", ids).Find(&us).Error
+	return us, err
+}
+
 // User 直接对应数据库表结构
 // 有些人叫做 entity，有些人叫做 model，有些人叫做 PO(persistent object)
 type User struct {
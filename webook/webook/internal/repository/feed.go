@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
+)
+
+// FeedRepository 渲染好的订阅源只放在缓存里，过期了就重新生成
+type FeedRepository interface {
+	Get(ctx context.Context, key string) (domain.FeedDoc, error)
+	Set(ctx context.Context, key string, doc domain.FeedDoc) error
+	Del(ctx context.Context, keys ...string) error
+}
+
+type CachedFeedRepository struct {
+	cache cache.FeedCache
+}
+
+func NewCachedFeedRepository(cache cache.FeedCache) FeedRepository {
+	return &CachedFeedRepository{
+		cache: cache,
+	}
+}
+
+func (repo *CachedFeedRepository) Get(ctx context.Context, key string) (domain.FeedDoc, error) {
+	return repo.cache.Get(ctx, key)
+}
+
+func (repo *CachedFeedRepository) Set(ctx context.Context, key string, doc domain.FeedDoc) error {
+	return repo.cache.Set(ctx, key, doc)
+}
+
+func (repo *CachedFeedRepository) Del(ctx context.Context, keys ...string) error {
+	return repo.cache.Del(ctx, keys...)
+}
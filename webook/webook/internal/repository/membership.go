@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"time"
+)
+
+var ErrOrderNotPending = dao.ErrOrderNotPending
+
+type MembershipRepository interface {
+	// FindByUid 不是会员的返回 ExpireAt 为零值的 Membership
+	FindByUid(ctx context.Context, uid int64) (domain.Membership, error)
+	CreateOrder(ctx context.Context, o domain.MembershipOrder) (int64, error)
+	MarkPaid(ctx context.Context, sn string, txnId string) (domain.Membership, error)
+	MarkFailed(ctx context.Context, sn string) error
+}
+
+type CachedMembershipRepository struct {
+	dao   dao.MembershipDAO
+	cache cache.MembershipCache
+	l     logger.Logger
+}
+
+func NewCachedMembershipRepository(dao dao.MembershipDAO,
+	cache cache.MembershipCache, l logger.Logger) MembershipRepository {
+	return &CachedMembershipRepository{
+		dao:   dao,
+		cache: cache,
+		l:     l,
+	}
+}
+
+func (repo *CachedMembershipRepository) FindByUid(ctx context.Context, uid int64) (domain.Membership, error) {
+	expireAt, err := repo.cache.Get(ctx, uid)
+	if err == nil {
+		return repo.toDomain(uid, expireAt), nil
+	}
+	m, err := repo.dao.FindByUid(ctx, uid)
+	switch {
+	case err == nil:
+		expireAt = m.ExpireAt
+	case errors.Is(err, dao.ErrMembershipNotFound):
+		// 不是会员的也缓存起来，不然每次看会员文章都要查数据库
+		expireAt = 0
+	default:
+		return domain.Membership{}, err
+	}
+	err = repo.cache.Set(ctx, uid, expireAt)
+	if err != nil {
+		repo.l.Error("回写会员缓存失败", logger.Error(err), logger.Int64("uid", uid))
+	}
+	return repo.toDomain(uid, expireAt), nil
+}
+
+func (repo *CachedMembershipRepository) CreateOrder(ctx context.Context, o domain.MembershipOrder) (int64, error) {
+	return repo.dao.InsertOrder(ctx, dao.MembershipOrder{
+		Sn:       o.Sn,
+		Uid:      o.Uid,
+		PlanId:   o.PlanId,
+		Amount:   o.Amount,
+		Duration: o.Duration.Milliseconds(),
+		Status:   o.Status.ToUint8(),
+	})
+}
+
+func (repo *CachedMembershipRepository) MarkPaid(ctx context.Context, sn string, txnId string) (domain.Membership, error) {
+	m, err := repo.dao.MarkPaid(ctx, sn, txnId)
+	if err != nil {
+		return domain.Membership{}, err
+	}
+	// 删除失败了，用户最多要等缓存过期才能看会员文章
+	err = repo.cache.Del(ctx, m.Uid)
+	if err != nil {
+		repo.l.Error("删除会员缓存失败", logger.Error(err), logger.Int64("uid", m.Uid))
+	}
+	return repo.toDomain(m.Uid, m.ExpireAt), nil
+}
+
+func (repo *CachedMembershipRepository) MarkFailed(ctx context.Context, sn string) error {
+	return repo.dao.MarkFailed(ctx, sn)
+}
+
+func (repo *CachedMembershipRepository) toDomain(uid int64, expireAt int64) domain.Membership {
+	m := domain.Membership{Uid: uid}
+	if expireAt > 0 {
+		m.ExpireAt = time.UnixMilli(expireAt)
+	}
+	return m
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+)
+
+var (
+	ErrReviewNotFound   = dao.ErrReviewNotFound
+	ErrReviewNotPending = dao.ErrReviewNotPending
+)
+
+type ModerationRepository interface {
+	SubmitReview(ctx context.Context, art domain.Article) (int64, error)
+	FindReviewById(ctx context.Context, id int64) (domain.ArticleReview, error)
+	FindLatestReview(ctx context.Context, aid int64) (domain.ArticleReview, error)
+	FindPendingReviews(ctx context.Context, minId int64, limit int) ([]domain.ArticleReview, error)
+	Resolve(ctx context.Context, r domain.ArticleReview) error
+	GetTrustLevel(ctx context.Context, uid int64) (domain.TrustLevel, error)
+	SetTrustLevel(ctx context.Context, uid int64, level domain.TrustLevel) error
+}
+
+// GORMModerationRepository 审核的量不大，不需要缓存
+type GORMModerationRepository struct {
+	dao dao.ModerationDAO
+}
+
+func NewGORMModerationRepository(dao dao.ModerationDAO) ModerationRepository {
+	return &GORMModerationRepository{
+		dao: dao,
+	}
+}
+
+func (repo *GORMModerationRepository) SubmitReview(ctx context.Context, art domain.Article) (int64, error) {
+	return repo.dao.UpsertPendingReview(ctx, dao.ArticleReview{
+		Aid:      art.Id,
+		AuthorId: art.Author.Id,
+		Title:    art.Title,
+		Version:  art.Version,
+	})
+}
+
+func (repo *GORMModerationRepository) FindReviewById(ctx context.Context, id int64) (domain.ArticleReview, error) {
+	r, err := repo.dao.FindReviewById(ctx, id)
+	if err != nil {
+		return domain.ArticleReview{}, err
+	}
+	return repo.toDomain(r), nil
+}
+
+func (repo *GORMModerationRepository) FindLatestReview(ctx context.Context, aid int64) (domain.ArticleReview, error) {
+	r, err := repo.dao.FindLatestReview(ctx, aid)
+	if err != nil {
+		return domain.ArticleReview{}, err
+	}
+	return repo.toDomain(r), nil
+}
+
+func (repo *GORMModerationRepository) FindPendingReviews(ctx context.Context, minId int64, limit int) ([]domain.ArticleReview, error) {
+	rs, err := repo.dao.FindPendingReviews(ctx, minId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.ArticleReview, domain.ArticleReview](rs, func(idx int, src dao.ArticleReview) domain.ArticleReview {
+		return repo.toDomain(src)
+	}), nil
+}
+
+func (repo *GORMModerationRepository) Resolve(ctx context.Context, r domain.ArticleReview) error {
+	return repo.dao.Resolve(ctx, dao.ArticleReview{
+		Id:          r.Id,
+		ModeratorId: r.Moderator,
+		Status:      r.Status.ToUint8(),
+		Reason:      r.Reason,
+	})
+}
+
+func (repo *GORMModerationRepository) GetTrustLevel(ctx context.Context, uid int64) (domain.TrustLevel, error) {
+	level, err := repo.dao.GetTrustLevel(ctx, uid)
+	return domain.TrustLevel(level), err
+}
+
+func (repo *GORMModerationRepository) SetTrustLevel(ctx context.Context, uid int64, level domain.TrustLevel) error {
+	return repo.dao.SetTrustLevel(ctx, uid, level.ToUint8())
+}
+
+func (repo *GORMModerationRepository) toDomain(r dao.ArticleReview) domain.ArticleReview {
+	return domain.ArticleReview{
+		Id: r.Id,
+		Article: domain.Article{
+			Id:      r.Aid,
+			Title:   r.Title,
+			Version: r.Version,
+			Author: domain.Author{
+				Id: r.AuthorId,
+			},
+		},
+		Moderator: r.ModeratorId,
+		Status:    domain.ReviewStatus(r.Status),
+		Reason:    r.Reason,
+		Ctime:     time.UnixMilli(r.Ctime),
+		Utime:     time.UnixMilli(r.Utime),
+	}
+}
@@ -4,43 +4,86 @@ import (
 	"context"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
+	"time"
 )
 
 type RankingRepository interface {
-	ReplaceTopN(ctx context.Context, arts []domain.Article) error
-	GetTopN(ctx context.Context) ([]domain.Article, error)
+	// ReplaceTopN 按照 ranking.Name 分开存
+	ReplaceTopN(ctx context.Context, ranking domain.Ranking) error
+	GetTopN(ctx context.Context, name string) (domain.Ranking, error)
+	// RemoveFromTopN 文章被删除之后，不能继续出现在任何一个热榜里
+	RemoveFromTopN(ctx context.Context, id int64) error
+
+	// IncrScores 实时热榜，按照互动事件累加分数，分数按照文章的 Utime 指数衰减
+	IncrScores(ctx context.Context, deltas []domain.RankingDelta, halfLife time.Duration) error
+	// ResetScores 用全量计算的结果覆盖实时热榜的分数
+	ResetScores(ctx context.Context, items []domain.RankingItem, halfLife time.Duration) error
+	// TrimScores 实时热榜只保留分数最高的 capacity 篇
+	TrimScores(ctx context.Context, capacity int) error
+	// TopScores 实时热榜的前 n 篇，文章只有 Id
+	TopScores(ctx context.Context, n int) ([]domain.RankingItem, error)
 }
 
 type CachedRankingRepository struct {
 	// 使用具体实现，可读性更好，对测试不友好，因为没有面向接口编程
 	redisCache *cache.RedisRankingCache
 	localCache *cache.LocalRankingCache
+	scoreCache *cache.RedisRankingScoreCache
 }
 
-func NewCachedRankingRepository(redis *cache.RedisRankingCache, local *cache.LocalRankingCache) RankingRepository {
+func NewCachedRankingRepository(redis *cache.RedisRankingCache, local *cache.LocalRankingCache,
+	score *cache.RedisRankingScoreCache) RankingRepository {
 	return &CachedRankingRepository{
 		redisCache: redis,
 		localCache: local,
+		scoreCache: score,
 	}
 }
 
-func (c *CachedRankingRepository) ReplaceTopN(ctx context.Context, arts []domain.Article) error {
+func (c *CachedRankingRepository) ReplaceTopN(ctx context.Context, ranking domain.Ranking) error {
 	// 先Set local， 在Set redis。 因为localcache基本不会出错
-	c.localCache.Set(ctx, arts)
-	err := c.redisCache.Set(ctx, arts)
+	c.localCache.Set(ctx, ranking)
+	err := c.redisCache.Set(ctx, ranking)
 	return err
 }
 
-func (c *CachedRankingRepository) GetTopN(ctx context.Context) ([]domain.Article, error) {
-	arts, err := c.localCache.Get(ctx)
+func (c *CachedRankingRepository) GetTopN(ctx context.Context, name string) (domain.Ranking, error) {
+	ranking, err := c.localCache.Get(ctx, name)
 	if err == nil {
-		return arts, nil
+		return ranking, nil
 	}
 	//读取local失败了，从redis里读取
-	arts, err = c.redisCache.Get(ctx)
+	ranking, err = c.redisCache.Get(ctx, name)
 	if err == nil {
-		return arts, nil
+		// 回写本地缓存，不然本地缓存过期之后每次都要查 redis
+		_ = c.localCache.Set(ctx, ranking)
+		return ranking, nil
 	}
 	// 如果此时还是报错，则强制从local里读
-	return c.localCache.ForceGet(ctx)
+	return c.localCache.ForceGet(ctx, name)
+}
+
+func (c *CachedRankingRepository) RemoveFromTopN(ctx context.Context, id int64) error {
+	c.localCache.Remove(ctx, id)
+	err := c.scoreCache.Remove(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.redisCache.Remove(ctx, id)
+}
+
+func (c *CachedRankingRepository) IncrScores(ctx context.Context, deltas []domain.RankingDelta, halfLife time.Duration) error {
+	return c.scoreCache.Incr(ctx, deltas, halfLife)
+}
+
+func (c *CachedRankingRepository) ResetScores(ctx context.Context, items []domain.RankingItem, halfLife time.Duration) error {
+	return c.scoreCache.Reset(ctx, items, halfLife)
+}
+
+func (c *CachedRankingRepository) TrimScores(ctx context.Context, capacity int) error {
+	return c.scoreCache.Trim(ctx, capacity)
+}
+
+func (c *CachedRankingRepository) TopScores(ctx context.Context, n int) ([]domain.RankingItem, error) {
+	return c.scoreCache.Top(ctx, n)
 }
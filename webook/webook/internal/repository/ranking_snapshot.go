@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao"
+	"time"
+)
+
+// RankingSnapshotRepository 热榜的历史快照，只存文章 id、名次和分数
+type RankingSnapshotRepository interface {
+	Save(ctx context.Context, ranking domain.Ranking) error
+	// ListTimes 最近 limit 次快照的时间，从新到旧
+	ListTimes(ctx context.Context, name string, limit int) ([]time.Time, error)
+	// Get 某一次快照，文章只有 Id
+	Get(ctx context.Context, name string, ctime time.Time) (domain.Ranking, error)
+	// History 一篇文章最近 limit 次上榜的名次，从新到旧
+	History(ctx context.Context, name string, aid int64, limit int) ([]domain.RankingPosition, error)
+	DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+type GORMRankingSnapshotRepository struct {
+	dao dao.RankingSnapshotDAO
+}
+
+func NewGORMRankingSnapshotRepository(dao dao.RankingSnapshotDAO) RankingSnapshotRepository {
+	return &GORMRankingSnapshotRepository{
+		dao: dao,
+	}
+}
+
+func (repo *GORMRankingSnapshotRepository) Save(ctx context.Context, ranking domain.Ranking) error {
+	ctime := ranking.Ctime.UnixMilli()
+	items := make([]dao.RankingSnapshotItem, 0, len(ranking.Items))
+	for i, item := range ranking.Items {
+		items = append(items, dao.RankingSnapshotItem{
+			Name:  ranking.Name,
+			Ctime: ctime,
+			Aid:   item.Article.Id,
+			Rank:  i + 1,
+			Score: item.Score,
+		})
+	}
+	return repo.dao.Insert(ctx, items)
+}
+
+func (repo *GORMRankingSnapshotRepository) ListTimes(ctx context.Context, name string, limit int) ([]time.Time, error) {
+	ctimes, err := repo.dao.ListCtimes(ctx, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]time.Time, 0, len(ctimes))
+	for _, ctime := range ctimes {
+		res = append(res, time.UnixMilli(ctime))
+	}
+	return res, nil
+}
+
+func (repo *GORMRankingSnapshotRepository) Get(ctx context.Context, name string, ctime time.Time) (domain.Ranking, error) {
+	items, err := repo.dao.FindByCtime(ctx, name, ctime.UnixMilli())
+	if err != nil {
+		return domain.Ranking{}, err
+	}
+	res := domain.Ranking{
+		Name:  name,
+		Items: make([]domain.RankingItem, 0, len(items)),
+		Ctime: ctime,
+	}
+	for _, item := range items {
+		res.Items = append(res.Items, domain.RankingItem{
+			Article: domain.Article{Id: item.Aid},
+			Score:   item.Score,
+		})
+	}
+	return res, nil
+}
+
+func (repo *GORMRankingSnapshotRepository) History(ctx context.Context, name string, aid int64, limit int) ([]domain.RankingPosition, error) {
+	items, err := repo.dao.FindByAid(ctx, name, aid, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.RankingPosition, 0, len(items))
+	for _, item := range items {
+		res = append(res, domain.RankingPosition{
+			Ctime: time.UnixMilli(item.Ctime),
+			Rank:  item.Rank,
+			Score: item.Score,
+		})
+	}
+	return res, nil
+}
+
+func (repo *GORMRankingSnapshotRepository) DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return repo.dao.DeleteBefore(ctx, before.UnixMilli(), limit)
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"time"
+)
+
+// ReadHistoryRepository 最近读的在 Redis 里，更早的在 MySQL 里
+type ReadHistoryRepository interface {
+	Record(ctx context.Context, uid int64, aid int64, readTime time.Time) error
+	SaveProgress(ctx context.Context, h domain.ReadHistory) error
+	List(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.ReadHistory, error)
+	Delete(ctx context.Context, uid int64, aids []int64) error
+	DeleteAll(ctx context.Context, uid int64) error
+}
+
+type CachedReadHistoryRepository struct {
+	dao   dao.ReadHistoryDAO
+	cache cache.ReadHistoryCache
+	l     logger.Logger
+}
+
+func NewCachedReadHistoryRepository(dao dao.ReadHistoryDAO,
+	cache cache.ReadHistoryCache, l logger.Logger) ReadHistoryRepository {
+	return &CachedReadHistoryRepository{
+		dao:   dao,
+		cache: cache,
+		l:     l,
+	}
+}
+
+func (repo *CachedReadHistoryRepository) Record(ctx context.Context, uid int64, aid int64, readTime time.Time) error {
+	err := repo.dao.Touch(ctx, uid, aid, readTime.UnixMilli())
+	if err != nil {
+		return err
+	}
+	repo.refreshCache(ctx, uid, aid)
+	return nil
+}
+
+func (repo *CachedReadHistoryRepository) SaveProgress(ctx context.Context, h domain.ReadHistory) error {
+	err := repo.dao.UpsertProgress(ctx, dao.ReadHistory{
+		Uid:      h.Uid,
+		Aid:      h.Aid,
+		Position: h.Position,
+		Progress: h.Progress,
+	})
+	if err != nil {
+		return err
+	}
+	repo.refreshCache(ctx, h.Uid, h.Aid)
+	return nil
+}
+
+func (repo *CachedReadHistoryRepository) List(ctx context.Context, uid int64,
+	cursor domain.ArticleCursor, limit int) ([]domain.ReadHistory, error) {
+	res, err := repo.cache.List(ctx, uid, cursor, limit)
+	// 缓存里的不够一页，说明要翻到更早的记录了
+	if err == nil && len(res) >= limit {
+		return res, nil
+	}
+	var cursorUtime int64
+	if !cursor.IsZero() {
+		cursorUtime = cursor.Utime.UnixMilli()
+	}
+	entities, err := repo.dao.List(ctx, uid, cursorUtime, cursor.Id, limit)
+	if err != nil {
+		return nil, err
+	}
+	res = make([]domain.ReadHistory, 0, len(entities))
+	for _, e := range entities {
+		res = append(res, repo.toDomain(e))
+	}
+	return res, nil
+}
+
+// Delete 缓存删不掉的话删掉的记录还会出现在列表里，所以要返回错误让用户重试，重复删除没关系
+func (repo *CachedReadHistoryRepository) Delete(ctx context.Context, uid int64, aids []int64) error {
+	err := repo.dao.Delete(ctx, uid, aids)
+	if err != nil {
+		return err
+	}
+	err = repo.cache.Del(ctx, uid, aids...)
+	if err != nil {
+		repo.l.Error("删除阅读历史缓存失败", logger.Error(err), logger.Int64("uid", uid))
+	}
+	return err
+}
+
+func (repo *CachedReadHistoryRepository) DeleteAll(ctx context.Context, uid int64) error {
+	err := repo.dao.DeleteAll(ctx, uid)
+	if err != nil {
+		return err
+	}
+	err = repo.cache.DelAll(ctx, uid)
+	if err != nil {
+		repo.l.Error("清空阅读历史缓存失败", logger.Error(err), logger.Int64("uid", uid))
+	}
+	return err
+}
+
+// refreshCache 用数据库里的最新数据更新缓存
+// List 在缓存够一页的时候是直接用缓存的，缓存里少了这一条就再也看不到了，
+// 所以更新失败的时候把这个用户的缓存整个删掉，下次从数据库查
+func (repo *CachedReadHistoryRepository) refreshCache(ctx context.Context, uid int64, aid int64) {
+	e, err := repo.dao.Find(ctx, uid, aid)
+	if err == nil {
+		err = repo.cache.Add(ctx, repo.toDomain(e))
+	}
+	if err == nil {
+		return
+	}
+	repo.l.Error("更新阅读历史缓存失败", logger.Error(err),
+		logger.Int64("uid", uid), logger.Int64("aid", aid))
+	err = repo.cache.DelAll(ctx, uid)
+	if err != nil {
+		repo.l.Error("清空阅读历史缓存失败", logger.Error(err), logger.Int64("uid", uid))
+	}
+}
+
+func (repo *CachedReadHistoryRepository) toDomain(e dao.ReadHistory) domain.ReadHistory {
+	return domain.ReadHistory{
+		Uid:      e.Uid,
+		Aid:      e.Aid,
+		Position: e.Position,
+		Progress: e.Progress,
+		Utime:    time.UnixMilli(e.Utime),
+	}
+}
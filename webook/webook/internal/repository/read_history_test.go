@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type fakeReadHistoryDAO struct {
+	dao.ReadHistoryDAO
+	findErr error
+}
+
+func (d *fakeReadHistoryDAO) Touch(ctx context.Context, uid int64, aid int64, utime int64) error {
+	return nil
+}
+
+func (d *fakeReadHistoryDAO) Find(ctx context.Context, uid int64, aid int64) (dao.ReadHistory, error) {
+	return dao.ReadHistory{Uid: uid, Aid: aid}, d.findErr
+}
+
+type fakeReadHistoryCache struct {
+	cache.ReadHistoryCache
+	addErr  error
+	added   []int64
+	cleared bool
+}
+
+func (c *fakeReadHistoryCache) Add(ctx context.Context, h domain.ReadHistory) error {
+	if c.addErr == nil {
+		c.added = append(c.added, h.Aid)
+	}
+	return c.addErr
+}
+
+func (c *fakeReadHistoryCache) DelAll(ctx context.Context, uid int64) error {
+	c.cleared = true
+	return nil
+}
+
+func TestCachedReadHistoryRepository_Record(t *testing.T) {
+	testCases := []struct {
+		name        string
+		findErr     error
+		addErr      error
+		wantAdded   []int64
+		wantCleared bool
+	}{
+		{
+			name:      "更新缓存",
+			wantAdded: []int64{2},
+		},
+		{
+			name:        "写缓存失败，清空缓存",
+			addErr:      errors.New("redis 崩了"),
+			wantCleared: true,
+		},
+		{
+			name:        "查数据库失败，清空缓存",
+			findErr:     errors.New("mysql 崩了"),
+			wantCleared: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &fakeReadHistoryCache{addErr: tc.addErr}
+			repo := NewCachedReadHistoryRepository(&fakeReadHistoryDAO{findErr: tc.findErr}, c,
+				logger.NewZapLogger(zap.NewNop(), false))
+			// 数据库已经记下来了，缓存的问题不影响结果
+			err := repo.Record(context.Background(), 123, 2, time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantAdded, c.added)
+			assert.Equal(t, tc.wantCleared, c.cleared)
+		})
+	}
+}
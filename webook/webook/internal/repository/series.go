@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"time"
+)
+
+var (
+	ErrSeriesNotFound       = dao.ErrSeriesNotFound
+	ErrArticleInOtherSeries = dao.ErrArticleInOtherSeries
+)
+
+type SeriesRepository interface {
+	Create(ctx context.Context, s domain.Series) (int64, error)
+	Update(ctx context.Context, s domain.Series) error
+	Delete(ctx context.Context, id int64, uid int64) error
+	// FindById 包括系列里的文章 id
+	FindById(ctx context.Context, id int64) (domain.Series, error)
+	// FindByAuthor 不包括系列里的文章 id
+	FindByAuthor(ctx context.Context, uid int64) ([]domain.Series, error)
+	SetArticles(ctx context.Context, id int64, uid int64, aids []int64) error
+	// FindByArticle 文章所在的系列 id，不在任何系列里返回 0
+	FindByArticle(ctx context.Context, aid int64) (int64, error)
+}
+
+type CachedSeriesRepository struct {
+	dao   dao.SeriesDAO
+	cache cache.SeriesCache
+	l     logger.Logger
+}
+
+func NewCachedSeriesRepository(dao dao.SeriesDAO, cache cache.SeriesCache, l logger.Logger) SeriesRepository {
+	return &CachedSeriesRepository{
+		dao:   dao,
+		cache: cache,
+		l:     l,
+	}
+}
+
+func (repo *CachedSeriesRepository) Create(ctx context.Context, s domain.Series) (int64, error) {
+	return repo.dao.Insert(ctx, repo.toEntity(s))
+}
+
+func (repo *CachedSeriesRepository) Update(ctx context.Context, s domain.Series) error {
+	err := repo.dao.Update(ctx, repo.toEntity(s))
+	if err != nil {
+		return err
+	}
+	repo.delCache(ctx, s.Id, nil)
+	return nil
+}
+
+func (repo *CachedSeriesRepository) Delete(ctx context.Context, id int64, uid int64) error {
+	aids, err := repo.dao.FindArticles(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = repo.dao.Delete(ctx, id, uid)
+	if err != nil {
+		return err
+	}
+	repo.delCache(ctx, id, aids)
+	return nil
+}
+
+func (repo *CachedSeriesRepository) FindById(ctx context.Context, id int64) (domain.Series, error) {
+	s, err := repo.cache.Get(ctx, id)
+	if err == nil {
+		return s, nil
+	}
+	entity, err := repo.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.Series{}, err
+	}
+	aids, err := repo.dao.FindArticles(ctx, id)
+	if err != nil {
+		return domain.Series{}, err
+	}
+	s = repo.toDomain(entity)
+	s.Aids = aids
+	err = repo.cache.Set(ctx, s)
+	if err != nil {
+		repo.l.Error("回写系列缓存失败", logger.Error(err), logger.Int64("id", id))
+	}
+	return s, nil
+}
+
+func (repo *CachedSeriesRepository) FindByAuthor(ctx context.Context, uid int64) ([]domain.Series, error) {
+	entities, err := repo.dao.FindByAuthor(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Series, 0, len(entities))
+	for _, e := range entities {
+		res = append(res, repo.toDomain(e))
+	}
+	return res, nil
+}
+
+func (repo *CachedSeriesRepository) SetArticles(ctx context.Context, id int64, uid int64, aids []int64) error {
+	old, err := repo.dao.FindArticles(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = repo.dao.SetArticles(ctx, id, uid, aids)
+	if err != nil {
+		return err
+	}
+	// 移出去的和新加进来的文章，缓存的系列 id 都要删掉
+	repo.delCache(ctx, id, append(old, aids...))
+	return nil
+}
+
+func (repo *CachedSeriesRepository) FindByArticle(ctx context.Context, aid int64) (int64, error) {
+	sid, err := repo.cache.GetByArticle(ctx, aid)
+	if err == nil {
+		return sid, nil
+	}
+	sid, err = repo.dao.FindByArticle(ctx, aid)
+	if err != nil {
+		return 0, err
+	}
+	err = repo.cache.SetByArticle(ctx, aid, sid)
+	if err != nil {
+		repo.l.Error("回写文章所属系列缓存失败", logger.Error(err), logger.Int64("aid", aid))
+	}
+	return sid, nil
+}
+
+// delCache 删除失败了只能等缓存过期
+func (repo *CachedSeriesRepository) delCache(ctx context.Context, id int64, aids []int64) {
+	err := repo.cache.Del(ctx, id)
+	if err == nil {
+		err = repo.cache.DelByArticle(ctx, aids...)
+	}
+	if err != nil {
+		repo.l.Error("删除系列缓存失败", logger.Error(err), logger.Int64("id", id))
+	}
+}
+
+func (repo *CachedSeriesRepository) toEntity(s domain.Series) dao.Series {
+	return dao.Series{
+		Id:          s.Id,
+		AuthorId:    s.Author.Id,
+		Title:       s.Title,
+		Description: s.Description,
+	}
+}
+
+func (repo *CachedSeriesRepository) toDomain(s dao.Series) domain.Series {
+	return domain.Series{
+		Id:          s.Id,
+		Author:      domain.Author{Id: s.AuthorId},
+		Title:       s.Title,
+		Description: s.Description,
+		Ctime:       time.UnixMilli(s.Ctime),
+		Utime:       time.UnixMilli(s.Utime),
+	}
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
+)
+
+// TopLikeRepository 组装好的点赞榜只放在缓存里，过期了就重新组装
+type TopLikeRepository interface {
+	Get(ctx context.Context, n int64) ([]domain.TopArticle, error)
+	Set(ctx context.Context, n int64, arts []domain.TopArticle) error
+}
+
+type CachedTopLikeRepository struct {
+	cache cache.TopLikeCache
+}
+
+func NewCachedTopLikeRepository(cache cache.TopLikeCache) TopLikeRepository {
+	return &CachedTopLikeRepository{
+		cache: cache,
+	}
+}
+
+func (repo *CachedTopLikeRepository) Get(ctx context.Context, n int64) ([]domain.TopArticle, error) {
+	return repo.cache.Get(ctx, n)
+}
+
+func (repo *CachedTopLikeRepository) Set(ctx context.Context, n int64, arts []domain.TopArticle) error {
+	return repo.cache.Set(ctx, n, arts)
+}
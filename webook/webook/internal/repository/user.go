@@ -19,6 +19,8 @@ type UserRepository interface {
 	FindByEmail(ctx context.Context, u domain.User) (domain.User, error)
 	FindByPhone(ctx context.Context, u domain.User) (domain.User, error)
 	FindById(ctx context.Context, id int64) (domain.User, error)
+	// FindByIds 先一次 MGET 查缓存，没命中的再一次查数据库，找不到的用户直接跳过
+	FindByIds(ctx context.Context, ids []int64) (map[int64]domain.User, error)
 	FindByWechat(ctx context.Context, openId string) (domain.User, error)
 	UpdateProfile(ctx context.Context, u domain.User) error
 	UpdatePassword(ctx context.Context, u domain.User) error
@@ -102,6 +104,36 @@ func (r *CachedUserRepository) FindById(ctx context.Context, id int64) (domain.U
 	return u, err
 }
 
+func (r *CachedUserRepository) FindByIds(ctx context.Context, ids []int64) (map[int64]domain.User, error) {
+	res, err := r.cache.GetMulti(ctx, ids)
+	if err != nil {
+		// 缓存出问题了就全部查数据库
+		res = make(map[int64]domain.User, len(ids))
+	}
+	missing := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := res[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return res, nil
+	}
+	users, err := r.dao.FindByIds(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	loaded := make([]domain.User, 0, len(users))
+	for _, user := range users {
+		u := r.entityToDomain(user)
+		res[u.Id] = u
+		loaded = append(loaded, u)
+	}
+	// 回写失败了只是下次还要查数据库
+	_ = r.cache.SetMulti(ctx, loaded)
+	return res, nil
+}
+
 func (r *CachedUserRepository) FindByWechat(ctx context.Context, openId string) (domain.User, error) {
 	user, err := r.dao.FindByWechat(ctx, openId)
 	if err != nil {
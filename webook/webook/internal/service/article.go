@@ -2,60 +2,212 @@ package service
 
 import (
 	"context"
+	intrSvc "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events/article"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	mediaSvc "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/service"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/textfilter"
+	"github.com/spf13/viper"
 	"time"
 )
 
+var (
+	ErrVersionConflict = repository.ErrVersionConflict
+	ErrArticleNotFound = repository.ErrArticleNotFound
+	// ErrSensitiveContent 标题或者内容里有需要拦截的敏感词
+	ErrSensitiveContent = textfilter.ErrBlocked
+)
+
+type VersionConflictError = repository.VersionConflictError
+
 type ArticleService interface {
 	Save(ctx context.Context, article domain.Article) (int64, error)
-	Publish(ctx context.Context, article domain.Article) (int64, error)
+	// Publish 开启了审核的时候，非可信作者的文章会进入待审核状态，
+	// 返回的状态就是 ArticleStatusPendingReview
+	Publish(ctx context.Context, article domain.Article) (int64, domain.ArticleStatus, error)
 	Withdraw(ctx context.Context, article domain.Article) error
-	List(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
+	// List 按照 (utime, id) 倒序分页，cursor 是上一页最后一篇文章的游标
+	List(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.Article, error)
 	// ListPub 只会取 start 七天内的数据
-	ListPub(ctx context.Context, start time.Time, offset, limit int) ([]domain.Article, error)
+	ListPub(ctx context.Context, start time.Time, cursor domain.ArticleCursor, limit int) ([]domain.Article, error)
 	Detail(ctx context.Context, id int64, uid int64) (domain.Article, error)
 	PubDetail(ctx context.Context, id int64, uid int64) (domain.Article, error)
+	// Delete 放进回收站，草稿和线上的文章都看不到了
+	Delete(ctx context.Context, id int64, uid int64) error
+	Restore(ctx context.Context, id int64, uid int64) error
+	ListTrash(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.Article, error)
+	// PurgeTrash 彻底删除 before 之前放进回收站的文章，返回删除的数量
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
 }
 
 type articleService struct {
-	repo     repository.ArticleRepository
-	producer article.Producer
-	l        logger.Logger
+	repo           repository.ArticleRepository
+	rankingRepo    repository.RankingRepository
+	moderationRepo repository.ModerationRepository
+	intrSvc        intrSvc.InteractiveService
+	mediaSvc       mediaSvc.MediaService
+	memberSvc      MembershipService
+	feedSvc        FeedService
+	filter         textfilter.Filter
+	producer       article.Producer
+	l              logger.Logger
+	biz            string
 }
 
-func NewArticleService(repo repository.ArticleRepository, producer article.Producer, l logger.Logger) ArticleService {
+func NewArticleService(repo repository.ArticleRepository,
+	rankingRepo repository.RankingRepository,
+	moderationRepo repository.ModerationRepository,
+	intrSvc intrSvc.InteractiveService,
+	mediaSvc mediaSvc.MediaService,
+	memberSvc MembershipService,
+	feedSvc FeedService,
+	filter textfilter.Filter,
+	producer article.Producer, l logger.Logger) ArticleService {
 	return &articleService{
-		repo:     repo,
-		producer: producer,
-		l:        l,
+		repo:           repo,
+		rankingRepo:    rankingRepo,
+		moderationRepo: moderationRepo,
+		intrSvc:        intrSvc,
+		mediaSvc:       mediaSvc,
+		memberSvc:      memberSvc,
+		feedSvc:        feedSvc,
+		filter:         filter,
+		producer:       producer,
+		l:              l,
+		biz:            "article",
 	}
 }
 
 func (s *articleService) Save(ctx context.Context, article domain.Article) (int64, error) {
 	article.Status = domain.ArticleStatusUnpublished
 	// 如何article的Id大于0， 证明该文章已经有了，所以是Update，否则是Create
-	if article.Id > 0 {
-		err := s.repo.Update(ctx, article)
-		return article.Id, err
+	var (
+		id  = article.Id
+		err error
+	)
+	if id > 0 {
+		err = s.repo.Update(ctx, article)
+	} else {
+		id, err = s.repo.Create(ctx, article)
 	}
-	return s.repo.Create(ctx, article)
+	if err == nil {
+		s.syncMediaRefs(ctx, id, article.Author.Id, article.Content)
+	}
+	return id, err
 }
 
-func (s *articleService) Publish(ctx context.Context, article domain.Article) (int64, error) {
+func (s *articleService) Publish(ctx context.Context, article domain.Article) (int64, domain.ArticleStatus, error) {
+	article, action := s.filterText(article)
+	if action == textfilter.ActionBlock {
+		return 0, domain.ArticleStatusUnknown, ErrSensitiveContent
+	}
+	needReview, err := s.needReview(ctx, article.Author.Id)
+	if err != nil {
+		return 0, domain.ArticleStatusUnknown, err
+	}
+	// 命中了需要审核的敏感词，可信作者也要审核
+	if needReview || action == textfilter.ActionReview {
+		id, err := s.submitReview(ctx, article)
+		return id, domain.ArticleStatusPendingReview, err
+	}
+
 	article.Status = domain.ArticleStatusPublished
-	return s.repo.Sync(ctx, article)
+	id, err := s.repo.Sync(ctx, article)
+	if err == nil {
+		s.syncMediaRefs(ctx, id, article.Author.Id, article.Content)
+		s.invalidateFeeds(ctx, article.Author.Id)
+	}
+	return id, domain.ArticleStatusPublished, err
+}
+
+// filterText 标题和内容分别过滤，返回最严重的处理方式
+func (s *articleService) filterText(article domain.Article) (domain.Article, textfilter.Action) {
+	title := s.filter.Check(article.Title)
+	content := s.filter.Check(article.Content)
+	article.Title = title.Text
+	article.Content = content.Text
+	if title.Action > content.Action {
+		return article, title.Action
+	}
+	return article, content.Action
+}
+
+// needReview 没有开启审核，或者是可信作者，就直接发表
+func (s *articleService) needReview(ctx context.Context, uid int64) (bool, error) {
+	if !viper.GetBool("moderation.enabled") {
+		return false, nil
+	}
+	level, err := s.moderationRepo.GetTrustLevel(ctx, uid)
+	if err != nil {
+		return false, err
+	}
+	return !level.BypassReview(), nil
+}
+
+// submitReview 只保存草稿，线上库等审核通过了再同步
+// 审核记录里保存的是保存之后的版本号
+func (s *articleService) submitReview(ctx context.Context, article domain.Article) (int64, error) {
+	article.Status = domain.ArticleStatusPendingReview
+	var err error
+	if article.Id > 0 {
+		err = s.repo.Update(ctx, article)
+		article.Version++
+	} else {
+		article.Id, err = s.repo.Create(ctx, article)
+		article.Version = 1
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.syncMediaRefs(ctx, article.Id, article.Author.Id, article.Content)
+
+	_, err = s.moderationRepo.SubmitReview(ctx, article)
+	return article.Id, err
+}
+
+// syncMediaRefs 记录文章引用了哪些上传的文件，没有被引用的文件会被定时回收
+// 草稿和线上版本共用一份引用关系，所以要把线上版本的内容也算进去，
+// 不然草稿里删掉一张图，线上还在用的这张图就被回收了
+// 失败了不影响保存，下一次保存的时候会再同步
+func (s *articleService) syncMediaRefs(ctx context.Context, id int64, uid int64, draft string) {
+	// 没有发表过的话拿到的是零值
+	pub, err := s.repo.GetPublishedById(ctx, id, uid)
+	if err != nil {
+		// 拿不到线上版本就先不同步，宁可少回收也不能误删
+		s.l.Error("同步文章引用的文件失败，查询线上版本失败", logger.Error(err),
+			logger.Int64("Aid", id))
+		return
+	}
+	err = s.mediaSvc.SyncRefs(ctx, s.biz, id, draft+"\n"+pub.Content)
+	if err != nil {
+		s.l.Error("同步文章引用的文件失败", logger.Error(err),
+			logger.Int64("Aid", id))
+	}
+}
+
+// invalidateFeeds 清不掉也只是订阅源晚几分钟更新，不影响文章本身
+func (s *articleService) invalidateFeeds(ctx context.Context, uid int64) {
+	err := s.feedSvc.Invalidate(ctx, uid)
+	if err != nil {
+		s.l.Error("清除订阅源缓存失败", logger.Error(err),
+			logger.Int64("Uid", uid))
+	}
 }
 
 func (s *articleService) Withdraw(ctx context.Context, article domain.Article) error {
 	article.Status = domain.ArticleStatusPrivate
-	return s.repo.SyncStatus(ctx, article)
+	err := s.repo.SyncStatus(ctx, article)
+	if err != nil {
+		return err
+	}
+	s.invalidateFeeds(ctx, article.Author.Id)
+	return nil
 }
 
-func (s *articleService) List(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
-	return s.repo.List(ctx, uid, offset, limit)
+func (s *articleService) List(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.Article, error) {
+	return s.repo.List(ctx, uid, cursor, limit)
 }
 
 func (s *articleService) Detail(ctx context.Context, id int64, uid int64) (domain.Article, error) {
@@ -68,22 +220,98 @@ func (s *articleService) PubDetail(ctx context.Context, id int64, uid int64) (do
 
 	// 这里如果用kafka增加read_cnt时
 	art, err := s.repo.GetPublishedById(ctx, id, uid)
-	if err == nil {
-		go func() {
-			err1 := s.producer.ProduceReadEvent(ctx, article.ReadEvent{
-				Uid: uid,
-				Aid: id,
-			})
-			if err1 != nil {
-				s.l.Error("发送读者阅读事件失败", logger.Error(err1),
-					logger.Int64("Uid", uid), logger.Int64("Aid", id))
-			}
-		}()
+	if err != nil {
+		return art, err
+	}
+	// 撤回了的只有作者自己能看，没登录的 uid 是 0，不会和作者对上
+	if art.Id == 0 || (art.Status != domain.ArticleStatusPublished && art.Author.Id != uid) {
+		return domain.Article{}, ErrArticleNotFound
+	}
+	ok, err := s.memberSvc.CanRead(ctx, uid, art)
+	if err != nil {
+		// 查不到会员资格的时候宁可少给，只给摘要
+		s.l.Error("检查文章访问权限失败", logger.Error(err),
+			logger.Int64("Uid", uid), logger.Int64("Aid", id))
+	}
+	if !ok {
+		art = art.Preview()
+	}
+	go func() {
+		err1 := s.producer.ProduceReadEvent(ctx, article.ReadEvent{
+			Uid: uid,
+			Aid: id,
+		})
+		if err1 != nil {
+			s.l.Error("发送读者阅读事件失败", logger.Error(err1),
+				logger.Int64("Uid", uid), logger.Int64("Aid", id))
+		}
+	}()
+
+	return art, nil
+}
+
+func (s *articleService) ListPub(ctx context.Context, start time.Time, cursor domain.ArticleCursor, limit int) ([]domain.Article, error) {
+	return s.repo.ListPub(ctx, start, cursor, limit)
+}
+
+func (s *articleService) Delete(ctx context.Context, id int64, uid int64) error {
+	err := s.repo.MoveToTrash(ctx, id, uid)
+	if err != nil {
+		return err
 	}
 
-	return art, err
+	// 文章已经进回收站了，热榜和点赞榜失败了也不影响删除本身
+	// 热榜下一次计算的时候也会把它去掉
+	err = s.rankingRepo.RemoveFromTopN(ctx, id)
+	if err != nil {
+		s.l.Error("从热榜中移除文章失败", logger.Error(err),
+			logger.Int64("Aid", id))
+	}
+	err = s.intrSvc.SetActive(ctx, s.biz, id, false)
+	if err != nil {
+		s.l.Error("将文章的互动记录置为失效失败", logger.Error(err),
+			logger.Int64("Aid", id))
+	}
+	s.invalidateFeeds(ctx, uid)
+	return nil
 }
 
-func (s *articleService) ListPub(ctx context.Context, start time.Time, offset, limit int) ([]domain.Article, error) {
-	return s.repo.ListPub(ctx, start, offset, limit)
+func (s *articleService) Restore(ctx context.Context, id int64, uid int64) error {
+	err := s.repo.Restore(ctx, id, uid)
+	if err != nil {
+		return err
+	}
+
+	err = s.intrSvc.SetActive(ctx, s.biz, id, true)
+	if err != nil {
+		s.l.Error("将文章的互动记录置为有效失败", logger.Error(err),
+			logger.Int64("Aid", id))
+	}
+	s.invalidateFeeds(ctx, uid)
+	return nil
+}
+
+func (s *articleService) ListTrash(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.Article, error) {
+	return s.repo.ListTrash(ctx, uid, cursor, limit)
+}
+
+func (s *articleService) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	// 一批一批删，避免一个大事务
+	const batchSize = 100
+	total := 0
+	for {
+		ids, err := s.repo.PurgeTrash(ctx, before, batchSize)
+		total += len(ids)
+		if err != nil {
+			return total, err
+		}
+		// 文章没了，它引用的文件也就可以被回收了
+		err = s.mediaSvc.DeleteRefs(ctx, s.biz, ids)
+		if err != nil {
+			return total, err
+		}
+		if len(ids) < batchSize {
+			return total, nil
+		}
+	}
 }
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/frontmatter"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/lithammer/shortuuid/v4"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrImportTooLarge     = errors.New("压缩包太大")
+	ErrInvalidImportFile  = errors.New("不是合法的 zip 压缩包")
+	ErrTooManyImportFiles = errors.New("压缩包里的文件太多")
+	ErrImportJobNotFound  = repository.ErrImportJobNotFound
+)
+
+// ArticleTransferService 用 Markdown 批量导入导出文章
+// 导入的文章都是草稿，作者检查过之后再自己发表
+type ArticleTransferService interface {
+	// Import 文件不多的时候直接导入完，返回的任务已经是完成状态
+	// 否则在后台导入，返回的任务是进行中状态，用 GetImportJob 查询进度
+	Import(ctx context.Context, uid int64, r io.Reader) (domain.ImportJob, error)
+	GetImportJob(ctx context.Context, uid int64, id string) (domain.ImportJob, error)
+	// Export 把自己所有的文章打包成 zip 写到 w 里面，回收站里的不导出
+	Export(ctx context.Context, uid int64, w io.Writer) error
+	// Close 退出的时候取消后台还没导入完的任务，并等它们把状态保存下来
+	Close(ctx context.Context) error
+}
+
+type articleTransferService struct {
+	artSvc ArticleService
+	repo   repository.ImportJobRepository
+	l      logger.Logger
+	// maxSize 整个压缩包的大小上限，maxFileSize 单个文件解压之后的大小上限
+	maxSize     int64
+	maxFileSize int64
+	maxFiles    int
+	// syncLimit 文件数不超过这个就直接在请求里导入
+	syncLimit int
+	// progressStep 后台导入的时候每处理这么多个文件保存一次进度
+	progressStep int
+	batchSize    int
+	// timeout 后台导入的超时时间，syncTimeout 直接导入的超时时间
+	timeout     time.Duration
+	syncTimeout time.Duration
+
+	// ctx 是所有后台导入任务的根，Close 的时候取消
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewArticleTransferService(artSvc ArticleService,
+	repo repository.ImportJobRepository, l logger.Logger) ArticleTransferService {
+	maxSize := viper.GetInt64("article.importMaxSize")
+	if maxSize <= 0 {
+		// 20M
+		maxSize = 20 << 20
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &articleTransferService{
+		artSvc:       artSvc,
+		repo:         repo,
+		l:            l,
+		maxSize:      maxSize,
+		maxFileSize:  1 << 20,
+		maxFiles:     1000,
+		syncLimit:    20,
+		progressStep: 20,
+		batchSize:    100,
+		timeout:      time.Minute * 30,
+		syncTimeout:  time.Minute,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+func (s *articleTransferService) Import(ctx context.Context, uid int64, r io.Reader) (domain.ImportJob, error) {
+	zr, name, err := s.saveUpload(r)
+	if err != nil {
+		return domain.ImportJob{}, err
+	}
+	// 交给后台导入之后由后台负责关闭和删除临时文件
+	async := false
+	defer func() {
+		if !async {
+			s.removeUpload(zr, name)
+		}
+	}()
+	files := make([]*zip.File, 0, len(zr.File))
+	for _, f := range zr.File {
+		// 目录和 macOS 打包时带上的隐藏文件直接跳过，不算在结果里
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") ||
+			strings.HasPrefix(path.Base(f.Name), ".") {
+			continue
+		}
+		files = append(files, f)
+	}
+	if len(files) > s.maxFiles {
+		return domain.ImportJob{}, ErrTooManyImportFiles
+	}
+
+	now := time.Now()
+	job := domain.ImportJob{
+		Id:      shortuuid.New(),
+		Uid:     uid,
+		Status:  domain.ImportStatusRunning,
+		Total:   len(files),
+		Results: make([]domain.ImportFileResult, 0, len(files)),
+		Ctime:   now,
+		Utime:   now,
+	}
+	if len(files) <= s.syncLimit {
+		s.runSync(&job, files)
+		return job, nil
+	}
+
+	err = s.repo.Save(ctx, job)
+	if err != nil {
+		return domain.ImportJob{}, err
+	}
+	async = true
+	s.wg.Add(1)
+	go s.runAsync(job, zr, name, files)
+	return job, nil
+}
+
+// saveUpload 压缩包先写到临时文件里，不整个读进内存
+func (s *articleTransferService) saveUpload(r io.Reader) (*zip.ReadCloser, string, error) {
+	f, err := os.CreateTemp("", "webook-import-*.zip")
+	if err != nil {
+		return nil, "", err
+	}
+	name := f.Name()
+	n, err := io.Copy(f, io.LimitReader(r, s.maxSize+1))
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil && n > s.maxSize {
+		err = ErrImportTooLarge
+	}
+	if err != nil {
+		_ = os.Remove(name)
+		return nil, "", err
+	}
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		_ = os.Remove(name)
+		return nil, "", ErrInvalidImportFile
+	}
+	return zr, name, nil
+}
+
+func (s *articleTransferService) removeUpload(zr *zip.ReadCloser, name string) {
+	_ = zr.Close()
+	err := os.Remove(name)
+	if err != nil {
+		s.l.Error("删除导入的临时文件失败", logger.Error(err), logger.String("name", name))
+	}
+}
+
+// runSync 文件不多的时候直接导入
+// 和 runAsync 一样从 s.ctx 派生，客户端断开连接不会导致导入到一半就停下来
+func (s *articleTransferService) runSync(job *domain.ImportJob, files []*zip.File) {
+	s.wg.Add(1)
+	defer s.wg.Done()
+	ctx, cancel := context.WithTimeout(s.ctx, s.syncTimeout)
+	defer cancel()
+	s.run(ctx, job, files)
+	// 结果已经直接返回了，保存失败也不影响
+	s.saveJob(ctx, *job)
+}
+
+// runAsync 后台导入，不管是超时、退出还是 panic，最后都会把任务状态保存下来
+// 不然前端会一直看到进行中
+func (s *articleTransferService) runAsync(job domain.ImportJob, zr *zip.ReadCloser, name string, files []*zip.File) {
+	defer s.wg.Done()
+	defer s.removeUpload(zr, name)
+	// 请求结束之后请求的 ctx 就被取消了，所以从 s.ctx 派生
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			s.l.Error("后台导入文章 panic", logger.String("id", job.Id),
+				logger.Any("panic", r))
+			job.Status = domain.ImportStatusFailed
+			job.Error = "系统错误"
+			job.Utime = time.Now()
+		}
+		// ctx 可能已经超时或者被取消了，保存状态要用一个新的
+		saveCtx, saveCancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer saveCancel()
+		s.saveJob(saveCtx, job)
+	}()
+	s.run(ctx, &job, files)
+}
+
+func (s *articleTransferService) Close(ctx context.Context) error {
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *articleTransferService) run(ctx context.Context, job *domain.ImportJob, files []*zip.File) {
+	for i, f := range files {
+		if ctx.Err() != nil {
+			job.Status = domain.ImportStatusFailed
+			job.Error = s.failReason(ctx.Err())
+			job.Utime = time.Now()
+			return
+		}
+		job.Results = append(job.Results, s.importFile(ctx, job.Uid, f))
+		if (i+1)%s.progressStep == 0 {
+			job.Utime = time.Now()
+			s.saveJob(ctx, *job)
+		}
+	}
+	job.Status = domain.ImportStatusDone
+	job.Utime = time.Now()
+}
+
+// failReason 告诉用户任务为什么中断了，已经导入的草稿不会回滚
+func (s *articleTransferService) failReason(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "导入超时，剩下的文件没有导入"
+	}
+	return "服务重启，剩下的文件没有导入"
+}
+
+// importFile 单个文件的问题只记录在结果里，不影响别的文件
+func (s *articleTransferService) importFile(ctx context.Context, uid int64, f *zip.File) domain.ImportFileResult {
+	res := domain.ImportFileResult{File: f.Name}
+	ext := strings.ToLower(path.Ext(f.Name))
+	if ext != ".md" && ext != ".markdown" {
+		res.Error = "不是 Markdown 文件"
+		return res
+	}
+	// 压缩包里声明的大小可能是假的，读的时候还要再限制一次
+	if f.UncompressedSize64 > uint64(s.maxFileSize) {
+		res.Error = "文件太大"
+		return res
+	}
+	rc, err := f.Open()
+	if err != nil {
+		res.Error = "文件损坏"
+		return res
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, s.maxFileSize+1))
+	_ = rc.Close()
+	if err != nil {
+		res.Error = "文件损坏"
+		return res
+	}
+	if int64(len(data)) > s.maxFileSize {
+		res.Error = "文件太大"
+		return res
+	}
+	if !utf8.Valid(data) {
+		res.Error = "文件不是 UTF-8 编码"
+		return res
+	}
+
+	doc, err := frontmatter.Parse(data)
+	if err != nil {
+		res.Error = fmt.Sprintf("front matter 格式不对: %s", err.Error())
+		return res
+	}
+	title := doc.Title
+	if title == "" {
+		// 没有写标题的，用文件名
+		title = strings.TrimSuffix(path.Base(f.Name), path.Ext(f.Name))
+	}
+	if utf8.RuneCountInString(title) > 1024 {
+		res.Error = "标题太长"
+		return res
+	}
+	if strings.TrimSpace(doc.Body) == "" {
+		res.Error = "内容为空"
+		return res
+	}
+	tags, dropped := domain.NormalizeTags(doc.Tags)
+	if len(dropped) > 0 {
+		res.Warning = fmt.Sprintf("标签不符合要求，已忽略: %s", strings.Join(dropped, ", "))
+	}
+
+	id, err := s.artSvc.Save(ctx, domain.Article{
+		Title:   title,
+		Content: doc.Body,
+		Tags:    tags,
+		Author:  domain.Author{Id: uid},
+		Ctime:   doc.Date,
+	})
+	if err != nil {
+		s.l.Error("导入文章失败", logger.Error(err),
+			logger.Int64("uid", uid), logger.String("file", f.Name))
+		res.Error = "系统错误"
+		return res
+	}
+	res.Id = id
+	return res
+}
+
+func (s *articleTransferService) saveJob(ctx context.Context, job domain.ImportJob) {
+	err := s.repo.Save(ctx, job)
+	if err != nil {
+		s.l.Error("保存导入任务失败", logger.Error(err),
+			logger.String("id", job.Id))
+	}
+}
+
+func (s *articleTransferService) GetImportJob(ctx context.Context, uid int64, id string) (domain.ImportJob, error) {
+	job, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return domain.ImportJob{}, err
+	}
+	// 只能看自己的
+	if job.Uid != uid {
+		return domain.ImportJob{}, ErrImportJobNotFound
+	}
+	return job, nil
+}
+
+func (s *articleTransferService) Export(ctx context.Context, uid int64, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	cursor := domain.ArticleCursor{}
+	for {
+		arts, err := s.artSvc.List(ctx, uid, cursor, s.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, a := range arts {
+			// 列表第一页走的缓存里只有摘要，要重新查一次完整的内容
+			art, err := s.artSvc.Detail(ctx, a.Id, uid)
+			if err != nil {
+				return err
+			}
+			err = s.exportFile(zw, art)
+			if err != nil {
+				return err
+			}
+		}
+		// 第一页可能走的是缓存，条数不一定是 batchSize，所以一直翻到空页为止
+		if len(arts) == 0 {
+			break
+		}
+		cursor = arts[len(arts)-1].Cursor()
+	}
+	return zw.Close()
+}
+
+func (s *articleTransferService) exportFile(zw *zip.Writer, art domain.Article) error {
+	data, err := frontmatter.Format(frontmatter.Document{
+		Title: art.Title,
+		Date:  art.Ctime,
+		Tags:  art.Tags,
+		Body:  art.Content,
+	})
+	if err != nil {
+		return err
+	}
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     s.exportName(art),
+		Method:   zip.Deflate,
+		Modified: art.Utime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+// exportName 带上 id 避免同名文章冲突，标题里不能做文件名的字符都替换掉
+func (s *articleTransferService) exportName(art domain.Article) string {
+	title := []rune(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(art.Title)))
+	if len(title) > 50 {
+		title = title[:50]
+	}
+	if len(title) == 0 {
+		return fmt.Sprintf("%d.md", art.Id)
+	}
+	return fmt.Sprintf("%d-%s.md", art.Id, string(title))
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"os"
+	"strings"
+	"testing"
+)
+
+type fakeSaveArticleService struct {
+	ArticleService
+	saved []string
+}
+
+func (s *fakeSaveArticleService) Save(ctx context.Context, art domain.Article) (int64, error) {
+	s.saved = append(s.saved, art.Title)
+	return int64(len(s.saved)), nil
+}
+
+type fakeImportJobRepo struct {
+	repository.ImportJobRepository
+}
+
+func (r *fakeImportJobRepo) Save(ctx context.Context, job domain.ImportJob) error {
+	return nil
+}
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestArticleTransferService_Import(t *testing.T) {
+	small := zipOf(t, map[string]string{"a.md": "正文", "__MACOSX/._a.md": "x"})
+	testCases := []struct {
+		name       string
+		data       []byte
+		maxSize    int64
+		cancelReq  bool
+		wantErr    error
+		wantSaved  []string
+		wantStatus domain.ImportStatus
+	}{
+		{
+			name:       "直接导入",
+			data:       small,
+			wantSaved:  []string{"a"},
+			wantStatus: domain.ImportStatusDone,
+		},
+		{
+			name:       "客户端断开了也要导入完",
+			data:       small,
+			cancelReq:  true,
+			wantSaved:  []string{"a"},
+			wantStatus: domain.ImportStatusDone,
+		},
+		{
+			name:    "太大",
+			data:    small,
+			maxSize: int64(len(small)) - 1,
+			wantErr: ErrImportTooLarge,
+		},
+		{
+			name:    "不是 zip",
+			data:    []byte("not a zip"),
+			wantErr: ErrInvalidImportFile,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// 临时文件用完要删掉
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+			artSvc := &fakeSaveArticleService{}
+			svc := NewArticleTransferService(artSvc, &fakeImportJobRepo{},
+				logger.NewZapLogger(zap.NewNop(), false)).(*articleTransferService)
+			if tc.maxSize > 0 {
+				svc.maxSize = tc.maxSize
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			if tc.cancelReq {
+				cancel()
+			}
+			defer cancel()
+
+			job, err := svc.Import(ctx, 123, bytes.NewReader(tc.data))
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantSaved, artSvc.saved)
+			if tc.wantErr == nil {
+				assert.Equal(t, tc.wantStatus, job.Status)
+			}
+			entries, err := os.ReadDir(tmp)
+			require.NoError(t, err)
+			for _, e := range entries {
+				assert.False(t, strings.HasPrefix(e.Name(), "webook-import-"), e.Name())
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/feed"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/spf13/viper"
+	"time"
+)
+
+var (
+	ErrUserNotFound = repository.ErrUserNotFound
+	// ErrInvalidFeedFormat 只支持 RSS 和 Atom
+	ErrInvalidFeedFormat = errors.New("不支持的订阅源格式")
+)
+
+type FeedService interface {
+	// Latest 全站最新发表的文章
+	Latest(ctx context.Context, format feed.Format) (domain.FeedDoc, error)
+	// Author 某个作者最新发表的文章
+	Author(ctx context.Context, uid int64, format feed.Format) (domain.FeedDoc, error)
+	// Top 热榜
+	Top(ctx context.Context, format feed.Format) (domain.FeedDoc, error)
+	// Invalidate 作者发表、撤回或者删除文章之后，清掉会受影响的订阅源缓存，
+	// 不然要等缓存过期才能看到变化
+	Invalidate(ctx context.Context, uid int64) error
+}
+
+type feedService struct {
+	repo        repository.FeedRepository
+	artRepo     repository.ArticleRepository
+	rankingRepo repository.RankingRepository
+	userRepo    repository.UserRepository
+	l           logger.Logger
+	// baseURL 网站的地址，订阅源里的链接都是绝对地址
+	baseURL string
+	limit   int
+}
+
+func NewFeedService(repo repository.FeedRepository,
+	artRepo repository.ArticleRepository,
+	rankingRepo repository.RankingRepository,
+	userRepo repository.UserRepository, l logger.Logger) FeedService {
+	return &feedService{
+		repo:        repo,
+		artRepo:     artRepo,
+		rankingRepo: rankingRepo,
+		userRepo:    userRepo,
+		l:           l,
+		baseURL:     viper.GetString("site.baseURL"),
+		limit:       20,
+	}
+}
+
+func (s *feedService) Latest(ctx context.Context, format feed.Format) (domain.FeedDoc, error) {
+	key := fmt.Sprintf("latest:%s", format)
+	return s.get(ctx, key, format, func() (feed.Feed, error) {
+		arts, err := s.artRepo.ListPub(ctx, time.Now(), domain.ArticleCursor{}, s.limit)
+		if err != nil {
+			return feed.Feed{}, err
+		}
+		// 线上库里还有被作者设为仅自己可见的
+		published := make([]domain.Article, 0, len(arts))
+		for _, art := range arts {
+			if !art.Status.NonPublished() {
+				published = append(published, art)
+			}
+		}
+		return s.build(ctx, feed.Feed{
+			Title:       "webook - 最新文章",
+			Description: "webook 上最新发表的文章",
+			Link:        s.baseURL,
+			Self:        s.baseURL + "/feeds/latest" + s.ext(format),
+		}, published), nil
+	})
+}
+
+func (s *feedService) Author(ctx context.Context, uid int64, format feed.Format) (domain.FeedDoc, error) {
+	key := fmt.Sprintf("author:%d:%s", uid, format)
+	return s.get(ctx, key, format, func() (feed.Feed, error) {
+		u, err := s.userRepo.FindById(ctx, uid)
+		if err != nil {
+			return feed.Feed{}, err
+		}
+		arts, err := s.artRepo.ListPubByAuthor(ctx, uid, domain.ArticleCursor{}, s.limit)
+		if err != nil {
+			return feed.Feed{}, err
+		}
+		return s.build(ctx, feed.Feed{
+			Title:       fmt.Sprintf("webook - %s 的文章", u.Nickname),
+			Description: u.Intro,
+			Link:        s.baseURL,
+			Self:        fmt.Sprintf("%s/feeds/authors/%d%s", s.baseURL, uid, s.ext(format)),
+		}, arts), nil
+	})
+}
+
+func (s *feedService) Top(ctx context.Context, format feed.Format) (domain.FeedDoc, error) {
+	key := fmt.Sprintf("top:%s", format)
+	return s.get(ctx, key, format, func() (feed.Feed, error) {
+		ranking, err := s.rankingRepo.GetTopN(ctx, RankingDefault)
+		if err != nil {
+			return feed.Feed{}, err
+		}
+		return s.build(ctx, feed.Feed{
+			Title:       "webook - 热榜",
+			Description: "webook 上最热门的文章",
+			Link:        s.baseURL,
+			Self:        s.baseURL + "/feeds/top" + s.ext(format),
+		}, ranking.Articles()), nil
+	})
+}
+
+func (s *feedService) Invalidate(ctx context.Context, uid int64) error {
+	formats := []feed.Format{feed.FormatRSS, feed.FormatAtom}
+	keys := make([]string, 0, len(formats)*3)
+	for _, format := range formats {
+		keys = append(keys,
+			fmt.Sprintf("latest:%s", format),
+			fmt.Sprintf("top:%s", format),
+			fmt.Sprintf("author:%d:%s", uid, format))
+	}
+	return s.repo.Del(ctx, keys...)
+}
+
+// get 先查缓存，没有就生成一份再回写
+func (s *feedService) get(ctx context.Context, key string, format feed.Format,
+	load func() (feed.Feed, error)) (domain.FeedDoc, error) {
+	if !format.IsValid() {
+		return domain.FeedDoc{}, ErrInvalidFeedFormat
+	}
+	doc, err := s.repo.Get(ctx, key)
+	if err == nil {
+		return doc, nil
+	}
+
+	f, err := load()
+	if err != nil {
+		return domain.FeedDoc{}, err
+	}
+	body, err := feed.Render(f, format)
+	if err != nil {
+		return domain.FeedDoc{}, err
+	}
+	doc = domain.FeedDoc{
+		Body:        body,
+		ContentType: format.ContentType(),
+		// 内容没变的话，重新生成之后 ETag 也不变
+		ETag:         fmt.Sprintf(`"%x"`, sha1.Sum(body)),
+		LastModified: f.Updated,
+	}
+	err = s.repo.Set(ctx, key, doc)
+	if err != nil {
+		s.l.Error("回写订阅源缓存失败", logger.Error(err),
+			logger.String("key", key))
+	}
+	return doc, nil
+}
+
+func (s *feedService) build(ctx context.Context, f feed.Feed, arts []domain.Article) feed.Feed {
+	names := s.authorNames(ctx, arts)
+	f.Items = make([]feed.Item, 0, len(arts))
+	for _, art := range arts {
+		link := fmt.Sprintf("%s/pub/%d", s.baseURL, art.Id)
+		f.Items = append(f.Items, feed.Item{
+			Id:          link,
+			Title:       art.Title,
+			Link:        link,
+			Description: art.Abstract(),
+			Author:      names[art.Author.Id],
+			Published:   art.Ctime,
+			Updated:     art.Utime,
+		})
+		if art.Utime.After(f.Updated) {
+			f.Updated = art.Utime
+		}
+	}
+	return f
+}
+
+// authorNames 查不到昵称的就空着，不影响整个订阅源
+func (s *feedService) authorNames(ctx context.Context, arts []domain.Article) map[int64]string {
+	names := make(map[int64]string, len(arts))
+	missing := make([]int64, 0, len(arts))
+	for _, art := range arts {
+		uid := art.Author.Id
+		if _, ok := names[uid]; ok {
+			continue
+		}
+		names[uid] = art.Author.Name
+		if art.Author.Name == "" {
+			missing = append(missing, uid)
+		}
+	}
+	if len(missing) == 0 {
+		return names
+	}
+	users, err := s.userRepo.FindByIds(ctx, missing)
+	if err != nil {
+		s.l.Error("查询作者昵称失败", logger.Error(err))
+		return names
+	}
+	for uid, u := range users {
+		names[uid] = u.Nickname
+	}
+	return names
+}
+
+func (s *feedService) ext(format feed.Format) string {
+	if format == feed.FormatAtom {
+		return ".atom"
+	}
+	return ".xml"
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/feed"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeFeedRepo 用 map 模拟缓存
+type fakeFeedRepo struct {
+	docs map[string]domain.FeedDoc
+	del  []string
+}
+
+func (r *fakeFeedRepo) Get(ctx context.Context, key string) (domain.FeedDoc, error) {
+	doc, ok := r.docs[key]
+	if !ok {
+		return domain.FeedDoc{}, errors.New("缓存未命中")
+	}
+	return doc, nil
+}
+
+func (r *fakeFeedRepo) Set(ctx context.Context, key string, doc domain.FeedDoc) error {
+	r.docs[key] = doc
+	return nil
+}
+
+func (r *fakeFeedRepo) Del(ctx context.Context, keys ...string) error {
+	r.del = append(r.del, keys...)
+	for _, key := range keys {
+		delete(r.docs, key)
+	}
+	return nil
+}
+
+type fakeFeedArticleRepo struct {
+	repository.ArticleRepository
+	arts  []domain.Article
+	calls int
+}
+
+func (r *fakeFeedArticleRepo) ListPub(ctx context.Context, start time.Time, cursor domain.ArticleCursor, limit int) ([]domain.Article, error) {
+	r.calls++
+	return r.arts, nil
+}
+
+type fakeFeedUserRepo struct {
+	repository.UserRepository
+	users map[int64]domain.User
+	ids   []int64
+}
+
+func (r *fakeFeedUserRepo) FindByIds(ctx context.Context, ids []int64) (map[int64]domain.User, error) {
+	r.ids = append(r.ids, ids...)
+	res := make(map[int64]domain.User, len(ids))
+	for _, id := range ids {
+		if u, ok := r.users[id]; ok {
+			res[id] = u
+		}
+	}
+	return res, nil
+}
+
+func TestFeedService_Latest(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	repo := &fakeFeedRepo{docs: map[string]domain.FeedDoc{}}
+	artRepo := &fakeFeedArticleRepo{arts: []domain.Article{
+		{Id: 1, Title: "第一篇", Status: domain.ArticleStatusPublished,
+			Author: domain.Author{Id: 11, Name: "大明"}, Utime: now.Add(-time.Hour)},
+		{Id: 2, Title: "仅自己可见", Status: domain.ArticleStatusPrivate,
+			Author: domain.Author{Id: 11, Name: "大明"}, Utime: now},
+		{Id: 3, Title: "第三篇", Status: domain.ArticleStatusPublished,
+			Author: domain.Author{Id: 12}, Utime: now.Add(-time.Minute)},
+	}}
+	userRepo := &fakeFeedUserRepo{users: map[int64]domain.User{12: {Id: 12, Nickname: "小明"}}}
+	svc := NewFeedService(repo, artRepo, nil, userRepo, logger.NewZapLogger(zap.NewNop(), false))
+
+	doc, err := svc.Latest(context.Background(), feed.FormatRSS)
+	require.NoError(t, err)
+	body := string(doc.Body)
+	assert.Equal(t, feed.FormatRSS.ContentType(), doc.ContentType)
+	assert.Contains(t, body, "第一篇")
+	assert.Contains(t, body, "第三篇")
+	// 线上库里仅自己可见的不能出现在订阅源里
+	assert.NotContains(t, body, "仅自己可见")
+	// 最后更新时间取的是可见文章里最新的那篇，不是被过滤掉的那篇
+	assert.Equal(t, now.Add(-time.Minute), doc.LastModified)
+	// 文章里没有昵称的才去查用户
+	assert.Equal(t, []int64{12}, userRepo.ids)
+	assert.Contains(t, body, "<dc:creator>小明</dc:creator>")
+	assert.True(t, strings.HasPrefix(doc.ETag, `"`))
+
+	// 第二次命中缓存，不会再查文章
+	cached, err := svc.Latest(context.Background(), feed.FormatRSS)
+	require.NoError(t, err)
+	assert.Equal(t, doc, cached)
+	assert.Equal(t, 1, artRepo.calls)
+
+	// 缓存清掉之后重新生成，内容没变 ETag 也不变
+	require.NoError(t, svc.Invalidate(context.Background(), 11))
+	again, err := svc.Latest(context.Background(), feed.FormatRSS)
+	require.NoError(t, err)
+	assert.Equal(t, 2, artRepo.calls)
+	assert.Equal(t, doc.ETag, again.ETag)
+}
+
+func TestFeedService_InvalidFormat(t *testing.T) {
+	artRepo := &fakeFeedArticleRepo{}
+	svc := NewFeedService(&fakeFeedRepo{docs: map[string]domain.FeedDoc{}}, artRepo, nil, nil,
+		logger.NewZapLogger(zap.NewNop(), false))
+	_, err := svc.Latest(context.Background(), feed.Format("json"))
+	assert.Equal(t, ErrInvalidFeedFormat, err)
+	assert.Equal(t, 0, artRepo.calls)
+}
+
+func TestFeedService_Invalidate(t *testing.T) {
+	repo := &fakeFeedRepo{docs: map[string]domain.FeedDoc{}}
+	svc := NewFeedService(repo, nil, nil, nil, logger.NewZapLogger(zap.NewNop(), false))
+	require.NoError(t, svc.Invalidate(context.Background(), 11))
+	// 两种格式的全站、热榜和这个作者的订阅源都要清掉
+	assert.ElementsMatch(t, []string{
+		"latest:rss", "top:rss", "author:11:rss",
+		"latest:atom", "top:atom", "author:11:atom",
+	}, repo.del)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/payment"
+	"github.com/lithammer/shortuuid/v4"
+	"time"
+)
+
+var (
+	ErrPlanNotFound    = errors.New("会员套餐不存在")
+	ErrPaymentDeclined = payment.ErrPaymentDeclined
+)
+
+type MembershipService interface {
+	Plans(ctx context.Context) []domain.MembershipPlan
+	// Subscribe 购买会员，支付成功之后返回延长后的会员资格
+	Subscribe(ctx context.Context, uid int64, planId int64) (domain.Membership, error)
+	Get(ctx context.Context, uid int64) (domain.Membership, error)
+	// CanRead uid 能不能看 art 的全文，uid 为 0 代表没有登录
+	CanRead(ctx context.Context, uid int64, art domain.Article) (bool, error)
+}
+
+type membershipService struct {
+	repo    repository.MembershipRepository
+	gateway payment.Gateway
+	plans   []domain.MembershipPlan
+	l       logger.Logger
+}
+
+func NewMembershipService(repo repository.MembershipRepository,
+	gateway payment.Gateway, l logger.Logger) MembershipService {
+	return &membershipService{
+		repo:    repo,
+		gateway: gateway,
+		plans: []domain.MembershipPlan{
+			{Id: 1, Name: "月度会员", Price: 1500, Duration: time.Hour * 24 * 30},
+			{Id: 2, Name: "年度会员", Price: 15000, Duration: time.Hour * 24 * 365},
+		},
+		l: l,
+	}
+}
+
+func (s *membershipService) Plans(ctx context.Context) []domain.MembershipPlan {
+	return s.plans
+}
+
+func (s *membershipService) Subscribe(ctx context.Context, uid int64, planId int64) (domain.Membership, error) {
+	plan, ok := s.findPlan(planId)
+	if !ok {
+		return domain.Membership{}, ErrPlanNotFound
+	}
+	sn := shortuuid.New()
+	_, err := s.repo.CreateOrder(ctx, domain.MembershipOrder{
+		Sn:       sn,
+		Uid:      uid,
+		PlanId:   plan.Id,
+		Amount:   plan.Price,
+		Duration: plan.Duration,
+		Status:   domain.MembershipOrderStatusPending,
+	})
+	if err != nil {
+		return domain.Membership{}, err
+	}
+	res, err := s.gateway.Pay(ctx, payment.PayReq{
+		Sn:          sn,
+		Uid:         uid,
+		Amount:      plan.Price,
+		Description: plan.Name,
+	})
+	if errors.Is(err, payment.ErrPaymentDeclined) {
+		err1 := s.repo.MarkFailed(ctx, sn)
+		if err1 != nil {
+			s.l.Error("标记会员订单失败出错", logger.Error(err1), logger.String("sn", sn))
+		}
+		return domain.Membership{}, err
+	}
+	if err != nil {
+		// 超时之类的错误不知道到底扣没扣钱，订单保持待支付，
+		// 用同一个 sn 再调用一次支付渠道就知道结果了
+		s.l.Error("调用支付渠道失败", logger.Error(err), logger.String("sn", sn))
+		return domain.Membership{}, err
+	}
+	m, err := s.repo.MarkPaid(ctx, sn, res.TxnId)
+	if err != nil {
+		s.l.Error("支付成功但是更新会员失败", logger.Error(err),
+			logger.String("sn", sn), logger.String("txnId", res.TxnId))
+	}
+	return m, err
+}
+
+func (s *membershipService) Get(ctx context.Context, uid int64) (domain.Membership, error) {
+	return s.repo.FindByUid(ctx, uid)
+}
+
+func (s *membershipService) CanRead(ctx context.Context, uid int64, art domain.Article) (bool, error) {
+	switch art.Access {
+	case domain.ArticleAccessPublic:
+		return true, nil
+	case domain.ArticleAccessLogin:
+		return uid > 0, nil
+	case domain.ArticleAccessSubscriber:
+		if uid <= 0 {
+			return false, nil
+		}
+		// 作者自己总是能看
+		if uid == art.Author.Id {
+			return true, nil
+		}
+		m, err := s.repo.FindByUid(ctx, uid)
+		if err != nil {
+			return false, err
+		}
+		return m.IsActive(time.Now()), nil
+	default:
+		return false, nil
+	}
+}
+
+func (s *membershipService) findPlan(id int64) (domain.MembershipPlan, bool) {
+	for _, p := range s.plans {
+		if p.Id == id {
+			return p, true
+		}
+	}
+	return domain.MembershipPlan{}, false
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeMembershipRepo struct {
+	repository.MembershipRepository
+	m     domain.Membership
+	err   error
+	calls int
+}
+
+func (r *fakeMembershipRepo) FindByUid(ctx context.Context, uid int64) (domain.Membership, error) {
+	r.calls++
+	return r.m, r.err
+}
+
+func TestMembershipService_CanRead(t *testing.T) {
+	const (
+		author int64 = 1
+		reader int64 = 2
+	)
+	artOf := func(access domain.ArticleAccess) domain.Article {
+		return domain.Article{Id: 10, Access: access, Author: domain.Author{Id: author}}
+	}
+	testCases := []struct {
+		name string
+		uid  int64
+		art  domain.Article
+		m    domain.Membership
+		err  error
+
+		wantOk    bool
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:   "公开文章，没登录也能看",
+			uid:    0,
+			art:    artOf(domain.ArticleAccessPublic),
+			wantOk: true,
+		},
+		{
+			name:   "登录可见，没登录",
+			uid:    0,
+			art:    artOf(domain.ArticleAccessLogin),
+			wantOk: false,
+		},
+		{
+			name:   "登录可见，已登录",
+			uid:    reader,
+			art:    artOf(domain.ArticleAccessLogin),
+			wantOk: true,
+		},
+		{
+			name:   "会员可见，没登录",
+			uid:    0,
+			art:    artOf(domain.ArticleAccessSubscriber),
+			wantOk: false,
+		},
+		{
+			name:   "会员可见，作者自己不用查会员",
+			uid:    author,
+			art:    artOf(domain.ArticleAccessSubscriber),
+			wantOk: true,
+		},
+		{
+			name:      "会员可见，不是会员",
+			uid:       reader,
+			art:       artOf(domain.ArticleAccessSubscriber),
+			m:         domain.Membership{Uid: reader},
+			wantOk:    false,
+			wantCalls: 1,
+		},
+		{
+			name:      "会员可见，会员过期了",
+			uid:       reader,
+			art:       artOf(domain.ArticleAccessSubscriber),
+			m:         domain.Membership{Uid: reader, ExpireAt: time.Now().Add(-time.Hour)},
+			wantOk:    false,
+			wantCalls: 1,
+		},
+		{
+			name:      "会员可见，会员有效",
+			uid:       reader,
+			art:       artOf(domain.ArticleAccessSubscriber),
+			m:         domain.Membership{Uid: reader, ExpireAt: time.Now().Add(time.Hour)},
+			wantOk:    true,
+			wantCalls: 1,
+		},
+		{
+			name:      "会员可见，查会员出错",
+			uid:       reader,
+			art:       artOf(domain.ArticleAccessSubscriber),
+			err:       errors.New("模拟数据库错误"),
+			wantOk:    false,
+			wantErr:   errors.New("模拟数据库错误"),
+			wantCalls: 1,
+		},
+		{
+			name:   "不认识的访问级别",
+			uid:    author,
+			art:    artOf(domain.ArticleAccess(100)),
+			wantOk: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeMembershipRepo{m: tc.m, err: tc.err}
+			svc := NewMembershipService(repo, nil, logger.NewZapLogger(zap.NewNop(), false))
+			ok, err := svc.CanRead(context.Background(), tc.uid, tc.art)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantOk, ok)
+			assert.Equal(t, tc.wantCalls, repo.calls)
+		})
+	}
+}
+
+type fakeDetailArticleRepo struct {
+	repository.ArticleRepository
+	art domain.Article
+}
+
+func (r *fakeDetailArticleRepo) GetPublishedById(ctx context.Context, id int64, uid int64) (domain.Article, error) {
+	return r.art, nil
+}
+
+type fakeArticleProducer struct {
+	article.Producer
+	read chan article.ReadEvent
+}
+
+func (p *fakeArticleProducer) ProduceReadEvent(ctx context.Context, evt article.ReadEvent) error {
+	p.read <- evt
+	return nil
+}
+
+func TestArticleService_PubDetail_Access(t *testing.T) {
+	const reader int64 = 2
+	art := domain.Article{
+		Id:      10,
+		Content: strings.Repeat("会员内容", 50),
+		Status:  domain.ArticleStatusPublished,
+		Access:  domain.ArticleAccessSubscriber,
+		Author:  domain.Author{Id: 1},
+	}
+	testCases := []struct {
+		name string
+		m    domain.Membership
+		err  error
+
+		wantArt domain.Article
+	}{
+		{
+			name:    "会员看全文",
+			m:       domain.Membership{Uid: reader, ExpireAt: time.Now().Add(time.Hour)},
+			wantArt: art,
+		},
+		{
+			name:    "不是会员只看摘要",
+			m:       domain.Membership{Uid: reader},
+			wantArt: art.Preview(),
+		},
+		{
+			name:    "查会员出错，宁可只给摘要",
+			err:     errors.New("模拟数据库错误"),
+			wantArt: art.Preview(),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := logger.NewZapLogger(zap.NewNop(), false)
+			memberSvc := NewMembershipService(&fakeMembershipRepo{m: tc.m, err: tc.err}, nil, l)
+			producer := &fakeArticleProducer{read: make(chan article.ReadEvent, 1)}
+			svc := NewArticleService(&fakeDetailArticleRepo{art: art}, nil, nil, nil, nil,
+				memberSvc, nil, nil, producer, l)
+			got, err := svc.PubDetail(context.Background(), art.Id, reader)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantArt, got)
+			// 阅读事件照常发出去
+			select {
+			case evt := <-producer.read:
+				assert.Equal(t, article.ReadEvent{Uid: reader, Aid: art.Id}, evt)
+			case <-time.After(time.Second):
+				t.Fatal("没有发送阅读事件")
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+)
+
+var (
+	ErrReviewNotFound   = repository.ErrReviewNotFound
+	ErrReviewNotPending = repository.ErrReviewNotPending
+	// ErrNotModerator 只有审核员才能审核
+	ErrNotModerator = errors.New("不是审核员")
+	// ErrReviewOutdated 提交审核之后作者又修改了文章，这次审核作废
+	ErrReviewOutdated = errors.New("文章在审核期间被修改过")
+)
+
+type ModerationService interface {
+	// ListPending 审核队列，按照提交的先后顺序
+	ListPending(ctx context.Context, moderator int64, minId int64, limit int) ([]domain.ArticleReview, error)
+	Approve(ctx context.Context, moderator int64, reviewId int64) error
+	Reject(ctx context.Context, moderator int64, reviewId int64, reason string) error
+	SetTrustLevel(ctx context.Context, moderator int64, uid int64, level domain.TrustLevel) error
+	// Latest 作者查看自己文章最近一次的审核结果
+	Latest(ctx context.Context, uid int64, aid int64) (domain.ArticleReview, error)
+}
+
+type moderationService struct {
+	repo        repository.ModerationRepository
+	articleRepo repository.ArticleRepository
+	feedSvc     FeedService
+	producer    article.Producer
+	l           logger.Logger
+}
+
+func NewModerationService(repo repository.ModerationRepository,
+	articleRepo repository.ArticleRepository,
+	feedSvc FeedService,
+	producer article.Producer, l logger.Logger) ModerationService {
+	return &moderationService{
+		repo:        repo,
+		articleRepo: articleRepo,
+		feedSvc:     feedSvc,
+		producer:    producer,
+		l:           l,
+	}
+}
+
+func (s *moderationService) ListPending(ctx context.Context, moderator int64, minId int64, limit int) ([]domain.ArticleReview, error) {
+	err := s.checkModerator(ctx, moderator)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindPendingReviews(ctx, minId, limit)
+}
+
+func (s *moderationService) Approve(ctx context.Context, moderator int64, reviewId int64) error {
+	r, art, err := s.pendingReview(ctx, moderator, reviewId)
+	if err != nil {
+		return err
+	}
+
+	// 先发表，成功了再更新审核记录，不然发表失败的文章会一直显示审核通过
+	// Sync 带着审核时的版本号，审核期间作者改过，或者别的审核员已经处理过的话这里会冲突
+	art.Status = domain.ArticleStatusPublished
+	_, err = s.articleRepo.Sync(ctx, art)
+	if errors.As(err, &VersionConflictError{}) {
+		return ErrReviewOutdated
+	}
+	if err != nil {
+		return err
+	}
+	err = s.feedSvc.Invalidate(ctx, art.Author.Id)
+	if err != nil {
+		s.l.Error("清除订阅源缓存失败", logger.Error(err),
+			logger.Int64("Uid", art.Author.Id))
+	}
+
+	r.Moderator = moderator
+	r.Status = domain.ReviewStatusApproved
+	err = s.resolve(ctx, r)
+	if err != nil {
+		return err
+	}
+	s.notify(ctx, r, true)
+	return nil
+}
+
+func (s *moderationService) Reject(ctx context.Context, moderator int64, reviewId int64, reason string) error {
+	r, art, err := s.pendingReview(ctx, moderator, reviewId)
+	if err != nil {
+		return err
+	}
+
+	// 和 Approve 一样，先改草稿再更新审核记录，草稿的版本号保证只有一个审核员能处理成功
+	// 草稿标记为被拒绝，线上的版本（如果有的话）不受影响
+	art.Status = domain.ArticleStatusRejected
+	err = s.articleRepo.Update(ctx, art)
+	if errors.As(err, &VersionConflictError{}) {
+		return ErrReviewOutdated
+	}
+	if err != nil {
+		return err
+	}
+
+	r.Moderator = moderator
+	r.Status = domain.ReviewStatusRejected
+	r.Reason = reason
+	err = s.resolve(ctx, r)
+	if err != nil {
+		return err
+	}
+	s.notify(ctx, r, false)
+	return nil
+}
+
+// resolve 文章已经处理完了才会走到这里，失败了审核记录会停留在待审核
+// 再处理的时候 pendingReview 会因为草稿的版本变了返回 ErrReviewOutdated，所以要记录下来人工修复
+func (s *moderationService) resolve(ctx context.Context, r domain.ArticleReview) error {
+	err := s.repo.Resolve(ctx, r)
+	if err != nil {
+		s.l.Error("文章已经处理，但是更新审核记录失败", logger.Error(err),
+			logger.Int64("ReviewId", r.Id), logger.Int64("Aid", r.Article.Id),
+			logger.Uint8("Status", r.Status.ToUint8()))
+	}
+	return err
+}
+
+// pendingReview 找到待审核的记录和对应的草稿，草稿要和提交审核时是同一个版本
+func (s *moderationService) pendingReview(ctx context.Context, moderator int64, reviewId int64) (domain.ArticleReview, domain.Article, error) {
+	err := s.checkModerator(ctx, moderator)
+	if err != nil {
+		return domain.ArticleReview{}, domain.Article{}, err
+	}
+	r, err := s.repo.FindReviewById(ctx, reviewId)
+	if err != nil {
+		return domain.ArticleReview{}, domain.Article{}, err
+	}
+	if r.Status != domain.ReviewStatusPending {
+		return domain.ArticleReview{}, domain.Article{}, ErrReviewNotPending
+	}
+	art, err := s.articleRepo.GetById(ctx, r.Article.Id, r.Article.Author.Id)
+	if err != nil {
+		return domain.ArticleReview{}, domain.Article{}, err
+	}
+	if art.Status != domain.ArticleStatusPendingReview || art.Version != r.Article.Version {
+		return domain.ArticleReview{}, domain.Article{}, ErrReviewOutdated
+	}
+	return r, art, nil
+}
+
+func (s *moderationService) SetTrustLevel(ctx context.Context, moderator int64, uid int64, level domain.TrustLevel) error {
+	err := s.checkModerator(ctx, moderator)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetTrustLevel(ctx, uid, level)
+}
+
+func (s *moderationService) Latest(ctx context.Context, uid int64, aid int64) (domain.ArticleReview, error) {
+	r, err := s.repo.FindLatestReview(ctx, aid)
+	if err != nil {
+		return domain.ArticleReview{}, err
+	}
+	// 只能看自己的
+	if r.Article.Author.Id != uid {
+		return domain.ArticleReview{}, ErrReviewNotFound
+	}
+	return r, nil
+}
+
+func (s *moderationService) checkModerator(ctx context.Context, uid int64) error {
+	level, err := s.repo.GetTrustLevel(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if !level.CanModerate() {
+		return ErrNotModerator
+	}
+	return nil
+}
+
+// notify 通知失败了不影响审核结果，作者也可以自己查询
+func (s *moderationService) notify(ctx context.Context, r domain.ArticleReview, approved bool) {
+	err := s.producer.ProduceModerationEvent(ctx, article.ModerationEvent{
+		Uid:      r.Article.Author.Id,
+		Aid:      r.Article.Id,
+		Title:    r.Article.Title,
+		Approved: approved,
+		Reason:   r.Reason,
+	})
+	if err != nil {
+		s.l.Error("发送审核结果事件失败", logger.Error(err),
+			logger.Int64("Aid", r.Article.Id), logger.Int64("ReviewId", r.Id))
+	}
+}
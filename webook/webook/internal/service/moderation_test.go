@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	mediaSvc "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/textfilter"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+const (
+	testModerator int64 = 1
+	testTrusted   int64 = 2
+	testAuthor    int64 = 3
+)
+
+type fakeModerationRepo struct {
+	repository.ModerationRepository
+	levels    map[int64]domain.TrustLevel
+	reviews   map[int64]domain.ArticleReview
+	submitted []domain.Article
+	resolved  []domain.ArticleReview
+}
+
+func (r *fakeModerationRepo) GetTrustLevel(ctx context.Context, uid int64) (domain.TrustLevel, error) {
+	return r.levels[uid], nil
+}
+
+func (r *fakeModerationRepo) SubmitReview(ctx context.Context, art domain.Article) (int64, error) {
+	r.submitted = append(r.submitted, art)
+	return int64(len(r.submitted)), nil
+}
+
+func (r *fakeModerationRepo) FindReviewById(ctx context.Context, id int64) (domain.ArticleReview, error) {
+	rv, ok := r.reviews[id]
+	if !ok {
+		return domain.ArticleReview{}, ErrReviewNotFound
+	}
+	return rv, nil
+}
+
+func (r *fakeModerationRepo) FindLatestReview(ctx context.Context, aid int64) (domain.ArticleReview, error) {
+	for _, rv := range r.reviews {
+		if rv.Article.Id == aid {
+			return rv, nil
+		}
+	}
+	return domain.ArticleReview{}, ErrReviewNotFound
+}
+
+func (r *fakeModerationRepo) Resolve(ctx context.Context, rv domain.ArticleReview) error {
+	r.resolved = append(r.resolved, rv)
+	return nil
+}
+
+// fakeDraftArticleRepo 只有一篇草稿，记录 Create、Update 和 Sync 收到的文章
+type fakeDraftArticleRepo struct {
+	repository.ArticleRepository
+	draft   domain.Article
+	created []domain.Article
+	updated []domain.Article
+	synced  []domain.Article
+	// 模拟乐观锁冲突
+	err error
+}
+
+func (r *fakeDraftArticleRepo) GetById(ctx context.Context, id int64, uid int64) (domain.Article, error) {
+	return r.draft, nil
+}
+
+func (r *fakeDraftArticleRepo) GetPublishedById(ctx context.Context, id int64, uid int64) (domain.Article, error) {
+	return domain.Article{}, nil
+}
+
+func (r *fakeDraftArticleRepo) Create(ctx context.Context, art domain.Article) (int64, error) {
+	r.created = append(r.created, art)
+	return 100, r.err
+}
+
+func (r *fakeDraftArticleRepo) Update(ctx context.Context, art domain.Article) error {
+	r.updated = append(r.updated, art)
+	return r.err
+}
+
+func (r *fakeDraftArticleRepo) Sync(ctx context.Context, art domain.Article) (int64, error) {
+	r.synced = append(r.synced, art)
+	return art.Id, r.err
+}
+
+type fakeModerationProducer struct {
+	article.Producer
+	evts []article.ModerationEvent
+}
+
+func (p *fakeModerationProducer) ProduceModerationEvent(ctx context.Context, evt article.ModerationEvent) error {
+	p.evts = append(p.evts, evt)
+	return nil
+}
+
+type fakeFeedService struct {
+	FeedService
+	invalidated []int64
+}
+
+func (s *fakeFeedService) Invalidate(ctx context.Context, uid int64) error {
+	s.invalidated = append(s.invalidated, uid)
+	return nil
+}
+
+type fakeMediaService struct {
+	mediaSvc.MediaService
+}
+
+func (s *fakeMediaService) SyncRefs(ctx context.Context, biz string, bizId int64, content string) error {
+	return nil
+}
+
+func testTrustLevels() map[int64]domain.TrustLevel {
+	return map[int64]domain.TrustLevel{
+		testModerator: domain.TrustLevelModerator,
+		testTrusted:   domain.TrustLevelTrusted,
+	}
+}
+
+func TestModerationService_Approve(t *testing.T) {
+	submitted := domain.Article{Id: 10, Title: "标题", Version: 3,
+		Author: domain.Author{Id: testAuthor}}
+	draft := submitted
+	draft.Status = domain.ArticleStatusPendingReview
+	pending := domain.ArticleReview{Id: 1, Article: submitted, Status: domain.ReviewStatusPending}
+
+	testCases := []struct {
+		name      string
+		moderator int64
+		review    domain.ArticleReview
+		draft     domain.Article
+		syncErr   error
+
+		wantErr     error
+		wantSynced  bool
+		wantResolve bool
+	}{
+		{
+			name:        "审核通过",
+			moderator:   testModerator,
+			review:      pending,
+			draft:       draft,
+			wantSynced:  true,
+			wantResolve: true,
+		},
+		{
+			name:      "可信作者不能审核",
+			moderator: testTrusted,
+			review:    pending,
+			draft:     draft,
+			wantErr:   ErrNotModerator,
+		},
+		{
+			name:      "已经处理过了",
+			moderator: testModerator,
+			review: domain.ArticleReview{Id: 1, Article: submitted,
+				Status: domain.ReviewStatusRejected},
+			draft:   draft,
+			wantErr: ErrReviewNotPending,
+		},
+		{
+			name:      "审核期间作者又改过",
+			moderator: testModerator,
+			review:    pending,
+			draft: func() domain.Article {
+				a := draft
+				a.Version++
+				return a
+			}(),
+			wantErr: ErrReviewOutdated,
+		},
+		{
+			name:      "发表的时候版本冲突",
+			moderator: testModerator,
+			review:    pending,
+			draft:     draft,
+			syncErr:   VersionConflictError{Id: 10, Version: 4},
+			// Sync 调用了，但是没有成功
+			wantSynced: true,
+			wantErr:    ErrReviewOutdated,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeModerationRepo{levels: testTrustLevels(),
+				reviews: map[int64]domain.ArticleReview{tc.review.Id: tc.review}}
+			artRepo := &fakeDraftArticleRepo{draft: tc.draft, err: tc.syncErr}
+			feedSvc := &fakeFeedService{}
+			producer := &fakeModerationProducer{}
+			svc := NewModerationService(repo, artRepo, feedSvc, producer,
+				logger.NewZapLogger(zap.NewNop(), false))
+
+			err := svc.Approve(context.Background(), tc.moderator, tc.review.Id)
+			assert.ErrorIs(t, err, tc.wantErr)
+			if tc.wantSynced {
+				require.Len(t, artRepo.synced, 1)
+				assert.Equal(t, domain.ArticleStatusPublished, artRepo.synced[0].Status)
+				assert.Equal(t, tc.draft.Version, artRepo.synced[0].Version)
+			} else {
+				assert.Empty(t, artRepo.synced)
+			}
+			if !tc.wantResolve {
+				assert.Empty(t, repo.resolved)
+				assert.Empty(t, producer.evts)
+				return
+			}
+			require.Len(t, repo.resolved, 1)
+			assert.Equal(t, domain.ReviewStatusApproved, repo.resolved[0].Status)
+			assert.Equal(t, tc.moderator, repo.resolved[0].Moderator)
+			assert.Equal(t, []int64{testAuthor}, feedSvc.invalidated)
+			assert.Equal(t, []article.ModerationEvent{
+				{Uid: testAuthor, Aid: 10, Title: "标题", Approved: true},
+			}, producer.evts)
+		})
+	}
+}
+
+func TestModerationService_Reject(t *testing.T) {
+	submitted := domain.Article{Id: 10, Title: "标题", Version: 3,
+		Author: domain.Author{Id: testAuthor}}
+	draft := submitted
+	draft.Status = domain.ArticleStatusPendingReview
+	pending := domain.ArticleReview{Id: 1, Article: submitted, Status: domain.ReviewStatusPending}
+
+	testCases := []struct {
+		name      string
+		updateErr error
+
+		wantErr     error
+		wantResolve bool
+	}{
+		{
+			name:        "拒绝",
+			wantResolve: true,
+		},
+		{
+			name:      "别的审核员已经处理过了",
+			updateErr: VersionConflictError{Id: 10, Version: 4},
+			wantErr:   ErrReviewOutdated,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeModerationRepo{levels: testTrustLevels(),
+				reviews: map[int64]domain.ArticleReview{pending.Id: pending}}
+			artRepo := &fakeDraftArticleRepo{draft: draft, err: tc.updateErr}
+			producer := &fakeModerationProducer{}
+			svc := NewModerationService(repo, artRepo, &fakeFeedService{}, producer,
+				logger.NewZapLogger(zap.NewNop(), false))
+
+			err := svc.Reject(context.Background(), testModerator, pending.Id, "内容不完整")
+			assert.ErrorIs(t, err, tc.wantErr)
+			// 拒绝只改草稿，不动线上库
+			assert.Empty(t, artRepo.synced)
+			require.Len(t, artRepo.updated, 1)
+			assert.Equal(t, domain.ArticleStatusRejected, artRepo.updated[0].Status)
+			if !tc.wantResolve {
+				assert.Empty(t, repo.resolved)
+				assert.Empty(t, producer.evts)
+				return
+			}
+			require.Len(t, repo.resolved, 1)
+			assert.Equal(t, domain.ReviewStatusRejected, repo.resolved[0].Status)
+			assert.Equal(t, "内容不完整", repo.resolved[0].Reason)
+			assert.Equal(t, []article.ModerationEvent{
+				{Uid: testAuthor, Aid: 10, Title: "标题", Reason: "内容不完整"},
+			}, producer.evts)
+		})
+	}
+}
+
+func TestModerationService_Latest(t *testing.T) {
+	repo := &fakeModerationRepo{reviews: map[int64]domain.ArticleReview{
+		1: {Id: 1, Article: domain.Article{Id: 10, Author: domain.Author{Id: testAuthor}},
+			Status: domain.ReviewStatusRejected, Reason: "内容不完整"},
+	}}
+	svc := NewModerationService(repo, nil, nil, nil, logger.NewZapLogger(zap.NewNop(), false))
+
+	r, err := svc.Latest(context.Background(), testAuthor, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "内容不完整", r.Reason)
+
+	// 别人的审核结果看不到
+	_, err = svc.Latest(context.Background(), testTrusted, 10)
+	assert.Equal(t, ErrReviewNotFound, err)
+}
+
+func TestArticleService_Publish_Moderation(t *testing.T) {
+	testCases := []struct {
+		name    string
+		enabled bool
+		art     domain.Article
+
+		wantStatus    domain.ArticleStatus
+		wantErr       error
+		wantSubmitted bool
+	}{
+		{
+			name:       "没有开启审核",
+			art:        domain.Article{Title: "标题", Content: "内容", Author: domain.Author{Id: testAuthor}},
+			wantStatus: domain.ArticleStatusPublished,
+		},
+		{
+			name:          "普通作者要审核",
+			enabled:       true,
+			art:           domain.Article{Title: "标题", Content: "内容", Author: domain.Author{Id: testAuthor}},
+			wantStatus:    domain.ArticleStatusPendingReview,
+			wantSubmitted: true,
+		},
+		{
+			name:       "可信作者直接发表",
+			enabled:    true,
+			art:        domain.Article{Title: "标题", Content: "内容", Author: domain.Author{Id: testTrusted}},
+			wantStatus: domain.ArticleStatusPublished,
+		},
+		{
+			name:          "可信作者命中需要审核的词",
+			enabled:       true,
+			art:           domain.Article{Title: "标题", Content: "打个广告", Author: domain.Author{Id: testTrusted}},
+			wantStatus:    domain.ArticleStatusPendingReview,
+			wantSubmitted: true,
+		},
+		{
+			name:       "没有开启审核也要拦截禁止的词",
+			art:        domain.Article{Title: "来赌博", Content: "内容", Author: domain.Author{Id: testTrusted}},
+			wantStatus: domain.ArticleStatusUnknown,
+			wantErr:    ErrSensitiveContent,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set("moderation.enabled", tc.enabled)
+			t.Cleanup(func() {
+				viper.Set("moderation.enabled", false)
+			})
+			repo := &fakeModerationRepo{levels: testTrustLevels()}
+			artRepo := &fakeDraftArticleRepo{}
+			filter := textfilter.NewACFilter([]textfilter.Word{
+				{Word: "广告", Action: textfilter.ActionReview},
+				{Word: "赌博", Action: textfilter.ActionBlock},
+			})
+			svc := NewArticleService(artRepo, nil, repo, nil, &fakeMediaService{},
+				nil, &fakeFeedService{}, filter, nil, logger.NewZapLogger(zap.NewNop(), false))
+
+			_, status, err := svc.Publish(context.Background(), tc.art)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantStatus, status)
+			switch {
+			case tc.wantErr != nil:
+				assert.Empty(t, artRepo.created)
+				assert.Empty(t, artRepo.synced)
+			case tc.wantSubmitted:
+				// 只保存草稿，线上库等审核通过再同步
+				assert.Empty(t, artRepo.synced)
+				require.Len(t, artRepo.created, 1)
+				assert.Equal(t, domain.ArticleStatusPendingReview, artRepo.created[0].Status)
+				require.Len(t, repo.submitted, 1)
+				assert.Equal(t, int64(100), repo.submitted[0].Id)
+				assert.Equal(t, int64(1), repo.submitted[0].Version)
+			default:
+				assert.Empty(t, repo.submitted)
+				require.Len(t, artRepo.synced, 1)
+				assert.Equal(t, domain.ArticleStatusPublished, artRepo.synced[0].Status)
+			}
+		})
+	}
+}
@@ -2,81 +2,344 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
 	"github.com/ecodeclub/ekit/queue"
 	"github.com/ecodeclub/ekit/slice"
-	"math"
+	"github.com/ecodeclub/ekit/syncx/atomicx"
+	"github.com/spf13/viper"
 	"time"
 )
 
 type RankingService interface {
 	TopN(ctx context.Context) error
+	// GetTopN 分页读名字是 name 的热榜，name 为空就是 RankingDefault。
+	// offset 和 limit 都是在前 n 名里面算的，返回这一页和整个热榜的长度
+	GetTopN(ctx context.Context, name string, offset, limit int) (domain.Ranking, int, error)
+	// Explain 用当前的打分配置现算一篇文章的热度，strategy 不为空的时候换成这个策略，
+	// 不会影响热榜
+	// 只有审核员能用，uid 是调用的人
+	Explain(ctx context.Context, uid int64, id int64, strategy string) (domain.RankingScore, error)
+}
+
+const (
+	RankingDaily   = "daily"
+	RankingWeekly  = "weekly"
+	RankingMonthly = "monthly"
+	// RankingMember 只看会员可见的文章
+	RankingMember = "member"
+	// RankingDefault 不指定名字的时候用，和最早只有一个热榜的时候一样是七天
+	RankingDefault = RankingWeekly
+	// RankingTagPrefix 按标签的热榜，名字是这个前缀加上标签，比如 tag:go
+	RankingTagPrefix = "tag:"
+)
+
+var (
+	ErrUnknownRanking = errors.New("未知的热榜")
+	// ErrInvalidRankingTags 配置的热榜标签不是合法的文章标签
+	ErrInvalidRankingTags = errors.New("热榜标签不合法")
+)
+
+// RankingDef 一个热榜的定义，同一次扫描按照不同的时间窗口和过滤条件算出多个热榜
+type RankingDef struct {
+	Name string
+	// Window 只有这么久之内更新过的文章才上榜
+	Window time.Duration
+	// Filter 为 nil 代表不过滤
+	Filter func(art domain.Article) bool
+}
+
+func (d RankingDef) accept(art domain.Article, now time.Time) bool {
+	if now.Sub(art.Utime) > d.Window {
+		return false
+	}
+	return d.Filter == nil || d.Filter(art)
+}
+
+// baseRankingDefs 固定的几个热榜
+var baseRankingDefs = []RankingDef{
+	{Name: RankingDaily, Window: time.Hour * 24},
+	{Name: RankingWeekly, Window: time.Hour * 24 * 7},
+	{Name: RankingMonthly, Window: time.Hour * 24 * 30},
+	{
+		Name:   RankingMember,
+		Window: time.Hour * 24 * 7,
+		Filter: func(art domain.Article) bool {
+			return art.Access == domain.ArticleAccessSubscriber
+		},
+	},
+}
+
+// rankingDefs 当前生效的热榜，固定的几个加上按标签的，配置变更的时候整个换掉
+var rankingDefs = atomicx.NewValueOf(baseRankingDefs)
+
+// UpdateRankingTags 每个标签一个热榜，窗口和周榜一样是七天
+// 标签要和文章的标签一样是规范过的，配错了就保持原来的热榜
+func UpdateRankingTags(tags []string) error {
+	kept, dropped := domain.NormalizeTags(tags)
+	if len(dropped) > 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidRankingTags, dropped)
+	}
+	defs := make([]RankingDef, 0, len(baseRankingDefs)+len(kept))
+	defs = append(defs, baseRankingDefs...)
+	for _, tag := range kept {
+		tag := tag
+		defs = append(defs, RankingDef{
+			Name:   RankingTagPrefix + tag,
+			Window: time.Hour * 24 * 7,
+			Filter: func(art domain.Article) bool {
+				return slice.Contains[string](art.Tags, tag)
+			},
+		})
+	}
+	rankingDefs.Store(defs)
+	return nil
+}
+
+func maxRankingWindow(defs []RankingDef) time.Duration {
+	var res time.Duration
+	for _, def := range defs {
+		if def.Window > res {
+			res = def.Window
+		}
+	}
+	return res
+}
+
+func findRankingDef(name string) (RankingDef, bool) {
+	for _, def := range rankingDefs.Load() {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return RankingDef{}, false
 }
 
 type BatchRankingService struct {
-	artSvc    ArticleService
-	intrSvc   service.InteractiveService
-	repo      repository.RankingRepository
-	batchSize int
-	n         int
-	biz       string
-	// scoreFunc 不能返回负数
-	scoreFunc func(t time.Time, likeCnt int64) float64
+	artSvc  ArticleService
+	artRepo repository.ArticleRepository
+	intrSvc service.InteractiveService
+	repo    repository.RankingRepository
+	// moderationRepo 用来判断是不是审核员
+	moderationRepo repository.ModerationRepository
+	// snapshotSvc 隔一段时间存一份热榜的快照
+	snapshotSvc RankingSnapshotService
+	batchSize   int
+	n           int
+	biz         string
+	l           logger.Logger
 }
 
 func NewBatchRankingService(artSvc ArticleService,
+	artRepo repository.ArticleRepository,
 	intrSvc service.InteractiveService,
-	repo repository.RankingRepository) RankingService {
-	return &BatchRankingService{
-		artSvc:    artSvc,
-		intrSvc:   intrSvc,
-		repo:      repo,
-		n:         100,
-		batchSize: 100,
-		biz:       "article",
-		scoreFunc: func(t time.Time, likeCnt int64) float64 {
-			sec := time.Since(t).Seconds()
-			return float64(likeCnt-1) / math.Pow(float64(sec+2), 1.5)
-		},
+	repo repository.RankingRepository,
+	moderationRepo repository.ModerationRepository,
+	snapshotSvc RankingSnapshotService,
+	l logger.Logger) (RankingService, error) {
+	// viper读取，打分策略可以在运行时通过配置切换
+	cfg, err := LoadRankingScoreConfig()
+	if err == nil {
+		err = UpdateRankingScorer(cfg)
+	}
+	if err != nil {
+		return nil, err
 	}
+	err = UpdateRankingMode(viper.GetString("ranking.mode"))
+	if err != nil {
+		return nil, err
+	}
+	err = UpdateRankingTags(viper.GetStringSlice("ranking.tags"))
+	if err != nil {
+		return nil, err
+	}
+	return &BatchRankingService{
+		artSvc:         artSvc,
+		artRepo:        artRepo,
+		intrSvc:        intrSvc,
+		repo:           repo,
+		moderationRepo: moderationRepo,
+		snapshotSvc:    snapshotSvc,
+		l:              l,
+		n:              100,
+		batchSize:      100,
+		biz:            "article",
+	}, nil
 }
 
 func (svc *BatchRankingService) TopN(ctx context.Context) error {
-	arts, err := svc.topN(ctx)
+	if RankingMode.Load() == RankingModeStreaming {
+		return svc.correct(ctx)
+	}
+	// 一次计算里面用同一个策略，算到一半配置变了也不受影响
+	scorer, err := NewRankingScorer(RankingScoreCfg.Load())
+	if err != nil {
+		return err
+	}
+	// 和策略一样，一次计算里面用同一份热榜定义
+	defs := rankingDefs.Load()
+	res, err := svc.topN(ctx, scorer, defs, svc.n)
 	if err != nil {
 		return err
 	}
 	// 在这里，存起来
-	return svc.repo.ReplaceTopN(ctx, arts)
+	now := time.Now()
+	for _, def := range defs {
+		ranking := domain.Ranking{
+			Name:  def.Name,
+			Items: res[def.Name],
+			Ctime: now,
+		}
+		err = svc.repo.ReplaceTopN(ctx, ranking)
+		if err != nil {
+			return err
+		}
+		saveSnapshot(ctx, svc.snapshotSvc, ranking, svc.l)
+	}
+	return nil
+}
+
+// saveSnapshot 快照存不下来不影响热榜
+func saveSnapshot(ctx context.Context, svc RankingSnapshotService, ranking domain.Ranking, l logger.Logger) {
+	err := svc.Save(ctx, ranking)
+	if err != nil {
+		l.Error("保存热榜快照失败", logger.Error(err),
+			logger.String("name", ranking.Name))
+	}
+}
+
+// correct 实时模式下全量扫描只用来修正实时热榜的分数，
+// 丢了的、重复的事件，撤回了的文章，都以这里算出来的为准。
+// 实时累加只能做指数衰减，所以这里固定用 exponential
+func (svc *BatchRankingService) correct(ctx context.Context) error {
+	cfg := RankingScoreCfg.Load()
+	cfg.Strategy = RankingScorerExponential
+	scorer, err := NewRankingScorer(cfg)
+	if err != nil {
+		return err
+	}
+	// 所有热榜共用一份分数，候选文章要覆盖最长的窗口，过滤留到组装热榜的时候再做
+	all := RankingDef{Name: "all", Window: maxRankingWindow(rankingDefs.Load())}
+	res, err := svc.topN(ctx, scorer, []RankingDef{all}, rankingCandidates)
+	if err != nil {
+		return err
+	}
+	// 扫描期间到达的事件会被覆盖掉，差的这一点等实时累加补回来
+	return svc.repo.ResetScores(ctx, res[all.Name], cfg.HalfLife)
+}
+
+func (svc *BatchRankingService) GetTopN(ctx context.Context, name string, offset, limit int) (domain.Ranking, int, error) {
+	if name == "" {
+		name = RankingDefault
+	}
+	if _, ok := findRankingDef(name); !ok {
+		return domain.Ranking{}, 0, fmt.Errorf("%w: %s", ErrUnknownRanking, name)
+	}
+	ranking, err := svc.repo.GetTopN(ctx, name)
+	if err != nil {
+		return domain.Ranking{}, 0, err
+	}
+	total := len(ranking.Items)
+	if offset >= total {
+		return domain.Ranking{Name: name, Ctime: ranking.Ctime}, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return domain.Ranking{
+		Name:  name,
+		Items: ranking.Items[offset:end],
+		Ctime: ranking.Ctime,
+	}, total, nil
+}
 
+func (svc *BatchRankingService) Explain(ctx context.Context, uid int64, id int64, strategy string) (domain.RankingScore, error) {
+	level, err := svc.moderationRepo.GetTrustLevel(ctx, uid)
+	if err != nil {
+		return domain.RankingScore{}, err
+	}
+	// 计算过程里有各项计数和打分参数，不对普通用户开放
+	if !level.CanModerate() {
+		return domain.RankingScore{}, ErrNotModerator
+	}
+	cfg := RankingScoreCfg.Load()
+	if RankingMode.Load() == RankingModeStreaming {
+		cfg.Strategy = RankingScorerExponential
+	}
+	if strategy != "" {
+		cfg.Strategy = strategy
+	}
+	scorer, err := NewRankingScorer(cfg)
+	if err != nil {
+		return domain.RankingScore{}, err
+	}
+	art, err := svc.artRepo.GetPublishedById(ctx, id, 0)
+	if err != nil {
+		return domain.RankingScore{}, err
+	}
+	// 撤回了的文章不会上榜，也不解释
+	if art.Id == 0 || art.Status != domain.ArticleStatusPublished {
+		return domain.RankingScore{}, ErrArticleNotFound
+	}
+	intrs, err := svc.intrSvc.GetByIds(ctx, svc.biz, []int64{id})
+	if err != nil {
+		return domain.RankingScore{}, err
+	}
+	return scorer.Score(art, intrs[id], time.Now()), nil
 }
 
-func (svc *BatchRankingService) topN(ctx context.Context) ([]domain.Article, error) {
-	// 我只取七天内的数据
+// topN 只扫描一遍，每个热榜各自取窗口之内、通过过滤的分数最高的 n 篇
+func (svc *BatchRankingService) topN(ctx context.Context, scorer RankingScorer,
+	defs []RankingDef, n int) (map[string][]domain.RankingItem, error) {
+	// 扫描到最长的那个窗口就可以停了
+	window := maxRankingWindow(defs)
 	now := time.Now()
 	// 先拿一批数据
-	offset := 0
+	// 用游标而不是 offset，这样扫描的过程中有新文章发表，也不会重复或者遗漏
+	var cursor domain.ArticleCursor
 	type ArticleWithScore struct {
 		article domain.Article
 		score   float64
 	}
 	// 这里可以用非并发安全
-	topN := queue.NewConcurrentPriorityQueue[ArticleWithScore](svc.n,
-		func(src ArticleWithScore, dst ArticleWithScore) int {
-			if src.score > dst.score {
-				return 1
-			} else if src.score == dst.score {
-				return 0
+	queues := make(map[string]*queue.ConcurrentPriorityQueue[ArticleWithScore], len(defs))
+	for _, def := range defs {
+		queues[def.Name] = queue.NewConcurrentPriorityQueue[ArticleWithScore](n,
+			func(src ArticleWithScore, dst ArticleWithScore) int {
+				if src.score > dst.score {
+					return 1
+				} else if src.score == dst.score {
+					return 0
+				} else {
+					return -1
+				}
+			})
+	}
+	push := func(topN *queue.ConcurrentPriorityQueue[ArticleWithScore], val ArticleWithScore) {
+		err := topN.Enqueue(val)
+		// 这种写法，要求 topN 已经满了
+		if err == queue.ErrOutOfCapacity {
+			// 拿到热度最低的
+			low, _ := topN.Dequeue()
+			if low.score < val.score {
+				err = topN.Enqueue(val)
+				if err != nil {
+					topN.Enqueue(low)
+				}
 			} else {
-				return -1
+				topN.Enqueue(low)
 			}
-		})
+		}
+	}
 
 	for {
 		// 这里拿了一批
-		arts, err := svc.artSvc.ListPub(ctx, now, offset, svc.batchSize)
+		arts, err := svc.artSvc.ListPub(ctx, now, cursor, svc.batchSize)
 		if err != nil {
 			return nil, err
 		}
@@ -89,59 +352,49 @@ func (svc *BatchRankingService) topN(ctx context.Context) ([]domain.Article, err
 			return nil, err
 		}
 
-		// 合并计算 score
-		// 排序
+		// 合并计算 score，同一篇文章只算一次，再分到各个热榜里
 		for _, art := range arts {
-			intr := intrs[art.Id]
-			//if !ok {
-			//	// 你都没有，肯定不可能是热榜
-			//	continue
-			//}
-			score := svc.scoreFunc(art.Utime, intr.LikeCnt)
-			// 我要考虑，我这个 score 在不在前一百名
-			// 拿到热度最低的
-			err = topN.Enqueue(ArticleWithScore{
-				article: art,
-				score:   score,
-			})
-			// 这种写法，要求 topN 已经满了
-			if err == queue.ErrOutOfCapacity {
-				val, _ := topN.Dequeue()
-				if val.score < score {
-					err = topN.Enqueue(ArticleWithScore{
+			var val *ArticleWithScore
+			for _, def := range defs {
+				if !def.accept(art, now) {
+					continue
+				}
+				if val == nil {
+					val = &ArticleWithScore{
 						article: art,
-						score:   score,
-					})
-
-					if err != nil {
-						topN.Enqueue(val)
+						score:   scorer.Score(art, intrs[art.Id], now).Score,
 					}
-				} else {
-					topN.Enqueue(val)
 				}
+				push(queues[def.Name], *val)
 			}
 		}
 
 		// 一批已经处理完了，问题来了，我要不要进入下一批？我怎么知道还有没有？
 		if len(arts) < svc.batchSize ||
-			now.Sub(arts[len(arts)-1].Utime).Hours() > 7*24 {
+			now.Sub(arts[len(arts)-1].Utime) > window {
 			// 我这一批都没取够，我当然可以肯定没有下一批了
-			// 又或者已经取到了七天之前的数据了，说明可以中断了
+			// 又或者已经取到了窗口之前的数据了，说明可以中断了
 			break
 		}
-		// 这边要更新 offset
-		offset += len(arts)
-	}
-	// 最后得出结果
-	res := make([]domain.Article, svc.n)
-	// 热度从低到高
-	for i := svc.n - 1; i >= 0; i-- {
-		val, err := topN.Dequeue()
-		if err != nil {
-			// 说明取完了，不够 n
-			break
+		// 这边要更新游标
+		cursor = arts[len(arts)-1].Cursor()
+	}
+	// 最后得出结果，不够 n 的时候队列里有多少就是多少
+	res := make(map[string][]domain.RankingItem, len(defs))
+	for name, topN := range queues {
+		items := make([]domain.RankingItem, topN.Len())
+		// 热度从低到高
+		for i := len(items) - 1; i >= 0; i-- {
+			val, err := topN.Dequeue()
+			if err != nil {
+				break
+			}
+			items[i] = domain.RankingItem{
+				Article: val.article,
+				Score:   val.score,
+			}
 		}
-		res[i] = val.article
+		res[name] = items
 	}
 	return res, nil
 
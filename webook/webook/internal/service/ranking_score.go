@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	domain2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/ecodeclub/ekit/syncx/atomicx"
+	"github.com/spf13/viper"
+	"math"
+	"time"
+)
+
+const (
+	// RankingScorerHackerNews (互动分 - 1) / (小时数 + 2) ^ gravity
+	RankingScorerHackerNews = "hacker_news"
+	// RankingScorerExponential 互动分 * 0.5 ^ (年龄 / 半衰期)
+	RankingScorerExponential = "exponential"
+)
+
+var (
+	ErrUnknownRankingScorer      = errors.New("未知的热榜打分策略")
+	ErrInvalidRankingScoreConfig = errors.New("热榜打分参数不合法")
+)
+
+// RankingScorer 热榜的打分策略
+type RankingScorer interface {
+	Name() string
+	// Score 算出一篇文章的热度，连同计算过程一起返回，分数不能是负数
+	Score(art domain.Article, intr domain2.Interactive, now time.Time) domain.RankingScore
+}
+
+// RankingScoreConfig 对应配置文件里的 ranking.score
+type RankingScoreConfig struct {
+	Strategy      string
+	ReadWeight    float64
+	LikeWeight    float64
+	CollectWeight float64
+	// Gravity hacker_news 用，越大旧文章掉得越快
+	Gravity float64
+	// HalfLife exponential 用，每过这么久热度减半
+	HalfLife time.Duration
+}
+
+// DefaultRankingScoreConfig 只看点赞，形式上和最早写死的公式一样，
+// 不过最早的公式里年龄用的是秒，这里改成了小时，同样的 gravity 下旧文章掉得没那么快
+func DefaultRankingScoreConfig() RankingScoreConfig {
+	return RankingScoreConfig{
+		Strategy:   RankingScorerHackerNews,
+		LikeWeight: 1,
+		Gravity:    1.5,
+		HalfLife:   time.Hour * 24,
+	}
+}
+
+// RankingScoreCfg 当前生效的打分配置，配置变更的时候整个换掉
+var RankingScoreCfg = atomicx.NewValueOf(DefaultRankingScoreConfig())
+
+// LoadRankingScoreConfig 从 viper 里读，没有配的项用默认值
+func LoadRankingScoreConfig() (RankingScoreConfig, error) {
+	cfg := DefaultRankingScoreConfig()
+	err := viper.UnmarshalKey("ranking.score", &cfg)
+	return cfg, err
+}
+
+// UpdateRankingScorer 配错了就保持原来的配置
+func UpdateRankingScorer(cfg RankingScoreConfig) error {
+	_, err := NewRankingScorer(cfg)
+	if err != nil {
+		return err
+	}
+	RankingScoreCfg.Store(cfg)
+	return nil
+}
+
+func NewRankingScorer(cfg RankingScoreConfig) (RankingScorer, error) {
+	if cfg.ReadWeight < 0 || cfg.LikeWeight < 0 || cfg.CollectWeight < 0 {
+		return nil, fmt.Errorf("%w: 权重不能是负数", ErrInvalidRankingScoreConfig)
+	}
+	switch cfg.Strategy {
+	case RankingScorerHackerNews:
+		if cfg.Gravity <= 0 {
+			return nil, fmt.Errorf("%w: gravity 必须大于 0", ErrInvalidRankingScoreConfig)
+		}
+		return &hackerNewsScorer{cfg: cfg}, nil
+	case RankingScorerExponential:
+		if cfg.HalfLife <= 0 {
+			return nil, fmt.Errorf("%w: halfLife 必须大于 0", ErrInvalidRankingScoreConfig)
+		}
+		return &exponentialScorer{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRankingScorer, cfg.Strategy)
+	}
+}
+
+// newRankingScore 各个策略共用的部分：计数、互动分和文章年龄
+func newRankingScore(name string, cfg RankingScoreConfig,
+	art domain.Article, intr domain2.Interactive, now time.Time) domain.RankingScore {
+	age := now.Sub(art.Utime)
+	if age < 0 {
+		// 机器之间时钟不一致
+		age = 0
+	}
+	return domain.RankingScore{
+		Strategy:   name,
+		ReadCnt:    intr.ReadCnt,
+		LikeCnt:    intr.LikeCnt,
+		CollectCnt: intr.CollectCnt,
+		Points: cfg.ReadWeight*float64(intr.ReadCnt) +
+			cfg.LikeWeight*float64(intr.LikeCnt) +
+			cfg.CollectWeight*float64(intr.CollectCnt),
+		Age: age,
+	}
+}
+
+type hackerNewsScorer struct {
+	cfg RankingScoreConfig
+}
+
+func (s *hackerNewsScorer) Name() string {
+	return RankingScorerHackerNews
+}
+
+func (s *hackerNewsScorer) Score(art domain.Article, intr domain2.Interactive, now time.Time) domain.RankingScore {
+	res := newRankingScore(s.Name(), s.cfg, art, intr, now)
+	// 和 HN 一样减掉一分，只有作者自己互动的文章上不了榜
+	res.Points -= 1
+	res.Decay = 1 / math.Pow(res.Age.Hours()+2, s.cfg.Gravity)
+	res.Score = math.Max(res.Points, 0) * res.Decay
+	res.Formula = fmt.Sprintf("(%g*read + %g*like + %g*collect - 1) / (hours + 2)^%g",
+		s.cfg.ReadWeight, s.cfg.LikeWeight, s.cfg.CollectWeight, s.cfg.Gravity)
+	return res
+}
+
+type exponentialScorer struct {
+	cfg RankingScoreConfig
+}
+
+func (s *exponentialScorer) Name() string {
+	return RankingScorerExponential
+}
+
+func (s *exponentialScorer) Score(art domain.Article, intr domain2.Interactive, now time.Time) domain.RankingScore {
+	res := newRankingScore(s.Name(), s.cfg, art, intr, now)
+	res.Decay = math.Pow(0.5, float64(res.Age)/float64(s.cfg.HalfLife))
+	res.Score = math.Max(res.Points, 0) * res.Decay
+	res.Formula = fmt.Sprintf("(%g*read + %g*like + %g*collect) * 0.5^(age / %s)",
+		s.cfg.ReadWeight, s.cfg.LikeWeight, s.cfg.CollectWeight, s.cfg.HalfLife)
+	return res
+}
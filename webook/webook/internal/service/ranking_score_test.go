@@ -0,0 +1,218 @@
+package service
+
+import (
+	domain2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNewRankingScorer(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cfg      func(cfg RankingScoreConfig) RankingScoreConfig
+		wantName string
+		wantErr  error
+	}{
+		{
+			name:     "默认配置",
+			cfg:      func(cfg RankingScoreConfig) RankingScoreConfig { return cfg },
+			wantName: RankingScorerHackerNews,
+		},
+		{
+			name: "指数衰减",
+			cfg: func(cfg RankingScoreConfig) RankingScoreConfig {
+				cfg.Strategy = RankingScorerExponential
+				return cfg
+			},
+			wantName: RankingScorerExponential,
+		},
+		{
+			name: "未知的策略",
+			cfg: func(cfg RankingScoreConfig) RankingScoreConfig {
+				cfg.Strategy = "abc"
+				return cfg
+			},
+			wantErr: ErrUnknownRankingScorer,
+		},
+		{
+			name: "权重是负数",
+			cfg: func(cfg RankingScoreConfig) RankingScoreConfig {
+				cfg.ReadWeight = -1
+				return cfg
+			},
+			wantErr: ErrInvalidRankingScoreConfig,
+		},
+		{
+			name: "gravity 不大于 0",
+			cfg: func(cfg RankingScoreConfig) RankingScoreConfig {
+				cfg.Gravity = 0
+				return cfg
+			},
+			wantErr: ErrInvalidRankingScoreConfig,
+		},
+		{
+			name: "半衰期不大于 0",
+			cfg: func(cfg RankingScoreConfig) RankingScoreConfig {
+				cfg.Strategy = RankingScorerExponential
+				cfg.HalfLife = 0
+				return cfg
+			},
+			wantErr: ErrInvalidRankingScoreConfig,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scorer, err := NewRankingScorer(tc.cfg(DefaultRankingScoreConfig()))
+			assert.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr == nil {
+				assert.Equal(t, tc.wantName, scorer.Name())
+			}
+		})
+	}
+}
+
+func TestHackerNewsScorer_Score(t *testing.T) {
+	now := time.Now()
+	cfg := RankingScoreConfig{
+		Strategy:      RankingScorerHackerNews,
+		ReadWeight:    0.1,
+		LikeWeight:    1,
+		CollectWeight: 2,
+		Gravity:       1,
+	}
+	testCases := []struct {
+		name       string
+		age        time.Duration
+		intr       domain2.Interactive
+		wantPoints float64
+		wantDecay  float64
+		wantScore  float64
+	}{
+		{
+			name:       "刚发表",
+			intr:       domain2.Interactive{ReadCnt: 10, LikeCnt: 3, CollectCnt: 1},
+			wantPoints: 5,
+			wantDecay:  0.5,
+			wantScore:  2.5,
+		},
+		{
+			name:       "两个小时之前",
+			age:        time.Hour * 2,
+			intr:       domain2.Interactive{LikeCnt: 9},
+			wantPoints: 8,
+			wantDecay:  0.25,
+			wantScore:  2,
+		},
+		{
+			// 只有作者自己点了赞
+			name:       "减一之后是 0",
+			intr:       domain2.Interactive{LikeCnt: 1},
+			wantPoints: 0,
+			wantDecay:  0.5,
+			wantScore:  0,
+		},
+		{
+			name:       "没有互动也不是负数",
+			wantPoints: -1,
+			wantDecay:  0.5,
+			wantScore:  0,
+		},
+		{
+			// 机器之间的时钟不一致
+			name:       "更新时间在未来",
+			age:        -time.Hour,
+			intr:       domain2.Interactive{LikeCnt: 3},
+			wantPoints: 2,
+			wantDecay:  0.5,
+			wantScore:  1,
+		},
+	}
+	scorer, err := NewRankingScorer(cfg)
+	require.NoError(t, err)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := scorer.Score(domain.Article{Utime: now.Add(-tc.age)}, tc.intr, now)
+			assert.Equal(t, RankingScorerHackerNews, res.Strategy)
+			assert.InDelta(t, tc.wantPoints, res.Points, 1e-9)
+			assert.InDelta(t, tc.wantDecay, res.Decay, 1e-9)
+			assert.InDelta(t, tc.wantScore, res.Score, 1e-9)
+			assert.GreaterOrEqual(t, res.Age, time.Duration(0))
+		})
+	}
+}
+
+func TestExponentialScorer_Score(t *testing.T) {
+	now := time.Now()
+	cfg := RankingScoreConfig{
+		Strategy:      RankingScorerExponential,
+		ReadWeight:    0.1,
+		LikeWeight:    1,
+		CollectWeight: 2,
+		HalfLife:      time.Hour * 24,
+	}
+	testCases := []struct {
+		name       string
+		age        time.Duration
+		intr       domain2.Interactive
+		wantPoints float64
+		wantDecay  float64
+		wantScore  float64
+	}{
+		{
+			name:       "刚发表",
+			intr:       domain2.Interactive{ReadCnt: 10, LikeCnt: 3, CollectCnt: 1},
+			wantPoints: 6,
+			wantDecay:  1,
+			wantScore:  6,
+		},
+		{
+			name:       "一个半衰期",
+			age:        time.Hour * 24,
+			intr:       domain2.Interactive{LikeCnt: 8},
+			wantPoints: 8,
+			wantDecay:  0.5,
+			wantScore:  4,
+		},
+		{
+			name:       "两个半衰期",
+			age:        time.Hour * 48,
+			intr:       domain2.Interactive{LikeCnt: 8},
+			wantPoints: 8,
+			wantDecay:  0.25,
+			wantScore:  2,
+		},
+		{
+			name:      "没有互动",
+			wantDecay: 1,
+		},
+	}
+	scorer, err := NewRankingScorer(cfg)
+	require.NoError(t, err)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := scorer.Score(domain.Article{Utime: now.Add(-tc.age)}, tc.intr, now)
+			assert.Equal(t, RankingScorerExponential, res.Strategy)
+			assert.InDelta(t, tc.wantPoints, res.Points, 1e-9)
+			assert.InDelta(t, tc.wantDecay, res.Decay, 1e-9)
+			assert.InDelta(t, tc.wantScore, res.Score, 1e-9)
+		})
+	}
+}
+
+func TestUpdateRankingScorer(t *testing.T) {
+	t.Cleanup(func() {
+		RankingScoreCfg.Store(DefaultRankingScoreConfig())
+	})
+	cfg := DefaultRankingScoreConfig()
+	cfg.Strategy = RankingScorerExponential
+	require.NoError(t, UpdateRankingScorer(cfg))
+	assert.Equal(t, cfg, RankingScoreCfg.Load())
+
+	// 配错了保持原来的
+	err := UpdateRankingScorer(RankingScoreConfig{Strategy: "abc"})
+	assert.ErrorIs(t, err, ErrUnknownRankingScorer)
+	assert.Equal(t, cfg, RankingScoreCfg.Load())
+}
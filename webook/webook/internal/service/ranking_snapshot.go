@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/ecodeclub/ekit/syncx"
+	"sort"
+	"time"
+)
+
+// RankingSnapshotService 热榜每次重新计算都会把原来的覆盖掉，
+// 这里隔一段时间存一份快照，用来看一篇文章的名次变化和上升最快的文章
+type RankingSnapshotService interface {
+	// Save 距离这个热榜上一次快照不到 interval 的时候直接跳过
+	Save(ctx context.Context, ranking domain.Ranking) error
+	// History 一篇文章在某个热榜上最近 limit 次快照里的名次，从新到旧，没上榜的快照不算
+	History(ctx context.Context, name string, aid int64, limit int) ([]domain.RankingPosition, error)
+	// Movers 对比最近两次快照，名次变化最大的 limit 篇文章
+	Movers(ctx context.Context, name string, limit int) (domain.RankingMovers, error)
+	// Purge 删掉 before 之前的快照，返回删掉的行数
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+type rankingSnapshotService struct {
+	repo    repository.RankingSnapshotRepository
+	artRepo repository.ArticleRepository
+	// lastSaved 每个热榜上一次快照的时间，没有的话去数据库里查
+	lastSaved *syncx.Map[string, time.Time]
+	interval  time.Duration
+	l         logger.Logger
+}
+
+func NewRankingSnapshotService(repo repository.RankingSnapshotRepository,
+	artRepo repository.ArticleRepository, l logger.Logger) RankingSnapshotService {
+	return &rankingSnapshotService{
+		repo:      repo,
+		artRepo:   artRepo,
+		lastSaved: &syncx.Map[string, time.Time]{},
+		interval:  time.Hour,
+		l:         l,
+	}
+}
+
+func (s *rankingSnapshotService) Save(ctx context.Context, ranking domain.Ranking) error {
+	last, ok := s.lastSaved.Load(ranking.Name)
+	if !ok {
+		times, err := s.repo.ListTimes(ctx, ranking.Name, 1)
+		if err != nil {
+			return err
+		}
+		if len(times) > 0 {
+			last = times[0]
+		}
+	}
+	if ranking.Ctime.Sub(last) < s.interval {
+		s.lastSaved.Store(ranking.Name, last)
+		return nil
+	}
+	err := s.repo.Save(ctx, ranking)
+	if err != nil {
+		return err
+	}
+	s.lastSaved.Store(ranking.Name, ranking.Ctime)
+	return nil
+}
+
+func (s *rankingSnapshotService) History(ctx context.Context, name string, aid int64, limit int) ([]domain.RankingPosition, error) {
+	if _, ok := findRankingDef(name); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRanking, name)
+	}
+	return s.repo.History(ctx, name, aid, limit)
+}
+
+func (s *rankingSnapshotService) Movers(ctx context.Context, name string, limit int) (domain.RankingMovers, error) {
+	if _, ok := findRankingDef(name); !ok {
+		return domain.RankingMovers{}, fmt.Errorf("%w: %s", ErrUnknownRanking, name)
+	}
+	res := domain.RankingMovers{Name: name}
+	times, err := s.repo.ListTimes(ctx, name, 2)
+	if err != nil || len(times) < 2 {
+		// 不到两次快照，没有可以对比的
+		return res, err
+	}
+	res.To, res.From = times[0], times[1]
+	cur, err := s.repo.Get(ctx, name, res.To)
+	if err != nil {
+		return res, err
+	}
+	prev, err := s.repo.Get(ctx, name, res.From)
+	if err != nil {
+		return res, err
+	}
+
+	moves := diffRanking(prev, cur)
+	sort.SliceStable(moves, func(i, j int) bool {
+		di, dj := abs(moves[i].Delta), abs(moves[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		// 变化一样大的，上升的排在前面
+		return moves[i].Delta > moves[j].Delta
+	})
+
+	// 删掉了的文章查不到，所以全部查一遍再截断
+	ids := make([]int64, 0, len(moves))
+	for _, m := range moves {
+		ids = append(ids, m.Article.Id)
+	}
+	arts, err := s.artRepo.GetPubByIds(ctx, ids)
+	if err != nil {
+		return res, err
+	}
+	artMap := make(map[int64]domain.Article, len(arts))
+	for _, art := range arts {
+		artMap[art.Id] = art
+	}
+	res.Moves = make([]domain.RankingMove, 0, limit)
+	for _, m := range moves {
+		if len(res.Moves) == limit {
+			break
+		}
+		art, ok := artMap[m.Article.Id]
+		if !ok || art.Status != domain.ArticleStatusPublished {
+			continue
+		}
+		m.Article = art
+		res.Moves = append(res.Moves, m)
+	}
+	return res, nil
+}
+
+// diffRanking 名次没有变化的不返回
+func diffRanking(prev, cur domain.Ranking) []domain.RankingMove {
+	type position struct {
+		rank  int
+		score float64
+	}
+	// 不在榜上的当作是排在两次快照里较长的那一次的最后一名后面
+	off := len(prev.Items) + 1
+	if len(cur.Items) >= off {
+		off = len(cur.Items) + 1
+	}
+	prevPos := make(map[int64]position, len(prev.Items))
+	for i, item := range prev.Items {
+		prevPos[item.Article.Id] = position{rank: i + 1, score: item.Score}
+	}
+	res := make([]domain.RankingMove, 0, len(cur.Items))
+	for i, item := range cur.Items {
+		m := domain.RankingMove{
+			Article: domain.Article{Id: item.Article.Id},
+			Rank:    i + 1,
+			Score:   item.Score,
+		}
+		if p, ok := prevPos[item.Article.Id]; ok {
+			m.PrevRank, m.PrevScore = p.rank, p.score
+			m.Delta = p.rank - m.Rank
+			delete(prevPos, item.Article.Id)
+		} else {
+			m.Delta = off - m.Rank
+		}
+		if m.Delta != 0 {
+			res = append(res, m)
+		}
+	}
+	// 剩下的是掉出热榜的
+	for aid, p := range prevPos {
+		res = append(res, domain.RankingMove{
+			Article:   domain.Article{Id: aid},
+			PrevRank:  p.rank,
+			PrevScore: p.score,
+			Delta:     p.rank - off,
+		})
+	}
+	return res
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func (s *rankingSnapshotService) Purge(ctx context.Context, before time.Time) (int, error) {
+	const batch = 1000
+	total := 0
+	for {
+		cnt, err := s.repo.DeleteBefore(ctx, before, batch)
+		total += int(cnt)
+		if err != nil || cnt < batch {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+// rankingOf 按照 ids 的顺序，分数从高到低
+func rankingOf(ids ...int64) domain.Ranking {
+	items := make([]domain.RankingItem, 0, len(ids))
+	for i, id := range ids {
+		items = append(items, domain.RankingItem{
+			Article: domain.Article{Id: id},
+			Score:   float64(len(ids) - i),
+		})
+	}
+	return domain.Ranking{Name: RankingWeekly, Items: items}
+}
+
+func TestDiffRanking(t *testing.T) {
+	type move struct {
+		id       int64
+		rank     int
+		prevRank int
+		delta    int
+	}
+	testCases := []struct {
+		name string
+		prev domain.Ranking
+		cur  domain.Ranking
+		want []move
+	}{
+		{
+			name: "没有变化",
+			prev: rankingOf(1, 2, 3),
+			cur:  rankingOf(1, 2, 3),
+			want: []move{},
+		},
+		{
+			name: "交换名次",
+			prev: rankingOf(1, 2, 3),
+			cur:  rankingOf(2, 1, 3),
+			want: []move{
+				{id: 2, rank: 1, prevRank: 2, delta: 1},
+				{id: 1, rank: 2, prevRank: 1, delta: -1},
+			},
+		},
+		{
+			// 新上榜的当作是从较长的那次快照的最后一名后面升上来的
+			name: "新上榜",
+			prev: rankingOf(1, 2),
+			cur:  rankingOf(3, 1, 2),
+			want: []move{
+				{id: 3, rank: 1, delta: 3},
+				{id: 1, rank: 2, prevRank: 1, delta: -1},
+				{id: 2, rank: 3, prevRank: 2, delta: -1},
+			},
+		},
+		{
+			name: "掉出热榜",
+			prev: rankingOf(1, 2, 3),
+			cur:  rankingOf(2, 3),
+			want: []move{
+				{id: 2, rank: 1, prevRank: 2, delta: 1},
+				{id: 3, rank: 2, prevRank: 3, delta: 1},
+				{id: 1, prevRank: 1, delta: -3},
+			},
+		},
+		{
+			name: "第一次快照",
+			cur:  rankingOf(1, 2),
+			want: []move{
+				{id: 1, rank: 1, delta: 2},
+				{id: 2, rank: 2, delta: 1},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			moves := diffRanking(tc.prev, tc.cur)
+			got := make([]move, 0, len(moves))
+			for _, m := range moves {
+				got = append(got, move{id: m.Article.Id, rank: m.Rank, prevRank: m.PrevRank, delta: m.Delta})
+			}
+			// 掉出热榜的是遍历 map 加进去的，顺序不固定
+			assert.ElementsMatch(t, tc.want, got)
+		})
+	}
+}
+
+type fakeSnapshotRepo struct {
+	repository.RankingSnapshotRepository
+	times     []time.Time
+	snapshots map[time.Time]domain.Ranking
+	saved     []time.Time
+	listCalls int
+}
+
+func (r *fakeSnapshotRepo) ListTimes(ctx context.Context, name string, limit int) ([]time.Time, error) {
+	r.listCalls++
+	if len(r.times) > limit {
+		return r.times[:limit], nil
+	}
+	return r.times, nil
+}
+
+func (r *fakeSnapshotRepo) Get(ctx context.Context, name string, ctime time.Time) (domain.Ranking, error) {
+	return r.snapshots[ctime], nil
+}
+
+func (r *fakeSnapshotRepo) Save(ctx context.Context, ranking domain.Ranking) error {
+	r.saved = append(r.saved, ranking.Ctime)
+	return nil
+}
+
+func TestRankingSnapshotService_Save(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name      string
+		times     []time.Time
+		ctimes    []time.Time
+		wantSaved []time.Time
+	}{
+		{
+			name:      "第一次快照",
+			ctimes:    []time.Time{now},
+			wantSaved: []time.Time{now},
+		},
+		{
+			name:   "离上一次不到一个小时",
+			times:  []time.Time{now.Add(-time.Minute * 30)},
+			ctimes: []time.Time{now},
+		},
+		{
+			name:      "离上一次超过一个小时",
+			times:     []time.Time{now.Add(-time.Hour * 2)},
+			ctimes:    []time.Time{now},
+			wantSaved: []time.Time{now},
+		},
+		{
+			// 第二次用的是内存里记下来的时间
+			name:      "连续计算",
+			ctimes:    []time.Time{now, now.Add(time.Minute * 10), now.Add(time.Hour)},
+			wantSaved: []time.Time{now, now.Add(time.Hour)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeSnapshotRepo{times: tc.times}
+			svc := NewRankingSnapshotService(repo, nil, logger.NewZapLogger(zap.NewNop(), false))
+			for _, ctime := range tc.ctimes {
+				err := svc.Save(context.Background(), domain.Ranking{Name: RankingWeekly, Ctime: ctime})
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantSaved, repo.saved)
+			assert.Equal(t, 1, repo.listCalls)
+		})
+	}
+}
+
+func TestRankingSnapshotService_Movers(t *testing.T) {
+	now := time.Now()
+	from, to := now.Add(-time.Hour), now
+	repo := &fakeSnapshotRepo{
+		times: []time.Time{to, from},
+		snapshots: map[time.Time]domain.Ranking{
+			from: rankingOf(1, 2, 3, 4),
+			to:   rankingOf(2, 1, 5, 4, 3),
+		},
+	}
+	artRepo := &fakePubArticleRepo{arts: map[int64]domain.Article{
+		1: {Id: 1, Status: domain.ArticleStatusPublished},
+		2: {Id: 2, Status: domain.ArticleStatusPublished},
+		3: {Id: 3, Status: domain.ArticleStatusPublished},
+		// 5 已经撤回了
+		5: {Id: 5, Status: domain.ArticleStatusPrivate},
+	}}
+	svc := NewRankingSnapshotService(repo, artRepo, logger.NewZapLogger(zap.NewNop(), false))
+
+	res, err := svc.Movers(context.Background(), RankingWeekly, 3)
+	require.NoError(t, err)
+	assert.Equal(t, from, res.From)
+	assert.Equal(t, to, res.To)
+	ids := make([]int64, 0, len(res.Moves))
+	for _, m := range res.Moves {
+		ids = append(ids, m.Article.Id)
+	}
+	// 5 升了 3 名但是撤回了，3 降了 2 名，
+	// 2 和 1 都是变了 1 名，上升的排在前面
+	assert.Equal(t, []int64{3, 2, 1}, ids)
+
+	_, err = svc.Movers(context.Background(), "abc", 3)
+	assert.ErrorIs(t, err, ErrUnknownRanking)
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"go.uber.org/atomic"
+	"time"
+)
+
+const (
+	// RankingModeBatch 每次都全量扫描一遍算热榜
+	RankingModeBatch = "batch"
+	// RankingModeStreaming 消费互动事件实时累加分数，全量扫描只用来定时修正
+	RankingModeStreaming = "streaming"
+)
+
+// rankingCandidates 实时热榜保留的候选文章数。比 n 多留一些，
+// 不然刚被挤出去的文章再有互动的时候，之前攒的分数就没了
+const rankingCandidates = 1000
+
+var ErrUnknownRankingMode = errors.New("未知的热榜模式")
+
+var RankingMode = atomic.NewString(RankingModeBatch)
+
+// UpdateRankingMode 配错了就保持原来的模式，不配就是 batch
+func UpdateRankingMode(mode string) error {
+	switch mode {
+	case "":
+		RankingMode.Store(RankingModeBatch)
+		return nil
+	case RankingModeBatch, RankingModeStreaming:
+		RankingMode.Store(mode)
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownRankingMode, mode)
+	}
+}
+
+// RankingStreamService 实时热榜，只在 streaming 模式下工作
+type RankingStreamService interface {
+	// Record 累加一批互动事件，找不到的、没有发表的、太旧的文章直接跳过
+	Record(ctx context.Context, evts []domain.RankingEvent) error
+	// Refresh 裁剪候选文章，每个热榜各自把分数最高的 n 篇组装好存起来
+	Refresh(ctx context.Context) error
+}
+
+type rankingStreamService struct {
+	repo        repository.RankingRepository
+	artRepo     repository.ArticleRepository
+	snapshotSvc RankingSnapshotService
+	n           int
+	l           logger.Logger
+}
+
+func NewRankingStreamService(repo repository.RankingRepository,
+	artRepo repository.ArticleRepository,
+	snapshotSvc RankingSnapshotService, l logger.Logger) RankingStreamService {
+	return &rankingStreamService{
+		repo:        repo,
+		artRepo:     artRepo,
+		snapshotSvc: snapshotSvc,
+		n:           100,
+		l:           l,
+	}
+}
+
+func (s *rankingStreamService) Record(ctx context.Context, evts []domain.RankingEvent) error {
+	if RankingMode.Load() != RankingModeStreaming {
+		return nil
+	}
+	// 同一篇文章的事件先合并，一批里面热门文章会有很多
+	merged := make(map[int64]domain.RankingEvent, len(evts))
+	ids := make([]int64, 0, len(evts))
+	for _, evt := range evts {
+		m, ok := merged[evt.Aid]
+		if !ok {
+			ids = append(ids, evt.Aid)
+		}
+		m.Aid = evt.Aid
+		m.ReadCnt += evt.ReadCnt
+		m.LikeCnt += evt.LikeCnt
+		m.CollectCnt += evt.CollectCnt
+		merged[evt.Aid] = m
+	}
+	// 衰减要用文章的 Utime，和全量计算保持一致
+	arts, err := s.artRepo.GetPubByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	cfg := RankingScoreCfg.Load()
+	// 最长的那个窗口，更早的文章不会上任何一个热榜
+	window := maxRankingWindow(rankingDefs.Load())
+	now := time.Now()
+	deltas := make([]domain.RankingDelta, 0, len(arts))
+	for _, art := range arts {
+		if art.Status != domain.ArticleStatusPublished || now.Sub(art.Utime) > window {
+			continue
+		}
+		m := merged[art.Id]
+		points := cfg.ReadWeight*float64(m.ReadCnt) +
+			cfg.LikeWeight*float64(m.LikeCnt) +
+			cfg.CollectWeight*float64(m.CollectCnt)
+		if points == 0 {
+			continue
+		}
+		deltas = append(deltas, domain.RankingDelta{
+			Aid:    art.Id,
+			Points: points,
+			Utime:  art.Utime,
+		})
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+	return s.repo.IncrScores(ctx, deltas, cfg.HalfLife)
+}
+
+func (s *rankingStreamService) Refresh(ctx context.Context) error {
+	if RankingMode.Load() != RankingModeStreaming {
+		return nil
+	}
+	err := s.repo.TrimScores(ctx, rankingCandidates)
+	if err != nil {
+		return err
+	}
+	// 所有热榜共用一份分数，按照各自的窗口和过滤条件挑
+	top, err := s.repo.TopScores(ctx, rankingCandidates)
+	if err != nil {
+		return err
+	}
+	if len(top) == 0 {
+		// 刚切换到实时模式，还没有修正过，先保留原来的热榜
+		s.l.Warn("实时热榜还没有数据")
+		return nil
+	}
+
+	now := time.Now()
+	defs := rankingDefs.Load()
+	res := make(map[string][]domain.RankingItem, len(defs))
+	// 分数是从高到低的，一段一段地查文章，所有热榜都满了就不用再往后查了
+	const chunk = 100
+	for start := 0; start < len(top) && !s.full(defs, res); start += chunk {
+		end := start + chunk
+		if end > len(top) {
+			end = len(top)
+		}
+		scores := make(map[int64]float64, end-start)
+		ids := make([]int64, 0, end-start)
+		for _, item := range top[start:end] {
+			scores[item.Article.Id] = item.Score
+			ids = append(ids, item.Article.Id)
+		}
+		arts, err := s.artRepo.GetPubByIds(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for _, art := range arts {
+			// 撤回了的文章要等下一次修正才会从分数里拿掉
+			if art.Status != domain.ArticleStatusPublished {
+				continue
+			}
+			for _, def := range defs {
+				if len(res[def.Name]) == s.n || !def.accept(art, now) {
+					continue
+				}
+				res[def.Name] = append(res[def.Name], domain.RankingItem{
+					Article: art,
+					Score:   scores[art.Id],
+				})
+			}
+		}
+	}
+
+	for _, def := range defs {
+		ranking := domain.Ranking{
+			Name:  def.Name,
+			Items: res[def.Name],
+			Ctime: now,
+		}
+		err = s.repo.ReplaceTopN(ctx, ranking)
+		if err != nil {
+			return err
+		}
+		saveSnapshot(ctx, s.snapshotSvc, ranking, s.l)
+	}
+	return nil
+}
+
+func (s *rankingStreamService) full(defs []RankingDef, res map[string][]domain.RankingItem) bool {
+	for _, def := range defs {
+		if len(res[def.Name]) < s.n {
+			return false
+		}
+	}
+	return true
+}
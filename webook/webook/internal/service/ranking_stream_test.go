@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type fakeRankingRepo struct {
+	repository.RankingRepository
+	top      []domain.RankingItem
+	replaced map[string][]int64
+	deltas   []domain.RankingDelta
+}
+
+func (r *fakeRankingRepo) IncrScores(ctx context.Context, deltas []domain.RankingDelta, halfLife time.Duration) error {
+	r.deltas = append(r.deltas, deltas...)
+	return nil
+}
+
+func (r *fakeRankingRepo) TrimScores(ctx context.Context, capacity int) error {
+	return nil
+}
+
+func (r *fakeRankingRepo) TopScores(ctx context.Context, n int) ([]domain.RankingItem, error) {
+	return r.top, nil
+}
+
+func (r *fakeRankingRepo) ReplaceTopN(ctx context.Context, ranking domain.Ranking) error {
+	ids := make([]int64, 0, len(ranking.Items))
+	for _, item := range ranking.Items {
+		ids = append(ids, item.Article.Id)
+	}
+	r.replaced[ranking.Name] = ids
+	return nil
+}
+
+// fakePubArticleRepo 记录 GetPubByIds 查了几次
+type fakePubArticleRepo struct {
+	repository.ArticleRepository
+	arts  map[int64]domain.Article
+	calls int
+}
+
+func (r *fakePubArticleRepo) GetPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error) {
+	r.calls++
+	res := make([]domain.Article, 0, len(ids))
+	for _, id := range ids {
+		if art, ok := r.arts[id]; ok {
+			res = append(res, art)
+		}
+	}
+	return res, nil
+}
+
+type fakeSnapshotService struct {
+	RankingSnapshotService
+	saved []string
+}
+
+func (s *fakeSnapshotService) Save(ctx context.Context, ranking domain.Ranking) error {
+	s.saved = append(s.saved, ranking.Name)
+	return nil
+}
+
+// useRankingDefs 测试里只用日榜和周榜
+func useRankingDefs(t *testing.T) {
+	rankingDefs.Store([]RankingDef{
+		{Name: RankingDaily, Window: time.Hour * 24},
+		{Name: RankingWeekly, Window: time.Hour * 24 * 7},
+	})
+	RankingMode.Store(RankingModeStreaming)
+	t.Cleanup(func() {
+		rankingDefs.Store(baseRankingDefs)
+		RankingMode.Store(RankingModeBatch)
+	})
+}
+
+func TestRankingStreamService_Refresh(t *testing.T) {
+	now := time.Now()
+	published := func(id int64, age time.Duration) domain.Article {
+		return domain.Article{Id: id, Status: domain.ArticleStatusPublished, Utime: now.Add(-age)}
+	}
+	topOf := func(ids ...int64) []domain.RankingItem {
+		res := make([]domain.RankingItem, 0, len(ids))
+		for i, id := range ids {
+			res = append(res, domain.RankingItem{
+				Article: domain.Article{Id: id},
+				Score:   float64(len(ids) - i),
+			})
+		}
+		return res
+	}
+	testCases := []struct {
+		name         string
+		mode         string
+		n            int
+		top          []domain.RankingItem
+		arts         map[int64]domain.Article
+		wantReplaced map[string][]int64
+		wantCalls    int
+	}{
+		{
+			name: "按照各自的窗口挑",
+			n:    10,
+			top:  topOf(1, 2, 3, 4),
+			arts: map[int64]domain.Article{
+				1: published(1, time.Hour*48),
+				2: published(2, time.Hour),
+				// 撤回了的
+				3: {Id: 3, Status: domain.ArticleStatusPrivate, Utime: now},
+				// 超过了最长的窗口
+				4: published(4, time.Hour*24*8),
+			},
+			wantReplaced: map[string][]int64{
+				RankingDaily:  {2},
+				RankingWeekly: {1, 2},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "第一段就都满了，不再往后查",
+			n:    1,
+			top:  topOf(manyIds(150)...),
+			arts: map[int64]domain.Article{
+				1:   published(1, time.Hour),
+				101: published(101, time.Hour),
+			},
+			wantReplaced: map[string][]int64{
+				RankingDaily:  {1},
+				RankingWeekly: {1},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "日榜没满，接着查下一段",
+			n:    1,
+			top:  topOf(manyIds(150)...),
+			arts: map[int64]domain.Article{
+				1:   published(1, time.Hour*48),
+				101: published(101, time.Hour),
+			},
+			wantReplaced: map[string][]int64{
+				RankingDaily:  {101},
+				RankingWeekly: {1},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:         "还没有数据，保留原来的热榜",
+			n:            10,
+			wantReplaced: map[string][]int64{},
+		},
+		{
+			name:         "不是实时模式",
+			mode:         RankingModeBatch,
+			n:            10,
+			top:          topOf(1),
+			arts:         map[int64]domain.Article{1: published(1, time.Hour)},
+			wantReplaced: map[string][]int64{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			useRankingDefs(t)
+			if tc.mode != "" {
+				RankingMode.Store(tc.mode)
+			}
+			repo := &fakeRankingRepo{top: tc.top, replaced: map[string][]int64{}}
+			artRepo := &fakePubArticleRepo{arts: tc.arts}
+			snapshotSvc := &fakeSnapshotService{}
+			svc := NewRankingStreamService(repo, artRepo, snapshotSvc,
+				logger.NewZapLogger(zap.NewNop(), false)).(*rankingStreamService)
+			svc.n = tc.n
+
+			err := svc.Refresh(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantReplaced, repo.replaced)
+			assert.Equal(t, tc.wantCalls, artRepo.calls)
+			assert.Len(t, snapshotSvc.saved, len(tc.wantReplaced))
+		})
+	}
+}
+
+// manyIds 1 到 n
+func manyIds(n int) []int64 {
+	res := make([]int64, 0, n)
+	for i := 1; i <= n; i++ {
+		res = append(res, int64(i))
+	}
+	return res
+}
+
+func TestRankingStreamService_Record(t *testing.T) {
+	useRankingDefs(t)
+	t.Cleanup(func() {
+		RankingScoreCfg.Store(DefaultRankingScoreConfig())
+	})
+	cfg := DefaultRankingScoreConfig()
+	cfg.ReadWeight, cfg.LikeWeight, cfg.CollectWeight = 0.5, 1, 2
+	RankingScoreCfg.Store(cfg)
+
+	now := time.Now()
+	artRepo := &fakePubArticleRepo{arts: map[int64]domain.Article{
+		1: {Id: 1, Status: domain.ArticleStatusPublished, Utime: now.Add(-time.Hour)},
+		2: {Id: 2, Status: domain.ArticleStatusPrivate, Utime: now},
+		3: {Id: 3, Status: domain.ArticleStatusPublished, Utime: now.Add(-time.Hour * 24 * 8)},
+		4: {Id: 4, Status: domain.ArticleStatusPublished, Utime: now},
+	}}
+	repo := &fakeRankingRepo{}
+	svc := NewRankingStreamService(repo, artRepo, &fakeSnapshotService{},
+		logger.NewZapLogger(zap.NewNop(), false))
+	err := svc.Record(context.Background(), []domain.RankingEvent{
+		{Aid: 1, ReadCnt: 1},
+		{Aid: 1, LikeCnt: 1},
+		{Aid: 1, CollectCnt: 1},
+		// 撤回了的、超过窗口的、没有分数的都跳过
+		{Aid: 2, LikeCnt: 1},
+		{Aid: 3, LikeCnt: 1},
+		{Aid: 4},
+		// 找不到的
+		{Aid: 5, LikeCnt: 1},
+	})
+	require.NoError(t, err)
+	require.Len(t, repo.deltas, 1)
+	assert.Equal(t, int64(1), repo.deltas[0].Aid)
+	assert.InDelta(t, 3.5, repo.deltas[0].Points, 1e-9)
+	// 一批事件只查一次文章
+	assert.Equal(t, 1, artRepo.calls)
+}
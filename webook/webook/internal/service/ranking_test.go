@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	domain2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/domain"
+	intrSvc "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestUpdateRankingTags(t *testing.T) {
+	t.Cleanup(func() {
+		rankingDefs.Store(baseRankingDefs)
+	})
+
+	err := UpdateRankingTags([]string{" go ", "go", "数据库"})
+	require.NoError(t, err)
+	assert.Len(t, rankingDefs.Load(), len(baseRankingDefs)+2)
+	_, ok := findRankingDef(RankingWeekly)
+	assert.True(t, ok)
+
+	def, ok := findRankingDef("tag:go")
+	require.True(t, ok)
+	now := time.Now()
+	assert.True(t, def.accept(domain.Article{Tags: []string{"gin", "go"}, Utime: now}, now))
+	assert.False(t, def.accept(domain.Article{Tags: []string{"gin"}, Utime: now}, now))
+	// 和周榜一样只看七天之内的
+	assert.False(t, def.accept(domain.Article{Tags: []string{"go"}, Utime: now.Add(-time.Hour * 24 * 8)}, now))
+	_, ok = findRankingDef("tag:数据库")
+	assert.True(t, ok)
+
+	// 配错了保持原来的热榜
+	err = UpdateRankingTags([]string{"a,b"})
+	assert.ErrorIs(t, err, ErrInvalidRankingTags)
+	_, ok = findRankingDef("tag:go")
+	assert.True(t, ok)
+
+	err = UpdateRankingTags(nil)
+	require.NoError(t, err)
+	_, ok = findRankingDef("tag:go")
+	assert.False(t, ok)
+}
+
+// fakeListPubService 按照 (utime, id) 倒序分页返回 arts
+type fakeListPubService struct {
+	ArticleService
+	arts  []domain.Article
+	calls int
+}
+
+func (s *fakeListPubService) ListPub(ctx context.Context, start time.Time,
+	cursor domain.ArticleCursor, limit int) ([]domain.Article, error) {
+	s.calls++
+	res := make([]domain.Article, 0, limit)
+	for _, art := range s.arts {
+		if !cursor.IsZero() && !art.Utime.Before(cursor.Utime) {
+			continue
+		}
+		if len(res) == limit {
+			break
+		}
+		res = append(res, art)
+	}
+	return res, nil
+}
+
+type fakeLikeCntService struct {
+	intrSvc.InteractiveService
+	likes map[int64]int64
+}
+
+func (s *fakeLikeCntService) GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain2.Interactive, error) {
+	res := make(map[int64]domain2.Interactive, len(ids))
+	for _, id := range ids {
+		res[id] = domain2.Interactive{LikeCnt: s.likes[id]}
+	}
+	return res, nil
+}
+
+func TestBatchRankingService_TopN(t *testing.T) {
+	rankingDefs.Store(baseRankingDefs)
+	t.Cleanup(func() {
+		rankingDefs.Store(baseRankingDefs)
+		RankingScoreCfg.Store(DefaultRankingScoreConfig())
+	})
+	RankingScoreCfg.Store(DefaultRankingScoreConfig())
+	require.NoError(t, UpdateRankingTags([]string{"go"}))
+
+	now := time.Now()
+	art := func(id int64, age time.Duration, access domain.ArticleAccess, tags ...string) domain.Article {
+		return domain.Article{Id: id, Utime: now.Add(-age), Access: access, Tags: tags,
+			Status: domain.ArticleStatusPublished}
+	}
+	// 按照 utime 倒序
+	artSvc := &fakeListPubService{arts: []domain.Article{
+		art(1, time.Hour, domain.ArticleAccessPublic, "go"),
+		art(2, time.Hour*2, domain.ArticleAccessSubscriber),
+		art(3, time.Hour*48, domain.ArticleAccessPublic),
+		art(4, time.Hour*24*10, domain.ArticleAccessSubscriber, "go"),
+		art(5, time.Hour*24*40, domain.ArticleAccessPublic),
+		art(6, time.Hour*24*50, domain.ArticleAccessPublic),
+	}}
+	repo := &fakeRankingRepo{replaced: map[string][]int64{}}
+	svc := &BatchRankingService{
+		artSvc:      artSvc,
+		intrSvc:     &fakeLikeCntService{likes: map[int64]int64{1: 2, 2: 10, 3: 100, 4: 100, 5: 100, 6: 100}},
+		repo:        repo,
+		snapshotSvc: &fakeSnapshotService{},
+		l:           logger.NewZapLogger(zap.NewNop(), false),
+		n:           2,
+		batchSize:   2,
+		biz:         "article",
+	}
+	require.NoError(t, svc.TopN(context.Background()))
+	// 2 是 9 / 4^1.5，3 是 99 / 50^1.5，4 是 99 / 242^1.5
+	// 会员榜和标签榜的窗口是七天，4 超过了
+	assert.Equal(t, map[string][]int64{
+		RankingDaily:   {2, 1},
+		RankingWeekly:  {2, 3},
+		RankingMonthly: {2, 3},
+		RankingMember:  {2},
+		"tag:go":       {1},
+	}, repo.replaced)
+	// 第三批已经超出了最长的窗口，不会再查第四批
+	assert.Equal(t, 3, artSvc.calls)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+)
+
+var ErrInvalidProgress = errors.New("阅读进度不对")
+
+type ReadHistoryService interface {
+	// ReportProgress 客户端上报阅读位置，progress 是 0 到 100 的百分比
+	ReportProgress(ctx context.Context, uid int64, aid int64, position int64, progress int) error
+	// List 按照阅读时间倒序，文章撤回或者删除了的 Article.Id 是 0
+	List(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.ReadHistory, error)
+	// Continue 最近读过但是还没读完的文章
+	Continue(ctx context.Context, uid int64, limit int) ([]domain.ReadHistory, error)
+	Delete(ctx context.Context, uid int64, aids []int64) error
+	Clear(ctx context.Context, uid int64) error
+}
+
+type readHistoryService struct {
+	repo        repository.ReadHistoryRepository
+	articleRepo repository.ArticleRepository
+	l           logger.Logger
+	// continueScan "继续阅读"只从最近读的这么多篇里面找
+	continueScan int
+}
+
+func NewReadHistoryService(repo repository.ReadHistoryRepository,
+	articleRepo repository.ArticleRepository, l logger.Logger) ReadHistoryService {
+	return &readHistoryService{
+		repo:         repo,
+		articleRepo:  articleRepo,
+		l:            l,
+		continueScan: 50,
+	}
+}
+
+func (s *readHistoryService) ReportProgress(ctx context.Context, uid int64, aid int64, position int64, progress int) error {
+	if aid <= 0 || position < 0 || progress < 0 || progress > 100 {
+		return ErrInvalidProgress
+	}
+	return s.repo.SaveProgress(ctx, domain.ReadHistory{
+		Uid:      uid,
+		Aid:      aid,
+		Position: position,
+		Progress: progress,
+	})
+}
+
+func (s *readHistoryService) List(ctx context.Context, uid int64,
+	cursor domain.ArticleCursor, limit int) ([]domain.ReadHistory, error) {
+	hs, err := s.repo.List(ctx, uid, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.fillArticles(ctx, hs)
+}
+
+func (s *readHistoryService) Continue(ctx context.Context, uid int64, limit int) ([]domain.ReadHistory, error) {
+	hs, err := s.repo.List(ctx, uid, domain.ArticleCursor{}, s.continueScan)
+	if err != nil {
+		return nil, err
+	}
+	unfinished := make([]domain.ReadHistory, 0, len(hs))
+	for _, h := range hs {
+		if !h.Finished() {
+			unfinished = append(unfinished, h)
+		}
+	}
+	res, err := s.fillArticles(ctx, unfinished)
+	if err != nil {
+		return nil, err
+	}
+	// 看不了的文章也没法继续读了，先过滤掉再截断，不然撤回的文章会占掉名额
+	visible := make([]domain.ReadHistory, 0, limit)
+	for _, h := range res {
+		if len(visible) >= limit {
+			break
+		}
+		if h.Article.Id > 0 {
+			visible = append(visible, h)
+		}
+	}
+	return visible, nil
+}
+
+func (s *readHistoryService) Delete(ctx context.Context, uid int64, aids []int64) error {
+	if len(aids) == 0 {
+		return nil
+	}
+	return s.repo.Delete(ctx, uid, aids)
+}
+
+func (s *readHistoryService) Clear(ctx context.Context, uid int64) error {
+	return s.repo.DeleteAll(ctx, uid)
+}
+
+// fillArticles 撤回或者删除了的文章保留记录，但是 Article 是零值
+func (s *readHistoryService) fillArticles(ctx context.Context,
+	hs []domain.ReadHistory) ([]domain.ReadHistory, error) {
+	aids := make([]int64, 0, len(hs))
+	for _, h := range hs {
+		aids = append(aids, h.Aid)
+	}
+	arts, err := s.articleRepo.GetPubByIds(ctx, aids)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[int64]domain.Article, len(arts))
+	for _, art := range arts {
+		if art.Status == domain.ArticleStatusPublished {
+			m[art.Id] = art
+		}
+	}
+	for i := range hs {
+		hs[i].Article = m[hs[i].Aid]
+	}
+	return hs, nil
+}
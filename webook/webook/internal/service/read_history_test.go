@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+type fakeReadHistoryRepo struct {
+	repository.ReadHistoryRepository
+	hs    []domain.ReadHistory
+	saved []domain.ReadHistory
+	limit int
+}
+
+func (r *fakeReadHistoryRepo) List(ctx context.Context, uid int64, cursor domain.ArticleCursor, limit int) ([]domain.ReadHistory, error) {
+	r.limit = limit
+	res := make([]domain.ReadHistory, len(r.hs))
+	copy(res, r.hs)
+	return res, nil
+}
+
+func (r *fakeReadHistoryRepo) SaveProgress(ctx context.Context, h domain.ReadHistory) error {
+	r.saved = append(r.saved, h)
+	return nil
+}
+
+func TestReadHistoryService_ReportProgress(t *testing.T) {
+	testCases := []struct {
+		name     string
+		aid      int64
+		position int64
+		progress int
+
+		wantErr error
+	}{
+		{name: "读到一半", aid: 1, position: 30, progress: 50},
+		{name: "读完了", aid: 1, position: 80, progress: 100},
+		{name: "文章不对", aid: 0, progress: 50, wantErr: ErrInvalidProgress},
+		{name: "位置是负数", aid: 1, position: -1, progress: 50, wantErr: ErrInvalidProgress},
+		{name: "进度超过 100", aid: 1, progress: 101, wantErr: ErrInvalidProgress},
+		{name: "进度是负数", aid: 1, progress: -1, wantErr: ErrInvalidProgress},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeReadHistoryRepo{}
+			svc := NewReadHistoryService(repo, nil, logger.NewZapLogger(zap.NewNop(), false))
+			err := svc.ReportProgress(context.Background(), 7, tc.aid, tc.position, tc.progress)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantErr != nil {
+				assert.Empty(t, repo.saved)
+				return
+			}
+			assert.Equal(t, []domain.ReadHistory{
+				{Uid: 7, Aid: tc.aid, Position: tc.position, Progress: tc.progress},
+			}, repo.saved)
+		})
+	}
+}
+
+// readHistoryArticles 3 撤回了，5 删除了
+func readHistoryArticles() map[int64]domain.Article {
+	arts := make(map[int64]domain.Article, 4)
+	for _, id := range []int64{1, 2, 4, 6} {
+		arts[id] = domain.Article{Id: id, Title: "标题", Status: domain.ArticleStatusPublished}
+	}
+	arts[3] = domain.Article{Id: 3, Title: "撤回了", Status: domain.ArticleStatusPrivate}
+	return arts
+}
+
+func aidsOf(hs []domain.ReadHistory) []int64 {
+	res := make([]int64, 0, len(hs))
+	for _, h := range hs {
+		res = append(res, h.Aid)
+	}
+	return res
+}
+
+func TestReadHistoryService_List(t *testing.T) {
+	repo := &fakeReadHistoryRepo{hs: []domain.ReadHistory{
+		{Aid: 1, Progress: 100}, {Aid: 3, Progress: 20}, {Aid: 5, Progress: 10}, {Aid: 2, Progress: 30},
+	}}
+	svc := NewReadHistoryService(repo, &fakePubArticleRepo{arts: readHistoryArticles()},
+		logger.NewZapLogger(zap.NewNop(), false))
+	hs, err := svc.List(context.Background(), 7, domain.ArticleCursor{}, 10)
+	require.NoError(t, err)
+	// 历史记录本身保留，撤回和删除了的文章是零值
+	assert.Equal(t, []int64{1, 3, 5, 2}, aidsOf(hs))
+	assert.Equal(t, int64(1), hs[0].Article.Id)
+	assert.Equal(t, domain.Article{}, hs[1].Article)
+	assert.Equal(t, domain.Article{}, hs[2].Article)
+	assert.Equal(t, int64(2), hs[3].Article.Id)
+}
+
+func TestReadHistoryService_Continue(t *testing.T) {
+	testCases := []struct {
+		name  string
+		hs    []domain.ReadHistory
+		limit int
+
+		wantAids []int64
+	}{
+		{
+			name: "跳过读完了的",
+			hs: []domain.ReadHistory{
+				{Aid: 1, Progress: 100}, {Aid: 2, Progress: 30}, {Aid: 4, Progress: 99},
+			},
+			limit:    10,
+			wantAids: []int64{2, 4},
+		},
+		{
+			name: "撤回和删除了的不占名额",
+			hs: []domain.ReadHistory{
+				{Aid: 3, Progress: 20}, {Aid: 5, Progress: 10},
+				{Aid: 2, Progress: 30}, {Aid: 4, Progress: 40}, {Aid: 6, Progress: 50},
+			},
+			limit:    2,
+			wantAids: []int64{2, 4},
+		},
+		{
+			name:     "没有历史记录",
+			limit:    2,
+			wantAids: []int64{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeReadHistoryRepo{hs: tc.hs}
+			svc := NewReadHistoryService(repo, &fakePubArticleRepo{arts: readHistoryArticles()},
+				logger.NewZapLogger(zap.NewNop(), false))
+			hs, err := svc.Continue(context.Background(), 7, tc.limit)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantAids, aidsOf(hs))
+			// 只在最近读的那些里面找
+			assert.Equal(t, 50, repo.limit)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+)
+
+var (
+	ErrSeriesNotFound       = repository.ErrSeriesNotFound
+	ErrArticleInOtherSeries = repository.ErrArticleInOtherSeries
+	// ErrInvalidSeriesArticle 只能把自己已经发表的文章加到系列里，并且不能重复
+	ErrInvalidSeriesArticle = errors.New("文章不存在、没有发表或者重复了")
+	ErrTooManySeriesArticle = errors.New("系列里的文章太多了")
+)
+
+type SeriesService interface {
+	Create(ctx context.Context, s domain.Series) (int64, error)
+	Update(ctx context.Context, s domain.Series) error
+	Delete(ctx context.Context, id int64, uid int64) error
+	ListByAuthor(ctx context.Context, uid int64) ([]domain.Series, error)
+	// SetArticles 用 aids 整个替换掉系列里的文章，aids 的顺序就是阅读顺序
+	SetArticles(ctx context.Context, id int64, uid int64, aids []int64) error
+	// Get 系列和里面线上可见的文章，按照阅读顺序
+	Get(ctx context.Context, id int64) (domain.Series, []domain.Article, error)
+	// Nav 文章在系列里的位置，不在任何系列里的返回零值
+	Nav(ctx context.Context, aid int64) (domain.SeriesNav, error)
+}
+
+type seriesService struct {
+	repo        repository.SeriesRepository
+	articleRepo repository.ArticleRepository
+	l           logger.Logger
+	maxArticles int
+}
+
+func NewSeriesService(repo repository.SeriesRepository,
+	articleRepo repository.ArticleRepository, l logger.Logger) SeriesService {
+	return &seriesService{
+		repo:        repo,
+		articleRepo: articleRepo,
+		l:           l,
+		maxArticles: 100,
+	}
+}
+
+func (s *seriesService) Create(ctx context.Context, series domain.Series) (int64, error) {
+	return s.repo.Create(ctx, series)
+}
+
+func (s *seriesService) Update(ctx context.Context, series domain.Series) error {
+	return s.repo.Update(ctx, series)
+}
+
+func (s *seriesService) Delete(ctx context.Context, id int64, uid int64) error {
+	return s.repo.Delete(ctx, id, uid)
+}
+
+func (s *seriesService) ListByAuthor(ctx context.Context, uid int64) ([]domain.Series, error) {
+	return s.repo.FindByAuthor(ctx, uid)
+}
+
+func (s *seriesService) SetArticles(ctx context.Context, id int64, uid int64, aids []int64) error {
+	if len(aids) > s.maxArticles {
+		return ErrTooManySeriesArticle
+	}
+	seen := make(map[int64]struct{}, len(aids))
+	for _, aid := range aids {
+		if _, ok := seen[aid]; ok {
+			return ErrInvalidSeriesArticle
+		}
+		seen[aid] = struct{}{}
+	}
+	arts, err := s.articleRepo.GetPubByIds(ctx, aids)
+	if err != nil {
+		return err
+	}
+	// 找不到的文章不会返回，所以数量对不上就说明有不存在的
+	if len(arts) != len(aids) {
+		return ErrInvalidSeriesArticle
+	}
+	for _, art := range arts {
+		if !s.visible(art) || art.Author.Id != uid {
+			return ErrInvalidSeriesArticle
+		}
+	}
+	return s.repo.SetArticles(ctx, id, uid, aids)
+}
+
+func (s *seriesService) Get(ctx context.Context, id int64) (domain.Series, []domain.Article, error) {
+	series, err := s.repo.FindById(ctx, id)
+	if err != nil {
+		return domain.Series{}, nil, err
+	}
+	all, err := s.articleRepo.GetPubByIds(ctx, series.Aids)
+	if err != nil {
+		return domain.Series{}, nil, err
+	}
+	arts := make([]domain.Article, 0, len(all))
+	for _, art := range all {
+		// 加进来之后又撤回或者删除了的，不展示
+		if s.visible(art) {
+			arts = append(arts, art)
+		}
+	}
+	return series, arts, nil
+}
+
+func (s *seriesService) Nav(ctx context.Context, aid int64) (domain.SeriesNav, error) {
+	sid, err := s.repo.FindByArticle(ctx, aid)
+	if err != nil || sid == 0 {
+		return domain.SeriesNav{}, err
+	}
+	series, arts, err := s.Get(ctx, sid)
+	if err != nil {
+		return domain.SeriesNav{}, err
+	}
+	for i, art := range arts {
+		if art.Id != aid {
+			continue
+		}
+		nav := domain.SeriesNav{
+			Series: series,
+			Index:  i + 1,
+			Total:  len(arts),
+		}
+		if i > 0 {
+			nav.Prev = arts[i-1]
+		}
+		if i < len(arts)-1 {
+			nav.Next = arts[i+1]
+		}
+		return nav, nil
+	}
+	return domain.SeriesNav{}, nil
+}
+
+func (s *seriesService) visible(art domain.Article) bool {
+	return art.Id > 0 && art.Status == domain.ArticleStatusPublished
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+type fakeSeriesRepo struct {
+	repository.SeriesRepository
+	series map[int64]domain.Series
+	// aid => 系列 id
+	articles map[int64]int64
+	set      [][]int64
+}
+
+func (r *fakeSeriesRepo) FindById(ctx context.Context, id int64) (domain.Series, error) {
+	s, ok := r.series[id]
+	if !ok {
+		return domain.Series{}, ErrSeriesNotFound
+	}
+	return s, nil
+}
+
+func (r *fakeSeriesRepo) FindByArticle(ctx context.Context, aid int64) (int64, error) {
+	return r.articles[aid], nil
+}
+
+func (r *fakeSeriesRepo) SetArticles(ctx context.Context, id int64, uid int64, aids []int64) error {
+	r.set = append(r.set, aids)
+	return nil
+}
+
+// seriesArticles 作者 1 发表了 1、2、4、5，3 是作者 1 撤回了的，6 是作者 2 的
+func seriesArticles() map[int64]domain.Article {
+	pub := func(id int64, uid int64) domain.Article {
+		return domain.Article{Id: id, Status: domain.ArticleStatusPublished, Author: domain.Author{Id: uid}}
+	}
+	arts := map[int64]domain.Article{
+		1: pub(1, 1), 2: pub(2, 1), 4: pub(4, 1), 5: pub(5, 1), 6: pub(6, 2),
+	}
+	arts[3] = domain.Article{Id: 3, Status: domain.ArticleStatusPrivate, Author: domain.Author{Id: 1}}
+	return arts
+}
+
+func TestSeriesService_SetArticles(t *testing.T) {
+	testCases := []struct {
+		name string
+		aids []int64
+
+		wantErr error
+		wantSet [][]int64
+	}{
+		{
+			name:    "按照给的顺序保存",
+			aids:    []int64{2, 1, 4},
+			wantSet: [][]int64{{2, 1, 4}},
+		},
+		{
+			name:    "清空",
+			aids:    []int64{},
+			wantSet: [][]int64{{}},
+		},
+		{
+			name:    "重复的文章",
+			aids:    []int64{1, 2, 1},
+			wantErr: ErrInvalidSeriesArticle,
+		},
+		{
+			name:    "文章不存在",
+			aids:    []int64{1, 404},
+			wantErr: ErrInvalidSeriesArticle,
+		},
+		{
+			name:    "没有发表的文章",
+			aids:    []int64{1, 3},
+			wantErr: ErrInvalidSeriesArticle,
+		},
+		{
+			name:    "别人的文章",
+			aids:    []int64{1, 6},
+			wantErr: ErrInvalidSeriesArticle,
+		},
+		{
+			name:    "文章太多",
+			aids:    manyIds(101),
+			wantErr: ErrTooManySeriesArticle,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeSeriesRepo{}
+			svc := NewSeriesService(repo, &fakePubArticleRepo{arts: seriesArticles()},
+				logger.NewZapLogger(zap.NewNop(), false))
+			err := svc.SetArticles(context.Background(), 10, 1, tc.aids)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantSet, repo.set)
+		})
+	}
+}
+
+func TestSeriesService_Get(t *testing.T) {
+	repo := &fakeSeriesRepo{series: map[int64]domain.Series{
+		10: {Id: 10, Title: "Go 入门", Aids: []int64{4, 3, 1, 404, 2}},
+	}}
+	svc := NewSeriesService(repo, &fakePubArticleRepo{arts: seriesArticles()},
+		logger.NewZapLogger(zap.NewNop(), false))
+
+	series, arts, err := svc.Get(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, "Go 入门", series.Title)
+	// 加进来之后撤回了的和删除了的都不展示，剩下的保持阅读顺序
+	ids := make([]int64, 0, len(arts))
+	for _, art := range arts {
+		ids = append(ids, art.Id)
+	}
+	assert.Equal(t, []int64{4, 1, 2}, ids)
+
+	_, _, err = svc.Get(context.Background(), 404)
+	assert.Equal(t, ErrSeriesNotFound, err)
+}
+
+func TestSeriesService_Nav(t *testing.T) {
+	arts := seriesArticles()
+	testCases := []struct {
+		name string
+		aid  int64
+
+		wantNav domain.SeriesNav
+	}{
+		{
+			name: "第一篇没有上一篇",
+			aid:  4,
+			wantNav: domain.SeriesNav{Index: 1, Total: 3,
+				Next: arts[1]},
+		},
+		{
+			name: "中间跳过撤回了的",
+			aid:  1,
+			wantNav: domain.SeriesNav{Index: 2, Total: 3,
+				Prev: arts[4], Next: arts[2]},
+		},
+		{
+			name: "最后一篇没有下一篇",
+			aid:  2,
+			wantNav: domain.SeriesNav{Index: 3, Total: 3,
+				Prev: arts[1]},
+		},
+		{
+			name: "不在任何系列里",
+			aid:  5,
+		},
+		{
+			name: "撤回了的文章自己不算在系列里",
+			aid:  3,
+		},
+	}
+	series := domain.Series{Id: 10, Title: "Go 入门", Aids: []int64{4, 3, 1, 2}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeSeriesRepo{
+				series:   map[int64]domain.Series{10: series},
+				articles: map[int64]int64{4: 10, 3: 10, 1: 10, 2: 10},
+			}
+			svc := NewSeriesService(repo, &fakePubArticleRepo{arts: arts},
+				logger.NewZapLogger(zap.NewNop(), false))
+			nav, err := svc.Nav(context.Background(), tc.aid)
+			require.NoError(t, err)
+			if tc.wantNav.Total > 0 {
+				tc.wantNav.Series = series
+			}
+			assert.Equal(t, tc.wantNav, nav)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/objectstore"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/sitemap"
+	"github.com/spf13/viper"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var ErrSitemapNotFound = errors.New("站点地图不存在")
+
+const (
+	SitemapIndexName = "index.xml"
+	sitemapKeyPrefix = "sitemap/"
+	sitemapStateKey  = sitemapKeyPrefix + "state.json"
+	sitemapXMLType   = "application/xml; charset=utf-8"
+)
+
+var sitemapShardName = regexp.MustCompile(`^sitemap-(\d+)\.xml$`)
+
+type SitemapService interface {
+	// Generate 增量更新，只重新生成上次之后有文章变更的分片
+	Generate(ctx context.Context) error
+	// Get name 是 index.xml 或者 sitemap-N.xml
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+// sitemapState 上一次生成到哪里了，和站点地图文件存在一起
+// 文件丢了状态也就丢了，下一次会全部重新生成
+type sitemapState struct {
+	// Utime 和 Id 是扫描线上库的游标
+	Utime int64 `json:"utime"`
+	Id    int64 `json:"id"`
+	// Shards 每个非空分片里面最新的 utime
+	Shards map[int64]int64 `json:"shards"`
+}
+
+// ShardedSitemapService 按照文章 id 分片，每 sitemap.MaxURLs 个 id 一个文件
+// 这样每个文件的地址数一定不超过上限，某篇文章变了也只需要重新生成它所在的分片
+type ShardedSitemapService struct {
+	artRepo repository.ArticleRepository
+	store   objectstore.Store
+	l       logger.Logger
+	baseURL string
+	// batchSize 扫描变更的时候一批取多少条
+	batchSize int
+}
+
+func NewShardedSitemapService(artRepo repository.ArticleRepository,
+	store objectstore.Store, l logger.Logger) SitemapService {
+	return &ShardedSitemapService{
+		artRepo:   artRepo,
+		store:     store,
+		l:         l,
+		baseURL:   viper.GetString("site.baseURL"),
+		batchSize: 1000,
+	}
+}
+
+func (s *ShardedSitemapService) Generate(ctx context.Context) error {
+	state, err := s.loadState(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 正在提交的事务可能会写入一个比较早的 utime，留一分钟的余量，避免漏掉
+	before := time.Now().Add(-time.Minute)
+	cursor := domain.ArticleCursor{}
+	if state.Utime > 0 {
+		cursor = domain.ArticleCursor{Utime: time.UnixMilli(state.Utime), Id: state.Id}
+	}
+	dirty := make(map[int64]struct{})
+	for {
+		arts, err := s.artRepo.ListPubUpdatedAfter(ctx, cursor, before, s.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, art := range arts {
+			dirty[art.Id/sitemap.MaxURLs] = struct{}{}
+		}
+		if len(arts) > 0 {
+			cursor = arts[len(arts)-1].Cursor()
+		}
+		if len(arts) < s.batchSize {
+			break
+		}
+	}
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	for shard := range dirty {
+		err = s.writeShard(ctx, shard, &state)
+		if err != nil {
+			// 状态还没保存，下一次会重新生成这些分片
+			return err
+		}
+	}
+	err = s.writeIndex(ctx, state)
+	if err != nil {
+		return err
+	}
+
+	state.Utime = cursor.Utime.UnixMilli()
+	state.Id = cursor.Id
+	s.l.Info("更新站点地图", logger.Int("dirty", len(dirty)),
+		logger.Int("shards", len(state.Shards)))
+	return s.saveState(ctx, state)
+}
+
+// writeShard 重新生成整个分片，分片里没有文章了就删掉
+func (s *ShardedSitemapService) writeShard(ctx context.Context, shard int64, state *sitemapState) error {
+	arts, err := s.artRepo.ListPubByIdRange(ctx, shard*sitemap.MaxURLs, (shard+1)*sitemap.MaxURLs)
+	if err != nil {
+		return err
+	}
+	key := sitemapKeyPrefix + s.shardName(shard)
+	if len(arts) == 0 {
+		delete(state.Shards, shard)
+		return s.store.Delete(ctx, key)
+	}
+
+	urls := make([]sitemap.URL, 0, len(arts))
+	var lastMod time.Time
+	for _, art := range arts {
+		urls = append(urls, sitemap.URL{
+			Loc:     fmt.Sprintf("%s/pub/%d", s.baseURL, art.Id),
+			LastMod: art.Utime,
+		})
+		if art.Utime.After(lastMod) {
+			lastMod = art.Utime
+		}
+	}
+	data, err := sitemap.RenderURLSet(urls)
+	if err != nil {
+		return err
+	}
+	err = s.store.Put(ctx, key, data, sitemapXMLType)
+	if err != nil {
+		return err
+	}
+	state.Shards[shard] = lastMod.UnixMilli()
+	return nil
+}
+
+func (s *ShardedSitemapService) writeIndex(ctx context.Context, state sitemapState) error {
+	shards := make([]int64, 0, len(state.Shards))
+	for shard := range state.Shards {
+		shards = append(shards, shard)
+	}
+	sort.Slice(shards, func(i, j int) bool {
+		return shards[i] < shards[j]
+	})
+	urls := make([]sitemap.URL, 0, len(shards))
+	for _, shard := range shards {
+		urls = append(urls, sitemap.URL{
+			Loc:     fmt.Sprintf("%s/sitemaps/%s", s.baseURL, s.shardName(shard)),
+			LastMod: time.UnixMilli(state.Shards[shard]),
+		})
+	}
+	data, err := sitemap.RenderIndex(urls)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(ctx, sitemapKeyPrefix+SitemapIndexName, data, sitemapXMLType)
+}
+
+func (s *ShardedSitemapService) Get(ctx context.Context, name string) ([]byte, error) {
+	// 只允许访问站点地图文件，不能把状态文件或者别的东西读出去
+	if name != SitemapIndexName && !sitemapShardName.MatchString(name) {
+		return nil, ErrSitemapNotFound
+	}
+	data, err := s.store.Get(ctx, sitemapKeyPrefix+name)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return nil, ErrSitemapNotFound
+	}
+	return data, err
+}
+
+func (s *ShardedSitemapService) loadState(ctx context.Context) (sitemapState, error) {
+	state := sitemapState{Shards: map[int64]int64{}}
+	data, err := s.store.Get(ctx, sitemapStateKey)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	if state.Shards == nil {
+		state.Shards = map[int64]int64{}
+	}
+	return state, err
+}
+
+func (s *ShardedSitemapService) saveState(ctx context.Context, state sitemapState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(ctx, sitemapStateKey, data, "application/json")
+}
+
+func (s *ShardedSitemapService) shardName(shard int64) string {
+	return "sitemap-" + strconv.FormatInt(shard, 10) + ".xml"
+}
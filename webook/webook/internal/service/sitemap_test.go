@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/objectstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeSitemapArticleRepo 模拟线上库，撤回了的文章扫描变更的时候能看到，按照 id 范围查的时候看不到
+type fakeSitemapArticleRepo struct {
+	repository.ArticleRepository
+	arts map[int64]domain.Article
+	// 每次重新生成的分片的起始 id
+	ranges []int64
+}
+
+func (r *fakeSitemapArticleRepo) ListPubUpdatedAfter(ctx context.Context, cursor domain.ArticleCursor, before time.Time, limit int) ([]domain.Article, error) {
+	res := make([]domain.Article, 0, len(r.arts))
+	for _, art := range r.arts {
+		if !art.Utime.Before(before) {
+			continue
+		}
+		if art.Utime.Before(cursor.Utime) || (art.Utime.Equal(cursor.Utime) && art.Id <= cursor.Id) {
+			continue
+		}
+		res = append(res, art)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Utime.Equal(res[j].Utime) {
+			return res[i].Id < res[j].Id
+		}
+		return res[i].Utime.Before(res[j].Utime)
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (r *fakeSitemapArticleRepo) ListPubByIdRange(ctx context.Context, minId int64, maxId int64) ([]domain.Article, error) {
+	r.ranges = append(r.ranges, minId)
+	res := make([]domain.Article, 0, len(r.arts))
+	for _, art := range r.arts {
+		if art.Id >= minId && art.Id < maxId && art.Status == domain.ArticleStatusPublished {
+			res = append(res, art)
+		}
+	}
+	return res, nil
+}
+
+func TestShardedSitemapService_Generate(t *testing.T) {
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	published := func(id int64, utime time.Time) domain.Article {
+		return domain.Article{Id: id, Status: domain.ArticleStatusPublished, Utime: utime}
+	}
+	artRepo := &fakeSitemapArticleRepo{arts: map[int64]domain.Article{
+		1:     published(1, base),
+		2:     published(2, base.Add(time.Second)),
+		3:     published(3, base.Add(2*time.Second)),
+		50001: published(50001, base.Add(3*time.Second)),
+	}}
+	store := objectstore.NewLocalStore(t.TempDir(), "")
+	svc := NewShardedSitemapService(artRepo, store, logger.NewZapLogger(zap.NewNop(), false))
+	// 一批只取两条，验证会翻页扫描
+	svc.(*ShardedSitemapService).batchSize = 2
+	svc.(*ShardedSitemapService).baseURL = "https://webook.com"
+
+	// 第一次全部生成
+	require.NoError(t, svc.Generate(ctx))
+	assert.ElementsMatch(t, []int64{0, 50000}, artRepo.ranges)
+	shard0, err := svc.Get(ctx, "sitemap-0.xml")
+	require.NoError(t, err)
+	assert.Contains(t, string(shard0), "<loc>https://webook.com/pub/1</loc>")
+	assert.Contains(t, string(shard0), "<loc>https://webook.com/pub/3</loc>")
+	assert.NotContains(t, string(shard0), "/pub/50001")
+	index, err := svc.Get(ctx, SitemapIndexName)
+	require.NoError(t, err)
+	assert.Contains(t, string(index), "<loc>https://webook.com/sitemaps/sitemap-0.xml</loc>")
+	assert.Contains(t, string(index), "<loc>https://webook.com/sitemaps/sitemap-1.xml</loc>")
+
+	// 没有变更，什么都不用做
+	artRepo.ranges = nil
+	require.NoError(t, svc.Generate(ctx))
+	assert.Empty(t, artRepo.ranges)
+
+	// 只有 2 变了，只重新生成第一个分片
+	artRepo.arts[2] = published(2, base.Add(10*time.Second))
+	require.NoError(t, svc.Generate(ctx))
+	assert.Equal(t, []int64{0}, artRepo.ranges)
+
+	// 50001 撤回了，它所在的分片空了，要从索引里去掉
+	artRepo.ranges = nil
+	art := artRepo.arts[50001]
+	art.Status = domain.ArticleStatusPrivate
+	art.Utime = base.Add(20 * time.Second)
+	artRepo.arts[50001] = art
+	require.NoError(t, svc.Generate(ctx))
+	assert.Equal(t, []int64{50000}, artRepo.ranges)
+	_, err = svc.Get(ctx, "sitemap-1.xml")
+	assert.Equal(t, ErrSitemapNotFound, err)
+	index, err = svc.Get(ctx, SitemapIndexName)
+	require.NoError(t, err)
+	assert.Contains(t, string(index), "sitemap-0.xml")
+	assert.NotContains(t, string(index), "sitemap-1.xml")
+}
+
+func TestShardedSitemapService_Get(t *testing.T) {
+	ctx := context.Background()
+	store := objectstore.NewLocalStore(t.TempDir(), "")
+	require.NoError(t, store.Put(ctx, sitemapStateKey, []byte(`{}`), "application/json"))
+	svc := NewShardedSitemapService(nil, store, logger.NewZapLogger(zap.NewNop(), false))
+
+	testCases := []struct {
+		name     string
+		filename string
+	}{
+		{name: "还没有生成过", filename: SitemapIndexName},
+		{name: "分片不存在", filename: "sitemap-9.xml"},
+		// 状态文件和站点地图放在一起，不能被读出去
+		{name: "状态文件", filename: "state.json"},
+		{name: "路径穿越", filename: "../sitemap/state.json"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := svc.Get(ctx, tc.filename)
+			assert.Equal(t, ErrSitemapNotFound, err)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	intrSvc "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+)
+
+type TopLikeService interface {
+	// TopLike 点赞最多的前 n 篇文章，带上标题、作者和各种计数
+	// limit 是点赞榜缓存没有的时候，从数据库里最多捞多少条
+	TopLike(ctx context.Context, n int64, limit int64) ([]domain.TopArticle, error)
+}
+
+type topLikeService struct {
+	repo        repository.TopLikeRepository
+	articleRepo repository.ArticleRepository
+	intrSvc     intrSvc.InteractiveService
+	l           logger.Logger
+	biz         string
+}
+
+func NewTopLikeService(repo repository.TopLikeRepository,
+	articleRepo repository.ArticleRepository,
+	intrSvc intrSvc.InteractiveService, l logger.Logger) TopLikeService {
+	return &topLikeService{
+		repo:        repo,
+		articleRepo: articleRepo,
+		intrSvc:     intrSvc,
+		l:           l,
+		biz:         "article",
+	}
+}
+
+func (s *topLikeService) TopLike(ctx context.Context, n int64, limit int64) ([]domain.TopArticle, error) {
+	res, err := s.repo.Get(ctx, n)
+	if err == nil {
+		return res, nil
+	}
+
+	tops, err := s.intrSvc.TopLike(ctx, s.biz, n, limit)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(tops))
+	for _, t := range tops {
+		ids = append(ids, t.Member)
+	}
+	arts, err := s.articleRepo.GetPubByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	intrs, err := s.intrSvc.GetByIds(ctx, s.biz, ids)
+	if err != nil {
+		return nil, err
+	}
+	artMap := make(map[int64]domain.Article, len(arts))
+	for _, art := range arts {
+		artMap[art.Id] = art
+	}
+
+	res = make([]domain.TopArticle, 0, len(tops))
+	for _, t := range tops {
+		art, ok := artMap[t.Member]
+		// 点赞榜里可能还有已经撤回或者删除了的文章
+		if !ok || art.Status != domain.ArticleStatusPublished {
+			continue
+		}
+		intr := intrs[t.Member]
+		res = append(res, domain.TopArticle{
+			Article:    art,
+			Score:      t.Score,
+			LikeCnt:    intr.LikeCnt,
+			ReadCnt:    intr.ReadCnt,
+			CollectCnt: intr.CollectCnt,
+		})
+	}
+
+	err = s.repo.Set(ctx, n, res)
+	if err != nil {
+		s.l.Error("回写点赞榜缓存失败", logger.Error(err), logger.Int64("n", n))
+	}
+	return res, nil
+}
@@ -5,12 +5,15 @@ import (
 	"errors"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/textfilter"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	ErrUserDuplicate         = repository.ErrUserDuplicate
 	ErrInvalidUserOrPassword = errors.New("账号/邮箱或密码不对")
+	// ErrSensitiveProfile 昵称或者简介里有敏感词
+	ErrSensitiveProfile = textfilter.ErrBlocked
 )
 
 type UserService interface {
@@ -21,15 +24,19 @@ type UserService interface {
 	EditProfile(ctx context.Context, u domain.User) error
 	EditPassword(ctx context.Context, u domain.User) error
 	Profile(ctx context.Context, id int64) (domain.User, error)
+	// FindByIds 列表页批量查昵称之类的，找不到的用户不在返回值里
+	FindByIds(ctx context.Context, ids []int64) (map[int64]domain.User, error)
 }
 
 type userService struct {
-	repo repository.UserRepository
+	repo   repository.UserRepository
+	filter textfilter.Filter
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
+func NewUserService(repo repository.UserRepository, filter textfilter.Filter) UserService {
 	return &userService{
-		repo: repo,
+		repo:   repo,
+		filter: filter,
 	}
 }
 
@@ -122,7 +129,16 @@ func (s *userService) Login(ctx context.Context, u domain.User) (domain.User, er
 }
 
 func (s *userService) EditProfile(ctx context.Context, u domain.User) error {
-
+	// 个人资料没有审核流程，需要审核的词也直接拒绝
+	var err error
+	u.Nickname, err = textfilter.MaskOrBlock(s.filter, u.Nickname)
+	if err != nil {
+		return err
+	}
+	u.Intro, err = textfilter.MaskOrBlock(s.filter, u.Intro)
+	if err != nil {
+		return err
+	}
 	return s.repo.UpdateProfile(ctx, u)
 }
 
@@ -146,3 +162,7 @@ func (s *userService) Profile(ctx context.Context, id int64) (domain.User, error
 
 	return user, nil
 }
+
+func (s *userService) FindByIds(ctx context.Context, ids []int64) (map[int64]domain.User, error) {
+	return s.repo.FindByIds(ctx, ids)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	domain2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/domain"
+	intrSvc "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/ecodeclub/ekit/slice"
+)
+
+// UserInteractiveService 我的点赞、我的收藏，以及收藏夹里的东西
+// 互动服务只有 biz 和 bizId，这里补上文章的标题之类的
+type UserInteractiveService interface {
+	// Liked 按照点赞时间倒序，biz 为空就是所有的
+	// cursor 是上一页最后一条的游标，第一页传零值
+	Liked(ctx context.Context, uid int64, biz string, cursor domain.InteractedCursor, limit int) ([]domain.InteractedItem, error)
+	// Collected 不分收藏夹，按照收藏时间倒序，biz 为空就是所有的
+	Collected(ctx context.Context, uid int64, biz string, cursor domain.InteractedCursor, limit int) ([]domain.InteractedItem, error)
+	// CollectionItems 一个收藏夹里的东西，私有的收藏夹只有主人能看
+	CollectionItems(ctx context.Context, cid int64, viewer int64, offset int, limit int) ([]domain.InteractedItem, error)
+}
+
+type userInteractiveService struct {
+	intrSvc     intrSvc.InteractiveService
+	articleRepo repository.ArticleRepository
+	l           logger.Logger
+	biz         string
+}
+
+func NewUserInteractiveService(intrSvc intrSvc.InteractiveService,
+	articleRepo repository.ArticleRepository, l logger.Logger) UserInteractiveService {
+	return &userInteractiveService{
+		intrSvc:     intrSvc,
+		articleRepo: articleRepo,
+		l:           l,
+		biz:         "article",
+	}
+}
+
+func (s *userInteractiveService) Liked(ctx context.Context, uid int64, biz string, cursor domain.InteractedCursor, limit int) ([]domain.InteractedItem, error) {
+	likes, err := s.intrSvc.ListLiked(ctx, uid, biz, domain2.Cursor(cursor), limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.fillArticles(ctx, slice.Map[domain2.LikedItem, domain.InteractedItem](likes,
+		func(idx int, src domain2.LikedItem) domain.InteractedItem {
+			return domain.InteractedItem{
+				Id:    src.Id,
+				Biz:   src.Biz,
+				BizId: src.BizId,
+				Time:  src.Ctime,
+			}
+		}))
+}
+
+func (s *userInteractiveService) Collected(ctx context.Context, uid int64, biz string, cursor domain.InteractedCursor, limit int) ([]domain.InteractedItem, error) {
+	items, err := s.intrSvc.ListCollected(ctx, uid, biz, domain2.Cursor(cursor), limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.fillArticles(ctx, s.toItems(items))
+}
+
+func (s *userInteractiveService) CollectionItems(ctx context.Context, cid int64, viewer int64, offset int, limit int) ([]domain.InteractedItem, error) {
+	items, err := s.intrSvc.ListCollectionItems(ctx, cid, viewer, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.fillArticles(ctx, s.toItems(items))
+}
+
+// fillArticles 撤回或者删除了的文章保留记录，但是 Article 是零值
+func (s *userInteractiveService) fillArticles(ctx context.Context,
+	items []domain.InteractedItem) ([]domain.InteractedItem, error) {
+	aids := make([]int64, 0, len(items))
+	for _, item := range items {
+		if item.Biz == s.biz {
+			aids = append(aids, item.BizId)
+		}
+	}
+	if len(aids) == 0 {
+		return items, nil
+	}
+	arts, err := s.articleRepo.GetPubByIds(ctx, aids)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[int64]domain.Article, len(arts))
+	for _, art := range arts {
+		if art.Status == domain.ArticleStatusPublished {
+			m[art.Id] = art
+		}
+	}
+	for i := range items {
+		if items[i].Biz == s.biz {
+			items[i].Article = m[items[i].BizId]
+		}
+	}
+	return items, nil
+}
+
+func (s *userInteractiveService) toItems(items []domain2.CollectionItem) []domain.InteractedItem {
+	return slice.Map[domain2.CollectionItem, domain.InteractedItem](items,
+		func(idx int, src domain2.CollectionItem) domain.InteractedItem {
+			return domain.InteractedItem{
+				Id:    src.Id,
+				Biz:   src.Biz,
+				BizId: src.BizId,
+				Cid:   src.Cid,
+				Time:  src.Ctime,
+			}
+		})
+}
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"errors"
 	"fmt"
 	domain2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/domain"
 	service2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
@@ -22,16 +23,25 @@ import (
 var _ handler = (*ArticleHandler)(nil)
 
 type ArticleHandler struct {
-	svc      service.ArticleService
-	interSvc service2.InteractiveService
-	l        logger.Logger
-	biz      string
+	svc       service.ArticleService
+	interSvc  service2.InteractiveService
+	seriesSvc service.SeriesService
+	// topLikeSvc 组装点赞榜
+	topLikeSvc service.TopLikeService
+	rankingSvc service.RankingService
+	// snapshotSvc 热榜的历史快照
+	snapshotSvc service.RankingSnapshotService
+	l           logger.Logger
+	biz         string
 }
 
 var TopLikeN atomic.Int64 = atomic.Int64{}
 var TopLikeLimit atomic.Int64 = atomic.Int64{}
 
-func NewArticleHandler(svc service.ArticleService, interSvc service2.InteractiveService, l logger.Logger) *ArticleHandler {
+func NewArticleHandler(svc service.ArticleService, interSvc service2.InteractiveService,
+	seriesSvc service.SeriesService, topLikeSvc service.TopLikeService,
+	rankingSvc service.RankingService, snapshotSvc service.RankingSnapshotService,
+	l logger.Logger) *ArticleHandler {
 	topLikeN := viper.GetInt64("TopLike.N")
 	topLikeLimit := viper.GetInt64("TopLike.Limit")
 	if topLikeN == 0 {
@@ -44,13 +54,28 @@ func NewArticleHandler(svc service.ArticleService, interSvc service2.Interactive
 	TopLikeLimit.Store(topLikeLimit)
 
 	return &ArticleHandler{
-		svc:      svc,
-		interSvc: interSvc,
-		l:        l,
-		biz:      "article",
+		svc:         svc,
+		interSvc:    interSvc,
+		seriesSvc:   seriesSvc,
+		topLikeSvc:  topLikeSvc,
+		rankingSvc:  rankingSvc,
+		snapshotSvc: snapshotSvc,
+		l:           l,
+		biz:         "article",
 	}
 }
 
+// PubDetailRoute 线上文章详情，不登录也能看，看不了全文的只给摘要
+const PubDetailRoute = "/pub/:id"
+
+// 点赞榜和热榜都是公开的，不登录也能看
+const (
+	TopLikeRoute        = "/pub/top/like"
+	RankingRoute        = "/pub/ranking"
+	RankingHistoryRoute = "/pub/ranking/history/:id"
+	RankingMoversRoute  = "/pub/ranking/movers"
+)
+
 func (h *ArticleHandler) RegisterRoutes(server *gin.Engine) {
 	g := server.Group("/articles")
 	g.POST("/edit", ginx.WrapBodyAndToken[ArticleReq, myjwt.UserClaims](h.Edit, "EditArticle", h.l))
@@ -58,23 +83,47 @@ func (h *ArticleHandler) RegisterRoutes(server *gin.Engine) {
 	g.POST("/withdraw", ginx.WrapBodyAndToken[WithdrawReq, myjwt.UserClaims](h.Withdraw, "WithdrawArticle", h.l))
 	g.POST("/list", ginx.WrapBodyAndToken[ListReq, myjwt.UserClaims](h.List, "ListArticle", h.l))
 	g.GET("/detail/:id", ginx.WrapToken[myjwt.UserClaims](h.Detail, "DetailArticle", h.l))
+	// 回收站
+	g.POST("/delete", ginx.WrapBodyAndToken[DeleteReq, myjwt.UserClaims](h.Delete, "DeleteArticle", h.l))
+	g.POST("/restore", ginx.WrapBodyAndToken[RestoreReq, myjwt.UserClaims](h.Restore, "RestoreArticle", h.l))
+	g.POST("/trash", ginx.WrapBodyAndToken[ListReq, myjwt.UserClaims](h.ListTrash, "ListTrashArticle", h.l))
 
+	// 没登录也能看，站点地图和订阅源里的链接都指向这里
+	server.GET(PubDetailRoute, ginx.WrapOptionalToken(h.PubDetail, "DetailPubArticle", h.l))
 	gpub := server.Group("/pub")
-	gpub.GET("/:id", ginx.WrapToken[myjwt.UserClaims](h.PubDetail, "DetailPubArticle", h.l))
 	// 点赞是这个接口，取消点赞也是这个接口
 	// RESTful 风格
 	//gpub.GET("/like/:id", ginx.WrapToken[myjwt.UserClaims](h.PubDetail, "DetailPubArticle", h.l))
 	gpub.POST("/like", ginx.WrapBodyAndToken[LikeReq, myjwt.UserClaims](h.Like, "LikeArticle", h.l))
 	gpub.POST("/collect", ginx.WrapBodyAndToken[CollectReq, myjwt.UserClaims](h.Collect, "CollectArticle", h.l))
-	gpub.GET("/top/like", ginx.WrapFunc(h.TopLike, "TopLikeArticle", h.l))
+	server.GET(TopLikeRoute, ginx.WrapFunc(h.TopLike, "TopLikeArticle", h.l))
+	server.GET(RankingRoute, ginx.WrapFunc(h.Ranking, "RankingArticle", h.l))
+	gpub.GET("/ranking/explain/:id", ginx.WrapToken[myjwt.UserClaims](h.ExplainRanking, "ExplainRankingArticle", h.l))
+	server.GET(RankingHistoryRoute, ginx.WrapFunc(h.RankingHistory, "RankingHistoryArticle", h.l))
+	server.GET(RankingMoversRoute, ginx.WrapFunc(h.RankingMovers, "RankingMoversArticle", h.l))
 }
 
 func (h *ArticleHandler) Edit(ctx *gin.Context, req ArticleReq, uc myjwt.UserClaims) (ginx.Result, error) {
 
 	uid := uc.Uid
+	if !req.valid() {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
 
 	id, err := h.svc.Save(ctx, req.toDomain(uid))
-
+	if res, ok := h.versionConflict(err); ok {
+		return res, nil
+	}
+	if errors.Is(err, service.ErrArticleNotFound) {
+		// 回收站里的文章要先恢复才能编辑
+		return ginx.Result{
+			Code: codes.ArticleNotFound,
+			Msg:  "文章不存在",
+		}, nil
+	}
 	if err != nil {
 		return ginx.Result{
 			Code: codes.ArticleInternalServerError,
@@ -82,19 +131,44 @@ func (h *ArticleHandler) Edit(ctx *gin.Context, req ArticleReq, uc myjwt.UserCla
 		}, err
 	}
 
+	// 新建的文章版本号是 1，否则就是在原来的版本号上 +1
 	return ginx.Result{
 		Code: codes.ArticleOK,
 		Msg:  "创建成功",
-		Data: id,
+		Data: ArticleVersionVO{
+			Id:      id,
+			Version: req.Version + 1,
+		},
 	}, nil
 
 }
 
 func (h *ArticleHandler) Publish(ctx *gin.Context, req ArticleReq, uc myjwt.UserClaims) (ginx.Result, error) {
 	uid := uc.Uid
+	if !req.valid() {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
 
-	id, err := h.svc.Publish(ctx, req.toDomain(uid))
-
+	id, status, err := h.svc.Publish(ctx, req.toDomain(uid))
+	if res, ok := h.versionConflict(err); ok {
+		return res, nil
+	}
+	if errors.Is(err, service.ErrArticleNotFound) {
+		// 回收站里的文章要先恢复才能编辑
+		return ginx.Result{
+			Code: codes.ArticleNotFound,
+			Msg:  "文章不存在",
+		}, nil
+	}
+	if errors.Is(err, service.ErrSensitiveContent) {
+		return ginx.Result{
+			Code: codes.ArticleSensitiveContent,
+			Msg:  "标题或内容包含敏感词",
+		}, nil
+	}
 	if err != nil {
 		return ginx.Result{
 			Code: codes.ArticleInternalServerError,
@@ -102,14 +176,39 @@ func (h *ArticleHandler) Publish(ctx *gin.Context, req ArticleReq, uc myjwt.User
 		}, err
 	}
 
+	msg := "发表成功"
+	if status == domain.ArticleStatusPendingReview {
+		msg = "已提交审核"
+	}
+	// 发表和提交审核都会保存草稿，版本号一样是 +1
 	return ginx.Result{
 		Code: codes.ArticleOK,
-		Msg:  "发表成功",
-		Data: id,
+		Msg:  msg,
+		Data: ArticleVersionVO{
+			Id:      id,
+			Version: req.Version + 1,
+		},
 	}, nil
 
 }
 
+// versionConflict 如果是乐观锁冲突，就把服务端当前的版本号返回给前端
+// 这是一个业务错误，不需要打错误日志
+func (h *ArticleHandler) versionConflict(err error) (ginx.Result, bool) {
+	var conflict service.VersionConflictError
+	if !errors.As(err, &conflict) {
+		return ginx.Result{}, false
+	}
+	return ginx.Result{
+		Code: codes.ArticleVersionConflict,
+		Msg:  "文章已经被修改过了，请刷新后再编辑",
+		Data: ArticleVersionVO{
+			Id:      conflict.Id,
+			Version: conflict.Version,
+		},
+	}, true
+}
+
 func (h *ArticleHandler) Withdraw(ctx *gin.Context, req WithdrawReq, uc myjwt.UserClaims) (ginx.Result, error) {
 	uid := uc.Uid
 
@@ -138,7 +237,22 @@ func (h *ArticleHandler) Withdraw(ctx *gin.Context, req WithdrawReq, uc myjwt.Us
 func (h *ArticleHandler) List(ctx *gin.Context, req ListReq, uc myjwt.UserClaims) (ginx.Result, error) {
 	uid := uc.Uid
 
-	arts, err := h.svc.List(ctx, uid, req.Offset, req.Limit)
+	// 一页最多 100 条
+	if req.Limit <= 0 || req.Limit > 100 {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+	cursor, err := decodeCursor(req.Cursor)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, err
+	}
+
+	arts, err := h.svc.List(ctx, uid, cursor, req.Limit)
 	if err != nil {
 		return ginx.Result{
 			Code: codes.ArticleInternalServerError,
@@ -148,27 +262,127 @@ func (h *ArticleHandler) List(ctx *gin.Context, req ListReq, uc myjwt.UserClaims
 	// 在列表页，不显示全文，只显示一个"摘要"
 	// 比如说，简单的摘要就是前几句话
 	// 强大的摘要是 AI 帮你生成的
+	var next string
+	if len(arts) > 0 && len(arts) >= req.Limit {
+		next = encodeCursor(arts[len(arts)-1].Cursor())
+	}
 	return ginx.Result{
 		Code: codes.ArticleOK,
-		Data: slice.Map[domain.Article, ArticleVO](arts,
-			func(idx int, src domain.Article) ArticleVO {
-				return ArticleVO{
-					Id:       src.Id,
-					Title:    src.Title,
-					Abstract: src.Abstract(),
-					Status:   src.Status.ToUint8(),
-					// 这个列表请求，不需要返回内容
-					//Content: src.Content,
-					// 这个是创作者看自己的文章列表，也不需要这个字段
-					//Author: src.Author
-					Ctime: src.Ctime.Format(time.DateTime),
-					Utime: src.Utime.Format(time.DateTime),
-				}
-			}),
+		Data: ListArticleVO{
+			Articles: slice.Map[domain.Article, ArticleVO](arts,
+				func(idx int, src domain.Article) ArticleVO {
+					return ArticleVO{
+						Id:       src.Id,
+						Title:    src.Title,
+						Abstract: src.Abstract(),
+						Status:   src.Status.ToUint8(),
+						// 这个列表请求，不需要返回内容
+						//Content: src.Content,
+						// 这个是创作者看自己的文章列表，也不需要这个字段
+						//Author: src.Author
+						Version: src.Version,
+						Ctime:   src.Ctime.Format(time.DateTime),
+						Utime:   src.Utime.Format(time.DateTime),
+					}
+				}),
+			NextCursor: next,
+		},
 	}, nil
 
 }
 
+func (h *ArticleHandler) Delete(ctx *gin.Context, req DeleteReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.Delete(ctx, req.Id, uc.Uid)
+	if errors.Is(err, service.ErrArticleNotFound) {
+		return ginx.Result{
+			Code: codes.ArticleNotFound,
+			Msg:  "文章不存在",
+		}, nil
+	}
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInternalServerError,
+			Msg:  "系统错误",
+		}, err
+	}
+
+	return ginx.Result{
+		Code: codes.ArticleOK,
+		Msg:  "删除成功",
+		Data: req.Id,
+	}, nil
+}
+
+func (h *ArticleHandler) Restore(ctx *gin.Context, req RestoreReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.Restore(ctx, req.Id, uc.Uid)
+	if errors.Is(err, service.ErrArticleNotFound) {
+		return ginx.Result{
+			Code: codes.ArticleNotFound,
+			Msg:  "回收站里没有这篇文章",
+		}, nil
+	}
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInternalServerError,
+			Msg:  "系统错误",
+		}, err
+	}
+
+	return ginx.Result{
+		Code: codes.ArticleOK,
+		Msg:  "恢复成功",
+		Data: req.Id,
+	}, nil
+}
+
+func (h *ArticleHandler) ListTrash(ctx *gin.Context, req ListReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	// 一页最多 100 条
+	if req.Limit <= 0 || req.Limit > 100 {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+	cursor, err := decodeCursor(req.Cursor)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, err
+	}
+
+	arts, err := h.svc.ListTrash(ctx, uc.Uid, cursor, req.Limit)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInternalServerError,
+			Msg:  "系统错误",
+		}, err
+	}
+
+	var next string
+	if len(arts) > 0 && len(arts) >= req.Limit {
+		next = encodeCursor(arts[len(arts)-1].Cursor())
+	}
+	return ginx.Result{
+		Code: codes.ArticleOK,
+		Data: ListArticleVO{
+			Articles: slice.Map[domain.Article, ArticleVO](arts,
+				func(idx int, src domain.Article) ArticleVO {
+					return ArticleVO{
+						Id:       src.Id,
+						Title:    src.Title,
+						Abstract: src.Abstract(),
+						Status:   src.Status.ToUint8(),
+						Ctime:    src.Ctime.Format(time.DateTime),
+						Utime:    src.Utime.Format(time.DateTime),
+						Dtime:    src.Dtime.Format(time.DateTime),
+					}
+				}),
+			NextCursor: next,
+		},
+	}, nil
+}
+
 func (h *ArticleHandler) Detail(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
 	uid := uc.Uid
 	idstr := ctx.Param("id")
@@ -196,6 +410,9 @@ func (h *ArticleHandler) Detail(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Res
 			Content:  article.Content,
 			Abstract: article.Abstract(),
 			Status:   article.Status.ToUint8(),
+			Access:   article.Access.ToUint8(),
+			Tags:     article.Tags,
+			Version:  article.Version,
 			Utime:    article.Utime.Format(time.DateTime),
 			Ctime:    article.Ctime.Format(time.DateTime),
 		},
@@ -217,8 +434,10 @@ func (h *ArticleHandler) PubDetail(ctx *gin.Context, uc myjwt.UserClaims) (ginx.
 	var eg errgroup.Group
 	var article domain.Article
 	var interactive domain2.Interactive
+	var nav domain.SeriesNav
 
 	eg.Go(func() error {
+		var err error
 		article, err = h.svc.PubDetail(ctx, id, uid)
 		return err
 		/*if err != nil {
@@ -230,6 +449,7 @@ func (h *ArticleHandler) PubDetail(ctx *gin.Context, uc myjwt.UserClaims) (ginx.
 	})
 
 	eg.Go(func() error {
+		var err error
 		interactive, err = h.interSvc.Get(ctx, h.biz, id, uid)
 		// 这种是容错的写法
 		//if err != nil {
@@ -240,6 +460,16 @@ func (h *ArticleHandler) PubDetail(ctx *gin.Context, uc myjwt.UserClaims) (ginx.
 
 	})
 
+	eg.Go(func() error {
+		// 系列导航拿不到也不影响看文章
+		var err1 error
+		nav, err1 = h.seriesSvc.Nav(ctx, id)
+		if err1 != nil {
+			h.l.Error("获取系列导航失败", logger.Error(err1), logger.Int64("aid", id))
+		}
+		return nil
+	})
+
 	// 增加阅读计数。
 	// 选择kafka来增加阅读计数时，不需要这个go routine
 	// 发送事件给kafka时，在service里做
@@ -253,7 +483,19 @@ func (h *ArticleHandler) PubDetail(ctx *gin.Context, uc myjwt.UserClaims) (ginx.
 
 	}()*/
 
-	eg.Wait()
+	err = eg.Wait()
+	if errors.Is(err, service.ErrArticleNotFound) {
+		return ginx.Result{
+			Code: codes.ArticleNotFound,
+			Msg:  "文章不存在",
+		}, nil
+	}
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInternalServerError,
+			Msg:  "系统错误",
+		}, err
+	}
 
 	return ginx.Result{
 		Code: codes.ArticleOK,
@@ -264,17 +506,41 @@ func (h *ArticleHandler) PubDetail(ctx *gin.Context, uc myjwt.UserClaims) (ginx.
 			Abstract:   article.Abstract(),
 			Status:     article.Status.ToUint8(),
 			Author:     article.Author.Name,
+			Access:     article.Access.ToUint8(),
+			Locked:     article.Locked,
+			Tags:       article.Tags,
 			ReadCnt:    interactive.ReadCnt,
 			LikeCnt:    interactive.LikeCnt,
 			CollectCnt: interactive.CollectCnt,
+			CommentCnt: interactive.CommentCnt,
 			Liked:      interactive.Liked,
 			Collected:  interactive.Collected,
 			Utime:      article.Utime.Format(time.DateTime),
 			Ctime:      article.Ctime.Format(time.DateTime),
+			Series:     h.toSeriesNavVO(nav),
 		},
 	}, nil
 }
 
+func (h *ArticleHandler) toSeriesNavVO(nav domain.SeriesNav) *SeriesNavVO {
+	if nav.Series.Id == 0 {
+		return nil
+	}
+	vo := &SeriesNavVO{
+		Id:    nav.Series.Id,
+		Title: nav.Series.Title,
+		Index: nav.Index,
+		Total: nav.Total,
+	}
+	if nav.Prev.Id > 0 {
+		vo.Prev = &SeriesPartVO{Id: nav.Prev.Id, Title: nav.Prev.Title}
+	}
+	if nav.Next.Id > 0 {
+		vo.Next = &SeriesPartVO{Id: nav.Next.Id, Title: nav.Next.Title}
+	}
+	return vo
+}
+
 func (h *ArticleHandler) Like(ctx *gin.Context, req LikeReq, uc myjwt.UserClaims) (ginx.Result, error) {
 	uid := uc.Uid
 	var err error
@@ -307,6 +573,12 @@ func (h *ArticleHandler) Collect(ctx *gin.Context, req CollectReq, uc myjwt.User
 	} else {
 		err = h.interSvc.DeleteCollect(ctx, h.biz, req.Id, req.Cid, uid)
 	}
+	if errors.Is(err, service2.ErrCollectionNotFound) {
+		return ginx.Result{
+			Code: codes.CollectionNotFound,
+			Msg:  "收藏夹不存在",
+		}, nil
+	}
 	if err != nil {
 		return ginx.Result{
 			Code: codes.ArticleInternalServerError,
@@ -320,10 +592,75 @@ func (h *ArticleHandler) Collect(ctx *gin.Context, req CollectReq, uc myjwt.User
 	}, nil
 }
 
+// TopLike 前端可以用 n 指定取前几名，不能超过配置的 TopLike.Limit
 func (h *ArticleHandler) TopLike(ctx *gin.Context) (ginx.Result, error) {
+	n := TopLikeN.Load()
+	limit := TopLikeLimit.Load()
+	if nstr := ctx.Query("n"); nstr != "" {
+		var err error
+		n, err = strconv.ParseInt(nstr, 10, 64)
+		if err != nil || n <= 0 || n > limit {
+			return ginx.Result{
+				Code: codes.ArticleInvalidInput,
+				Msg:  "参数错误",
+			}, nil
+		}
+	}
+
+	tops, err := h.topLikeSvc.TopLike(ctx, n, limit)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInternalServerError,
+			Msg:  "系统错误",
+		}, err
+	}
 
-	data, err := h.interSvc.TopLike(ctx, h.biz, TopLikeN.Load(), TopLikeLimit.Load())
+	return ginx.Result{
+		Code: codes.ArticleOK,
+		Msg:  "OK",
+		Data: slice.Map[domain.TopArticle, ArticleVO](tops,
+			func(idx int, src domain.TopArticle) ArticleVO {
+				return ArticleVO{
+					Id:         src.Article.Id,
+					Title:      src.Article.Title,
+					Abstract:   src.Article.Abstract(),
+					Author:     src.Article.Author.Name,
+					Access:     src.Article.Access.ToUint8(),
+					LikeCnt:    src.LikeCnt,
+					ReadCnt:    src.ReadCnt,
+					CollectCnt: src.CollectCnt,
+					Ctime:      src.Article.Ctime.Format(time.DateTime),
+					Utime:      src.Article.Utime.Format(time.DateTime),
+				}
+			}),
+	}, nil
+}
 
+// Ranking 热榜，name 指定哪一个热榜，不传就是默认的周榜。
+// offset 和 limit 都是在热榜里面分页
+func (h *ArticleHandler) Ranking(ctx *gin.Context) (ginx.Result, error) {
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+
+	ranking, total, err := h.rankingSvc.GetTopN(ctx, ctx.Query("name"), offset, limit)
+	if errors.Is(err, service.ErrUnknownRanking) {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "热榜不存在",
+		}, nil
+	}
 	if err != nil {
 		return ginx.Result{
 			Code: codes.ArticleInternalServerError,
@@ -331,9 +668,192 @@ func (h *ArticleHandler) TopLike(ctx *gin.Context) (ginx.Result, error) {
 		}, err
 	}
 
+	vo := RankingVO{
+		Items: slice.Map[domain.RankingItem, RankingItemVO](ranking.Items,
+			func(idx int, src domain.RankingItem) RankingItemVO {
+				return RankingItemVO{
+					ArticleVO: ArticleVO{
+						Id:       src.Article.Id,
+						Title:    src.Article.Title,
+						Abstract: src.Article.Abstract(),
+						Author:   src.Article.Author.Name,
+						Access:   src.Article.Access.ToUint8(),
+						Ctime:    src.Article.Ctime.Format(time.DateTime),
+						Utime:    src.Article.Utime.Format(time.DateTime),
+					},
+					Rank:  offset + idx + 1,
+					Score: src.Score,
+				}
+			}),
+		Name:  ranking.Name,
+		Total: total,
+	}
+	// 热榜还没有算出来过
+	if !ranking.Ctime.IsZero() {
+		vo.GeneratedAt = ranking.Ctime.Format(time.DateTime)
+	}
+	return ginx.Result{
+		Code: codes.ArticleOK,
+		Msg:  "OK",
+		Data: vo,
+	}, nil
+}
+
+// ExplainRanking 现算一篇文章的热度，看看分数是怎么来的。
+// 可以用 strategy 指定别的打分策略，方便调参的时候对比，只有审核员能用
+func (h *ArticleHandler) ExplainRanking(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, fmt.Errorf("前端输入id错误，%v", err)
+	}
+
+	score, err := h.rankingSvc.Explain(ctx, uc.Uid, id, ctx.Query("strategy"))
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrNotModerator):
+		return ginx.Result{
+			Code: codes.ArticlePermissionDenied,
+			Msg:  "没有权限",
+		}, nil
+	case errors.Is(err, service.ErrUnknownRankingScorer),
+		errors.Is(err, service.ErrInvalidRankingScoreConfig):
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "打分策略不可用",
+		}, nil
+	case errors.Is(err, service.ErrArticleNotFound):
+		return ginx.Result{
+			Code: codes.ArticleNotFound,
+			Msg:  "文章不存在",
+		}, nil
+	default:
+		return ginx.Result{
+			Code: codes.ArticleInternalServerError,
+			Msg:  "系统错误",
+		}, err
+	}
+
+	return ginx.Result{
+		Code: codes.ArticleOK,
+		Msg:  "OK",
+		Data: RankingScoreVO{
+			Id:         id,
+			Strategy:   score.Strategy,
+			Formula:    score.Formula,
+			ReadCnt:    score.ReadCnt,
+			LikeCnt:    score.LikeCnt,
+			CollectCnt: score.CollectCnt,
+			Points:     score.Points,
+			AgeHours:   score.Age.Hours(),
+			Decay:      score.Decay,
+			Score:      score.Score,
+		},
+	}, nil
+}
+
+// RankingHistory 一篇文章在某个热榜上最近的名次变化，从新到旧
+func (h *ArticleHandler) RankingHistory(ctx *gin.Context) (ginx.Result, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, fmt.Errorf("前端输入id错误，%v", err)
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "24"))
+	if err != nil || limit <= 0 || limit > 100 {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+	name := ctx.DefaultQuery("name", service.RankingDefault)
+
+	positions, err := h.snapshotSvc.History(ctx, name, id, limit)
+	if errors.Is(err, service.ErrUnknownRanking) {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "热榜不存在",
+		}, nil
+	}
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInternalServerError,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Code: codes.ArticleOK,
+		Msg:  "OK",
+		Data: slice.Map[domain.RankingPosition, RankingPositionVO](positions,
+			func(idx int, src domain.RankingPosition) RankingPositionVO {
+				return RankingPositionVO{
+					Rank:  src.Rank,
+					Score: src.Score,
+					Ctime: src.Ctime.Format(time.DateTime),
+				}
+			}),
+	}, nil
+}
+
+// RankingMovers 最近两次快照之间名次变化最大的文章，delta 是正数代表上升
+func (h *ArticleHandler) RankingMovers(ctx *gin.Context) (ginx.Result, error) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+	name := ctx.DefaultQuery("name", service.RankingDefault)
+
+	movers, err := h.snapshotSvc.Movers(ctx, name, limit)
+	if errors.Is(err, service.ErrUnknownRanking) {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "热榜不存在",
+		}, nil
+	}
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInternalServerError,
+			Msg:  "系统错误",
+		}, err
+	}
+
+	vo := RankingMoversVO{
+		Name: movers.Name,
+		Items: slice.Map[domain.RankingMove, RankingMoveVO](movers.Moves,
+			func(idx int, src domain.RankingMove) RankingMoveVO {
+				return RankingMoveVO{
+					ArticleVO: ArticleVO{
+						Id:       src.Article.Id,
+						Title:    src.Article.Title,
+						Abstract: src.Article.Abstract(),
+						Author:   src.Article.Author.Name,
+						Access:   src.Article.Access.ToUint8(),
+						Ctime:    src.Article.Ctime.Format(time.DateTime),
+						Utime:    src.Article.Utime.Format(time.DateTime),
+					},
+					Rank:      src.Rank,
+					PrevRank:  src.PrevRank,
+					Delta:     src.Delta,
+					Score:     src.Score,
+					PrevScore: src.PrevScore,
+				}
+			}),
+	}
+	// 快照还不够两次
+	if !movers.To.IsZero() {
+		vo.From = movers.From.Format(time.DateTime)
+		vo.To = movers.To.Format(time.DateTime)
+	}
 	return ginx.Result{
 		Code: codes.ArticleOK,
 		Msg:  "OK",
-		Data: data,
+		Data: vo,
 	}, nil
 }
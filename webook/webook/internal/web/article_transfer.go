@@ -0,0 +1,182 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/codes"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	myjwt "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web/jwt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/ginx"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+var _ handler = (*ArticleTransferHandler)(nil)
+
+// ArticleTransferHandler Markdown 批量导入导出
+type ArticleTransferHandler struct {
+	svc service.ArticleTransferService
+	l   logger.Logger
+}
+
+func NewArticleTransferHandler(svc service.ArticleTransferService, l logger.Logger) *ArticleTransferHandler {
+	return &ArticleTransferHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *ArticleTransferHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/articles")
+	g.POST("/import", ginx.WrapToken[myjwt.UserClaims](h.Import, "ImportArticle", h.l))
+	g.GET("/import/:id", ginx.WrapToken[myjwt.UserClaims](h.ImportJob, "ImportArticleJob", h.l))
+	// 导出直接返回 zip 文件，不走 ginx
+	g.GET("/export", h.Export)
+}
+
+func (h *ArticleTransferHandler) Import(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	f, err := h.filePart(ctx, "file")
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInvalidInput,
+			Msg:  "参数错误",
+		}, err
+	}
+	defer f.Close()
+
+	job, err := h.svc.Import(ctx, uc.Uid, f)
+	switch {
+	case errors.Is(err, service.ErrImportTooLarge):
+		return ginx.Result{
+			Code: codes.ArticleImportTooLarge,
+			Msg:  "压缩包太大",
+		}, nil
+	case errors.Is(err, service.ErrTooManyImportFiles):
+		return ginx.Result{
+			Code: codes.ArticleImportTooLarge,
+			Msg:  "压缩包里的文件太多",
+		}, nil
+	case errors.Is(err, service.ErrInvalidImportFile):
+		return ginx.Result{
+			Code: codes.ArticleImportInvalidFile,
+			Msg:  "不是合法的 zip 压缩包",
+		}, nil
+	case err != nil:
+		return ginx.Result{
+			Code: codes.ArticleInternalServerError,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Code: codes.ArticleOK,
+		Msg:  "OK",
+		Data: h.toJobVO(job),
+	}, nil
+}
+
+// filePart 直接读 multipart 里的文件，不用 FormFile，FormFile 会先把整个文件读进内存
+func (h *ArticleTransferHandler) filePart(ctx *gin.Context, name string) (*multipart.Part, error) {
+	mr, err := ctx.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, fmt.Errorf("没有找到文件 %s: %w", name, err)
+		}
+		if part.FormName() == name {
+			return part, nil
+		}
+		_ = part.Close()
+	}
+}
+
+func (h *ArticleTransferHandler) ImportJob(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	job, err := h.svc.GetImportJob(ctx, uc.Uid, ctx.Param("id"))
+	if errors.Is(err, service.ErrImportJobNotFound) {
+		return ginx.Result{
+			Code: codes.ArticleImportJobNotFound,
+			Msg:  "导入任务不存在",
+		}, nil
+	}
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ArticleInternalServerError,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Code: codes.ArticleOK,
+		Msg:  "OK",
+		Data: h.toJobVO(job),
+	}, nil
+}
+
+func (h *ArticleTransferHandler) Export(ctx *gin.Context) {
+	val, ok := ctx.Get("claims")
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	uc, ok := val.(*myjwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="articles-%s.zip"`,
+		time.Now().Format("20060102")))
+	ctx.Status(http.StatusOK)
+	// 边查边写，已经开始写响应之后就没办法再改状态码了，只能记录日志
+	err := h.svc.Export(ctx, uc.Uid, ctx.Writer)
+	if err != nil {
+		h.l.Error("导出文章失败", logger.Error(err), logger.Int64("uid", uc.Uid))
+	}
+}
+
+func (h *ArticleTransferHandler) toJobVO(job domain.ImportJob) ImportJobVO {
+	files := make([]ImportFileVO, 0, len(job.Results))
+	for _, r := range job.Results {
+		files = append(files, ImportFileVO{
+			File:    r.File,
+			Id:      r.Id,
+			Error:   r.Error,
+			Warning: r.Warning,
+		})
+	}
+	return ImportJobVO{
+		Id:     job.Id,
+		Status: job.Status.ToUint8(),
+		Total:  job.Total,
+		Done:   len(job.Results),
+		Files:  files,
+		Error:  job.Error,
+		Ctime:  job.Ctime.Format(time.DateTime),
+		Utime:  job.Utime.Format(time.DateTime),
+	}
+}
+
+type ImportJobVO struct {
+	Id string `json:"id"`
+	// Status 1 进行中，2 完成，3 失败
+	Status uint8          `json:"status"`
+	Total  int            `json:"total"`
+	Done   int            `json:"done"`
+	Files  []ImportFileVO `json:"files"`
+	// Error 任务失败的原因
+	Error string `json:"error,omitempty"`
+	Ctime string `json:"ctime"`
+	Utime string `json:"utime"`
+}
+
+type ImportFileVO struct {
+	File    string `json:"file"`
+	Id      int64  `json:"id,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Warning string `json:"warning,omitempty"`
+}
@@ -1,6 +1,12 @@
 package web
 
-import "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"time"
+)
 
 // VO view object，就是对标前端的
 
@@ -17,10 +23,18 @@ type ArticleVO struct {
 	// 涉及到国际化，也是后端来处理
 	Status uint8  `json:"status"`
 	Author string `json:"author"`
+	// Access 0 公开，1 登录可见，2 会员可见
+	Access uint8 `json:"access"`
+	// Locked 没有权限看全文，Content 只有摘要
+	Locked bool     `json:"locked,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	// 乐观锁版本号，编辑的时候要原样带回来
+	Version int64 `json:"version"`
 	// 计数
 	ReadCnt    int64 `json:"read_cnt"`
 	LikeCnt    int64 `json:"like_cnt"`
 	CollectCnt int64 `json:"collect_cnt"`
+	CommentCnt int64 `json:"comment_cnt"`
 
 	// 我个人有没有收藏，有没有点赞
 	Liked     bool `json:"liked"`
@@ -28,21 +42,137 @@ type ArticleVO struct {
 
 	Ctime string `json:"ctime"`
 	Utime string `json:"utime"`
+	// 放进回收站的时间，只有回收站列表才有
+	Dtime string `json:"dtime,omitempty"`
+	// 所在的系列，只有文章详情页才有
+	Series *SeriesNavVO `json:"series,omitempty"`
+}
+
+type RankingVO struct {
+	// Name daily、weekly、monthly 或者 member
+	Name  string          `json:"name"`
+	Items []RankingItemVO `json:"items"`
+	// Total 整个热榜有多少篇，用来算一共有几页
+	Total int `json:"total"`
+	// GeneratedAt 这一份热榜的计算时间
+	GeneratedAt string `json:"generated_at"`
+}
+
+type RankingItemVO struct {
+	ArticleVO
+	// Rank 从 1 开始
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"`
+}
+
+// RankingPositionVO 某一次热榜快照里的名次
+type RankingPositionVO struct {
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"`
+	// Ctime 快照的时间
+	Ctime string `json:"ctime"`
+}
+
+type RankingMoversVO struct {
+	Name string `json:"name"`
+	// From 和 To 是用来对比的两次快照的时间
+	From  string          `json:"from"`
+	To    string          `json:"to"`
+	Items []RankingMoveVO `json:"items"`
+}
+
+type RankingMoveVO struct {
+	ArticleVO
+	// Rank 0 代表掉出了热榜
+	Rank int `json:"rank"`
+	// PrevRank 0 代表新上榜
+	PrevRank  int     `json:"prev_rank"`
+	Delta     int     `json:"delta"`
+	Score     float64 `json:"score"`
+	PrevScore float64 `json:"prev_score"`
+}
+
+// RankingScoreVO 一篇文章热度的计算过程
+type RankingScoreVO struct {
+	Id         int64   `json:"id"`
+	Strategy   string  `json:"strategy"`
+	Formula    string  `json:"formula"`
+	ReadCnt    int64   `json:"read_cnt"`
+	LikeCnt    int64   `json:"like_cnt"`
+	CollectCnt int64   `json:"collect_cnt"`
+	Points     float64 `json:"points"`
+	AgeHours   float64 `json:"age_hours"`
+	Decay      float64 `json:"decay"`
+	Score      float64 `json:"score"`
 }
 
 type ListReq struct {
-	Offset int `json:"offset"`
-	Limit  int `json:"limit"`
+	// Cursor 上一页返回的 next_cursor，第一页不用传
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+}
+
+type ListArticleVO struct {
+	Articles []ArticleVO `json:"articles"`
+	// NextCursor 为空说明没有下一页了
+	NextCursor string `json:"next_cursor"`
+}
+
+var errInvalidCursor = errors.New("非法的游标")
+
+// encodeCursor 游标对前端来说是不透明的，前端只需要原样带回来
+func encodeCursor(c domain.ArticleCursor) string {
+	raw := fmt.Sprintf("%d:%d", c.Utime.UnixMilli(), c.Id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(token string) (domain.ArticleCursor, error) {
+	if token == "" {
+		return domain.ArticleCursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return domain.ArticleCursor{}, fmt.Errorf("%w: %s", errInvalidCursor, token)
+	}
+	var utime, id int64
+	_, err = fmt.Sscanf(string(raw), "%d:%d", &utime, &id)
+	if err != nil || utime <= 0 || id <= 0 {
+		return domain.ArticleCursor{}, fmt.Errorf("%w: %s", errInvalidCursor, token)
+	}
+	return domain.ArticleCursor{
+		Utime: time.UnixMilli(utime),
+		Id:    id,
+	}, nil
 }
 
 type WithdrawReq struct {
 	Id int64 `json:"id"`
 }
 
+type DeleteReq struct {
+	Id int64 `json:"id"`
+}
+
+type RestoreReq struct {
+	Id int64 `json:"id"`
+}
+
+// ArticleVersionVO 保存、发表成功之后的版本号，或者乐观锁冲突时服务端当前的版本号
+// 前端下一次编辑要带上这个版本号
+type ArticleVersionVO struct {
+	Id      int64 `json:"id"`
+	Version int64 `json:"version"`
+}
+
 type ArticleReq struct {
 	Id      int64  `json:"id"`
 	Title   string `json:"title"`
 	Content string `json:"content"`
+	// Version 前端拿到这篇草稿时的版本号，新建的时候不用传
+	Version int64 `json:"version"`
+	// Access 0 公开，1 登录可见，2 会员可见
+	Access uint8    `json:"access"`
+	Tags   []string `json:"tags"`
 }
 
 type LikeReq struct {
@@ -59,11 +189,21 @@ type CollectReq struct {
 	Collect bool `json:"collect"`
 }
 
+// valid 标签不符合要求的直接拒绝，不悄悄丢掉
+func (req ArticleReq) valid() bool {
+	_, dropped := domain.NormalizeTags(req.Tags)
+	return domain.ArticleAccess(req.Access).IsValid() && len(dropped) == 0
+}
+
 func (req ArticleReq) toDomain(uid int64) domain.Article {
+	tags, _ := domain.NormalizeTags(req.Tags)
 	return domain.Article{
 		Id:      req.Id,
 		Title:   req.Title,
 		Content: req.Content,
+		Version: req.Version,
+		Access:  domain.ArticleAccess(req.Access),
+		Tags:    tags,
 		Author: domain.Author{
 			Id: uid,
 		},
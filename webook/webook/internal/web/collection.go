@@ -0,0 +1,193 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/codes"
+	service2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	myjwt "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web/jwt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/ginx"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+var _ handler = (*CollectionHandler)(nil)
+
+type CollectionHandler struct {
+	svc service.InteractiveService
+	// userIntrSvc 收藏夹里的文章要带上标题之类的
+	userIntrSvc service2.UserInteractiveService
+	l           logger.Logger
+	biz         string
+	maxLimit    int
+}
+
+func NewCollectionHandler(svc service.InteractiveService,
+	userIntrSvc service2.UserInteractiveService, l logger.Logger) *CollectionHandler {
+	return &CollectionHandler{
+		svc:         svc,
+		userIntrSvc: userIntrSvc,
+		l:           l,
+		biz:         "article",
+		maxLimit:    100,
+	}
+}
+
+func (h *CollectionHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/collections")
+	g.POST("/create", ginx.WrapBodyAndToken[CollectionReq, myjwt.UserClaims](h.Create, "CreateCollection", h.l))
+	g.POST("/edit", ginx.WrapBodyAndToken[CollectionReq, myjwt.UserClaims](h.Edit, "EditCollection", h.l))
+	g.POST("/delete", ginx.WrapBodyAndToken[DeleteCollectionReq, myjwt.UserClaims](h.Delete, "DeleteCollection", h.l))
+	g.GET("/mine", ginx.WrapToken[myjwt.UserClaims](h.Mine, "MyCollections", h.l))
+	// 公开的收藏夹，分享出去别人也能看
+	g.GET("/:id", ginx.WrapToken[myjwt.UserClaims](h.Detail, "DetailCollection", h.l))
+	// id 为 0 是自己的默认收藏夹
+	g.GET("/:id/items", ginx.WrapToken[myjwt.UserClaims](h.Items, "CollectionItems", h.l))
+}
+
+func (h *CollectionHandler) Create(ctx *gin.Context, req CollectionReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	if res, ok := h.checkReq(req); !ok {
+		return res, nil
+	}
+	id, err := h.svc.CreateCollection(ctx, h.toDomain(req, uc.Uid))
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.CollectionOK,
+		Data: id,
+	}, nil
+}
+
+func (h *CollectionHandler) Edit(ctx *gin.Context, req CollectionReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	if res, ok := h.checkReq(req); !ok {
+		return res, nil
+	}
+	err := h.svc.UpdateCollection(ctx, h.toDomain(req, uc.Uid))
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.CollectionOK,
+		Data: req.Id,
+	}, nil
+}
+
+func (h *CollectionHandler) Delete(ctx *gin.Context, req DeleteCollectionReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.DeleteCollection(ctx, req.Id, uc.Uid)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.CollectionOK,
+		Msg:  "删除成功",
+	}, nil
+}
+
+func (h *CollectionHandler) Mine(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	cs, err := h.svc.ListCollections(ctx, uc.Uid)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.CollectionOK,
+		Data: slice.Map[domain.Collection, CollectionVO](cs, func(idx int, src domain.Collection) CollectionVO {
+			return h.toVO(src)
+		}),
+	}, nil
+}
+
+func (h *CollectionHandler) Detail(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.CollectionInvalidInput,
+			Msg:  "参数错误",
+		}, fmt.Errorf("前端输入id错误，%v", err)
+	}
+	c, err := h.svc.GetCollection(ctx, id, uc.Uid)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.CollectionOK,
+		Data: h.toVO(c),
+	}, nil
+}
+
+func (h *CollectionHandler) Items(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.CollectionInvalidInput,
+			Msg:  "参数错误",
+		}, fmt.Errorf("前端输入id错误，%v", err)
+	}
+	offset, limit, ok := parsePage(ctx, h.maxLimit)
+	if !ok {
+		return ginx.Result{
+			Code: codes.CollectionInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+	items, err := h.userIntrSvc.CollectionItems(ctx, id, uc.Uid, offset, limit)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.CollectionOK,
+		Data: toInteractedItemVOs(items, h.biz),
+	}, nil
+}
+
+func (h *CollectionHandler) checkReq(req CollectionReq) (ginx.Result, bool) {
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > 64 ||
+		!domain.CollectionVisibility(req.Visibility).Valid() {
+		return ginx.Result{
+			Code: codes.CollectionInvalidInput,
+			Msg:  "名字不能为空也不能太长，可见性只能是私有或者公开",
+		}, false
+	}
+	return ginx.Result{}, true
+}
+
+// errResult 业务错误不需要打错误日志
+func (h *CollectionHandler) errResult(err error) (ginx.Result, error) {
+	if errors.Is(err, service.ErrCollectionNotFound) {
+		return ginx.Result{
+			Code: codes.CollectionNotFound,
+			Msg:  "收藏夹不存在",
+		}, nil
+	}
+	return ginx.Result{
+		Code: codes.CollectionInternalServer,
+		Msg:  "系统错误",
+	}, err
+}
+
+func (h *CollectionHandler) toDomain(req CollectionReq, uid int64) domain.Collection {
+	return domain.Collection{
+		Id:         req.Id,
+		Uid:        uid,
+		Name:       req.Name,
+		Visibility: domain.CollectionVisibility(req.Visibility),
+	}
+}
+
+func (h *CollectionHandler) toVO(c domain.Collection) CollectionVO {
+	return CollectionVO{
+		Id:         c.Id,
+		Uid:        c.Uid,
+		Name:       c.Name,
+		Visibility: c.Visibility.ToUint8(),
+		ItemCnt:    c.ItemCnt,
+		Ctime:      c.Ctime.Format(time.DateTime),
+		Utime:      c.Utime.Format(time.DateTime),
+	}
+}
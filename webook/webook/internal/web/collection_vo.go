@@ -0,0 +1,23 @@
+package web
+
+type CollectionReq struct {
+	// 新建的时候不用传
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+	// Visibility 0-私有，1-公开
+	Visibility uint8 `json:"visibility"`
+}
+
+type DeleteCollectionReq struct {
+	Id int64 `json:"id"`
+}
+
+type CollectionVO struct {
+	Id         int64  `json:"id"`
+	Uid        int64  `json:"uid"`
+	Name       string `json:"name"`
+	Visibility uint8  `json:"visibility"`
+	ItemCnt    int64  `json:"item_cnt"`
+	Ctime      string `json:"ctime"`
+	Utime      string `json:"utime"`
+}
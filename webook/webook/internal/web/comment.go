@@ -0,0 +1,250 @@
+package web
+
+import (
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/service"
+	domain2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/domain"
+	service2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/codes"
+	domain3 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	service3 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	myjwt "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web/jwt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/ginx"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"time"
+	"unicode/utf8"
+)
+
+var _ handler = (*CommentHandler)(nil)
+
+type CommentHandler struct {
+	svc      service.CommentService
+	interSvc service2.InteractiveService
+	userSvc  service3.UserService
+	l        logger.Logger
+	biz      string
+}
+
+func NewCommentHandler(svc service.CommentService, interSvc service2.InteractiveService,
+	userSvc service3.UserService, l logger.Logger) *CommentHandler {
+	return &CommentHandler{
+		svc:      svc,
+		interSvc: interSvc,
+		userSvc:  userSvc,
+		l:        l,
+		biz:      service.BizComment,
+	}
+}
+
+func (h *CommentHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/comments")
+	g.POST("/create", ginx.WrapBodyAndToken[CreateCommentReq, myjwt.UserClaims](h.Create, "CreateComment", h.l))
+	g.POST("/delete", ginx.WrapBodyAndToken[DeleteCommentReq, myjwt.UserClaims](h.Delete, "DeleteComment", h.l))
+	g.POST("/list", ginx.WrapBodyAndToken[ListCommentReq, myjwt.UserClaims](h.List, "ListComment", h.l))
+	g.POST("/replies", ginx.WrapBodyAndToken[ListReplyReq, myjwt.UserClaims](h.Replies, "ListReply", h.l))
+	// 点赞和取消点赞都是这个接口，走的是 interactive 的 Like
+	g.POST("/like", ginx.WrapBodyAndToken[LikeCommentReq, myjwt.UserClaims](h.Like, "LikeComment", h.l))
+}
+
+func (h *CommentHandler) Create(ctx *gin.Context, req CreateCommentReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	if req.Biz == "" || req.BizId <= 0 ||
+		req.Content == "" || utf8.RuneCountInString(req.Content) > 1024 {
+		return ginx.Result{
+			Code: codes.CommentInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+
+	c := domain.Comment{
+		Commentator: domain.User{
+			Id: uc.Uid,
+		},
+		Biz:     req.Biz,
+		BizId:   req.BizId,
+		Content: req.Content,
+	}
+	if req.ParentId > 0 {
+		c.ParentComment = &domain.Comment{Id: req.ParentId}
+	}
+
+	id, err := h.svc.Create(ctx, c)
+	switch {
+	case err == nil:
+		return ginx.Result{
+			Code: codes.CommentOK,
+			Msg:  "评论成功",
+			Data: id,
+		}, nil
+	case errors.Is(err, service.ErrSensitiveContent):
+		return ginx.Result{
+			Code: codes.CommentSensitiveContent,
+			Msg:  "评论包含敏感词",
+		}, nil
+	case errors.Is(err, service.ErrCommentNotFound),
+		errors.Is(err, service.ErrInvalidParentBiz):
+		return ginx.Result{
+			Code: codes.CommentNotFound,
+			Msg:  "回复的评论不存在",
+		}, err
+	default:
+		return ginx.Result{
+			Code: codes.CommentInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+}
+
+func (h *CommentHandler) Delete(ctx *gin.Context, req DeleteCommentReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.Delete(ctx, req.Id, uc.Uid)
+	switch {
+	case err == nil:
+		return ginx.Result{
+			Code: codes.CommentOK,
+			Msg:  "删除成功",
+			Data: req.Id,
+		}, nil
+	case errors.Is(err, service.ErrCommentNotFound):
+		return ginx.Result{
+			Code: codes.CommentNotFound,
+			Msg:  "评论不存在",
+		}, err
+	case errors.Is(err, service.ErrPermissionDenied):
+		return ginx.Result{
+			Code: codes.CommentPermissionDenied,
+			Msg:  "只能删除自己的评论",
+		}, err
+	default:
+		return ginx.Result{
+			Code: codes.CommentInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+}
+
+func (h *CommentHandler) List(ctx *gin.Context, req ListCommentReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	cs, err := h.svc.List(ctx, req.Biz, req.BizId, req.MinId, req.Limit)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.CommentInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+
+	// 点赞数和回复数都在 interactive 里面，一次性拿出来
+	ids := make([]int64, 0, len(cs))
+	uids := make([]int64, 0, len(cs))
+	for _, c := range cs {
+		ids = append(ids, c.Id)
+		uids = append(uids, c.Commentator.Id)
+		for _, child := range c.Children {
+			ids = append(ids, child.Id)
+			uids = append(uids, child.Commentator.Id)
+		}
+	}
+	intrs, err := h.interSvc.GetByIds(ctx, h.biz, ids)
+	if err != nil {
+		// 计数拿不到，评论照样返回
+		h.l.Error("获取评论计数失败", logger.Error(err))
+	}
+
+	return ginx.Result{
+		Code: codes.CommentOK,
+		Data: h.toVOs(cs, intrs, h.users(ctx, uids)),
+	}, nil
+}
+
+func (h *CommentHandler) Replies(ctx *gin.Context, req ListReplyReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	cs, err := h.svc.Replies(ctx, req.Rid, req.MaxId, req.Limit)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.CommentInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+
+	ids := slice.Map[domain.Comment, int64](cs, func(idx int, src domain.Comment) int64 {
+		return src.Id
+	})
+	uids := slice.Map[domain.Comment, int64](cs, func(idx int, src domain.Comment) int64 {
+		return src.Commentator.Id
+	})
+	intrs, err := h.interSvc.GetByIds(ctx, h.biz, ids)
+	if err != nil {
+		h.l.Error("获取回复计数失败", logger.Error(err))
+	}
+
+	return ginx.Result{
+		Code: codes.CommentOK,
+		Data: h.toVOs(cs, intrs, h.users(ctx, uids)),
+	}, nil
+}
+
+func (h *CommentHandler) Like(ctx *gin.Context, req LikeCommentReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	var err error
+	if req.Like {
+		err = h.interSvc.Like(ctx, h.biz, req.Id, uc.Uid, TopLikeLimit.Load())
+	} else {
+		err = h.interSvc.Unlike(ctx, h.biz, req.Id, uc.Uid, TopLikeLimit.Load())
+	}
+	if err != nil {
+		return ginx.Result{
+			Code: codes.CommentInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+
+	return ginx.Result{
+		Code: codes.CommentOK,
+		Msg:  "OK",
+	}, nil
+}
+
+// users 评论人的昵称拿不到，评论照样返回
+func (h *CommentHandler) users(ctx *gin.Context, uids []int64) map[int64]domain3.User {
+	users, err := h.userSvc.FindByIds(ctx, uids)
+	if err != nil {
+		h.l.Error("获取评论人昵称失败", logger.Error(err))
+	}
+	return users
+}
+
+func (h *CommentHandler) toVOs(cs []domain.Comment, intrs map[int64]domain2.Interactive,
+	users map[int64]domain3.User) []CommentVO {
+	return slice.Map[domain.Comment, CommentVO](cs, func(idx int, src domain.Comment) CommentVO {
+		vo := h.toVO(src, intrs, users)
+		vo.Children = h.toVOs(src.Children, intrs, users)
+		return vo
+	})
+}
+
+func (h *CommentHandler) toVO(c domain.Comment, intrs map[int64]domain2.Interactive,
+	users map[int64]domain3.User) CommentVO {
+	vo := CommentVO{
+		Id:       c.Id,
+		Uid:      c.Commentator.Id,
+		Nickname: users[c.Commentator.Id].Nickname,
+		Content:  c.Content,
+		Ctime:    c.Ctime.Format(time.DateTime),
+	}
+	if c.RootComment != nil {
+		vo.RootId = c.RootComment.Id
+	}
+	if c.ParentComment != nil {
+		vo.ParentId = c.ParentComment.Id
+	}
+	intr := intrs[c.Id]
+	vo.LikeCnt = intr.LikeCnt
+	if c.IsRoot() {
+		vo.ReplyCnt = intr.CommentCnt
+	}
+	return vo
+}
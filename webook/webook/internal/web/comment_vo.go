@@ -0,0 +1,51 @@
+package web
+
+type CommentVO struct {
+	Id  int64 `json:"id"`
+	Uid int64 `json:"uid"`
+	// Nickname 评论人的昵称，查不到的时候为空
+	Nickname string `json:"nickname"`
+	Content  string `json:"content"`
+	// 根评论和直接回复的评论，根评论这两个都是 0
+	RootId   int64 `json:"root_id"`
+	ParentId int64 `json:"parent_id"`
+	LikeCnt  int64 `json:"like_cnt"`
+	// 只有根评论有，代表回复数
+	ReplyCnt int64 `json:"reply_cnt"`
+	// 最早的几条回复
+	Children []CommentVO `json:"children"`
+
+	Ctime string `json:"ctime"`
+}
+
+type CreateCommentReq struct {
+	Biz     string `json:"biz"`
+	BizId   int64  `json:"biz_id"`
+	Content string `json:"content"`
+	// 回复的评论，为 0 就是根评论
+	ParentId int64 `json:"parent_id"`
+}
+
+type DeleteCommentReq struct {
+	Id int64 `json:"id"`
+}
+
+type ListCommentReq struct {
+	Biz   string `json:"biz"`
+	BizId int64  `json:"biz_id"`
+	// 上一页最后一条根评论的 ID，第一页传 0
+	MinId int64 `json:"min_id"`
+	Limit int64 `json:"limit"`
+}
+
+type ListReplyReq struct {
+	Rid int64 `json:"rid"`
+	// 上一页最后一条回复的 ID，第一页传 0
+	MaxId int64 `json:"max_id"`
+	Limit int64 `json:"limit"`
+}
+
+type LikeCommentReq struct {
+	Id   int64 `json:"id"`
+	Like bool  `json:"like"`
+}
@@ -0,0 +1,95 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/feed"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// FeedsPath 订阅源不需要登录
+const FeedsPath = "/feeds"
+
+var _ handler = (*FeedHandler)(nil)
+
+// FeedHandler RSS 和 Atom 订阅源，.xml 结尾的是 RSS 2.0，.atom 结尾的是 Atom
+type FeedHandler struct {
+	svc service.FeedService
+	l   logger.Logger
+}
+
+func NewFeedHandler(svc service.FeedService, l logger.Logger) *FeedHandler {
+	return &FeedHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *FeedHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group(FeedsPath)
+	g.GET("/latest.xml", h.Latest)
+	g.GET("/latest.atom", h.Latest)
+	g.GET("/top.xml", h.Top)
+	g.GET("/top.atom", h.Top)
+	// gin 的路径参数不能带后缀，所以 123.xml 整个作为参数
+	g.GET("/authors/:file", h.Author)
+}
+
+func (h *FeedHandler) Latest(ctx *gin.Context) {
+	doc, err := h.svc.Latest(ctx, h.format(ctx.Request.URL.Path))
+	h.serve(ctx, doc, err)
+}
+
+func (h *FeedHandler) Top(ctx *gin.Context) {
+	doc, err := h.svc.Top(ctx, h.format(ctx.Request.URL.Path))
+	h.serve(ctx, doc, err)
+}
+
+func (h *FeedHandler) Author(ctx *gin.Context) {
+	file := ctx.Param("file")
+	format := h.format(file)
+	uid, err := strconv.ParseInt(strings.TrimSuffix(file, path.Ext(file)), 10, 64)
+	if err != nil || !format.IsValid() {
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	doc, err := h.svc.Author(ctx, uid, format)
+	h.serve(ctx, doc, err)
+}
+
+// serve 交给 http.ServeContent 处理 If-None-Match 和 If-Modified-Since
+func (h *FeedHandler) serve(ctx *gin.Context, doc domain.FeedDoc, err error) {
+	if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrInvalidFeedFormat) {
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.l.Error("生成订阅源失败", logger.Error(err),
+			logger.String("path", ctx.Request.URL.Path))
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	ctx.Header("Content-Type", doc.ContentType)
+	ctx.Header("ETag", doc.ETag)
+	ctx.Header("Cache-Control", "public, max-age=300")
+	http.ServeContent(ctx.Writer, ctx.Request, "", doc.LastModified, bytes.NewReader(doc.Body))
+}
+
+// format 别的后缀返回空的格式，IsValid 不通过
+func (h *FeedHandler) format(name string) feed.Format {
+	switch path.Ext(name) {
+	case ".xml":
+		return feed.FormatRSS
+	case ".atom":
+		return feed.FormatAtom
+	default:
+		return ""
+	}
+}
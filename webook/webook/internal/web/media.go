@@ -0,0 +1,99 @@
+package web
+
+import (
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/codes"
+	myjwt "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web/jwt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/ginx"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/objectstore"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+var _ handler = (*MediaHandler)(nil)
+
+// MediaFilesPath 本地存储的时候，文件从这个路径对外提供访问，不需要登录
+const MediaFilesPath = "/media/files"
+
+type MediaHandler struct {
+	svc   service.MediaService
+	store objectstore.Store
+	l     logger.Logger
+}
+
+func NewMediaHandler(svc service.MediaService, store objectstore.Store, l logger.Logger) *MediaHandler {
+	return &MediaHandler{
+		svc:   svc,
+		store: store,
+		l:     l,
+	}
+}
+
+func (h *MediaHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/media")
+	g.POST("/upload", ginx.WrapToken[myjwt.UserClaims](h.Upload, "UploadMedia", h.l))
+	// S3 之类的有自己的访问域名，只有本地存储需要我们自己提供访问
+	if fs, ok := h.store.(http.Handler); ok {
+		g.GET("/files/*key", gin.WrapH(http.StripPrefix(MediaFilesPath, fs)))
+	}
+}
+
+func (h *MediaHandler) Upload(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		return ginx.Result{
+			Code: codes.MediaInvalidInput,
+			Msg:  "参数错误",
+		}, err
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return ginx.Result{
+			Code: codes.MediaInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+	defer f.Close()
+
+	m, err := h.svc.Upload(ctx, uc.Uid, f)
+	switch {
+	case errors.Is(err, service.ErrMediaTooLarge):
+		return ginx.Result{
+			Code: codes.MediaTooLarge,
+			Msg:  "文件太大",
+		}, nil
+	case errors.Is(err, service.ErrUnsupportedMediaType):
+		return ginx.Result{
+			Code: codes.MediaUnsupportedType,
+			Msg:  "不支持的文件类型",
+		}, nil
+	case err != nil:
+		return ginx.Result{
+			Code: codes.MediaInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+
+	return ginx.Result{
+		Code: codes.MediaOK,
+		Msg:  "上传成功",
+		Data: MediaVO{
+			Id:          m.Id,
+			URL:         m.URL,
+			Hash:        m.Hash,
+			ContentType: m.ContentType,
+			Size:        m.Size,
+		},
+	}, nil
+}
+
+type MediaVO struct {
+	Id int64 `json:"id"`
+	// 文章内容里面直接引用这个地址
+	URL         string `json:"url"`
+	Hash        string `json:"hash"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
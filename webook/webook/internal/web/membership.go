@@ -0,0 +1,101 @@
+package web
+
+import (
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/codes"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	myjwt "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web/jwt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/ginx"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+var _ handler = (*MembershipHandler)(nil)
+
+type MembershipHandler struct {
+	svc service.MembershipService
+	l   logger.Logger
+}
+
+func NewMembershipHandler(svc service.MembershipService, l logger.Logger) *MembershipHandler {
+	return &MembershipHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *MembershipHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/membership")
+	g.GET("/plans", ginx.WrapToken[myjwt.UserClaims](h.Plans, "MembershipPlans", h.l))
+	g.GET("/mine", ginx.WrapToken[myjwt.UserClaims](h.Mine, "MyMembership", h.l))
+	g.POST("/subscribe", ginx.WrapBodyAndToken[SubscribeReq, myjwt.UserClaims](h.Subscribe, "Subscribe", h.l))
+}
+
+func (h *MembershipHandler) Plans(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	plans := h.svc.Plans(ctx)
+	return ginx.Result{
+		Code: codes.MembershipOK,
+		Data: slice.Map[domain.MembershipPlan, MembershipPlanVO](plans,
+			func(idx int, src domain.MembershipPlan) MembershipPlanVO {
+				return MembershipPlanVO{
+					Id:    src.Id,
+					Name:  src.Name,
+					Price: src.Price,
+					Days:  int64(src.Duration / (time.Hour * 24)),
+				}
+			}),
+	}, nil
+}
+
+func (h *MembershipHandler) Mine(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	m, err := h.svc.Get(ctx, uc.Uid)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.MembershipInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Code: codes.MembershipOK,
+		Data: h.toVO(m),
+	}, nil
+}
+
+func (h *MembershipHandler) Subscribe(ctx *gin.Context, req SubscribeReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	m, err := h.svc.Subscribe(ctx, uc.Uid, req.PlanId)
+	switch {
+	case err == nil:
+		return ginx.Result{
+			Code: codes.MembershipOK,
+			Data: h.toVO(m),
+		}, nil
+	case errors.Is(err, service.ErrPlanNotFound):
+		return ginx.Result{
+			Code: codes.MembershipInvalidInput,
+			Msg:  "会员套餐不存在",
+		}, nil
+	case errors.Is(err, service.ErrPaymentDeclined):
+		return ginx.Result{
+			Code: codes.MembershipPaymentFailed,
+			Msg:  "支付失败",
+		}, nil
+	default:
+		return ginx.Result{
+			Code: codes.MembershipInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+}
+
+func (h *MembershipHandler) toVO(m domain.Membership) MembershipVO {
+	vo := MembershipVO{
+		Active: m.IsActive(time.Now()),
+	}
+	if !m.ExpireAt.IsZero() {
+		vo.ExpireAt = m.ExpireAt.Format(time.DateTime)
+	}
+	return vo
+}
@@ -0,0 +1,19 @@
+package web
+
+type SubscribeReq struct {
+	PlanId int64 `json:"plan_id"`
+}
+
+type MembershipPlanVO struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+	// Price 单位是分
+	Price int64 `json:"price"`
+	Days  int64 `json:"days"`
+}
+
+type MembershipVO struct {
+	Active bool `json:"active"`
+	// ExpireAt 不是会员的时候为空
+	ExpireAt string `json:"expire_at,omitempty"`
+}
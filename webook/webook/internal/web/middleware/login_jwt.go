@@ -5,12 +5,18 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"net/http"
+	"strings"
 )
 
 // 用JWT的方式登录校验
 type LoginJWTMiddlewareBuilder struct {
-	paths      []string
-	jwtHandler myjwt.JwtHandler
+	paths    []string
+	prefixes []string
+	// routes 按照路由模式忽略的，路径里带参数的用这个
+	routes []string
+	// optionalRoutes 登录和不登录都能访问，登录了的话照样设置 claims
+	optionalRoutes []string
+	jwtHandler     myjwt.JwtHandler
 }
 
 func NewLoginJWTMiddlewareBuilder(jwtHdl myjwt.JwtHandler) *LoginJWTMiddlewareBuilder {
@@ -24,6 +30,25 @@ func (l *LoginJWTMiddlewareBuilder) IgnorePath(path string) *LoginJWTMiddlewareB
 	return l
 }
 
+// IgnorePrefix 以 prefix 开头的路径都不需要登录校验，比如公开访问的文件
+func (l *LoginJWTMiddlewareBuilder) IgnorePrefix(prefix string) *LoginJWTMiddlewareBuilder {
+	l.prefixes = append(l.prefixes, prefix)
+	return l
+}
+
+// IgnoreRoute 和 IgnorePath 一样不需要登录，route 是注册路由时用的模式，比如 /pub/ranking/history/:id
+func (l *LoginJWTMiddlewareBuilder) IgnoreRoute(route string) *LoginJWTMiddlewareBuilder {
+	l.routes = append(l.routes, route)
+	return l
+}
+
+// OptionalRoute 登录是可选的，比如公开的文章详情页，route 是注册路由时用的模式，比如 /pub/:id
+// 没有登录或者 token 不对都当成游客，handler 要用 ginx.WrapOptionalToken
+func (l *LoginJWTMiddlewareBuilder) OptionalRoute(route string) *LoginJWTMiddlewareBuilder {
+	l.optionalRoutes = append(l.optionalRoutes, route)
+	return l
+}
+
 func (l *LoginJWTMiddlewareBuilder) Build() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// 不需要登录校验的
@@ -32,67 +57,92 @@ func (l *LoginJWTMiddlewareBuilder) Build() gin.HandlerFunc {
 				return
 			}
 		}
-
-		// 用JWT的方式来校验
-		// 得到请求头里的Authorization， 一般是 Bearer *****的格式
-		tokenStr := l.jwtHandler.ExtractToken(ctx)
-		// 这里定义claims为指针，因为ParseWithClaims函数需要修改这个claims
-		claims := &myjwt.UserClaims{}
-		/*token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte("TXqESPLch4roEwRPzo0WOkvGhpW4y0FU"), nil
-		})*/
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return l.jwtHandler.GetAtKey(ctx), nil
-		})
-		if err != nil {
-			// 没登录
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
+		for _, prefix := range l.prefixes {
+			if strings.HasPrefix(ctx.Request.URL.Path, prefix) {
+				return
+			}
 		}
-
-		if claims.UserAgent != ctx.Request.UserAgent() {
-			// UserAgent不一致，有安全隐患，需要重新登录
-			// 加监控
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
+		// 中间件是在路由匹配之后执行的，所以 FullPath 就是路由的模式
+		for _, route := range l.routes {
+			if ctx.FullPath() == route {
+				return
+			}
 		}
-
-		// err 为 nil，token 不为 nil
-		if token == nil || !token.Valid || claims.Uid == 0 {
-			// 没登录
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
+		for _, route := range l.optionalRoutes {
+			if ctx.FullPath() == route {
+				if claims, ok := l.parse(ctx); ok {
+					ctx.Set("claims", claims)
+				}
+				return
+			}
 		}
 
-		// 这里要在Redis里查询ssid这个key是否存在, 如果有证明已经登出
-		if l.jwtHandler.CheckSession(ctx, claims.Ssid) {
+		claims, ok := l.parse(ctx)
+		if !ok {
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
-		// 你以为的退出登录，没有用的
-		//token.Valid = false
-		//// tokenStr 是一个新的字符串
-		//tokenStr, err = token.SignedString([]byte("95osj3fUD7fo0mlYdDbncXz4VD2igvf0"))
-		//if err != nil {
-		//	// 记录日志
-		//	log.Println("jwt 续约失败", err)
-		//}
-		//ctx.Header("x-jwt-token", tokenStr)
-
-		// 短的 token 过期了，搞个新的
-		//now := time.Now()
-		// 每十秒钟刷新一次
-		//if claims.ExpiresAt.Sub(now) < time.Second*50 {
-		//	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
-		//	tokenStr, err = token.SignedString([]byte("95osj3fUD7fo0mlYdDbncXz4VD2igvf0"))
-		//	if err != nil {
-		//		// 记录日志
-		//		log.Println("jwt 续约失败", err)
-		//	}
-		//	ctx.Header("x-jwt-token", tokenStr)
-		//}
-
 		// JWT 方式需要将claims存到context里，供后续调用
 		ctx.Set("claims", claims)
 	}
 }
+
+// parse 校验请求里的 token，没登录、token 不对或者已经退出登录都返回 false
+func (l *LoginJWTMiddlewareBuilder) parse(ctx *gin.Context) (*myjwt.UserClaims, bool) {
+	// 用JWT的方式来校验
+	// 得到请求头里的Authorization， 一般是 Bearer *****的格式
+	tokenStr := l.jwtHandler.ExtractToken(ctx)
+	// 这里定义claims为指针，因为ParseWithClaims函数需要修改这个claims
+	claims := &myjwt.UserClaims{}
+	/*token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte("TXqESPLch4roEwRPzo0WOkvGhpW4y0FU"), nil
+	})*/
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return l.jwtHandler.GetAtKey(ctx), nil
+	})
+	if err != nil {
+		// 没登录
+		return nil, false
+	}
+
+	if claims.UserAgent != ctx.Request.UserAgent() {
+		// UserAgent不一致，有安全隐患，需要重新登录
+		// 加监控
+		return nil, false
+	}
+
+	// err 为 nil，token 不为 nil
+	if token == nil || !token.Valid || claims.Uid == 0 {
+		// 没登录
+		return nil, false
+	}
+
+	// 这里要在Redis里查询ssid这个key是否存在, 如果有证明已经登出
+	if l.jwtHandler.CheckSession(ctx, claims.Ssid) {
+		return nil, false
+	}
+	// 你以为的退出登录，没有用的
+	//token.Valid = false
+	//// tokenStr 是一个新的字符串
+	//tokenStr, err = token.SignedString([]byte("95osj3fUD7fo0mlYdDbncXz4VD2igvf0"))
+	//if err != nil {
+	//	// 记录日志
+	//	log.Println("jwt 续约失败", err)
+	//}
+	//ctx.Header("x-jwt-token", tokenStr)
+
+	// 短的 token 过期了，搞个新的
+	//now := time.Now()
+	// 每十秒钟刷新一次
+	//if claims.ExpiresAt.Sub(now) < time.Second*50 {
+	//	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
+	//	tokenStr, err = token.SignedString([]byte("95osj3fUD7fo0mlYdDbncXz4VD2igvf0"))
+	//	if err != nil {
+	//		// 记录日志
+	//		log.Println("jwt 续约失败", err)
+	//	}
+	//	ctx.Header("x-jwt-token", tokenStr)
+	//}
+
+	return claims, true
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	myjwt "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// anonymousJwtHandler 请求里永远没有 token
+type anonymousJwtHandler struct {
+	myjwt.JwtHandler
+}
+
+func (anonymousJwtHandler) ExtractToken(ctx *gin.Context) string {
+	return ""
+}
+
+func (anonymousJwtHandler) GetAtKey(ctx *gin.Context) []byte {
+	return []byte("key")
+}
+
+func TestLoginJWTMiddlewareBuilder_Anonymous(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(NewLoginJWTMiddlewareBuilder(anonymousJwtHandler{}).
+		IgnorePath("/users/login").
+		IgnoreRoute("/pub/ranking").
+		IgnoreRoute("/pub/ranking/history/:id").
+		OptionalRoute("/pub/:id").Build())
+	ok := func(ctx *gin.Context) {
+		_, exists := ctx.Get("claims")
+		assert.False(t, exists)
+		ctx.Status(http.StatusOK)
+	}
+	for _, route := range []string{"/users/login", "/users/profile", "/pub/ranking",
+		"/pub/ranking/history/:id", "/pub/ranking/explain/:id", "/pub/:id"} {
+		server.GET(route, ok)
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		wantCode int
+	}{
+		{name: "忽略的路径", path: "/users/login", wantCode: http.StatusOK},
+		{name: "需要登录", path: "/users/profile", wantCode: http.StatusUnauthorized},
+		{name: "忽略的路由", path: "/pub/ranking", wantCode: http.StatusOK},
+		{name: "忽略的带参数的路由", path: "/pub/ranking/history/12", wantCode: http.StatusOK},
+		{name: "同一个前缀下需要登录的路由", path: "/pub/ranking/explain/12", wantCode: http.StatusUnauthorized},
+		{name: "可选登录", path: "/pub/12", wantCode: http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+		})
+	}
+}
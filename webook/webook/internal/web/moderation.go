@@ -0,0 +1,165 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/codes"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	myjwt "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web/jwt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/ginx"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+var _ handler = (*ModerationHandler)(nil)
+
+type ModerationHandler struct {
+	svc service.ModerationService
+	l   logger.Logger
+}
+
+func NewModerationHandler(svc service.ModerationService, l logger.Logger) *ModerationHandler {
+	return &ModerationHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *ModerationHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/moderation")
+	// 下面这几个只有审核员能用
+	g.POST("/queue", ginx.WrapBodyAndToken[ReviewQueueReq, myjwt.UserClaims](h.Queue, "ReviewQueue", h.l))
+	g.POST("/approve", ginx.WrapBodyAndToken[ApproveReq, myjwt.UserClaims](h.Approve, "ApproveArticle", h.l))
+	g.POST("/reject", ginx.WrapBodyAndToken[RejectReq, myjwt.UserClaims](h.Reject, "RejectArticle", h.l))
+	g.POST("/trust", ginx.WrapBodyAndToken[TrustLevelReq, myjwt.UserClaims](h.SetTrustLevel, "SetTrustLevel", h.l))
+	// 作者查看自己文章的审核结果
+	g.GET("/article/:id", ginx.WrapToken[myjwt.UserClaims](h.Latest, "LatestReview", h.l))
+}
+
+func (h *ModerationHandler) Queue(ctx *gin.Context, req ReviewQueueReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	rs, err := h.svc.ListPending(ctx, uc.Uid, req.MinId, req.Limit)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.ModerationOK,
+		Data: slice.Map[domain.ArticleReview, ReviewVO](rs, func(idx int, src domain.ArticleReview) ReviewVO {
+			return h.toVO(src)
+		}),
+	}, nil
+}
+
+func (h *ModerationHandler) Approve(ctx *gin.Context, req ApproveReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.Approve(ctx, uc.Uid, req.Id)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.ModerationOK,
+		Msg:  "审核通过",
+		Data: req.Id,
+	}, nil
+}
+
+func (h *ModerationHandler) Reject(ctx *gin.Context, req RejectReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	// 拒绝一定要告诉作者理由
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > 1024 {
+		return ginx.Result{
+			Code: codes.ModerationInvalidInput,
+			Msg:  "请填写拒绝理由",
+		}, nil
+	}
+	err := h.svc.Reject(ctx, uc.Uid, req.Id, req.Reason)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.ModerationOK,
+		Msg:  "已拒绝",
+		Data: req.Id,
+	}, nil
+}
+
+func (h *ModerationHandler) SetTrustLevel(ctx *gin.Context, req TrustLevelReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	level := domain.TrustLevel(req.Level)
+	if req.Uid <= 0 || !level.IsValid() {
+		return ginx.Result{
+			Code: codes.ModerationInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+	err := h.svc.SetTrustLevel(ctx, uc.Uid, req.Uid, level)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.ModerationOK,
+		Msg:  "设置成功",
+	}, nil
+}
+
+func (h *ModerationHandler) Latest(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.ModerationInvalidInput,
+			Msg:  "参数错误",
+		}, fmt.Errorf("前端输入id错误，%v", err)
+	}
+	r, err := h.svc.Latest(ctx, uc.Uid, id)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.ModerationOK,
+		Data: h.toVO(r),
+	}, nil
+}
+
+// errResult 业务错误不需要打错误日志
+func (h *ModerationHandler) errResult(err error) (ginx.Result, error) {
+	switch {
+	case errors.Is(err, service.ErrNotModerator):
+		return ginx.Result{
+			Code: codes.ModerationPermissionDenied,
+			Msg:  "没有审核权限",
+		}, nil
+	case errors.Is(err, service.ErrReviewNotFound):
+		return ginx.Result{
+			Code: codes.ModerationReviewNotFound,
+			Msg:  "审核记录不存在",
+		}, nil
+	case errors.Is(err, service.ErrReviewNotPending),
+		errors.Is(err, service.ErrReviewOutdated):
+		return ginx.Result{
+			Code: codes.ModerationReviewOutdated,
+			Msg:  err.Error(),
+		}, nil
+	default:
+		return ginx.Result{
+			Code: codes.ModerationInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+}
+
+func (h *ModerationHandler) toVO(r domain.ArticleReview) ReviewVO {
+	return ReviewVO{
+		Id:       r.Id,
+		Aid:      r.Article.Id,
+		AuthorId: r.Article.Author.Id,
+		Title:    r.Article.Title,
+		Status:   r.Status.ToUint8(),
+		Reason:   r.Reason,
+		Ctime:    r.Ctime.Format(time.DateTime),
+		Utime:    r.Utime.Format(time.DateTime),
+	}
+}
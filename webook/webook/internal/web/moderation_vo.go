@@ -0,0 +1,34 @@
+package web
+
+type ReviewVO struct {
+	Id       int64  `json:"id"`
+	Aid      int64  `json:"aid"`
+	AuthorId int64  `json:"author_id"`
+	Title    string `json:"title"`
+	// 1 待审核，2 通过，3 拒绝
+	Status uint8  `json:"status"`
+	Reason string `json:"reason"`
+	Ctime  string `json:"ctime"`
+	Utime  string `json:"utime"`
+}
+
+type ReviewQueueReq struct {
+	// 上一页最后一条审核记录的 ID，第一页传 0
+	MinId int64 `json:"min_id"`
+	Limit int   `json:"limit"`
+}
+
+type ApproveReq struct {
+	Id int64 `json:"id"`
+}
+
+type RejectReq struct {
+	Id     int64  `json:"id"`
+	Reason string `json:"reason"`
+}
+
+type TrustLevelReq struct {
+	Uid int64 `json:"uid"`
+	// 0 普通作者，1 可信作者，2 审核员
+	Level uint8 `json:"level"`
+}
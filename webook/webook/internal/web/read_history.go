@@ -0,0 +1,152 @@
+package web
+
+import (
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/codes"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	myjwt "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web/jwt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/ginx"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+var _ handler = (*ReadHistoryHandler)(nil)
+
+type ReadHistoryHandler struct {
+	svc service.ReadHistoryService
+	l   logger.Logger
+	// continueLimit "继续阅读"最多返回几篇
+	continueLimit int
+	maxLimit      int
+}
+
+func NewReadHistoryHandler(svc service.ReadHistoryService, l logger.Logger) *ReadHistoryHandler {
+	return &ReadHistoryHandler{
+		svc:           svc,
+		l:             l,
+		continueLimit: 10,
+		maxLimit:      100,
+	}
+}
+
+func (h *ReadHistoryHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/history")
+	g.POST("/progress", ginx.WrapBodyAndToken[ProgressReq, myjwt.UserClaims](h.Progress, "ReportReadProgress", h.l))
+	g.POST("/list", ginx.WrapBodyAndToken[ListReq, myjwt.UserClaims](h.List, "ListReadHistory", h.l))
+	g.GET("/continue", ginx.WrapToken[myjwt.UserClaims](h.Continue, "ContinueReading", h.l))
+	g.POST("/delete", ginx.WrapBodyAndToken[DeleteHistoryReq, myjwt.UserClaims](h.Delete, "DeleteReadHistory", h.l))
+	g.POST("/clear", ginx.WrapToken[myjwt.UserClaims](h.Clear, "ClearReadHistory", h.l))
+}
+
+func (h *ReadHistoryHandler) Progress(ctx *gin.Context, req ProgressReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.ReportProgress(ctx, uc.Uid, req.Aid, req.Position, req.Progress)
+	if errors.Is(err, service.ErrInvalidProgress) {
+		return ginx.Result{
+			Code: codes.HistoryInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+	if err != nil {
+		return ginx.Result{
+			Code: codes.HistoryInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Code: codes.HistoryOK,
+	}, nil
+}
+
+func (h *ReadHistoryHandler) List(ctx *gin.Context, req ListReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	cursor, err := decodeCursor(req.Cursor)
+	if err != nil || req.Limit <= 0 || req.Limit > h.maxLimit {
+		return ginx.Result{
+			Code: codes.HistoryInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+	hs, err := h.svc.List(ctx, uc.Uid, cursor, req.Limit)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.HistoryInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+	var next string
+	if len(hs) >= req.Limit {
+		next = encodeCursor(hs[len(hs)-1].Cursor())
+	}
+	return ginx.Result{
+		Code: codes.HistoryOK,
+		Data: ListReadHistoryVO{
+			Items:      h.toVOs(hs),
+			NextCursor: next,
+		},
+	}, nil
+}
+
+func (h *ReadHistoryHandler) Continue(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	hs, err := h.svc.Continue(ctx, uc.Uid, h.continueLimit)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.HistoryInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Code: codes.HistoryOK,
+		Data: h.toVOs(hs),
+	}, nil
+}
+
+func (h *ReadHistoryHandler) Delete(ctx *gin.Context, req DeleteHistoryReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	if len(req.Aids) > h.maxLimit {
+		return ginx.Result{
+			Code: codes.HistoryInvalidInput,
+			Msg:  "一次删除的太多了",
+		}, nil
+	}
+	err := h.svc.Delete(ctx, uc.Uid, req.Aids)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.HistoryInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Code: codes.HistoryOK,
+		Msg:  "删除成功",
+	}, nil
+}
+
+func (h *ReadHistoryHandler) Clear(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.Clear(ctx, uc.Uid)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.HistoryInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Code: codes.HistoryOK,
+		Msg:  "清空成功",
+	}, nil
+}
+
+func (h *ReadHistoryHandler) toVOs(hs []domain.ReadHistory) []ReadHistoryVO {
+	return slice.Map[domain.ReadHistory, ReadHistoryVO](hs,
+		func(idx int, src domain.ReadHistory) ReadHistoryVO {
+			return ReadHistoryVO{
+				Aid:      src.Aid,
+				Title:    src.Article.Title,
+				Abstract: src.Article.Abstract(),
+				Position: src.Position,
+				Progress: src.Progress,
+				Removed:  src.Article.Id == 0,
+				ReadTime: src.Utime.Format(time.DateTime),
+			}
+		})
+}
@@ -0,0 +1,30 @@
+package web
+
+type ProgressReq struct {
+	Aid int64 `json:"aid"`
+	// Position 客户端自己定义的阅读位置，继续阅读的时候原样返回
+	Position int64 `json:"position"`
+	// Progress 0 到 100 的百分比
+	Progress int `json:"progress"`
+}
+
+type DeleteHistoryReq struct {
+	Aids []int64 `json:"aids"`
+}
+
+type ReadHistoryVO struct {
+	Aid      int64  `json:"aid"`
+	Title    string `json:"title"`
+	Abstract string `json:"abstract"`
+	Position int64  `json:"position"`
+	Progress int    `json:"progress"`
+	// Removed 文章已经撤回或者删除了，只能从历史里删掉
+	Removed  bool   `json:"removed,omitempty"`
+	ReadTime string `json:"read_time"`
+}
+
+type ListReadHistoryVO struct {
+	Items []ReadHistoryVO `json:"items"`
+	// NextCursor 为空说明没有下一页了
+	NextCursor string `json:"next_cursor"`
+}
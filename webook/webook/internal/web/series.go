@@ -0,0 +1,215 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	service2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/codes"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	myjwt "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web/jwt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/ginx"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+var _ handler = (*SeriesHandler)(nil)
+
+// SeriesDetailRoute 系列页，不登录也能看
+const SeriesDetailRoute = "/series/:id"
+
+type SeriesHandler struct {
+	svc      service.SeriesService
+	interSvc service2.InteractiveService
+	l        logger.Logger
+	biz      string
+}
+
+func NewSeriesHandler(svc service.SeriesService, interSvc service2.InteractiveService, l logger.Logger) *SeriesHandler {
+	return &SeriesHandler{
+		svc:      svc,
+		interSvc: interSvc,
+		l:        l,
+		biz:      "article",
+	}
+}
+
+func (h *SeriesHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/series")
+	g.POST("/create", ginx.WrapBodyAndToken[SeriesReq, myjwt.UserClaims](h.Create, "CreateSeries", h.l))
+	g.POST("/edit", ginx.WrapBodyAndToken[SeriesReq, myjwt.UserClaims](h.Edit, "EditSeries", h.l))
+	g.POST("/delete", ginx.WrapBodyAndToken[DeleteSeriesReq, myjwt.UserClaims](h.Delete, "DeleteSeries", h.l))
+	g.POST("/articles", ginx.WrapBodyAndToken[SeriesArticlesReq, myjwt.UserClaims](h.SetArticles, "SetSeriesArticles", h.l))
+	g.GET("/mine", ginx.WrapToken[myjwt.UserClaims](h.Mine, "MySeries", h.l))
+	// 系列页，所有人都能看，没登录也可以
+	server.GET(SeriesDetailRoute, ginx.WrapOptionalToken(h.Detail, "DetailSeries", h.l))
+}
+
+func (h *SeriesHandler) Create(ctx *gin.Context, req SeriesReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	if res, ok := h.checkReq(req); !ok {
+		return res, nil
+	}
+	id, err := h.svc.Create(ctx, h.toDomain(req, uc.Uid))
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.SeriesOK,
+		Data: id,
+	}, nil
+}
+
+func (h *SeriesHandler) Edit(ctx *gin.Context, req SeriesReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	if res, ok := h.checkReq(req); !ok {
+		return res, nil
+	}
+	err := h.svc.Update(ctx, h.toDomain(req, uc.Uid))
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.SeriesOK,
+		Data: req.Id,
+	}, nil
+}
+
+func (h *SeriesHandler) Delete(ctx *gin.Context, req DeleteSeriesReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.Delete(ctx, req.Id, uc.Uid)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.SeriesOK,
+		Msg:  "删除成功",
+	}, nil
+}
+
+func (h *SeriesHandler) SetArticles(ctx *gin.Context, req SeriesArticlesReq, uc myjwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.SetArticles(ctx, req.Id, uc.Uid, req.Aids)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.SeriesOK,
+		Msg:  "保存成功",
+	}, nil
+}
+
+func (h *SeriesHandler) Mine(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	ss, err := h.svc.ListByAuthor(ctx, uc.Uid)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: codes.SeriesOK,
+		Data: slice.Map[domain.Series, SeriesVO](ss, func(idx int, src domain.Series) SeriesVO {
+			return h.toVO(src)
+		}),
+	}, nil
+}
+
+// Detail 没登录的时候 uc 是零值，系列页对所有人都一样，用不到登录信息
+func (h *SeriesHandler) Detail(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.SeriesInvalidInput,
+			Msg:  "参数错误",
+		}, fmt.Errorf("前端输入id错误，%v", err)
+	}
+	s, arts, err := h.svc.Get(ctx, id)
+	if err != nil {
+		return h.errResult(err)
+	}
+	ids := slice.Map[domain.Article, int64](arts, func(idx int, src domain.Article) int64 {
+		return src.Id
+	})
+	intrs, err := h.interSvc.GetByIds(ctx, h.biz, ids)
+	if err != nil {
+		// 计数拿不到也可以先把系列展示出来
+		h.l.Error("获取系列文章的计数失败", logger.Error(err), logger.Int64("id", id))
+	}
+	vo := h.toVO(s)
+	vo.Articles = slice.Map[domain.Article, ArticleVO](arts, func(idx int, src domain.Article) ArticleVO {
+		intr := intrs[src.Id]
+		return ArticleVO{
+			Id:         src.Id,
+			Title:      src.Title,
+			Abstract:   src.Abstract(),
+			Status:     src.Status.ToUint8(),
+			Author:     src.Author.Name,
+			ReadCnt:    intr.ReadCnt,
+			LikeCnt:    intr.LikeCnt,
+			CollectCnt: intr.CollectCnt,
+			CommentCnt: intr.CommentCnt,
+			Ctime:      src.Ctime.Format(time.DateTime),
+			Utime:      src.Utime.Format(time.DateTime),
+		}
+	})
+	return ginx.Result{
+		Code: codes.SeriesOK,
+		Data: vo,
+	}, nil
+}
+
+func (h *SeriesHandler) checkReq(req SeriesReq) (ginx.Result, bool) {
+	if req.Title == "" || utf8.RuneCountInString(req.Title) > 256 ||
+		utf8.RuneCountInString(req.Description) > 1024 {
+		return ginx.Result{
+			Code: codes.SeriesInvalidInput,
+			Msg:  "标题不能为空，标题和简介不能太长",
+		}, false
+	}
+	return ginx.Result{}, true
+}
+
+// errResult 业务错误不需要打错误日志
+func (h *SeriesHandler) errResult(err error) (ginx.Result, error) {
+	switch {
+	case errors.Is(err, service.ErrSeriesNotFound):
+		return ginx.Result{
+			Code: codes.SeriesNotFound,
+			Msg:  "系列不存在",
+		}, nil
+	case errors.Is(err, service.ErrArticleInOtherSeries):
+		return ginx.Result{
+			Code: codes.SeriesArticleConflict,
+			Msg:  "文章已经在别的系列里了",
+		}, nil
+	case errors.Is(err, service.ErrInvalidSeriesArticle),
+		errors.Is(err, service.ErrTooManySeriesArticle):
+		return ginx.Result{
+			Code: codes.SeriesInvalidInput,
+			Msg:  err.Error(),
+		}, nil
+	default:
+		return ginx.Result{
+			Code: codes.SeriesInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+}
+
+func (h *SeriesHandler) toDomain(req SeriesReq, uid int64) domain.Series {
+	return domain.Series{
+		Id:          req.Id,
+		Author:      domain.Author{Id: uid},
+		Title:       req.Title,
+		Description: req.Description,
+	}
+}
+
+func (h *SeriesHandler) toVO(s domain.Series) SeriesVO {
+	return SeriesVO{
+		Id:          s.Id,
+		AuthorId:    s.Author.Id,
+		Title:       s.Title,
+		Description: s.Description,
+		Ctime:       s.Ctime.Format(time.DateTime),
+		Utime:       s.Utime.Format(time.DateTime),
+	}
+}
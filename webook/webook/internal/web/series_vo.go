@@ -0,0 +1,45 @@
+package web
+
+type SeriesReq struct {
+	// 新建的时候不用传
+	Id          int64  `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+type DeleteSeriesReq struct {
+	Id int64 `json:"id"`
+}
+
+type SeriesArticlesReq struct {
+	Id int64 `json:"id"`
+	// Aids 按照阅读顺序排列，会整个替换掉原来的
+	Aids []int64 `json:"aids"`
+}
+
+type SeriesVO struct {
+	Id          int64  `json:"id"`
+	AuthorId    int64  `json:"author_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Articles 只有系列页才有
+	Articles []ArticleVO `json:"articles,omitempty"`
+	Ctime    string      `json:"ctime"`
+	Utime    string      `json:"utime"`
+}
+
+// SeriesNavVO 文章详情页上的系列导航，没有上一篇或者下一篇的时候对应的字段是空的
+type SeriesNavVO struct {
+	Id    int64  `json:"id"`
+	Title string `json:"title"`
+	// Index 当前文章是第几篇，从 1 开始
+	Index int           `json:"index"`
+	Total int           `json:"total"`
+	Prev  *SeriesPartVO `json:"prev,omitempty"`
+	Next  *SeriesPartVO `json:"next,omitempty"`
+}
+
+type SeriesPartVO struct {
+	Id    int64  `json:"id"`
+	Title string `json:"title"`
+}
@@ -0,0 +1,58 @@
+package web
+
+import (
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const (
+	// SitemapIndexPath 和 SitemapsPath 给搜索引擎用的，不需要登录
+	SitemapIndexPath = "/sitemap.xml"
+	SitemapsPath     = "/sitemaps"
+)
+
+var _ handler = (*SitemapHandler)(nil)
+
+// SitemapHandler 站点地图由定时任务生成，这里只负责读出来
+type SitemapHandler struct {
+	svc service.SitemapService
+	l   logger.Logger
+}
+
+func NewSitemapHandler(svc service.SitemapService, l logger.Logger) *SitemapHandler {
+	return &SitemapHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *SitemapHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET(SitemapIndexPath, h.Index)
+	server.GET(SitemapsPath+"/:file", h.Shard)
+}
+
+func (h *SitemapHandler) Index(ctx *gin.Context) {
+	h.serve(ctx, service.SitemapIndexName)
+}
+
+func (h *SitemapHandler) Shard(ctx *gin.Context) {
+	h.serve(ctx, ctx.Param("file"))
+}
+
+func (h *SitemapHandler) serve(ctx *gin.Context, name string) {
+	data, err := h.svc.Get(ctx, name)
+	if errors.Is(err, service.ErrSitemapNotFound) {
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.l.Error("读取站点地图失败", logger.Error(err),
+			logger.String("name", name))
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	ctx.Data(http.StatusOK, "application/xml; charset=utf-8", data)
+}
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"errors"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/codes"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
@@ -863,6 +864,12 @@ func (u *UserHandler) EditProfileByJWTV1(ctx *gin.Context, req EditProfileReq, c
 		Intro:    req.Intro,
 	})
 
+	if errors.Is(err, service.ErrSensitiveProfile) {
+		return ginx.Result{
+			Code: codes.UserSensitiveContent,
+			Msg:  "昵称或简介包含敏感词",
+		}, nil
+	}
 	if err != nil {
 		return ginx.Result{
 			Code: codes.UserInternalServerError,
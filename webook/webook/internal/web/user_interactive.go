@@ -0,0 +1,84 @@
+package web
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/codes"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	myjwt "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web/jwt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/ginx"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+var _ handler = (*UserInteractiveHandler)(nil)
+
+// UserInteractiveHandler 我的点赞和我的收藏
+type UserInteractiveHandler struct {
+	svc      service.UserInteractiveService
+	l        logger.Logger
+	biz      string
+	maxLimit int
+}
+
+func NewUserInteractiveHandler(svc service.UserInteractiveService, l logger.Logger) *UserInteractiveHandler {
+	return &UserInteractiveHandler{
+		svc:      svc,
+		l:        l,
+		biz:      "article",
+		maxLimit: 100,
+	}
+}
+
+func (h *UserInteractiveHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/interactive")
+	// 都可以用 ?biz= 过滤，不传就是所有的
+	g.GET("/liked", ginx.WrapToken[myjwt.UserClaims](h.Liked, "MyLiked", h.l))
+	g.GET("/collected", ginx.WrapToken[myjwt.UserClaims](h.Collected, "MyCollected", h.l))
+}
+
+func (h *UserInteractiveHandler) Liked(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	return h.list(ctx, uc.Uid, h.svc.Liked)
+}
+
+func (h *UserInteractiveHandler) Collected(ctx *gin.Context, uc myjwt.UserClaims) (ginx.Result, error) {
+	return h.list(ctx, uc.Uid, h.svc.Collected)
+}
+
+// list 用 ?cursor= 翻页，cursor 是上一页返回的 next_cursor，第一页不用传
+func (h *UserInteractiveHandler) list(ctx *gin.Context, uid int64,
+	fn func(ctx context.Context, uid int64, biz string, cursor domain.InteractedCursor, limit int) ([]domain.InteractedItem, error)) (ginx.Result, error) {
+	limit, ok := parseLimit(ctx, h.maxLimit)
+	if !ok {
+		return ginx.Result{
+			Code: codes.InteractiveInvalidInput,
+			Msg:  "参数错误",
+		}, nil
+	}
+	cursor, err := decodeCursor(ctx.Query("cursor"))
+	if err != nil {
+		return ginx.Result{
+			Code: codes.InteractiveInvalidInput,
+			Msg:  "参数错误",
+		}, err
+	}
+	items, err := fn(ctx, uid, ctx.Query("biz"), domain.InteractedCursor(cursor), limit)
+	if err != nil {
+		return ginx.Result{
+			Code: codes.InteractiveInternalServer,
+			Msg:  "系统错误",
+		}, err
+	}
+	var next string
+	if len(items) > 0 && len(items) >= limit {
+		// 和文章列表的游标格式一样，都是 (时间, id)
+		next = encodeCursor(domain.ArticleCursor(items[len(items)-1].Cursor()))
+	}
+	return ginx.Result{
+		Code: codes.InteractiveOK,
+		Data: ListInteractedVO{
+			Items:      toInteractedItemVOs(items, h.biz),
+			NextCursor: next,
+		},
+	}, nil
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/domain"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+)
+
+// InteractedItemVO 我的点赞、我的收藏和收藏夹里的一个东西
+type InteractedItemVO struct {
+	Biz   string `json:"biz"`
+	BizId int64  `json:"biz_id"`
+	// Cid 收藏夹 id，只有收藏才有
+	Cid int64 `json:"cid,omitempty"`
+	// 下面这几个只有文章才有
+	Title    string `json:"title,omitempty"`
+	Abstract string `json:"abstract,omitempty"`
+	Author   string `json:"author,omitempty"`
+	// Removed 文章已经撤回或者删除了
+	Removed bool `json:"removed,omitempty"`
+	// Time 点赞或者收藏的时间
+	Time string `json:"time"`
+}
+
+type ListInteractedVO struct {
+	Items []InteractedItemVO `json:"items"`
+	// NextCursor 为空说明没有下一页了
+	NextCursor string `json:"next_cursor"`
+}
+
+func toInteractedItemVOs(items []domain.InteractedItem, biz string) []InteractedItemVO {
+	return slice.Map[domain.InteractedItem, InteractedItemVO](items,
+		func(idx int, src domain.InteractedItem) InteractedItemVO {
+			vo := InteractedItemVO{
+				Biz:   src.Biz,
+				BizId: src.BizId,
+				Cid:   src.Cid,
+				Time:  src.Time.Format(time.DateTime),
+			}
+			if src.Biz == biz {
+				vo.Title = src.Article.Title
+				vo.Abstract = src.Article.Abstract()
+				vo.Author = src.Article.Author.Name
+				vo.Removed = src.Article.Id == 0
+			}
+			return vo
+		})
+}
+
+// parsePage 从 query 里面拿 offset 和 limit，limit 默认 20，最多 maxLimit
+func parsePage(ctx *gin.Context, maxLimit int) (int, int, bool) {
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		return 0, 0, false
+	}
+	limit, ok := parseLimit(ctx, maxLimit)
+	if !ok {
+		return 0, 0, false
+	}
+	return offset, limit, true
+}
+
+// parseLimit 从 query 里面拿 limit，默认 20，最多 maxLimit
+func parseLimit(ctx *gin.Context, maxLimit int) (int, bool) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > maxLimit {
+		return 0, false
+	}
+	return limit, true
+}
@@ -0,0 +1,72 @@
+package ioc
+
+import (
+	"github.com/IBM/sarama"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article/consistency"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article/migrator"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// InitArticleDAO 在迁移的时候由迁移的阶段决定，否则按照 article.storage 配置
+// gorm 是内容也存在数据库里，s3 是线上库的内容存到对象存储里
+func InitArticleDAO(db *gorm.DB, m *migrator.Migrator) article.ArticleDAO {
+	if m != nil {
+		return m.DAO()
+	}
+	switch typ := viper.GetString("article.storage"); typ {
+	case "", "gorm":
+		return article.NewGORMArticleDAO(db)
+	case "s3":
+		// 撤回的文章在删除对象之前不能被直接访问到，所以不能和媒体文件共用
+		return article.NewOssDAO(initPrivateStore("article.content", "./data/articles"), db)
+	default:
+		panic("未知的文章存储 " + typ)
+	}
+}
+
+// InitArticleMigrator 没有开启迁移的时候返回 nil
+// src 和 dst 可以是 gorm 或者 mongo，迁回去的时候反过来配置就可以
+func InitArticleMigrator(db *gorm.DB, producer sarama.SyncProducer, l logger.Logger) *migrator.Migrator {
+	if !viper.GetBool("migrator.article.enabled") {
+		return nil
+	}
+	src := initMigratableArticleDAO(viper.GetString("migrator.article.src"), db)
+	dst := initMigratableArticleDAO(viper.GetString("migrator.article.dst"), db)
+	err := migrator.UpdatePattern(viper.GetString("migrator.article.pattern"))
+	if err != nil {
+		panic(err)
+	}
+	return migrator.NewMigrator(src, dst, migrator.NewKafkaProducer(producer), l)
+}
+
+// InitArticleMigratorConsumer 没有开启迁移的时候返回 nil
+func InitArticleMigratorConsumer(client sarama.Client, m *migrator.Migrator, l logger.Logger) *migrator.Consumer {
+	if m == nil {
+		return nil
+	}
+	return migrator.NewConsumer(client, m, l)
+}
+
+func initMigratableArticleDAO(typ string, db *gorm.DB) article.MigratableDAO {
+	switch typ {
+	case "gorm":
+		return article.NewGORMArticleDAO(db).(article.MigratableDAO)
+	case "mongo":
+		return article.NewMongoArticle(InitMongoDB(), InitSnowflakeNode()).(article.MigratableDAO)
+	default:
+		panic("未知的文章存储 " + typ)
+	}
+}
+
+// InitArticleChecker 检查制作库和线上库是否一致，DAO 不支持扫描的时候返回 nil
+func InitArticleChecker(dao article.ArticleDAO, l logger.Logger) *consistency.Checker {
+	cd, ok := dao.(consistency.DAO)
+	if !ok {
+		l.Warn("文章 DAO 不支持一致性检查")
+		return nil
+	}
+	return consistency.NewChecker(cd, l)
+}
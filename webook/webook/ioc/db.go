@@ -1,8 +1,10 @@
 package ioc
 
 import (
+	comment_dao "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/repository/dao"
 	interactive_dao "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository/dao"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao"
+	media_dao "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/repository/dao"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/gormx"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
 	"github.com/spf13/viper"
@@ -70,6 +72,16 @@ func InitDB(l logger.Logger) *gorm.DB {
 	if err != nil {
 		panic(err)
 	}
+
+	err = comment_dao.InitTable(db)
+	if err != nil {
+		panic(err)
+	}
+
+	err = media_dao.InitTable(db)
+	if err != nil {
+		panic(err)
+	}
 	return db
 }
 
@@ -4,6 +4,9 @@ import (
 	"github.com/IBM/sarama"
 	events2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/events"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events/ranking"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article/migrator"
 	"github.com/spf13/viper"
 )
 
@@ -45,6 +48,14 @@ func NewSyncProducer(client sarama.Client) sarama.SyncProducer {
 /*func NewConsumers(c1 *article.InteractiveReadEventBatchConsumer) []events.Consumer {
 	return []events.Consumer{c1}
 }*/
-func NewConsumers(c1 *events2.InteractiveReadEventConsumer) []events.Consumer {
-	return []events.Consumer{c1}
+func NewConsumers(c1 *events2.InteractiveReadEventConsumer,
+	historyConsumer *article.ReadHistoryConsumer,
+	rankingConsumer *ranking.RankingEventConsumer,
+	migratorConsumer *migrator.Consumer) []events.Consumer {
+	res := []events.Consumer{c1, historyConsumer, rankingConsumer}
+	// 没有开启迁移的时候是 nil
+	if migratorConsumer != nil {
+		res = append(res, migratorConsumer)
+	}
+	return res
 }
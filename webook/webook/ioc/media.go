@@ -0,0 +1,95 @@
+package ioc
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/objectstore"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+// InitObjectStore 根据配置决定文件存在本地还是 S3
+func InitObjectStore() objectstore.Store {
+	type Config struct {
+		// local 或者 s3
+		Store   string
+		Root    string
+		BaseURL string
+		Bucket  string
+	}
+	// 本地存储的文件由 web 服务自己提供访问，默认跟着站点的地址走
+	cfg := Config{
+		Store:   "local",
+		Root:    "./data/media",
+		BaseURL: strings.TrimRight(viper.GetString("site.baseURL"), "/") + web.MediaFilesPath,
+	}
+	err := viper.UnmarshalKey("media", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Store == "s3" {
+		return objectstore.NewS3Store(InitOSS(), cfg.Bucket, cfg.BaseURL)
+	}
+	return objectstore.NewLocalStore(cfg.Root, cfg.BaseURL)
+}
+
+// initPrivateStore 只在服务内部读写的对象存储，不对外提供地址
+// 不能和 media 共用同一个 bucket 或者目录，不然通过媒体文件的地址就能直接访问到
+func initPrivateStore(key string, defRoot string) objectstore.Store {
+	type Config struct {
+		// local 或者 s3
+		Store  string
+		Root   string
+		Bucket string
+	}
+	cfg := Config{
+		Store: "local",
+		Root:  defRoot,
+	}
+	err := viper.UnmarshalKey(key, &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Store == "s3" {
+		if cfg.Bucket == "" {
+			panic("没有配置 " + key + ".bucket")
+		}
+		return objectstore.NewS3Store(InitOSS(), cfg.Bucket, "")
+	}
+	return objectstore.NewLocalStore(cfg.Root, "")
+}
+
+// InitOSS 腾讯云的 COS 兼容 S3 协议，和 S3DAO 用的是同一个客户端
+func InitOSS() *s3.S3 {
+	// 腾讯云中对标 s3 和 OSS 的产品叫做 COS
+	cosId, ok := os.LookupEnv("COS_APP_ID")
+	if !ok {
+		panic("没有找到环境变量 COS_APP_ID ")
+	}
+	cosKey, ok := os.LookupEnv("COS_APP_SECRET")
+	if !ok {
+		panic("没有找到环境变量 COS_APP_SECRET")
+	}
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(cosId, cosKey, ""),
+		Region:      stringOr(viper.GetString("media.region"), "ap-nanjing"),
+		Endpoint:    stringOr(viper.GetString("media.endpoint"), "https://cos.ap-nanjing.myqcloud.com"),
+		// 强制使用 /bucket/key 的形态
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return s3.New(sess)
+}
+
+func stringOr(val string, def string) *string {
+	if val == "" {
+		return aws.String(def)
+	}
+	return aws.String(val)
+}
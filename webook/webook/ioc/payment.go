@@ -0,0 +1,18 @@
+package ioc
+
+import (
+	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/payment"
+	"github.com/spf13/viper"
+)
+
+// InitPaymentGateway 目前只有内存里的假支付渠道，接入真的渠道之后在这里按照配置切换
+func InitPaymentGateway() payment.Gateway {
+	provider := viper.GetString("payment.provider")
+	switch provider {
+	case "", "fake":
+		return payment.NewFakeGateway()
+	default:
+		panic(fmt.Sprintf("不支持的支付渠道 %s", provider))
+	}
+}
@@ -36,9 +36,14 @@ func InitJobs(l logger.Logger, rankingJob *job.RankingJob) *cron.Cron {
 }
 
 func InitRedisRankingCache(client redis.Cmdable) *cache.RedisRankingCache {
-	return cache.NewRedisRankingCache(client, "RankingTopN", time.Minute*10)
+	// 每个热榜是 hash 里的一个 field，换一个 key，免得读到旧格式的数据
+	return cache.NewRedisRankingCache(client, "ranking:article:rankings", time.Minute*10)
 }
 
 func InitLocalRankingCache() *cache.LocalRankingCache {
 	return cache.NewLocalRankingCache(time.Minute * 10)
 }
+
+func InitRedisRankingScoreCache(client redis.Cmdable) *cache.RedisRankingScoreCache {
+	return cache.NewRedisRankingScoreCache(client, "ranking:article:score")
+}
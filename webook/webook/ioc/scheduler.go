@@ -2,10 +2,14 @@ package ioc
 
 import (
 	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article/consistency"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article/migrator"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	mediaSvc "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/service"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/cronJobScheduler/domain"
 	schedulerSvc "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/cronJobScheduler/service"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/spf13/viper"
 	"time"
 )
 
@@ -27,6 +31,13 @@ func InitCronJobScheduler(l logger.Logger,
 }
 
 func InitLocalFuncExecutor(svc service.RankingService,
+	rankingStreamSvc service.RankingStreamService,
+	rankingSnapshotSvc service.RankingSnapshotService,
+	artSvc service.ArticleService,
+	mediaSvc mediaSvc.MediaService,
+	sitemapSvc service.SitemapService,
+	articleMigrator *migrator.Migrator,
+	articleChecker *consistency.Checker,
 	l logger.Logger) *schedulerSvc.LocalFuncExecutor {
 	res := schedulerSvc.NewLocalFuncExecutor(l)
 	// 要在数据库里面插入一条记录。 手动插入RankingJob的记录
@@ -36,6 +47,80 @@ func InitLocalFuncExecutor(svc service.RankingService,
 		defer cancel()
 		return svc.TopN(ctx)
 	})
+	// 实时热榜，同样要插入一条 ranking_stream 的记录，可以跑得比 ranking 频繁得多。
+	// 只在 ranking.mode 是 streaming 的时候干活，这时候 ranking 只做定时修正
+	res.RegisterFunc("ranking_stream", func(ctx context.Context, j domain.Job) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+		defer cancel()
+		return rankingStreamSvc.Refresh(ctx)
+	})
+	// 热榜快照清理，要插入一条 ranking_snapshot_purge 的记录，一天一次就够了
+	res.RegisterFunc("ranking_snapshot_purge", func(ctx context.Context, j domain.Job) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		days := viper.GetInt("ranking.snapshot.retentionDays")
+		if days <= 0 {
+			days = 30
+		}
+		cnt, err := rankingSnapshotSvc.Purge(ctx, time.Now().AddDate(0, 0, -days))
+		l.Info("清理热榜快照", logger.Int("cnt", cnt), logger.Error(err))
+		return err
+	})
+	// 回收站清理，同样要在数据库里面插入一条 article_trash_purge 的记录，一天一次就够了
+	res.RegisterFunc("article_trash_purge", func(ctx context.Context, j domain.Job) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		days := viper.GetInt("article.trashRetentionDays")
+		if days <= 0 {
+			days = 30
+		}
+		cnt, err := artSvc.PurgeTrash(ctx, time.Now().AddDate(0, 0, -days))
+		l.Info("清理回收站", logger.Int("cnt", cnt), logger.Error(err))
+		return err
+	})
+	// 回收没有被任何文章引用的文件，刚上传还没来得及保存文章的文件要留一段时间
+	res.RegisterFunc("media_gc", func(ctx context.Context, j domain.Job) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		cnt, err := mediaSvc.GC(ctx, time.Now().Add(-time.Hour*24))
+		l.Info("回收孤儿文件", logger.Int("cnt", cnt), logger.Error(err))
+		return err
+	})
+	// 站点地图，要在数据库里面插入一条 sitemap 的记录
+	// 每次只处理上一次之后有变更的分片，所以可以跑得比较频繁
+	res.RegisterFunc("sitemap", func(ctx context.Context, j domain.Job) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+		defer cancel()
+		return sitemapSvc.Generate(ctx)
+	})
+	// 制作库和线上库的一致性检查，要在数据库里面插入一条 article_consistency 的记录
+	// 修复是以制作库为准的，可以先关掉修复跑一段时间，看看报告出来的是不是都是真的不一致
+	if articleChecker != nil {
+		res.RegisterFunc("article_consistency", func(ctx context.Context, j domain.Job) error {
+			ctx, cancel := context.WithTimeout(ctx, time.Minute*30)
+			defer cancel()
+			mismatches, err := articleChecker.Check(ctx, viper.GetBool("article.consistency.autoRepair"))
+			l.Info("文章一致性检查", logger.Int("mismatch", len(mismatches)), logger.Error(err))
+			return err
+		})
+	}
+	// 文章存储迁移，开启了迁移才有。全量复制只需要手动跑一次，校验在双写阶段定期跑
+	if articleMigrator != nil {
+		res.RegisterFunc("article_migration_full_copy", func(ctx context.Context, j domain.Job) error {
+			ctx, cancel := context.WithTimeout(ctx, time.Hour)
+			defer cancel()
+			cnt, err := articleMigrator.FullCopy(ctx)
+			l.Info("文章全量复制", logger.Int("cnt", cnt), logger.Error(err))
+			return err
+		})
+		res.RegisterFunc("article_migration_validate", func(ctx context.Context, j domain.Job) error {
+			ctx, cancel := context.WithTimeout(ctx, time.Hour)
+			defer cancel()
+			cnt, err := articleMigrator.Validate(ctx)
+			l.Info("文章迁移校验", logger.Int("inconsistent", cnt), logger.Error(err))
+			return err
+		})
+	}
 
 	return res
 }
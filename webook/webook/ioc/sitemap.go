@@ -0,0 +1,12 @@
+package ioc
+
+import (
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+)
+
+// InitSitemapService 站点地图和生成进度存在单独的对象存储里，只通过 SitemapHandler 对外提供
+func InitSitemapService(artRepo repository.ArticleRepository, l logger.Logger) service.SitemapService {
+	return service.NewShardedSitemapService(artRepo, initPrivateStore("sitemap", "./data/sitemap"), l)
+}
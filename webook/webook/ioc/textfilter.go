@@ -0,0 +1,22 @@
+package ioc
+
+import (
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/textfilter"
+	"github.com/spf13/viper"
+)
+
+// InitTextFilter 敏感词字典单独放一个文件，改了之后自动生效
+// 没有配置字典就什么都不过滤
+func InitTextFilter(l logger.Logger) textfilter.Filter {
+	f := textfilter.NewACFilter(nil)
+	path := viper.GetString("textfilter.dict")
+	if path == "" {
+		return f
+	}
+	err := textfilter.WatchDict(path, f, l)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
@@ -21,12 +21,29 @@ import (
 )
 
 func InitWebServer(mdls []gin.HandlerFunc, userHdl *web.UserHandler,
-	oauth2wechatHdl *web.OAuth2WechatHandler, articleHdl *web.ArticleHandler) *gin.Engine {
+	oauth2wechatHdl *web.OAuth2WechatHandler, articleHdl *web.ArticleHandler,
+	commentHdl *web.CommentHandler, mediaHdl *web.MediaHandler,
+	moderationHdl *web.ModerationHandler, feedHdl *web.FeedHandler,
+	sitemapHdl *web.SitemapHandler, articleTransferHdl *web.ArticleTransferHandler,
+	seriesHdl *web.SeriesHandler, membershipHdl *web.MembershipHandler,
+	historyHdl *web.ReadHistoryHandler, collectionHdl *web.CollectionHandler,
+	userIntrHdl *web.UserInteractiveHandler) *gin.Engine {
 	server := gin.Default()
 	server.Use(mdls...)
 	userHdl.RegisterRoutes(server)
 	oauth2wechatHdl.RegisterRoutes(server)
 	articleHdl.RegisterRoutes(server)
+	commentHdl.RegisterRoutes(server)
+	mediaHdl.RegisterRoutes(server)
+	moderationHdl.RegisterRoutes(server)
+	feedHdl.RegisterRoutes(server)
+	sitemapHdl.RegisterRoutes(server)
+	articleTransferHdl.RegisterRoutes(server)
+	seriesHdl.RegisterRoutes(server)
+	membershipHdl.RegisterRoutes(server)
+	historyHdl.RegisterRoutes(server)
+	collectionHdl.RegisterRoutes(server)
+	userIntrHdl.RegisterRoutes(server)
 	return server
 }
 
@@ -51,7 +68,17 @@ func InitMiddlewares(redisClient redis.Cmdable, jwtHandler jwt.JwtHandler, l log
 			IgnorePath("/users/signup").
 			IgnorePath("/users/login_sms/code/send").
 			IgnorePath("/users/login_sms").
-			IgnorePath("/users/login").Build(),
+			IgnorePath("/users/login").
+			IgnorePrefix(web.MediaFilesPath + "/").
+			IgnorePrefix(web.FeedsPath + "/").
+			IgnorePath(web.SitemapIndexPath).
+			IgnorePrefix(web.SitemapsPath + "/").
+			IgnoreRoute(web.TopLikeRoute).
+			IgnoreRoute(web.RankingRoute).
+			IgnoreRoute(web.RankingHistoryRoute).
+			IgnoreRoute(web.RankingMoversRoute).
+			OptionalRoute(web.PubDetailRoute).
+			OptionalRoute(web.SeriesDetailRoute).Build(),
 		//ratelimit.NewBuilder(redisClient, time.Second, 100).Build(),
 		setJWTToken(),
 	}
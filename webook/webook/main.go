@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article/migrator"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web"
 	"github.com/fsnotify/fsnotify"
@@ -18,7 +19,10 @@ func main() {
 	//initViperFromReader()
 	//initViperFromProgramArgument()
 	//initViperFromRemote()
-	app := InitWebServer()
+	app, err := InitWebServer()
+	if err != nil {
+		panic(err)
+	}
 	for _, c := range app.consumers {
 		err := c.Start()
 		if err != nil {
@@ -43,6 +47,11 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
 
+	err = app.articleTransferSvc.Close(ctx)
+	if err != nil {
+		fmt.Println(err)
+	}
+
 	// 分布式锁
 	//ctx = app.cron.Stop()
 	//app.rankJob.Close()
@@ -76,6 +85,25 @@ func initViperFromLocalConfigFile() {
 		service.CodeTplId.Store(viper.GetString("TplId.code"))
 		web.TopLikeN.Store(viper.GetInt64("TopLike.N"))
 		web.TopLikeLimit.Store(viper.GetInt64("TopLike.Limit"))
+		// 热榜打分策略，下一次计算热榜的时候生效，配错了就保持原来的策略
+		rankingScoreCfg, err := service.LoadRankingScoreConfig()
+		if err == nil {
+			err = service.UpdateRankingScorer(rankingScoreCfg)
+		}
+		if err != nil {
+			fmt.Println(err)
+		}
+		err = service.UpdateRankingMode(viper.GetString("ranking.mode"))
+		if err != nil {
+			fmt.Println(err)
+		}
+		// 文章存储迁移按阶段切换，配错了就保持原来的阶段
+		if viper.GetBool("migrator.article.enabled") {
+			err := migrator.UpdatePattern(viper.GetString("migrator.article.pattern"))
+			if err != nil {
+				fmt.Println(err)
+			}
+		}
 	})
 	err := viper.ReadInConfig()
 	if err != nil {
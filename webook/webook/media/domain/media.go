@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// Media 上传的图片或者附件
+// 同样内容的文件只会存一份，Hash 是内容的 sha256
+type Media struct {
+	Id int64
+	// 第一个上传者
+	Uid         int64
+	Hash        string
+	ContentType string
+	Size        int64
+	// Key 在对象存储里面的 key
+	Key string
+	// URL 对外访问的地址
+	URL   string
+	Ctime time.Time
+	Utime time.Time
+}
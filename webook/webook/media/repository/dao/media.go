@@ -0,0 +1,142 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrRecordNotFound = gorm.ErrRecordNotFound
+
+type MediaDAO interface {
+	// Insert 同样的 hash 已经存在的时候什么也不做
+	Insert(ctx context.Context, m Media) error
+	FindByHash(ctx context.Context, hash string) (Media, error)
+	FindByHashes(ctx context.Context, hashes []string) ([]Media, error)
+	// Touch 更新 utime，重复上传的时候用，避免刚被引用就被当成孤儿回收
+	Touch(ctx context.Context, id int64) error
+	// ReplaceRefs 用 mediaIds 整个替换掉某个资源引用的文件
+	ReplaceRefs(ctx context.Context, biz string, bizId int64, mediaIds []int64) error
+	DeleteRefs(ctx context.Context, biz string, bizIds []int64) error
+	// FindOrphans 找到 utime 在 before 之前，并且没有被任何资源引用的文件
+	FindOrphans(ctx context.Context, before time.Time, limit int) ([]Media, error)
+	// DeleteOrphan 再确认一次没有被引用才删除，返回是否真的删掉了
+	DeleteOrphan(ctx context.Context, id int64) (bool, error)
+}
+
+type GORMMediaDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMMediaDAO(db *gorm.DB) MediaDAO {
+	return &GORMMediaDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMMediaDAO) Insert(ctx context.Context, m Media) error {
+	now := time.Now().UnixMilli()
+	m.Ctime = now
+	m.Utime = now
+	// 并发上传同一个文件的时候，只有一个能插入成功，另一个什么也不做
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoNothing: true,
+	}).Create(&m).Error
+}
+
+func (dao *GORMMediaDAO) FindByHash(ctx context.Context, hash string) (Media, error) {
+	var m Media
+	err := dao.db.WithContext(ctx).Where("hash = ?", hash).First(&m).Error
+	return m, err
+}
+
+func (dao *GORMMediaDAO) FindByHashes(ctx context.Context, hashes []string) ([]Media, error) {
+	var res []Media
+	if len(hashes) == 0 {
+		return res, nil
+	}
+	err := dao.db.WithContext(ctx).Where("hash IN ?", hashes).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMMediaDAO) Touch(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Model(&Media{}).
+		Where("id = ?", id).
+		Update("utime", time.Now().UnixMilli()).Error
+}
+
+func (dao *GORMMediaDAO) ReplaceRefs(ctx context.Context, biz string, bizId int64, mediaIds []int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("biz = ? AND biz_id = ?", biz, bizId).
+			Delete(&MediaRef{}).Error
+		if err != nil || len(mediaIds) == 0 {
+			return err
+		}
+		refs := make([]MediaRef, 0, len(mediaIds))
+		for _, id := range mediaIds {
+			refs = append(refs, MediaRef{
+				MediaId: id,
+				Biz:     biz,
+				BizId:   bizId,
+				Ctime:   now,
+			})
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&refs).Error
+	})
+}
+
+func (dao *GORMMediaDAO) DeleteRefs(ctx context.Context, biz string, bizIds []int64) error {
+	if len(bizIds) == 0 {
+		return nil
+	}
+	return dao.db.WithContext(ctx).
+		Where("biz = ? AND biz_id IN ?", biz, bizIds).
+		Delete(&MediaRef{}).Error
+}
+
+func (dao *GORMMediaDAO) FindOrphans(ctx context.Context, before time.Time, limit int) ([]Media, error) {
+	var res []Media
+	err := dao.db.WithContext(ctx).Model(&Media{}).
+		Where("utime < ?", before.UnixMilli()).
+		Where("NOT EXISTS (SELECT 1 FROM media_refs WHERE media_refs.media_id = media.id)").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMMediaDAO) DeleteOrphan(ctx context.Context, id int64) (bool, error) {
+	// 查找和删除之间，可能有文章引用了这个文件，所以删除的时候还要再判断一次
+	res := dao.db.WithContext(ctx).
+		Where("id = ?", id).
+		Where("NOT EXISTS (SELECT 1 FROM media_refs WHERE media_refs.media_id = media.id)").
+		Delete(&Media{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Media 上传的文件
+type Media struct {
+	Id int64 `gorm:"primaryKey,autoIncrement"`
+	// 内容的 sha256，用来去重
+	Hash        string `gorm:"type:char(64);uniqueIndex"`
+	Uid         int64
+	ContentType string `gorm:"type:varchar(128)"`
+	Size        int64
+	// ObjKey 在对象存储里的 key
+	ObjKey string `gorm:"type:varchar(256)"`
+	Ctime  int64
+	// 回收孤儿文件的时候按照 utime 判断
+	Utime int64 `gorm:"index"`
+}
+
+// MediaRef 哪个资源引用了哪个文件
+// 一个文件可以被多篇文章引用，没有任何引用的文件会被定时任务回收
+type MediaRef struct {
+	Id      int64  `gorm:"primaryKey,autoIncrement"`
+	MediaId int64  `gorm:"uniqueIndex:media_biz_id;index"`
+	Biz     string `gorm:"type:varchar(128);uniqueIndex:media_biz_id;index:biz_type_id"`
+	BizId   int64  `gorm:"uniqueIndex:media_biz_id;index:biz_type_id"`
+	Ctime   int64
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/repository/dao"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+)
+
+var ErrMediaNotFound = dao.ErrRecordNotFound
+
+type MediaRepository interface {
+	Create(ctx context.Context, m domain.Media) error
+	FindByHash(ctx context.Context, hash string) (domain.Media, error)
+	FindByHashes(ctx context.Context, hashes []string) ([]domain.Media, error)
+	Touch(ctx context.Context, id int64) error
+	ReplaceRefs(ctx context.Context, biz string, bizId int64, mediaIds []int64) error
+	DeleteRefs(ctx context.Context, biz string, bizIds []int64) error
+	FindOrphans(ctx context.Context, before time.Time, limit int) ([]domain.Media, error)
+	DeleteOrphan(ctx context.Context, id int64) (bool, error)
+}
+
+// GORMMediaRepository 文件的元数据访问不频繁，暂时不需要缓存
+type GORMMediaRepository struct {
+	dao dao.MediaDAO
+}
+
+func NewGORMMediaRepository(dao dao.MediaDAO) MediaRepository {
+	return &GORMMediaRepository{
+		dao: dao,
+	}
+}
+
+func (repo *GORMMediaRepository) Create(ctx context.Context, m domain.Media) error {
+	return repo.dao.Insert(ctx, repo.toEntity(m))
+}
+
+func (repo *GORMMediaRepository) FindByHash(ctx context.Context, hash string) (domain.Media, error) {
+	m, err := repo.dao.FindByHash(ctx, hash)
+	if err != nil {
+		return domain.Media{}, err
+	}
+	return repo.toDomain(m), nil
+}
+
+func (repo *GORMMediaRepository) FindByHashes(ctx context.Context, hashes []string) ([]domain.Media, error) {
+	ms, err := repo.dao.FindByHashes(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.Media, domain.Media](ms, func(idx int, src dao.Media) domain.Media {
+		return repo.toDomain(src)
+	}), nil
+}
+
+func (repo *GORMMediaRepository) Touch(ctx context.Context, id int64) error {
+	return repo.dao.Touch(ctx, id)
+}
+
+func (repo *GORMMediaRepository) ReplaceRefs(ctx context.Context, biz string, bizId int64, mediaIds []int64) error {
+	return repo.dao.ReplaceRefs(ctx, biz, bizId, mediaIds)
+}
+
+func (repo *GORMMediaRepository) DeleteRefs(ctx context.Context, biz string, bizIds []int64) error {
+	return repo.dao.DeleteRefs(ctx, biz, bizIds)
+}
+
+func (repo *GORMMediaRepository) FindOrphans(ctx context.Context, before time.Time, limit int) ([]domain.Media, error) {
+	ms, err := repo.dao.FindOrphans(ctx, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.Media, domain.Media](ms, func(idx int, src dao.Media) domain.Media {
+		return repo.toDomain(src)
+	}), nil
+}
+
+func (repo *GORMMediaRepository) DeleteOrphan(ctx context.Context, id int64) (bool, error) {
+	return repo.dao.DeleteOrphan(ctx, id)
+}
+
+func (repo *GORMMediaRepository) toEntity(m domain.Media) dao.Media {
+	return dao.Media{
+		Id:          m.Id,
+		Hash:        m.Hash,
+		Uid:         m.Uid,
+		ContentType: m.ContentType,
+		Size:        m.Size,
+		ObjKey:      m.Key,
+	}
+}
+
+func (repo *GORMMediaRepository) toDomain(m dao.Media) domain.Media {
+	return domain.Media{
+		Id:          m.Id,
+		Uid:         m.Uid,
+		Hash:        m.Hash,
+		ContentType: m.ContentType,
+		Size:        m.Size,
+		Key:         m.ObjKey,
+		Ctime:       time.UnixMilli(m.Ctime),
+		Utime:       time.UnixMilli(m.Utime),
+	}
+}
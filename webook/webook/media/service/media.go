@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/domain"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/repository"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/objectstore"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrMediaTooLarge        = errors.New("文件太大")
+	ErrUnsupportedMediaType = errors.New("不支持的文件类型")
+)
+
+// allowedTypes 允许上传的类型，以及存储时用的扩展名
+// 类型是根据文件内容判断的，不相信前端传过来的 Content-Type
+var allowedTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+}
+
+// hashPattern 文件的地址里面带着内容的 sha256，从文章内容里面把它们找出来
+// 即便误匹配到了别的 64 位十六进制串，最多也就是让一个文件晚点被回收
+var hashPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+type MediaService interface {
+	// Upload 校验大小和类型，同样内容的文件只存一份
+	Upload(ctx context.Context, uid int64, r io.Reader) (domain.Media, error)
+	// SyncRefs 根据内容里面引用的文件，更新引用关系
+	SyncRefs(ctx context.Context, biz string, bizId int64, content string) error
+	// DeleteRefs 资源被彻底删除的时候调用
+	DeleteRefs(ctx context.Context, biz string, bizIds []int64) error
+	// GC 回收 before 之前上传，并且没有被任何资源引用的文件，返回回收的数量
+	GC(ctx context.Context, before time.Time) (int, error)
+}
+
+type mediaService struct {
+	repo    repository.MediaRepository
+	store   objectstore.Store
+	l       logger.Logger
+	maxSize int64
+}
+
+func NewMediaService(repo repository.MediaRepository, store objectstore.Store, l logger.Logger) MediaService {
+	maxSize := viper.GetInt64("media.maxSize")
+	if maxSize <= 0 {
+		maxSize = 10 << 20
+	}
+	return &mediaService{
+		repo:    repo,
+		store:   store,
+		l:       l,
+		maxSize: maxSize,
+	}
+}
+
+func (s *mediaService) Upload(ctx context.Context, uid int64, r io.Reader) (domain.Media, error) {
+	// 多读一个字节，就知道是不是超过了限制，不用把整个大文件读进内存
+	data, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return domain.Media{}, err
+	}
+	if int64(len(data)) > s.maxSize {
+		return domain.Media{}, ErrMediaTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return domain.Media{}, ErrUnsupportedMediaType
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	m, err := s.repo.FindByHash(ctx, hash)
+	if err == nil {
+		// 已经有人传过一样的文件了，直接复用
+		err = s.repo.Touch(ctx, m.Id)
+		m.URL = s.store.URL(m.Key)
+		return m, err
+	}
+	if !errors.Is(err, repository.ErrMediaNotFound) {
+		return domain.Media{}, err
+	}
+
+	m = domain.Media{
+		Uid:         uid,
+		Hash:        hash,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		// 前两位做一层目录，避免一个目录下文件太多
+		Key: hash[:2] + "/" + hash + ext,
+	}
+	// 先存文件再写元数据，这样元数据存在的时候文件一定存在
+	err = s.store.Put(ctx, m.Key, data, contentType)
+	if err != nil {
+		return domain.Media{}, err
+	}
+	err = s.repo.Create(ctx, m)
+	if err != nil {
+		return domain.Media{}, err
+	}
+	// 并发上传的时候，插入的可能是别人的那一条
+	m, err = s.repo.FindByHash(ctx, hash)
+	if err != nil {
+		return domain.Media{}, err
+	}
+	m.URL = s.store.URL(m.Key)
+	return m, nil
+}
+
+func (s *mediaService) SyncRefs(ctx context.Context, biz string, bizId int64, content string) error {
+	hashes := hashPattern.FindAllString(content, -1)
+	ms, err := s.repo.FindByHashes(ctx, hashes)
+	if err != nil {
+		return err
+	}
+	ids := make([]int64, 0, len(ms))
+	for _, m := range ms {
+		ids = append(ids, m.Id)
+	}
+	return s.repo.ReplaceRefs(ctx, biz, bizId, ids)
+}
+
+func (s *mediaService) DeleteRefs(ctx context.Context, biz string, bizIds []int64) error {
+	return s.repo.DeleteRefs(ctx, biz, bizIds)
+}
+
+func (s *mediaService) GC(ctx context.Context, before time.Time) (int, error) {
+	const batchSize = 100
+	total := 0
+	for {
+		ms, err := s.repo.FindOrphans(ctx, before, batchSize)
+		if err != nil {
+			return total, err
+		}
+		for _, m := range ms {
+			// 先删元数据，删成功了再删文件
+			// 文件删失败了顶多是对象存储里多一个文件，重新上传的时候会覆盖
+			ok, err := s.repo.DeleteOrphan(ctx, m.Id)
+			if err != nil {
+				return total, err
+			}
+			if !ok {
+				continue
+			}
+			err = s.store.Delete(ctx, m.Key)
+			if err != nil {
+				s.l.Error("删除孤儿文件失败", logger.Error(err),
+					logger.String("key", m.Key))
+			}
+			total++
+		}
+		if len(ms) < batchSize {
+			return total, nil
+		}
+	}
+}
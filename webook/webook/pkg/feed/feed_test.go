@@ -0,0 +1,95 @@
+package feed
+
+import (
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() Feed {
+	pub := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CST", 8*3600))
+	return Feed{
+		Title:       "webook - 最新文章",
+		Description: "webook 上最新发表的文章",
+		Link:        "https://webook.com",
+		Self:        "https://webook.com/feeds/latest.xml",
+		Updated:     pub.Add(time.Hour),
+		Items: []Item{
+			{
+				Id:          "https://webook.com/pub/1",
+				Title:       "Go & <泛型>",
+				Link:        "https://webook.com/pub/1",
+				Description: "摘要",
+				Author:      "大明",
+				Published:   pub,
+				Updated:     pub.Add(time.Hour),
+			},
+			{
+				Id:        "https://webook.com/pub/2",
+				Title:     "没有作者",
+				Link:      "https://webook.com/pub/2",
+				Published: pub,
+				Updated:   pub,
+			},
+		},
+	}
+}
+
+func TestRender_RSS(t *testing.T) {
+	data, err := Render(testFeed(), FormatRSS)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), xml.Header))
+
+	var doc rss
+	require.NoError(t, xml.Unmarshal(data, &doc))
+	assert.Equal(t, "2.0", doc.Version)
+	assert.Equal(t, "webook - 最新文章", doc.Channel.Title)
+	// 带前缀的 atom:link 解析不回来，直接看输出
+	assert.Contains(t, string(data),
+		`<atom:link href="https://webook.com/feeds/latest.xml" rel="self" type="application/rss+xml"></atom:link>`)
+	// 时间都转成 UTC
+	assert.Equal(t, "Mon, 01 Jan 2024 20:04:05 +0000", doc.Channel.LastBuildDate)
+	require.Len(t, doc.Channel.Items, 2)
+	item := doc.Channel.Items[0]
+	// 特殊字符要转义，解析回来和原文一样
+	assert.Equal(t, "Go & <泛型>", item.Title)
+	assert.True(t, item.Guid.IsPermaLink)
+	assert.Equal(t, "https://webook.com/pub/1", item.Guid.Value)
+	assert.Equal(t, "Mon, 01 Jan 2024 19:04:05 +0000", item.PubDate)
+	assert.Contains(t, string(data), "<dc:creator>大明</dc:creator>")
+	// 没有作者的不输出 dc:creator
+	assert.Equal(t, 1, strings.Count(string(data), "<dc:creator>"))
+}
+
+func TestRender_Atom(t *testing.T) {
+	data, err := Render(testFeed(), FormatAtom)
+	require.NoError(t, err)
+
+	var doc atomFeed
+	require.NoError(t, xml.Unmarshal(data, &doc))
+	assert.Equal(t, "https://webook.com/feeds/latest.xml", doc.Id)
+	assert.Equal(t, "2024-01-01T20:04:05Z", doc.Updated)
+	assert.Equal(t, []atomLink{
+		{Href: "https://webook.com", Rel: "alternate"},
+		{Href: "https://webook.com/feeds/latest.xml", Rel: "self", Type: "application/atom+xml"},
+	}, doc.Links)
+	require.Len(t, doc.Entries, 2)
+	assert.Equal(t, "Go & <泛型>", doc.Entries[0].Title)
+	assert.Equal(t, "2024-01-01T19:04:05Z", doc.Entries[0].Published)
+	assert.Equal(t, &atomAuthor{Name: "大明"}, doc.Entries[0].Author)
+	assert.Nil(t, doc.Entries[1].Author)
+}
+
+func TestRender_Stable(t *testing.T) {
+	// 同样的内容渲染两次结果要一样，ETag 靠这个
+	for _, format := range []Format{FormatRSS, FormatAtom} {
+		a, err := Render(testFeed(), format)
+		require.NoError(t, err)
+		b, err := Render(testFeed(), format)
+		require.NoError(t, err)
+		assert.Equal(t, a, b)
+	}
+}
@@ -0,0 +1,56 @@
+package feed
+
+import "time"
+
+// Format 输出的格式
+type Format string
+
+const (
+	FormatRSS  Format = "rss"
+	FormatAtom Format = "atom"
+)
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatAtom:
+		return "application/atom+xml; charset=utf-8"
+	default:
+		return "application/rss+xml; charset=utf-8"
+	}
+}
+
+func (f Format) IsValid() bool {
+	return f == FormatRSS || f == FormatAtom
+}
+
+// Feed RSS 和 Atom 共用的模型，渲染的时候再转换
+type Feed struct {
+	Title       string
+	Description string
+	// Link 网站上对应的页面
+	Link string
+	// Self 订阅地址本身
+	Self string
+	// Updated 最新一条的更新时间，同样的内容渲染出来的结果要一样，所以不要用当前时间
+	Updated time.Time
+	Items   []Item
+}
+
+type Item struct {
+	// Id 要全局唯一而且不会变，一般就用文章的链接
+	Id          string
+	Title       string
+	Link        string
+	Description string
+	Author      string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// Render 按照格式渲染成 XML
+func Render(f Feed, format Format) ([]byte, error) {
+	if format == FormatAtom {
+		return renderAtom(f)
+	}
+	return renderRSS(f)
+}
@@ -0,0 +1,117 @@
+package frontmatter
+
+import (
+	"bytes"
+	"errors"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"time"
+)
+
+var ErrInvalidDate = errors.New("front matter 里的 date 格式不对")
+
+const delimiter = "---"
+
+// Document 带 YAML front matter 的 Markdown 文件
+//
+//	---
+//	title: 标题
+//	tags: [go, gin]
+//	date: 2023-10-01
+//	---
+//	正文
+type Document struct {
+	Title string
+	Tags  []string
+	// Date 零值代表没有
+	Date time.Time
+	Body string
+}
+
+type meta struct {
+	Title string   `yaml:"title,omitempty"`
+	Tags  []string `yaml:"tags,omitempty"`
+	// 不同平台导出的格式不一样，先按字符串读出来再挨个试
+	Date string `yaml:"date,omitempty"`
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Parse 没有 front matter 的话，整个文件都是正文
+func Parse(data []byte) (Document, error) {
+	// Windows 上的编辑器保存的文件可能有 BOM 和 \r\n
+	text := strings.TrimPrefix(string(data), "\uFEFF")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.HasPrefix(text, delimiter+"\n") {
+		return Document{Body: text}, nil
+	}
+	rest := text[len(delimiter)+1:]
+	var header, body string
+	switch {
+	case strings.HasPrefix(rest, delimiter+"\n") || rest == delimiter:
+		// 空的 front matter
+		body = strings.TrimPrefix(rest, delimiter)
+	case strings.Contains(rest, "\n"+delimiter+"\n"):
+		end := strings.Index(rest, "\n"+delimiter+"\n")
+		header, body = rest[:end], rest[end+len(delimiter)+2:]
+	case strings.HasSuffix(rest, "\n"+delimiter):
+		header = strings.TrimSuffix(rest, "\n"+delimiter)
+	default:
+		// 没有结束的分隔符，当成普通的正文
+		return Document{Body: text}, nil
+	}
+
+	var m meta
+	err := yaml.Unmarshal([]byte(header), &m)
+	if err != nil {
+		return Document{}, err
+	}
+	doc := Document{
+		Title: strings.TrimSpace(m.Title),
+		Tags:  m.Tags,
+		Body:  strings.TrimPrefix(body, "\n"),
+	}
+	if m.Date != "" {
+		doc.Date, err = parseDate(m.Date)
+		if err != nil {
+			return Document{}, err
+		}
+	}
+	return doc, nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrInvalidDate
+}
+
+// Format 生成带 front matter 的 Markdown
+func Format(doc Document) ([]byte, error) {
+	m := meta{
+		Title: doc.Title,
+		Tags:  doc.Tags,
+	}
+	if !doc.Date.IsZero() {
+		m.Date = doc.Date.Format(time.RFC3339)
+	}
+	header, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(delimiter + "\n")
+	buf.Write(header)
+	buf.WriteString(delimiter + "\n\n")
+	buf.WriteString(doc.Body)
+	return buf.Bytes(), nil
+}
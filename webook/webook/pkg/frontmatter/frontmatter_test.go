@@ -0,0 +1,136 @@
+package frontmatter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    string
+		wantDoc Document
+		wantErr error
+	}{
+		{
+			name:    "没有 front matter",
+			data:    "# 标题\n正文",
+			wantDoc: Document{Body: "# 标题\n正文"},
+		},
+		{
+			name: "完整的 front matter",
+			data: "---\ntitle: 标题\ntags: [go, gin]\ndate: 2023-10-01\n---\n正文",
+			wantDoc: Document{
+				Title: "标题",
+				Tags:  []string{"go", "gin"},
+				Date:  time.Date(2023, 10, 1, 0, 0, 0, 0, time.Local),
+				Body:  "正文",
+			},
+		},
+		{
+			name: "BOM 和 CRLF",
+			data: "\uFEFF---\r\ntitle: 标题\r\n---\r\n正文\r\n第二行",
+			wantDoc: Document{
+				Title: "标题",
+				Body:  "正文\n第二行",
+			},
+		},
+		{
+			name:    "空的 front matter",
+			data:    "---\n---\n正文",
+			wantDoc: Document{Body: "正文"},
+		},
+		{
+			name:    "只有 front matter",
+			data:    "---\ntitle: 标题\n---",
+			wantDoc: Document{Title: "标题"},
+		},
+		{
+			name:    "没有结束的分隔符",
+			data:    "---\ntitle: 标题\n正文",
+			wantDoc: Document{Body: "---\ntitle: 标题\n正文"},
+		},
+		{
+			name: "带时分秒的日期",
+			data: "---\ndate: 2023-10-01 08:30:00\n---\n正文",
+			wantDoc: Document{
+				Date: time.Date(2023, 10, 1, 8, 30, 0, 0, time.Local),
+				Body: "正文",
+			},
+		},
+		{
+			name:    "日期格式不对",
+			data:    "---\ndate: 十月一号\n---\n正文",
+			wantErr: ErrInvalidDate,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tc.data))
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantDoc.Title, doc.Title)
+			assert.Equal(t, tc.wantDoc.Tags, doc.Tags)
+			assert.True(t, tc.wantDoc.Date.Equal(doc.Date))
+			assert.Equal(t, tc.wantDoc.Body, doc.Body)
+		})
+	}
+}
+
+func TestParseInvalidYAML(t *testing.T) {
+	_, err := Parse([]byte("---\ntitle: [标题\n---\n正文"))
+	assert.Error(t, err)
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		name string
+		doc  Document
+		want string
+	}{
+		{
+			name: "完整的文档",
+			doc: Document{
+				Title: "标题",
+				Tags:  []string{"go", "gin"},
+				Date:  time.Date(2023, 10, 1, 8, 30, 0, 0, time.UTC),
+				Body:  "正文",
+			},
+			want: "---\ntitle: 标题\ntags:\n    - go\n    - gin\ndate: \"2023-10-01T08:30:00Z\"\n---\n\n正文",
+		},
+		{
+			name: "没有标签和日期",
+			doc:  Document{Title: "标题", Body: "正文"},
+			want: "---\ntitle: 标题\n---\n\n正文",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := Format(tc.doc)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(data))
+		})
+	}
+}
+
+// 导出之后再导入，内容要和原来的一样
+func TestFormatParse(t *testing.T) {
+	doc := Document{
+		Title: "标题: 带冒号",
+		Tags:  []string{"go", "中文标签"},
+		Date:  time.Date(2023, 10, 1, 8, 30, 0, 0, time.Local),
+		Body:  "# 正文\n\n---\n\n分隔线后面的内容\n",
+	}
+	data, err := Format(doc)
+	require.NoError(t, err)
+	got, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, doc.Title, got.Title)
+	assert.Equal(t, doc.Tags, got.Tags)
+	assert.True(t, doc.Date.Equal(got.Date))
+	assert.Equal(t, doc.Body, got.Body)
+}
@@ -62,7 +62,9 @@ func (b *Builder) Build() gin.HandlerFunc {
 			Method: method,
 			Url:    url,
 		}
-		if b.allowReqBody && ctx.Request.Body != nil {
+		// 上传文件的请求体可能很大，读出来会全部放进内存，也没有记录的意义
+		if b.allowReqBody && ctx.Request.Body != nil &&
+			!strings.HasPrefix(ctx.ContentType(), "multipart/") {
 			// Body 读完就没有了
 			//reqBody := ctx.Request.Body
 			body, _ := ctx.GetRawData()
@@ -70,6 +70,29 @@ func WrapToken[C any](fn func(ctx *gin.Context, uc C) (Result, error), method st
 	}
 }
 
+// WrapOptionalToken 登录是可选的，没有登录的时候 uc 是零值，也就是 Uid 为 0
+// 路由要在 LoginJWTMiddlewareBuilder.OptionalRoute 里注册，不然没登录的请求到不了这里
+func WrapOptionalToken(fn func(ctx *gin.Context, uc myjwt.UserClaims) (Result, error), method string, l logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var uc myjwt.UserClaims
+		if val, ok := ctx.Get("claims"); ok {
+			if c, ok := val.(*myjwt.UserClaims); ok {
+				uc = *c
+			}
+		}
+
+		res, err := fn(ctx, uc)
+		if err != nil {
+			l.Debug("处理业务逻辑出错", logger.String("method", method), logger.Error(err))
+		} else {
+			l.Info("处理业务逻辑成功", logger.String("method", method))
+		}
+
+		ctx.JSON(http.StatusOK, res)
+		vector.WithLabelValues(method, strconv.Itoa(res.Code)).Inc()
+	}
+}
+
 func WrapBodyAndToken[Req any, C any](fn func(ctx *gin.Context, req Req, uc C) (Result, error), method string, l logger.Logger) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var req Req
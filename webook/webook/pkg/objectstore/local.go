@@ -0,0 +1,83 @@
+package objectstore
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore 存在本地磁盘上，适合开发环境或者单机部署
+// 它本身也是一个 http.Handler，可以直接挂在 web 服务器上对外提供访问
+type LocalStore struct {
+	root    string
+	baseURL string
+	fs      http.Handler
+}
+
+func NewLocalStore(root string, baseURL string) *LocalStore {
+	return &LocalStore{
+		root:    root,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		fs:      http.FileServer(http.Dir(root)),
+	}
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	dst := s.path(key)
+	err := os.MkdirAll(filepath.Dir(dst), 0o755)
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再改名，避免别人读到写了一半的文件
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// ServeHTTP 只能访问具体的文件，目录一律 404，不然 http.FileServer 会列出目录下所有的文件
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	info, err := os.Stat(s.path(r.URL.Path))
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	s.fs.ServeHTTP(w, r)
+}
+
+// path 防止 key 里面带 ../ 跑到 root 外面去
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+key)))
+}
@@ -0,0 +1,68 @@
+package objectstore
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocalStore_ServeHTTP(t *testing.T) {
+	s := NewLocalStore(t.TempDir(), "")
+	err := s.Put(context.Background(), "ab/abc.png", []byte("png"), "image/png")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "文件",
+			path:     "/ab/abc.png",
+			wantCode: http.StatusOK,
+			wantBody: "png",
+		},
+		{
+			name:     "根目录",
+			path:     "/",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "子目录",
+			path:     "/ab/",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "子目录不带斜杠",
+			path:     "/ab",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "不存在的文件",
+			path:     "/ab/abd.png",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "跑到 root 外面",
+			path:     "/../../etc/passwd",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost"+tc.path, nil)
+			// 挂载的时候已经 StripPrefix 过了，这里直接用 key 作为路径
+			req.URL.Path = tc.path
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, resp.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/ecodeclub/ekit"
+	"io"
+	"strings"
+)
+
+// S3Store 兼容 S3 协议的都可以用，比如腾讯云的 COS
+type S3Store struct {
+	client *s3.S3
+	bucket *string
+	// baseURL 一般是 CDN 或者 bucket 的访问域名
+	baseURL string
+}
+
+func NewS3Store(client *s3.S3, bucket string, baseURL string) *S3Store {
+	return &S3Store{
+		client:  client,
+		bucket:  ekit.ToPtr[string](bucket),
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      s.bucket,
+		Key:         ekit.ToPtr[string](key),
+		Body:        bytes.NewReader(data),
+		ContentType: ekit.ToPtr[string](contentType),
+	})
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: s.bucket,
+		Key:    ekit.ToPtr[string](key),
+	})
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	// S3 删除不存在的对象也不会报错
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: s.bucket,
+		Key:    ekit.ToPtr[string](key),
+	})
+	return err
+}
+
+func (s *S3Store) URL(key string) string {
+	return s.baseURL + "/" + key
+}
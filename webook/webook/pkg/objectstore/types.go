@@ -0,0 +1,20 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNotFound = errors.New("对象不存在")
+
+// Store 对象存储的抽象，本地磁盘和 S3 各有一个实现
+// key 由调用者决定，一般是内容的哈希，所以同一个 key 写多次内容也是一样的
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get key 不存在的时候返回 ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete key 不存在的时候不返回错误
+	Delete(ctx context.Context, key string) error
+	// URL 对外访问的地址，同一个 key 返回的地址是稳定的
+	URL(key string) string
+}
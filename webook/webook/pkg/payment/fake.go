@@ -0,0 +1,50 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FakeGateway 内存里的假支付渠道，开发和测试环境用
+// 默认所有支付都成功，Decline 之后的用户的支付都会被拒绝
+type FakeGateway struct {
+	mu       sync.Mutex
+	paid     map[string]PayResult
+	declined map[int64]struct{}
+	seq      int64
+}
+
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{
+		paid:     make(map[string]PayResult),
+		declined: make(map[int64]struct{}),
+	}
+}
+
+func (g *FakeGateway) Pay(ctx context.Context, req PayReq) (PayResult, error) {
+	if err := ctx.Err(); err != nil {
+		return PayResult{}, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if res, ok := g.paid[req.Sn]; ok {
+		return res, nil
+	}
+	if _, ok := g.declined[req.Uid]; ok || req.Amount <= 0 {
+		return PayResult{}, ErrPaymentDeclined
+	}
+	g.seq++
+	res := PayResult{
+		TxnId: fmt.Sprintf("fake-%d", g.seq),
+	}
+	g.paid[req.Sn] = res
+	return res, nil
+}
+
+// Decline 之后 uid 的支付都会被拒绝
+func (g *FakeGateway) Decline(uid int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.declined[uid] = struct{}{}
+}
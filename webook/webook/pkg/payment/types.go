@@ -0,0 +1,28 @@
+package payment
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPaymentDeclined 支付渠道明确拒绝了这笔支付，比如余额不足，重试也没用
+var ErrPaymentDeclined = errors.New("支付被拒绝")
+
+// Gateway 支付渠道的抽象，同一个 Sn 重复调用只会扣一次钱
+type Gateway interface {
+	Pay(ctx context.Context, req PayReq) (PayResult, error)
+}
+
+type PayReq struct {
+	// Sn 业务方的订单号，用来做幂等
+	Sn  string
+	Uid int64
+	// Amount 单位是分
+	Amount      int64
+	Description string
+}
+
+type PayResult struct {
+	// TxnId 支付渠道那边的流水号
+	TxnId string
+}
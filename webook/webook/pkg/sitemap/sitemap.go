@@ -0,0 +1,64 @@
+package sitemap
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// MaxURLs 协议规定一个站点地图文件最多 5 万个地址
+const MaxURLs = 50000
+
+const ns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name  `xml:"urlset"`
+	NS      string    `xml:"xmlns,attr"`
+	URLs    []xmlNode `xml:"url"`
+}
+
+type index struct {
+	XMLName  xml.Name  `xml:"sitemapindex"`
+	NS       string    `xml:"xmlns,attr"`
+	Sitemaps []xmlNode `xml:"sitemap"`
+}
+
+// xmlNode url 和 sitemap 两种节点的结构是一样的
+type xmlNode struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// RenderURLSet 渲染一个站点地图文件，调用者保证 urls 不超过 MaxURLs
+func RenderURLSet(urls []URL) ([]byte, error) {
+	return marshal(urlSet{NS: ns, URLs: toNodes(urls)})
+}
+
+// RenderIndex 渲染站点地图索引，sitemaps 里面是每个站点地图文件的地址
+func RenderIndex(sitemaps []URL) ([]byte, error) {
+	return marshal(index{NS: ns, Sitemaps: toNodes(sitemaps)})
+}
+
+func toNodes(urls []URL) []xmlNode {
+	nodes := make([]xmlNode, 0, len(urls))
+	for _, u := range urls {
+		node := xmlNode{Loc: u.Loc}
+		if !u.LastMod.IsZero() {
+			node.LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func marshal(doc any) ([]byte, error) {
+	data, err := xml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,32 @@
+package sitemap
+
+import (
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRenderURLSet(t *testing.T) {
+	data, err := RenderURLSet([]URL{
+		{Loc: "https://webook.com/pub/1?a=1&b=2",
+			LastMod: time.Date(2024, 1, 2, 8, 0, 0, 0, time.FixedZone("CST", 8*3600))},
+		{Loc: "https://webook.com/pub/2"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, xml.Header+`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+
+		`<url><loc>https://webook.com/pub/1?a=1&amp;b=2</loc><lastmod>2024-01-02T00:00:00Z</lastmod></url>`+
+		`<url><loc>https://webook.com/pub/2</loc></url>`+
+		`</urlset>`, string(data))
+}
+
+func TestRenderIndex(t *testing.T) {
+	data, err := RenderIndex([]URL{
+		{Loc: "https://webook.com/sitemaps/sitemap-0.xml", LastMod: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, xml.Header+`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+
+		`<sitemap><loc>https://webook.com/sitemaps/sitemap-0.xml</loc><lastmod>2024-01-02T00:00:00Z</lastmod></sitemap>`+
+		`</sitemapindex>`, string(data))
+}
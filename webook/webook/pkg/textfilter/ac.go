@@ -0,0 +1,105 @@
+package textfilter
+
+import "unicode"
+
+// automaton Aho-Corasick 自动机，按照 rune 匹配，忽略大小写
+// 构建完之后是只读的，可以并发使用
+type automaton struct {
+	nodes []acNode
+	// lens 每个词有多少个 rune，命中的时候用来算起点
+	lens []int
+}
+
+type acNode struct {
+	children map[rune]int
+	fail     int
+	// outputs 以这个节点结尾的词在 words 里的下标，包括沿着 fail 能到达的
+	outputs []int
+}
+
+// hit 一次命中，[start, end) 是 rune 的下标
+type hit struct {
+	word  int
+	start int
+	end   int
+}
+
+func newAutomaton(words []Word) *automaton {
+	a := &automaton{
+		nodes: []acNode{{children: map[rune]int{}}},
+		lens:  make([]int, len(words)),
+	}
+	for i, w := range words {
+		word := []rune(w.Word)
+		a.lens[i] = len(word)
+		a.insert(word, i)
+	}
+	a.build()
+	return a
+}
+
+func (a *automaton) insert(word []rune, idx int) {
+	if len(word) == 0 {
+		return
+	}
+	cur := 0
+	for _, r := range word {
+		r = unicode.ToLower(r)
+		next, ok := a.nodes[cur].children[r]
+		if !ok {
+			next = len(a.nodes)
+			a.nodes = append(a.nodes, acNode{children: map[rune]int{}})
+			a.nodes[cur].children[r] = next
+		}
+		cur = next
+	}
+	a.nodes[cur].outputs = append(a.nodes[cur].outputs, idx)
+}
+
+// build 按层序遍历计算 fail 指针
+func (a *automaton) build() {
+	queue := make([]int, 0, len(a.nodes))
+	for _, child := range a.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range a.nodes[cur].children {
+			fail := a.nodes[cur].fail
+			for fail > 0 {
+				if _, ok := a.nodes[fail].children[r]; ok {
+					break
+				}
+				fail = a.nodes[fail].fail
+			}
+			if next, ok := a.nodes[fail].children[r]; ok && next != child {
+				a.nodes[child].fail = next
+			}
+			f := a.nodes[child].fail
+			a.nodes[child].outputs = append(a.nodes[child].outputs, a.nodes[f].outputs...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+func (a *automaton) match(text []rune) []hit {
+	var hits []hit
+	cur := 0
+	for i, r := range text {
+		r = unicode.ToLower(r)
+		for cur > 0 {
+			if _, ok := a.nodes[cur].children[r]; ok {
+				break
+			}
+			cur = a.nodes[cur].fail
+		}
+		if next, ok := a.nodes[cur].children[r]; ok {
+			cur = next
+		}
+		for _, idx := range a.nodes[cur].outputs {
+			hits = append(hits, hit{word: idx, start: i + 1 - a.lens[idx], end: i + 1})
+		}
+	}
+	return hits
+}
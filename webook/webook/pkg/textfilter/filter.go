@@ -0,0 +1,63 @@
+package textfilter
+
+import "sync/atomic"
+
+// ACFilter 基于 Aho-Corasick 自动机的敏感词过滤
+// 字典可以热更新，更新的时候整个替换掉自动机，检查不需要加锁
+type ACFilter struct {
+	dict atomic.Pointer[dictionary]
+}
+
+type dictionary struct {
+	words []Word
+	ac    *automaton
+}
+
+func NewACFilter(words []Word) *ACFilter {
+	f := &ACFilter{}
+	f.Reload(words)
+	return f
+}
+
+// Reload 替换整个字典
+func (f *ACFilter) Reload(words []Word) {
+	words = append([]Word(nil), words...)
+	f.dict.Store(&dictionary{
+		words: words,
+		ac:    newAutomaton(words),
+	})
+}
+
+func (f *ACFilter) Check(text string) Result {
+	d := f.dict.Load()
+	runes := []rune(text)
+	hits := d.ac.match(runes)
+	if len(hits) == 0 {
+		return Result{Action: ActionPass, Text: text}
+	}
+
+	res := Result{Action: ActionPass}
+	seen := make(map[int]struct{}, len(hits))
+	masked := false
+	for _, h := range hits {
+		w := d.words[h.word]
+		if w.Action > res.Action {
+			res.Action = w.Action
+		}
+		if _, ok := seen[h.word]; !ok {
+			seen[h.word] = struct{}{}
+			res.Words = append(res.Words, w.Word)
+		}
+		if w.Action == ActionMask {
+			for i := h.start; i < h.end; i++ {
+				runes[i] = '*'
+			}
+			masked = true
+		}
+	}
+	res.Text = text
+	if masked {
+		res.Text = string(runes)
+	}
+	return res
+}
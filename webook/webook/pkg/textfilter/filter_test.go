@@ -0,0 +1,153 @@
+package textfilter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAutomaton_Match(t *testing.T) {
+	testCases := []struct {
+		name  string
+		words []string
+		text  string
+		want  []hit
+	}{
+		{
+			name:  "没有命中",
+			words: []string{"abc"},
+			text:  "abd",
+		},
+		{
+			name:  "重叠",
+			words: []string{"abc", "bcd"},
+			text:  "abcd",
+			want: []hit{
+				{word: 0, start: 0, end: 3},
+				{word: 1, start: 1, end: 4},
+			},
+		},
+		{
+			name:  "嵌套",
+			words: []string{"she", "he", "hers"},
+			text:  "ushers",
+			want: []hit{
+				{word: 0, start: 1, end: 4},
+				{word: 1, start: 2, end: 4},
+				{word: 2, start: 2, end: 6},
+			},
+		},
+		{
+			name:  "同一个词出现多次",
+			words: []string{"aa"},
+			text:  "aaa",
+			want: []hit{
+				{word: 0, start: 0, end: 2},
+				{word: 0, start: 1, end: 3},
+			},
+		},
+		{
+			name:  "多字节字符",
+			words: []string{"敏感", "感词"},
+			text:  "有敏感词",
+			want: []hit{
+				{word: 0, start: 1, end: 3},
+				{word: 1, start: 2, end: 4},
+			},
+		},
+		{
+			name:  "忽略大小写",
+			words: []string{"Go"},
+			text:  "gO语言",
+			want: []hit{
+				{word: 0, start: 0, end: 2},
+			},
+		},
+		{
+			name:  "空的词跳过",
+			words: []string{"", "a"},
+			text:  "a",
+			want: []hit{
+				{word: 1, start: 0, end: 1},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			words := make([]Word, 0, len(tc.words))
+			for _, w := range tc.words {
+				words = append(words, Word{Word: w, Action: ActionMask})
+			}
+			hits := newAutomaton(words).match([]rune(tc.text))
+			assert.ElementsMatch(t, tc.want, hits)
+		})
+	}
+}
+
+func TestACFilter_Check(t *testing.T) {
+	testCases := []struct {
+		name  string
+		words []Word
+		text  string
+		want  Result
+	}{
+		{
+			name:  "没有命中",
+			words: []Word{{Word: "敏感", Action: ActionMask}},
+			text:  "正常内容",
+			want:  Result{Action: ActionPass, Text: "正常内容"},
+		},
+		{
+			name:  "替换多字节字符",
+			words: []Word{{Word: "敏感", Action: ActionMask}},
+			text:  "有a敏感b词",
+			want:  Result{Action: ActionMask, Text: "有a**b词", Words: []string{"敏感"}},
+		},
+		{
+			name: "重叠的词都替换掉",
+			words: []Word{
+				{Word: "敏感", Action: ActionMask},
+				{Word: "感词", Action: ActionMask},
+			},
+			text: "一个敏感词。",
+			want: Result{Action: ActionMask, Text: "一个***。", Words: []string{"敏感", "感词"}},
+		},
+		{
+			name: "嵌套的词",
+			words: []Word{
+				{Word: "坏人", Action: ActionMask},
+				{Word: "大坏人们", Action: ActionMask},
+			},
+			text: "一群大坏人们",
+			want: Result{Action: ActionMask, Text: "一群****", Words: []string{"坏人", "大坏人们"}},
+		},
+		{
+			name:  "同一个词出现多次只记一次",
+			words: []Word{{Word: "ab", Action: ActionMask}},
+			text:  "ab-AB",
+			want:  Result{Action: ActionMask, Text: "**-**", Words: []string{"ab"}},
+		},
+		{
+			name: "取最严重的处理方式，不需要替换的原样返回",
+			words: []Word{
+				{Word: "敏感", Action: ActionMask},
+				{Word: "违禁", Action: ActionBlock},
+			},
+			text: "敏感和违禁",
+			want: Result{Action: ActionBlock, Text: "**和违禁", Words: []string{"敏感", "违禁"}},
+		},
+		{
+			name:  "需要审核的不替换",
+			words: []Word{{Word: "审核", Action: ActionReview}},
+			text:  "要审核",
+			want:  Result{Action: ActionReview, Text: "要审核", Words: []string{"审核"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := NewACFilter(tc.words).Check(tc.text)
+			assert.Equal(t, tc.want.Action, res.Action)
+			assert.Equal(t, tc.want.Text, res.Text)
+			assert.ElementsMatch(t, tc.want.Words, res.Words)
+		})
+	}
+}
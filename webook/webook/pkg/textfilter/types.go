@@ -0,0 +1,62 @@
+package textfilter
+
+import "errors"
+
+// ErrBlocked 命中了需要拦截的敏感词
+var ErrBlocked = errors.New("内容包含敏感词")
+
+type Filter interface {
+	// Check 检查一段文本，返回最严重的那个处理方式
+	Check(text string) Result
+}
+
+// Action 命中敏感词之后的处理方式，越往后越严重
+type Action uint8
+
+const (
+	ActionPass Action = iota
+	// ActionMask 敏感词替换成 *，内容照常保存
+	ActionMask
+	// ActionReview 内容照常保存，但是要人工审核之后才能对外展示
+	ActionReview
+	// ActionBlock 直接拒绝
+	ActionBlock
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionPass:
+		return "pass"
+	case ActionMask:
+		return "mask"
+	case ActionReview:
+		return "review"
+	case ActionBlock:
+		return "block"
+	default:
+		return "unknown"
+	}
+}
+
+type Result struct {
+	Action Action
+	// Text 把需要 mask 的敏感词替换掉之后的文本，没有命中就是原文
+	Text string
+	// Words 命中的敏感词，去重过了
+	Words []string
+}
+
+// Word 字典里的一个敏感词
+type Word struct {
+	Word   string
+	Action Action
+}
+
+// MaskOrBlock 给没有人工审核流程的输入用的，需要审核的也直接拒绝
+func MaskOrBlock(f Filter, text string) (string, error) {
+	res := f.Check(text)
+	if res.Action >= ActionReview {
+		return text, ErrBlocked
+	}
+	return res.Text, nil
+}
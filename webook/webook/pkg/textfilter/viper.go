@@ -0,0 +1,58 @@
+package textfilter
+
+import (
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/logger"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// WatchDict 从 yaml 文件加载字典，文件变了之后自动重新加载
+// 文件的格式是：
+//
+//	block:
+//	  - 词1
+//	mask:
+//	  - 词2
+//	review:
+//	  - 词3
+//
+// 同一个词出现在多个列表里，以最严重的为准
+func WatchDict(path string, f *ACFilter, l logger.Logger) error {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	err := v.ReadInConfig()
+	if err != nil {
+		return err
+	}
+	f.Reload(wordsFromViper(v))
+
+	v.OnConfigChange(func(in fsnotify.Event) {
+		// 编辑器保存的时候可能先清空再写，读到半截的文件就保留旧的字典
+		err1 := v.ReadInConfig()
+		if err1 != nil {
+			l.Error("重新加载敏感词字典失败", logger.Error(err1),
+				logger.String("path", path))
+			return
+		}
+		words := wordsFromViper(v)
+		f.Reload(words)
+		l.Info("重新加载敏感词字典", logger.String("path", path),
+			logger.Int("count", len(words)))
+	})
+	v.WatchConfig()
+	return nil
+}
+
+func wordsFromViper(v *viper.Viper) []Word {
+	var words []Word
+	for _, a := range []Action{ActionBlock, ActionReview, ActionMask} {
+		for _, w := range v.GetStringSlice(a.String()) {
+			if w == "" {
+				continue
+			}
+			words = append(words, Word{Word: w, Action: a})
+		}
+	}
+	return words
+}
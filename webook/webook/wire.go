@@ -3,20 +3,27 @@
 package main
 
 import (
+	comment_repo "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/repository"
+	comment_cache "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/repository/cache"
+	comment_dao "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/repository/dao"
+	comment_service "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/service"
 	repository2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interacitve/repository"
 	cache2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interacitve/repository/cache"
 	dao2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interacitve/repository/dao"
 	service2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interacitve/service"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/events"
 	event_article "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events/article"
+	event_ranking "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events/ranking"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/key_expired_event"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao"
-	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/ioc"
+	media_repo "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/repository"
+	media_dao "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/repository/dao"
+	media_service "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/service"
 	schedule_repo "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/cronJobScheduler/repository"
 	schedule_dao "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/cronJobScheduler/repository/dao"
 	schedule_service "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/cronJobScheduler/service"
@@ -32,20 +39,86 @@ var interactiveSvcProvider = wire.NewSet(
 	cache2.NewRedisInteractiveCache,
 )
 
+var commentSvcProvider = wire.NewSet(
+	comment_service.NewCommentService,
+	comment_repo.NewCachedCommentRepository,
+	comment_dao.NewGORMCommentDAO,
+	comment_cache.NewRedisCommentCache,
+)
+
+var mediaSvcProvider = wire.NewSet(
+	media_service.NewMediaService,
+	media_repo.NewGORMMediaRepository,
+	media_dao.NewGORMMediaDAO,
+	ioc.InitObjectStore,
+)
+
 var articleServiceSet = wire.NewSet(
 	service.NewArticleService,
 	repository.NewCachedArticleRepository,
-	article.NewGORMArticleDAO,
+	// 默认是 GORM，可以配置成 S3；开启迁移之后由迁移的阶段决定读写哪边
+	ioc.InitArticleDAO,
+	ioc.InitArticleMigrator,
 	cache.NewRedisArticleCache,
 	//article.NewMongoArticle, //MongoDB
-	//article.NewOssDAO, //OSS
+)
+
+var moderationServiceSet = wire.NewSet(
+	service.NewModerationService,
+	repository.NewGORMModerationRepository,
+	dao.NewGORMModerationDAO,
+)
+
+var feedServiceSet = wire.NewSet(
+	service.NewFeedService,
+	repository.NewCachedFeedRepository,
+	cache.NewRedisFeedCache,
+)
+
+var seriesServiceSet = wire.NewSet(
+	service.NewSeriesService,
+	repository.NewCachedSeriesRepository,
+	dao.NewGORMSeriesDAO,
+	cache.NewRedisSeriesCache,
+)
+
+var membershipServiceSet = wire.NewSet(
+	service.NewMembershipService,
+	repository.NewCachedMembershipRepository,
+	dao.NewGORMMembershipDAO,
+	cache.NewRedisMembershipCache,
+	ioc.InitPaymentGateway,
+)
+
+var readHistoryServiceSet = wire.NewSet(
+	service.NewReadHistoryService,
+	repository.NewCachedReadHistoryRepository,
+	dao.NewGORMReadHistoryDAO,
+	cache.NewRedisReadHistoryCache,
+)
+
+var topLikeServiceSet = wire.NewSet(
+	service.NewTopLikeService,
+	repository.NewCachedTopLikeRepository,
+	cache.NewRedisTopLikeCache,
+)
+
+var articleTransferServiceSet = wire.NewSet(
+	service.NewArticleTransferService,
+	repository.NewCachedImportJobRepository,
+	cache.NewRedisImportJobCache,
 )
 
 var rankingServiceSet = wire.NewSet(
 	service.NewBatchRankingService,
+	service.NewRankingStreamService,
+	service.NewRankingSnapshotService,
+	repository.NewGORMRankingSnapshotRepository,
+	dao.NewGORMRankingSnapshotDAO,
 	repository.NewCachedRankingRepository,
 	ioc.InitLocalRankingCache,
 	ioc.InitRedisRankingCache,
+	ioc.InitRedisRankingScoreCache,
 	ioc.InitRedisLoadSortCache,
 )
 
@@ -53,6 +126,7 @@ var rankingServiceSet = wire.NewSet(
 var cronJobSchedulerSet = wire.NewSet(
 	ioc.InitCronJobScheduler,
 	ioc.InitLocalFuncExecutor,
+	ioc.InitArticleChecker,
 )
 
 var cronJobSvcProvider = wire.NewSet(
@@ -75,7 +149,7 @@ var codeSvcProvider = wire.NewSet(
 	cache.NewCodeCache,
 )
 
-func InitWebServer() *App {
+func InitWebServer() (*App, error) {
 	wire.Build(
 		// 最基础的第三方依赖
 		ioc.InitDB, ioc.InitRedis,
@@ -87,11 +161,15 @@ func InitWebServer() *App {
 		ioc.InitKafka,
 		ioc.NewSyncProducer,
 		ioc.NewConsumers,
+		ioc.InitArticleMigratorConsumer,
 
 		// consumer & producer
 		event_article.NewKafkaProducer,
 		//event_article.NewInteractiveReadEventBatchConsumer,
 		events.NewInteractiveReadEventConsumer,
+		event_article.NewReadHistoryConsumer,
+		event_ranking.NewRankingEventConsumer,
+		events.NewKafkaProducer,
 
 		// redis key expired notify
 		wire.Value(string("article")),
@@ -103,7 +181,18 @@ func InitWebServer() *App {
 
 		// Service
 		interactiveSvcProvider,
+		commentSvcProvider,
+		mediaSvcProvider,
 		articleServiceSet,
+		moderationServiceSet,
+		feedServiceSet,
+		articleTransferServiceSet,
+		seriesServiceSet,
+		membershipServiceSet,
+		readHistoryServiceSet,
+		topLikeServiceSet,
+		service.NewUserInteractiveService,
+		ioc.InitSitemapService,
 		rankingServiceSet,
 		codeSvcProvider,
 		userServiceSet,
@@ -111,6 +200,7 @@ func InitWebServer() *App {
 		cronJobSvcProvider,
 		cronJobSchedulerSet,
 
+		ioc.InitTextFilter,
 		ioc.InitWechatService,
 		// 直接基于内存实现
 		ioc.InitSMSService,
@@ -124,6 +214,17 @@ func InitWebServer() *App {
 		web.NewUserHandler,
 		web.NewOAuth2WechatHandler,
 		web.NewArticleHandler,
+		web.NewCommentHandler,
+		web.NewMediaHandler,
+		web.NewModerationHandler,
+		web.NewFeedHandler,
+		web.NewSitemapHandler,
+		web.NewArticleTransferHandler,
+		web.NewSeriesHandler,
+		web.NewMembershipHandler,
+		web.NewReadHistoryHandler,
+		web.NewCollectionHandler,
+		web.NewUserInteractiveHandler,
 		// 你中间件呢？
 		// 你注册路由呢？
 		// 你这个地方没有用到前面的任何东西
@@ -134,5 +235,5 @@ func InitWebServer() *App {
 		// 组装我这个结构体的所有字段
 		wire.Struct(new(App), "*"),
 	)
-	return new(App)
+	return new(App), nil
 }
@@ -7,20 +7,27 @@
 package main
 
 import (
+	repository4 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/repository"
+	cache3 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/repository/cache"
+	dao4 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/repository/dao"
+	service4 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/comment/service"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/events"
 	repository3 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository"
 	cache2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository/cache"
 	dao3 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/repository/dao"
 	service3 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/interactive/service"
 	article2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events/article"
+	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/events/ranking"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/key_expired_event"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/cache"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao"
-	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/repository/dao/article"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/service"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/internal/web"
 	"github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/ioc"
+	repository5 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/repository"
+	dao5 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/repository/dao"
+	service5 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/media/service"
 	repository2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/cronJobScheduler/repository"
 	dao2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/cronJobScheduler/repository/dao"
 	service2 "github.com/bolognagene/geektime-gocamp/geektime-gocamp/webook/webook/pkg/cronJobScheduler/service"
@@ -35,7 +42,7 @@ import (
 
 // Injectors from wire.go:
 
-func InitWebServer() *App {
+func InitWebServer() (*App, error) {
 	cmdable := ioc.InitRedis()
 	jwtHandler := ioc.InitRedisJWTHander(cmdable)
 	logger := ioc.InitLogger()
@@ -44,7 +51,8 @@ func InitWebServer() *App {
 	userDAO := dao.NewUserDAO(db)
 	userCache := ioc.InitUserCache(cmdable)
 	userRepository := repository.NewUserRepository(userDAO, userCache)
-	userService := service.NewUserService(userRepository)
+	textFilter := ioc.InitTextFilter(logger)
+	userService := service.NewUserService(userRepository, textFilter)
 	codeCache := cache.NewCodeCache(cmdable)
 	codeRepository := repository.NewCodeRepository(codeCache)
 	smsService := ioc.InitSMSService(cmdable)
@@ -53,42 +61,103 @@ func InitWebServer() *App {
 	wechatService := ioc.InitWechatService()
 	wechatHandlerConfig := ioc.NewWechatHandlerConfig()
 	oAuth2WechatHandler := web.NewOAuth2WechatHandler(wechatService, userService, wechatHandlerConfig)
-	articleDAO := article.NewGORMArticleDAO(db)
-	articleCache := cache.NewRedisArticleCache(cmdable)
-	articleRepository := repository.NewCachedArticleRepository(articleDAO, userDAO, articleCache, logger)
 	client := ioc.InitKafka()
 	syncProducer := ioc.NewSyncProducer(client)
-	producer := article2.NewKafkaProducer(syncProducer)
-	articleService := service.NewArticleService(articleRepository, producer, logger)
+	migratorMigrator := ioc.InitArticleMigrator(db, syncProducer, logger)
+	articleDAO := ioc.InitArticleDAO(db, migratorMigrator)
+	articleCache := cache.NewRedisArticleCache(cmdable)
+	articleRepository := repository.NewCachedArticleRepository(articleDAO, userRepository, articleCache, logger)
+	redisRankingCache := ioc.InitRedisRankingCache(cmdable)
+	localRankingCache := ioc.InitLocalRankingCache()
+	redisRankingScoreCache := ioc.InitRedisRankingScoreCache(cmdable)
+	rankingRepository := repository.NewCachedRankingRepository(redisRankingCache, localRankingCache, redisRankingScoreCache)
 	interactiveDAO := dao3.NewGORMInteractiveDAO(db)
 	interactiveCache := cache2.NewRedisInteractiveCache(cmdable)
 	interactiveRepository := repository3.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, cmdable, logger)
-	interactiveService := service3.NewInteractiveService(interactiveRepository)
-	articleHandler := web.NewArticleHandler(articleService, interactiveService, logger)
-	engine := ioc.InitWebServer(v, userHandler, oAuth2WechatHandler, articleHandler)
+	eventsProducer := events.NewKafkaProducer(syncProducer)
+	interactiveService := service3.NewInteractiveService(interactiveRepository, eventsProducer, logger)
+	mediaDAO := dao5.NewGORMMediaDAO(db)
+	mediaRepository := repository5.NewGORMMediaRepository(mediaDAO)
+	store := ioc.InitObjectStore()
+	mediaService := service5.NewMediaService(mediaRepository, store, logger)
+	producer := article2.NewKafkaProducer(syncProducer)
+	moderationDAO := dao.NewGORMModerationDAO(db)
+	moderationRepository := repository.NewGORMModerationRepository(moderationDAO)
+	membershipDAO := dao.NewGORMMembershipDAO(db)
+	membershipCache := cache.NewRedisMembershipCache(cmdable)
+	membershipRepository := repository.NewCachedMembershipRepository(membershipDAO, membershipCache, logger)
+	gateway := ioc.InitPaymentGateway()
+	membershipService := service.NewMembershipService(membershipRepository, gateway, logger)
+	feedCache := cache.NewRedisFeedCache(cmdable)
+	feedRepository := repository.NewCachedFeedRepository(feedCache)
+	feedService := service.NewFeedService(feedRepository, articleRepository, rankingRepository, userRepository, logger)
+	articleService := service.NewArticleService(articleRepository, rankingRepository, moderationRepository, interactiveService, mediaService, membershipService, feedService, textFilter, producer, logger)
+	seriesDAO := dao.NewGORMSeriesDAO(db)
+	seriesCache := cache.NewRedisSeriesCache(cmdable)
+	seriesRepository := repository.NewCachedSeriesRepository(seriesDAO, seriesCache, logger)
+	seriesService := service.NewSeriesService(seriesRepository, articleRepository, logger)
+	topLikeCache := cache.NewRedisTopLikeCache(cmdable)
+	topLikeRepository := repository.NewCachedTopLikeRepository(topLikeCache)
+	topLikeService := service.NewTopLikeService(topLikeRepository, articleRepository, interactiveService, logger)
+	rankingSnapshotDAO := dao.NewGORMRankingSnapshotDAO(db)
+	rankingSnapshotRepository := repository.NewGORMRankingSnapshotRepository(rankingSnapshotDAO)
+	rankingSnapshotService := service.NewRankingSnapshotService(rankingSnapshotRepository, articleRepository, logger)
+	rankingService, err := service.NewBatchRankingService(articleService, articleRepository, interactiveService, rankingRepository, moderationRepository, rankingSnapshotService, logger)
+	if err != nil {
+		return nil, err
+	}
+	articleHandler := web.NewArticleHandler(articleService, interactiveService, seriesService, topLikeService, rankingService, rankingSnapshotService, logger)
+	commentDAO := dao4.NewGORMCommentDAO(db)
+	commentCache := cache3.NewRedisCommentCache(cmdable)
+	commentRepository := repository4.NewCachedCommentRepository(commentDAO, commentCache, logger)
+	commentService := service4.NewCommentService(commentRepository, interactiveService, textFilter, logger)
+	commentHandler := web.NewCommentHandler(commentService, interactiveService, userService, logger)
+	mediaHandler := web.NewMediaHandler(mediaService, store, logger)
+	moderationService := service.NewModerationService(moderationRepository, articleRepository, feedService, producer, logger)
+	moderationHandler := web.NewModerationHandler(moderationService, logger)
+	feedHandler := web.NewFeedHandler(feedService, logger)
+	sitemapService := ioc.InitSitemapService(articleRepository, logger)
+	sitemapHandler := web.NewSitemapHandler(sitemapService, logger)
+	importJobCache := cache.NewRedisImportJobCache(cmdable)
+	importJobRepository := repository.NewCachedImportJobRepository(importJobCache)
+	articleTransferService := service.NewArticleTransferService(articleService, importJobRepository, logger)
+	articleTransferHandler := web.NewArticleTransferHandler(articleTransferService, logger)
+	seriesHandler := web.NewSeriesHandler(seriesService, interactiveService, logger)
+	membershipHandler := web.NewMembershipHandler(membershipService, logger)
+	readHistoryDAO := dao.NewGORMReadHistoryDAO(db)
+	readHistoryCache := cache.NewRedisReadHistoryCache(cmdable)
+	readHistoryRepository := repository.NewCachedReadHistoryRepository(readHistoryDAO, readHistoryCache, logger)
+	readHistoryService := service.NewReadHistoryService(readHistoryRepository, articleRepository, logger)
+	readHistoryHandler := web.NewReadHistoryHandler(readHistoryService, logger)
+	userInteractiveService := service.NewUserInteractiveService(interactiveService, articleRepository, logger)
+	collectionHandler := web.NewCollectionHandler(interactiveService, userInteractiveService, logger)
+	userInteractiveHandler := web.NewUserInteractiveHandler(userInteractiveService, logger)
+	engine := ioc.InitWebServer(v, userHandler, oAuth2WechatHandler, articleHandler, commentHandler, mediaHandler, moderationHandler, feedHandler, sitemapHandler, articleTransferHandler, seriesHandler, membershipHandler, readHistoryHandler, collectionHandler, userInteractiveHandler)
 	interactiveReadEventConsumer := events.NewInteractiveReadEventConsumer(client, interactiveRepository, logger)
-	v2 := ioc.NewConsumers(interactiveReadEventConsumer)
+	readHistoryConsumer := article2.NewReadHistoryConsumer(client, readHistoryRepository, logger)
+	rankingStreamService := service.NewRankingStreamService(rankingRepository, articleRepository, rankingSnapshotService, logger)
+	rankingEventConsumer := ranking.NewRankingEventConsumer(client, rankingStreamService, logger)
+	consumer := ioc.InitArticleMigratorConsumer(client, migratorMigrator, logger)
+	v2 := ioc.NewConsumers(interactiveReadEventConsumer, readHistoryConsumer, rankingEventConsumer, consumer)
 	string2 := _wireStringValue
 	topLikeKey := key_expired_event.NewTopLikeKey(interactiveRepository, logger, string2)
 	v3 := ioc.NewKeyExpiredKeys(topLikeKey)
 	handler := redisx.NewHandler(cmdable, v3)
-	redisRankingCache := ioc.InitRedisRankingCache(cmdable)
-	localRankingCache := ioc.InitLocalRankingCache()
-	rankingRepository := repository.NewCachedRankingRepository(redisRankingCache, localRankingCache)
-	rankingService := service.NewBatchRankingService(articleService, interactiveService, rankingRepository)
-	localFuncExecutor := ioc.InitLocalFuncExecutor(rankingService, logger)
+	checker := ioc.InitArticleChecker(articleDAO, logger)
+	localFuncExecutor := ioc.InitLocalFuncExecutor(rankingService, rankingStreamService, rankingSnapshotService, articleService, mediaService, sitemapService, migratorMigrator, checker, logger)
 	cronJobDAO := dao2.NewGORMCronJobDAO(db)
 	cronJobRepository := repository2.NewPreemptCronJobRepository(cronJobDAO)
 	duration := _wireDurationValue
 	cronJobService := service2.NewPreemptCronJobService(cronJobRepository, duration, logger)
 	cronJobScheduler := ioc.InitCronJobScheduler(logger, localFuncExecutor, cronJobService)
 	app := &App{
-		web:              engine,
-		consumers:        v2,
-		rh:               handler,
-		cronJobScheduler: cronJobScheduler,
+		web:                engine,
+		consumers:          v2,
+		rh:                 handler,
+		cronJobScheduler:   cronJobScheduler,
+		articleTransferSvc: articleTransferService,
 	}
-	return app
+	return app, nil
 }
 
 var (
@@ -100,12 +169,30 @@ var (
 
 var interactiveSvcProvider = wire.NewSet(service3.NewInteractiveService, repository3.NewCachedInteractiveRepository, dao3.NewGORMInteractiveDAO, cache2.NewRedisInteractiveCache)
 
-var articleServiceSet = wire.NewSet(service.NewArticleService, repository.NewCachedArticleRepository, article.NewGORMArticleDAO, cache.NewRedisArticleCache)
+var commentSvcProvider = wire.NewSet(service4.NewCommentService, repository4.NewCachedCommentRepository, dao4.NewGORMCommentDAO, cache3.NewRedisCommentCache)
+
+var mediaSvcProvider = wire.NewSet(service5.NewMediaService, repository5.NewGORMMediaRepository, dao5.NewGORMMediaDAO, ioc.InitObjectStore)
+
+var articleServiceSet = wire.NewSet(service.NewArticleService, repository.NewCachedArticleRepository, ioc.InitArticleDAO, ioc.InitArticleMigrator, cache.NewRedisArticleCache)
+
+var moderationServiceSet = wire.NewSet(service.NewModerationService, repository.NewGORMModerationRepository, dao.NewGORMModerationDAO)
+
+var feedServiceSet = wire.NewSet(service.NewFeedService, repository.NewCachedFeedRepository, cache.NewRedisFeedCache)
+
+var seriesServiceSet = wire.NewSet(service.NewSeriesService, repository.NewCachedSeriesRepository, dao.NewGORMSeriesDAO, cache.NewRedisSeriesCache)
+
+var membershipServiceSet = wire.NewSet(service.NewMembershipService, repository.NewCachedMembershipRepository, dao.NewGORMMembershipDAO, cache.NewRedisMembershipCache, ioc.InitPaymentGateway)
+
+var readHistoryServiceSet = wire.NewSet(service.NewReadHistoryService, repository.NewCachedReadHistoryRepository, dao.NewGORMReadHistoryDAO, cache.NewRedisReadHistoryCache)
+
+var topLikeServiceSet = wire.NewSet(service.NewTopLikeService, repository.NewCachedTopLikeRepository, cache.NewRedisTopLikeCache)
+
+var articleTransferServiceSet = wire.NewSet(service.NewArticleTransferService, repository.NewCachedImportJobRepository, cache.NewRedisImportJobCache)
 
-var rankingServiceSet = wire.NewSet(service.NewBatchRankingService, repository.NewCachedRankingRepository, ioc.InitLocalRankingCache, ioc.InitRedisRankingCache, ioc.InitRedisLoadSortCache)
+var rankingServiceSet = wire.NewSet(service.NewBatchRankingService, service.NewRankingStreamService, service.NewRankingSnapshotService, repository.NewGORMRankingSnapshotRepository, dao.NewGORMRankingSnapshotDAO, repository.NewCachedRankingRepository, ioc.InitLocalRankingCache, ioc.InitRedisRankingCache, ioc.InitRedisRankingScoreCache, ioc.InitRedisLoadSortCache)
 
 // 用于mysql任务调度的实现方式
-var cronJobSchedulerSet = wire.NewSet(ioc.InitCronJobScheduler, ioc.InitLocalFuncExecutor)
+var cronJobSchedulerSet = wire.NewSet(ioc.InitCronJobScheduler, ioc.InitLocalFuncExecutor, ioc.InitArticleChecker)
 
 var cronJobSvcProvider = wire.NewSet(wire.Value(time.Duration(time.Minute)), service2.NewPreemptCronJobService, repository2.NewPreemptCronJobRepository, dao2.NewGORMCronJobDAO)
 